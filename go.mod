@@ -1,30 +1,36 @@
 module github.com/favbox/gosky
 
-go 1.20
+go 1.22
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/bytedance/mockey v1.2.4
-	github.com/bytedance/sonic v1.9.2
+	github.com/bytedance/sonic v1.15.0
 	github.com/cloudwego/netpoll v0.3.2
 	github.com/fsnotify/fsnotify v1.6.0
-	github.com/stretchr/testify v1.8.4
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.10.0
 	github.com/zeromicro/go-zero v1.5.3
-	golang.org/x/net v0.10.0
-	golang.org/x/sys v0.8.0
+	golang.org/x/net v0.24.0
+	golang.org/x/sys v0.22.0
 	google.golang.org/protobuf v1.30.0
 )
 
 require (
-	github.com/bytedance/gopkg v0.0.0-20220413063733-65bf48ffb3a7 // indirect
-	github.com/chenzhuoyu/base64x v0.0.0-20221115062448-fe3a3abad311 // indirect
+	github.com/bytedance/gopkg v0.1.3 // indirect
+	github.com/bytedance/sonic/loader v0.5.0 // indirect
+	github.com/cloudwego/base64x v0.1.6 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
-	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
+	github.com/klauspost/cpuid/v2 v2.2.9 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/smartystreets/assertions v0.0.0-20180927180507-b2de0cb4f26d // indirect
 	github.com/smartystreets/goconvey v1.6.4 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,15 +1,34 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/favbox/gosky/wind/internal/bytestr"
 	"github.com/favbox/gosky/wind/internal/nocopy"
 	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/utils"
+	"github.com/favbox/gosky/wind/pkg/network/dialer"
 	"github.com/favbox/gosky/wind/pkg/protocol"
 	"github.com/favbox/gosky/wind/pkg/protocol/client"
+	"github.com/favbox/gosky/wind/pkg/protocol/http1"
+	"github.com/favbox/gosky/wind/pkg/protocol/http1/factory"
 	"github.com/favbox/gosky/wind/pkg/protocol/suite"
 )
 
+var (
+	errorInvalidURI          = errors.NewPublic("无效的网址")
+	errorLastMiddlewareExist = errors.NewPublic("最终中间件已存在")
+)
+
 // Client 实现 http 客户端。
 //
 // 禁止值拷贝 Client。可新建实例。
@@ -37,6 +56,342 @@ type Client struct {
 	mLock sync.Mutex
 	m     map[string]client.HostClient
 	ms    map[string]client.HostClient
-	//mws            Middleware // TODO 待实现客户端中间件
-	//lastMiddleware Middleware
+
+	mws            Middleware
+	lastMiddleware Middleware
+}
+
+// GetOptions 返回客户端选项。
+func (c *Client) GetOptions() *config.ClientOptions {
+	return c.options
+}
+
+// SetRetryIfFunc 设置重试决策函数。
+//
+// 注意：仅对之后新建的主机客户端生效。
+func (c *Client) SetRetryIfFunc(retryIf client.RetryIfFunc) {
+	c.RetryIfFunc = retryIf
+}
+
+// SetProxy 设置客户端代理。
+//
+// 注意：仅对之后新建的主机客户端生效。
+func (c *Client) SetProxy(p protocol.Proxy) {
+	c.Proxy = p
+}
+
+// SetClientFactory 设置用于创建主机客户端的工厂，默认为 HTTP1 客户端工厂。
+func (c *Client) SetClientFactory(cf suite.ClientFactory) {
+	c.clientFactory = cf
+}
+
+// Get 返回 url 的状态码和正文。
+//
+// dst 的内容将被正文替换并返回，若 dst 太小将分配新切片。
+//
+// 该函数遵循重定向。使用 Do* 可手动处理重定向。
+func (c *Client) Get(ctx context.Context, dst []byte, url string, requestOptions ...config.RequestOption) (statusCode int, body []byte, err error) {
+	return client.GetURL(ctx, dst, url, c, requestOptions...)
+}
+
+// GetTimeout 返回 url 的状态码和正文。
+//
+// dst 的内容将被正文替换并返回，若 dst 太小将分配新切片。
+//
+// 该函数遵循重定向。使用 Do* 可手动处理重定向。
+//
+// 若 url 内容无法在给定超时时长内获取，则返回 errTimeout。
+func (c *Client) GetTimeout(ctx context.Context, dst []byte, url string, timeout time.Duration, requestOptions ...config.RequestOption) (statusCode int, body []byte, err error) {
+	return client.GetURLTimeout(ctx, dst, url, timeout, c, requestOptions...)
+}
+
+// GetDeadline 返回 url 的状态码和正文。
+//
+// dst 的内容将被正文替换并返回，若 dst 太小将分配新切片。
+//
+// 该函数遵循重定向。使用 Do* 可手动处理重定向。
+//
+// 若 url 内容无法在给定截止时间前获取，则返回 errTimeout。
+func (c *Client) GetDeadline(ctx context.Context, dst []byte, url string, deadline time.Time, requestOptions ...config.RequestOption) (statusCode int, body []byte, err error) {
+	return client.GetURLDeadline(ctx, dst, url, deadline, c, requestOptions...)
+}
+
+// Post 使用给定的 POST 参数发送 POST 请求至 url。
+//
+// 响应正文将附加至 dst 并返回。若 dst 太小将分配新切片。
+//
+// 该函数遵循重定向。使用 Do* 可手动处理重定向。
+//
+// 若 postArgs 为空，则发送空 POST 正文。
+func (c *Client) Post(ctx context.Context, dst []byte, url string, postArgs *protocol.Args, requestOptions ...config.RequestOption) (statusCode int, body []byte, err error) {
+	return client.PostURL(ctx, dst, url, postArgs, c, requestOptions...)
+}
+
+// DoTimeout 执行给定的请求 req 并在给定的超时时间内等待响应。
+//
+// 该函数不遵循重定向。使用 Get* 用于遵循重定向。
+//
+// 若 resp 为空，则忽略 Response 处理。
+//
+// 若在给定超时时长内未收到响应，则返回 errTimeout。
+//
+// 推荐获取 req 和 resp 的方式为 AcquireRequest 和 AcquireResponse，在性能关键代码中可提升性能。
+func (c *Client) DoTimeout(ctx context.Context, req *protocol.Request, resp *protocol.Response, timeout time.Duration) error {
+	return client.DoTimeout(ctx, req, resp, timeout, c)
+}
+
+// DoDeadline 执行给定的请求 req 并等待响应直至到达截止时间。
+//
+// 该函数不遵循重定向。使用 Get* 用于遵循重定向。
+//
+// 若 resp 为空，则忽略 Response 处理。
+//
+// 若在截止时间前未收到响应，则返回 errTimeout。
+//
+// 推荐获取 req 和 resp 的方式为 AcquireRequest 和 AcquireResponse，在性能关键代码中可提升性能。
+func (c *Client) DoDeadline(ctx context.Context, req *protocol.Request, resp *protocol.Response, deadline time.Time) error {
+	return client.DoDeadline(ctx, req, resp, deadline, c)
+}
+
+// DoRedirects 执行给定的请求 req 并遵循至多 maxRedirectsCount 次的重定向。
+//
+// 若重定向次数超过 maxRedirectsCount，则返回 errTooManyRedirects。
+//
+// 推荐获取 req 和 resp 的方式为 AcquireRequest 和 AcquireResponse，在性能关键代码中可提升性能。
+func (c *Client) DoRedirects(ctx context.Context, req *protocol.Request, resp *protocol.Response, maxRedirectsCount int) error {
+	_, _, err := client.DoRequestFollowRedirects(ctx, req, resp, req.URI().String(), maxRedirectsCount, c)
+	return err
+}
+
+// Do 执行给定的 http 请求 req 并设置相应的 resp。
+//
+// Request 至少包含带有完整网址（包括 schema 和 主机）的非空 RequestURI，或 非空 Host 头 + RequestURI。
+//
+// 客户端根据 schema 和主机地址选择或新建对应的主机客户端。
+//
+// 该函数不遵循重定向。使用 Get* 用于遵循重定向。
+//
+// 若 resp 为空，则忽略 Response 处理。
+//
+// ErrNoFreeConns 将在到主机的所有 MaxConnsPerHost 连接都繁忙时返回。
+//
+// 推荐获取 req 和 resp 的方式为 AcquireRequest 和 AcquireResponse，在性能关键代码中可提升性能。
+func (c *Client) Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	next := Endpoint(c.do)
+	if c.lastMiddleware != nil {
+		next = c.lastMiddleware(next)
+	}
+	if c.mws != nil {
+		next = c.mws(next)
+	}
+	return next(ctx, req, resp)
+}
+
+// Use 添加客户端中间件，作用于之后发出的每个请求。
+//
+// 中间件按添加顺序由外向内执行：先添加的先处理请求、后处理响应。
+//
+// 注意：该方法不是协程安全的，应在发出请求前调用。
+func (c *Client) Use(mws ...Middleware) {
+	// 已有的中间件排在最前面
+	middlewares := make([]Middleware, 0, 1+len(mws))
+	if c.mws != nil {
+		middlewares = append(middlewares, c.mws)
+	}
+	middlewares = append(middlewares, mws...)
+	c.mws = chain(middlewares...)
+}
+
+// UseAsLast 设置最终中间件，它总是在 Use 添加的所有中间件之后、真实请求之前执行。
+//
+// 若已存在最终中间件，则返回错误。可先用 TakeOutLastMiddleware 取出。
+func (c *Client) UseAsLast(mw Middleware) error {
+	if c.lastMiddleware != nil {
+		return errorLastMiddlewareExist
+	}
+	c.lastMiddleware = mw
+	return nil
+}
+
+// TakeOutLastMiddleware 取出并返回最终中间件，若不存在则返回 nil。
+func (c *Client) TakeOutLastMiddleware() Middleware {
+	last := c.lastMiddleware
+	c.lastMiddleware = nil
+	return last
+}
+
+// CloseIdleConnections 关闭所有主机客户端中以前建立而当前闲置的长连接。
+// 不会中断当前正在使用的连接。
+func (c *Client) CloseIdleConnections() {
+	c.mLock.Lock()
+	for _, v := range c.m {
+		v.CloseIdleConnections()
+	}
+	for _, v := range c.ms {
+		v.CloseIdleConnections()
+	}
+	c.mLock.Unlock()
+}
+
+// GetDialerName 返回客户端所用拨号器的名称。
+func (c *Client) GetDialerName() (dName string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			dName = "unknown"
+			err = fmt.Errorf("获取拨号器名称出错：%v", r)
+		}
+	}()
+
+	opt := c.GetOptions()
+	if opt == nil || opt.Dialer == nil {
+		return "", fmt.Errorf("拨号器为空")
+	}
+
+	dName = reflect.TypeOf(opt.Dialer).String()
+	dSlice := strings.Split(dName, ".")
+	dName = strings.TrimPrefix(dSlice[0], "*")
+	return
+}
+
+func (c *Client) do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	if !c.options.KeepAlive {
+		req.Header.SetConnectionClose(true)
+	}
+
+	uri := req.URI()
+	if uri == nil {
+		return errorInvalidURI
+	}
+
+	var proxyURI *protocol.URI
+	var err error
+	if c.Proxy != nil {
+		proxyURI, err = c.Proxy(req)
+		if err != nil {
+			return fmt.Errorf("代理错误=%w", err)
+		}
+	}
+
+	isTLS := false
+	scheme := uri.Scheme()
+	if bytes.Equal(scheme, bytestr.StrHTTPS) {
+		isTLS = true
+	} else if !bytes.Equal(scheme, bytestr.StrHTTP) {
+		return fmt.Errorf("不支持的协议 %q。仅支持 http 和 https", scheme)
+	}
+
+	host := uri.Host()
+	if len(host) == 0 {
+		return errorInvalidURI
+	}
+
+	startCleaner := false
+
+	c.mLock.Lock()
+	m := c.m
+	if isTLS {
+		m = c.ms
+	}
+
+	h := string(host)
+	hc := m[h]
+	if hc == nil {
+		if c.clientFactory == nil {
+			// 默认加载 HTTP1 客户端工厂
+			c.clientFactory = factory.NewClientFactory(newHttp1OptionFromClient(c))
+		}
+		hc, err = c.clientFactory.NewHostClient()
+		if err != nil {
+			c.mLock.Unlock()
+			return err
+		}
+		hc.SetDynamicConfig(&client.DynamicConfig{
+			Addr:     utils.AddMissingPort(h, isTLS),
+			ProxyURI: proxyURI,
+			IsTLS:    isTLS,
+		})
+		m[h] = hc
+		if len(m) == 1 {
+			startCleaner = true
+		}
+	}
+	c.mLock.Unlock()
+
+	if startCleaner {
+		go c.mCleaner(m)
+	}
+
+	return hc.Do(ctx, req, resp)
+}
+
+// 定期清理无需保留的主机客户端，映射为空时退出。
+func (c *Client) mCleaner(m map[string]client.HostClient) {
+	mustStop := false
+
+	for {
+		c.mLock.Lock()
+		for k, v := range m {
+			if v.ShouldRemove() {
+				delete(m, k)
+				if f, ok := v.(io.Closer); ok {
+					if err := f.Close(); err != nil {
+						hlog.SystemLogger().Warnf("清理主机客户端出错，地址=%s，错误=%s", k, err.Error())
+					}
+				}
+			}
+		}
+		if len(m) == 0 {
+			mustStop = true
+		}
+		c.mLock.Unlock()
+
+		if mustStop {
+			break
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// NewClient 创建应用给定选项的 http 客户端。
+func NewClient(opts ...config.ClientOption) (*Client, error) {
+	opt := config.NewClientOptions(opts)
+	if opt.Dialer == nil {
+		opt.Dialer = dialer.DefaultDialer()
+	}
+
+	c := &Client{
+		options: opt,
+		m:       make(map[string]client.HostClient),
+		ms:      make(map[string]client.HostClient),
+	}
+	if len(defaultMiddlewares) > 0 {
+		c.Use(defaultMiddlewares...)
+	}
+
+	return c, nil
+}
+
+func newHttp1OptionFromClient(c *Client) *http1.ClientOptions {
+	return &http1.ClientOptions{
+		Name:                          c.options.Name,
+		NoDefaultUserAgentHeader:      c.options.NoDefaultUserAgentHeader,
+		Dialer:                        c.options.Dialer,
+		DialTimeout:                   c.options.DialTimeout,
+		DialDualStack:                 c.options.DialDualStack,
+		TLSConfig:                     c.options.TLSConfig,
+		MaxConns:                      c.options.MaxConnsPerHost,
+		MaxConnDuration:               c.options.MaxConnDuration,
+		MaxIdleConnDuration:           c.options.MaxIdleConnDuration,
+		ReadTimeout:                   c.options.ReadTimeout,
+		WriteTimeout:                  c.options.WriteTimeout,
+		MaxResponseBodySize:           c.options.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: c.options.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        c.options.DisablePathNormalizing,
+		MaxConnWaitTimeout:            c.options.MaxConnWaitTimeout,
+		ResponseBodyStream:            c.options.ResponseBodyStream,
+		RetryConfig:                   c.options.RetryConfig,
+		RetryIfFunc:                   c.RetryIfFunc,
+		StateObserve:                  c.options.HostClientStateObserve,
+		ObservationInterval:           c.options.ObservationInterval,
+	}
 }
@@ -1,47 +1,236 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/retry"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/common/test/mock"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
 )
 
-var errTooManyRedirects = errors.New("too many redirects detected when doing the request")
-
-//
-//func TestCloseIdleConnections(t *testing.T) {
-//	opt := config.NewOptions([]config.Option{})
-//	opt.Addr = "unix-test-10000"
-//	opt.Network = "unix"
-//	engine := route.NewEngine(opt)
-//
-//	go engine.Run()
-//	defer func() {
-//		engine.Close()
-//	}()
-//	time.Sleep(time.Millisecond * 500)
-//
-//	c, _ := NewClient(WithDialer(newMockDialerWithCustomFunc(opt.Network, opt.Addr, 1*time.Second, nil)))
-//
-//	if _, _, err := c.Get(context.Background(), nil, "http://google.com"); err != nil {
-//		t.Fatal(err)
-//	}
-//
-//	connsLen := func() int {
-//		c.mLock.Lock()
-//		defer c.mLock.Unlock()
-//
-//		if _, ok := c.m["google.com"]; !ok {
-//			return 0
-//		}
-//		return c.m["google.com"].ConnectionCount()
-//	}
-//
-//	if conns := connsLen(); conns > 1 {
-//		t.Errorf("expected 1 conns got %d", conns)
-//	}
-//
-//	c.CloseIdleConnections()
-//
-//	if conns := connsLen(); conns > 0 {
-//		t.Errorf("expected 0 conns got %d", conns)
-//	}
-//}
+const okResponse = "HTTP/1.1 200 OK\r\nContent-Length: 10\r\nContent-Type: foo/bar\r\n\r\n0123456789"
+
+type mockDialer struct {
+	mu    sync.Mutex
+	addrs []string
+	dial  func(addr string) (network.Conn, error)
+}
+
+func (m *mockDialer) DialConnection(network, address string, timeout time.Duration, tlsConfig *tls.Config) (conn network.Conn, err error) {
+	m.mu.Lock()
+	m.addrs = append(m.addrs, address)
+	m.mu.Unlock()
+	return m.dial(address)
+}
+
+func (m *mockDialer) DialTimeout(network, address string, timeout time.Duration, tlsConfig *tls.Config) (conn net.Conn, err error) {
+	return nil, nil
+}
+
+func (m *mockDialer) AddTLS(conn network.Conn, tlsConfig *tls.Config) (network.Conn, error) {
+	return conn, nil
+}
+
+func newMockDialer(dial func(addr string) (network.Conn, error)) *mockDialer {
+	return &mockDialer{dial: dial}
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := NewClient(WithDialTimeout(2*time.Second), WithMaxConnsPerHost(10), WithKeepAlive(false))
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 2*time.Second, c.GetOptions().DialTimeout)
+	assert.DeepEqual(t, 10, c.GetOptions().MaxConnsPerHost)
+	assert.False(t, c.GetOptions().KeepAlive)
+	assert.True(t, c.GetOptions().Dialer != nil)
+}
+
+func TestClientDo(t *testing.T) {
+	d := newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})
+	c, _ := NewClient(WithDialer(d))
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	defer func() {
+		protocol.ReleaseRequest(req)
+		protocol.ReleaseResponse(resp)
+	}()
+
+	req.SetRequestURI("http://foobar/baz")
+	err := c.Do(context.Background(), req, resp)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, consts.StatusOK, resp.StatusCode())
+	assert.DeepEqual(t, "0123456789", string(resp.Body()))
+
+	req.SetRequestURI("https://foobar/baz")
+	err = c.Do(context.Background(), req, resp)
+	assert.Nil(t, err)
+
+	c.mLock.Lock()
+	assert.NotNil(t, c.m["foobar"])
+	assert.NotNil(t, c.ms["foobar"])
+	c.mLock.Unlock()
+	assert.DeepEqual(t, []string{"foobar:80", "foobar:443"}, d.addrs)
+}
+
+func TestClientDoUnsupportedScheme(t *testing.T) {
+	c, _ := NewClient(WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})))
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("ftp://foobar/baz")
+	err := c.Do(context.Background(), req, resp)
+	assert.NotNil(t, err)
+}
+
+func TestClientGetAndPost(t *testing.T) {
+	c, _ := NewClient(WithKeepAlive(false), WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})))
+
+	statusCode, body, err := c.Get(context.Background(), nil, "http://foobar/baz")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, consts.StatusOK, statusCode)
+	assert.DeepEqual(t, "0123456789", string(body))
+
+	args := &protocol.Args{}
+	args.Add("a", "b")
+	statusCode, body, err = c.Post(context.Background(), nil, "http://foobar/baz", args)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, consts.StatusOK, statusCode)
+	assert.DeepEqual(t, "0123456789", string(body))
+}
+
+func TestClientDoRedirects(t *testing.T) {
+	var times int
+	c, _ := NewClient(WithKeepAlive(false), WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		times++
+		if times == 1 {
+			return mock.NewConn("HTTP/1.1 302 Found\r\nLocation: /qux\r\nContent-Length: 0\r\n\r\n"), nil
+		}
+		return mock.NewConn(okResponse), nil
+	})))
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	err := c.DoRedirects(context.Background(), req, resp, 1)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, consts.StatusOK, resp.StatusCode())
+	assert.DeepEqual(t, "/qux", string(req.URI().Path()))
+}
+
+func TestClientDoTimeout(t *testing.T) {
+	c, _ := NewClient(WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})))
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	assert.NotNil(t, c.DoTimeout(context.Background(), req, resp, 0))
+	assert.NotNil(t, c.DoDeadline(context.Background(), req, resp, time.Now().Add(-time.Second)))
+	assert.Nil(t, c.DoTimeout(context.Background(), req, resp, time.Second))
+}
+
+func TestClientRetry(t *testing.T) {
+	var times int
+	c, _ := NewClient(
+		WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+			times++
+			if times < 3 {
+				return nil, errors.New("拨号失败")
+			}
+			return mock.NewConn(okResponse), nil
+		})),
+		WithRetryConfig(retry.WithMaxAttemptTimes(3)),
+	)
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	err := c.Do(context.Background(), req, resp)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 3, times)
+	assert.DeepEqual(t, consts.StatusOK, resp.StatusCode())
+}
+
+func TestClientRetryIfFunc(t *testing.T) {
+	var times int
+	c, _ := NewClient(
+		WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+			times++
+			return nil, errors.New("拨号失败")
+		})),
+		WithRetryConfig(retry.WithMaxAttemptTimes(5)),
+	)
+	c.SetRetryIfFunc(func(req *protocol.Request, resp *protocol.Response, err error) bool {
+		return times < 2
+	})
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	err := c.Do(context.Background(), req, resp)
+	assert.NotNil(t, err)
+	assert.DeepEqual(t, 2, times)
+}
+
+func TestClientProxy(t *testing.T) {
+	d := newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})
+	c, _ := NewClient(WithDialer(d))
+	c.SetProxy(protocol.ProxyURI(protocol.ParseURI("http://127.0.0.1:8080")))
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	err := c.Do(context.Background(), req, resp)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, []string{"127.0.0.1:8080"}, d.addrs)
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	srv := &http.Server{
+		Addr: "127.0.0.1:10021",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello world"))
+		}),
+	}
+	go srv.ListenAndServe()
+	defer srv.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	c, _ := NewClient()
+
+	if _, _, err := c.Get(context.Background(), nil, "http://127.0.0.1:10021"); err != nil {
+		t.Fatal(err)
+	}
+
+	connsLen := func() int {
+		c.mLock.Lock()
+		defer c.mLock.Unlock()
+
+		if _, ok := c.m["127.0.0.1:10021"]; !ok {
+			return 0
+		}
+		return c.m["127.0.0.1:10021"].ConnectionCount()
+	}
+
+	if conns := connsLen(); conns > 1 {
+		t.Errorf("期望 1 个连接，实际 %d 个", conns)
+	}
+
+	c.CloseIdleConnections()
+
+	if conns := connsLen(); conns > 0 {
+		t.Errorf("期望 0 个连接，实际 %d 个", conns)
+	}
+}
@@ -0,0 +1,143 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/favbox/gosky/wind/pkg/app/server/registry"
+	"github.com/favbox/gosky/wind/pkg/common/utils"
+)
+
+// TargetInfo 是待解析的目标服务信息。
+type TargetInfo struct {
+	Host string            // 服务名称，取自请求网址的主机
+	Tags map[string]string // 实例须满足的标签，取自请求选项
+}
+
+// Resolver 定义服务发现所需实现的接口。
+type Resolver interface {
+	// Target 返回目标服务的描述符，同一描述符的解析结果会被缓存和复用。
+	Target(ctx context.Context, target *TargetInfo) string
+
+	// Resolve 解析描述符对应的服务实例。
+	Resolve(ctx context.Context, desc string) (Result, error)
+
+	// Watch 监听描述符对应的实例变化，每次变化都会发送最新的解析结果，
+	// ctx 结束后关闭通道。
+	//
+	// 若不支持推送，则返回空通道，调用方将按间隔定期调用 Resolve 刷新。
+	Watch(ctx context.Context, desc string) (<-chan Result, error)
+
+	// Name 返回解析器名称。
+	Name() string
+}
+
+// Result 是服务发现的解析结果。
+type Result struct {
+	CacheKey  string
+	Instances []Instance
+}
+
+// Instance 是服务实例的信息。
+type Instance interface {
+	Address() net.Addr
+	Weight() int
+	Tag(key string) (value string, exist bool)
+}
+
+type instance struct {
+	addr   net.Addr
+	weight int
+	tags   map[string]string
+}
+
+func (i *instance) Address() net.Addr {
+	return i.addr
+}
+
+func (i *instance) Weight() int {
+	return i.weight
+}
+
+func (i *instance) Tag(key string) (value string, exist bool) {
+	value, exist = i.tags[key]
+	return
+}
+
+// NewInstance 创建服务实例，权重小于等于 0 时使用 registry.DefaultWeight。
+func NewInstance(network, address string, weight int, tags map[string]string) Instance {
+	if weight <= 0 {
+		weight = registry.DefaultWeight
+	}
+	return &instance{
+		addr:   utils.NewNetAddr(network, address),
+		weight: weight,
+		tags:   tags,
+	}
+}
+
+// NewInstanceFromInfo 由服务注册信息创建服务实例，沿用其发布的地址、权重和标签。
+func NewInstanceFromInfo(info *registry.Info) Instance {
+	weight := info.Weight
+	if weight <= 0 {
+		weight = registry.DefaultWeight
+	}
+	return &instance{
+		addr:   info.Addr,
+		weight: weight,
+		tags:   info.Tags,
+	}
+}
+
+// TargetDesc 将目标服务信息编码为描述符，形如 "echo" 或 "echo?env=prod"，标签按键排序。
+func TargetDesc(target *TargetInfo) string {
+	if len(target.Tags) == 0 {
+		return target.Host
+	}
+	values := make(url.Values, len(target.Tags))
+	for k, v := range target.Tags {
+		values.Set(k, v)
+	}
+	return target.Host + "?" + values.Encode()
+}
+
+// ParseTargetDesc 解析 TargetDesc 生成的描述符。
+func ParseTargetDesc(desc string) *TargetInfo {
+	host, query, found := strings.Cut(desc, "?")
+	target := &TargetInfo{Host: host}
+	if !found {
+		return target
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return target
+	}
+	target.Tags = make(map[string]string, len(values))
+	for k := range values {
+		target.Tags[k] = values.Get(k)
+	}
+	return target
+}
+
+// FilterByTags 返回拥有全部给定标签的实例。
+func FilterByTags(instances []Instance, tags map[string]string) []Instance {
+	if len(tags) == 0 {
+		return instances
+	}
+	filtered := make([]Instance, 0, len(instances))
+	for _, ins := range instances {
+		matched := true
+		for k, v := range tags {
+			if tv, ok := ins.Tag(k); !ok || tv != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filtered = append(filtered, ins)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/server/registry"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+)
+
+func TestTargetDesc(t *testing.T) {
+	assert.DeepEqual(t, "echo", TargetDesc(&TargetInfo{Host: "echo"}))
+
+	desc := TargetDesc(&TargetInfo{Host: "echo", Tags: map[string]string{"env": "prod", "az": "a"}})
+	assert.DeepEqual(t, "echo?az=a&env=prod", desc)
+
+	target := ParseTargetDesc(desc)
+	assert.DeepEqual(t, "echo", target.Host)
+	assert.DeepEqual(t, map[string]string{"env": "prod", "az": "a"}, target.Tags)
+}
+
+func TestNewInstance(t *testing.T) {
+	ins := NewInstance("tcp", "127.0.0.1:8888", 0, map[string]string{"env": "prod"})
+	assert.DeepEqual(t, registry.DefaultWeight, ins.Weight())
+	assert.DeepEqual(t, "127.0.0.1:8888", ins.Address().String())
+	v, ok := ins.Tag("env")
+	assert.True(t, ok)
+	assert.DeepEqual(t, "prod", v)
+
+	addr, _ := net.ResolveTCPAddr("tcp", "127.0.0.1:9999")
+	ins = NewInstanceFromInfo(&registry.Info{ServiceName: "echo", Addr: addr, Weight: 20, Tags: map[string]string{"az": "b"}})
+	assert.DeepEqual(t, 20, ins.Weight())
+	assert.DeepEqual(t, "127.0.0.1:9999", ins.Address().String())
+	v, _ = ins.Tag("az")
+	assert.DeepEqual(t, "b", v)
+}
+
+func TestStaticResolver(t *testing.T) {
+	r := NewStaticResolver(map[string][]Instance{
+		"echo": {
+			NewInstance("tcp", "127.0.0.1:8001", 10, map[string]string{"env": "prod"}),
+			NewInstance("tcp", "127.0.0.1:8002", 10, map[string]string{"env": "test"}),
+		},
+	})
+
+	ctx := context.Background()
+	desc := r.Target(ctx, &TargetInfo{Host: "echo", Tags: map[string]string{"env": "prod"}})
+	res, err := r.Resolve(ctx, desc)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, desc, res.CacheKey)
+	assert.DeepEqual(t, 1, len(res.Instances))
+	assert.DeepEqual(t, "127.0.0.1:8001", res.Instances[0].Address().String())
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	ch, err := r.Watch(watchCtx, desc)
+	assert.Nil(t, err)
+
+	r.Update("echo", []Instance{NewInstance("tcp", "127.0.0.1:8003", 10, map[string]string{"env": "prod"})})
+	select {
+	case res = <-ch:
+		assert.DeepEqual(t, "127.0.0.1:8003", res.Instances[0].Address().String())
+	case <-time.After(time.Second):
+		t.Fatal("未收到实例变化")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("订阅通道未关闭")
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.json")
+	err := os.WriteFile(path, []byte(`{"echo":[{"addr":"127.0.0.1:8001","weight":5,"tags":{"env":"prod"}}]}`), 0o644)
+	assert.Nil(t, err)
+
+	r, err := NewFileResolver(path)
+	assert.Nil(t, err)
+	defer r.Close()
+	assert.DeepEqual(t, "file", r.Name())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	desc := r.Target(ctx, &TargetInfo{Host: "echo"})
+	res, err := r.Resolve(ctx, desc)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 1, len(res.Instances))
+	assert.DeepEqual(t, 5, res.Instances[0].Weight())
+	assert.DeepEqual(t, "tcp", res.Instances[0].Address().Network())
+
+	ch, _ := r.Watch(ctx, desc)
+	err = os.WriteFile(path, []byte(`{"echo":[{"addr":"127.0.0.1:8001"},{"addr":"127.0.0.1:8002"}]}`), 0o644)
+	assert.Nil(t, err)
+	select {
+	case res = <-ch:
+		assert.DeepEqual(t, 2, len(res.Instances))
+	case <-time.After(3 * time.Second):
+		t.Fatal("文件变化后未重载")
+	}
+
+	_, err = NewFileResolver(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(t, err)
+}
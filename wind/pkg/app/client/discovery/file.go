@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/json"
+	"github.com/fsnotify/fsnotify"
+)
+
+var _ Resolver = (*FileResolver)(nil)
+
+// FileResolver 是基于本地 JSON 文件的服务解析器，文件变化后自动重载并通知订阅者。
+//
+// 文件内容为服务名到实例列表的映射，字段与 registry.Info 发布的信息对应：
+//
+//	{
+//		"echo": [
+//			{"addr": "127.0.0.1:8888", "weight": 10, "tags": {"env": "prod"}},
+//			{"network": "tcp", "addr": "127.0.0.1:8889", "weight": 20}
+//		]
+//	}
+type FileResolver struct {
+	*StaticResolver
+
+	path    string
+	watcher *fsnotify.Watcher
+}
+
+type fileInstance struct {
+	Network string            `json:"network"`
+	Addr    string            `json:"addr"`
+	Weight  int               `json:"weight"`
+	Tags    map[string]string `json:"tags"`
+}
+
+// NewFileResolver 加载给定文件并创建文件解析器。
+func NewFileResolver(path string) (*FileResolver, error) {
+	r := &FileResolver{
+		StaticResolver: NewStaticResolver(nil),
+		path:           path,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// 监视所在目录，以兼容编辑器先写临时文件再重命名的保存方式
+	if err = watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	r.watcher = watcher
+	go r.watch()
+
+	return r, nil
+}
+
+// Name 实现 Resolver 接口。
+func (r *FileResolver) Name() string {
+	return "file"
+}
+
+// Reload 重新加载文件内容。
+func (r *FileResolver) Reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string][]fileInstance
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	services := make(map[string][]Instance, len(raw))
+	for name, list := range raw {
+		instances := make([]Instance, 0, len(list))
+		for _, fi := range list {
+			network := fi.Network
+			if network == "" {
+				network = "tcp"
+			}
+			instances = append(instances, NewInstance(network, fi.Addr, fi.Weight, fi.Tags))
+		}
+		services[name] = instances
+	}
+	r.Reset(services)
+	return nil
+}
+
+// Close 停止监视文件。
+func (r *FileResolver) Close() error {
+	return r.watcher.Close()
+}
+
+func (r *FileResolver) watch() {
+	target := filepath.Clean(r.path)
+	for {
+		select {
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != target || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				hlog.SystemLogger().Errorf("[服务发现] 重载文件 %s 出错：%v", r.path, err)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			hlog.SystemLogger().Errorf("[服务发现] 监视文件 %s 出错：%v", r.path, err)
+		}
+	}
+}
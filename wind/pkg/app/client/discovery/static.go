@@ -0,0 +1,119 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Resolver = (*StaticResolver)(nil)
+
+// StaticResolver 是基于内存静态列表的服务解析器，适用于测试和固定拓扑。
+//
+// 可通过 Update 更新实例，变化会推送给 Watch 的订阅者。
+type StaticResolver struct {
+	mu       sync.RWMutex
+	services map[string][]Instance
+	watchers map[string]map[chan Result]struct{}
+}
+
+// NewStaticResolver 创建给定服务名与实例映射的静态解析器。
+func NewStaticResolver(services map[string][]Instance) *StaticResolver {
+	r := &StaticResolver{
+		services: make(map[string][]Instance, len(services)),
+		watchers: make(map[string]map[chan Result]struct{}),
+	}
+	for name, ins := range services {
+		r.services[name] = ins
+	}
+	return r
+}
+
+// Target 实现 Resolver 接口。
+func (r *StaticResolver) Target(_ context.Context, target *TargetInfo) string {
+	return TargetDesc(target)
+}
+
+// Resolve 实现 Resolver 接口。
+func (r *StaticResolver) Resolve(_ context.Context, desc string) (Result, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolve(desc), nil
+}
+
+// Watch 实现 Resolver 接口。
+func (r *StaticResolver) Watch(ctx context.Context, desc string) (<-chan Result, error) {
+	ch := make(chan Result, 1)
+
+	r.mu.Lock()
+	subs := r.watchers[desc]
+	if subs == nil {
+		subs = make(map[chan Result]struct{})
+		r.watchers[desc] = subs
+	}
+	subs[ch] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		delete(r.watchers[desc], ch)
+		if len(r.watchers[desc]) == 0 {
+			delete(r.watchers, desc)
+		}
+		close(ch)
+		r.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+// Name 实现 Resolver 接口。
+func (r *StaticResolver) Name() string {
+	return "static"
+}
+
+// Update 更新给定服务的实例列表，并通知订阅者。
+func (r *StaticResolver) Update(service string, instances []Instance) {
+	r.mu.Lock()
+	r.services[service] = instances
+	r.notify(func(name string) bool { return name == service })
+	r.mu.Unlock()
+}
+
+// Reset 替换全部服务的实例列表，并通知订阅者。
+func (r *StaticResolver) Reset(services map[string][]Instance) {
+	r.mu.Lock()
+	r.services = make(map[string][]Instance, len(services))
+	for name, ins := range services {
+		r.services[name] = ins
+	}
+	r.notify(func(string) bool { return true })
+	r.mu.Unlock()
+}
+
+func (r *StaticResolver) resolve(desc string) Result {
+	target := ParseTargetDesc(desc)
+	return Result{
+		CacheKey:  desc,
+		Instances: FilterByTags(r.services[target.Host], target.Tags),
+	}
+}
+
+// 向匹配服务的订阅者推送最新结果，调用方需持有写锁。
+//
+// 订阅通道仅缓存最新一次结果，未及时消费的旧结果会被丢弃。
+func (r *StaticResolver) notify(match func(service string) bool) {
+	for desc, subs := range r.watchers {
+		if !match(ParseTargetDesc(desc).Host) {
+			continue
+		}
+		res := r.resolve(desc)
+		for ch := range subs {
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- res
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package loadbalance
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/favbox/gosky/air/gopkg/lang/fastrand"
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+var _ Loadbalancer = (*consistentHashBalancer)(nil)
+
+// KeyFunc 返回用于一致性哈希的请求键，如用户 ID 或会话 ID。
+type KeyFunc func(ctx context.Context, req *protocol.Request) string
+
+// ConsistentHashOption 一致性哈希负载均衡的选项。
+type ConsistentHashOption struct {
+	// 返回请求键的函数。若返回空串，则随机选取实例。
+	KeyFunc KeyFunc
+
+	// 每单位权重对应的虚拟节点数，默认 10。
+	// 实例的虚拟节点总数为 权重 * VirtualFactor。
+	VirtualFactor int
+}
+
+// NewConsistentHashBalancer 创建一致性哈希负载均衡器。
+//
+// 相同请求键总是落到同一实例，实例增减时仅影响少量键的映射。
+func NewConsistentHashBalancer(opt ConsistentHashOption) Loadbalancer {
+	if opt.VirtualFactor <= 0 {
+		opt.VirtualFactor = 10
+	}
+	return &consistentHashBalancer{opt: opt}
+}
+
+type consistentHashBalancer struct {
+	opt   ConsistentHashOption
+	rings sync.Map // cacheKey -> *hashRing
+}
+
+type hashRing struct {
+	hashes []uint32
+	nodes  map[uint32]discovery.Instance
+	list   []discovery.Instance
+}
+
+func newHashRing(instances []discovery.Instance, factor int) *hashRing {
+	r := &hashRing{
+		nodes: make(map[uint32]discovery.Instance),
+		list:  make([]discovery.Instance, 0, len(instances)),
+	}
+	for _, ins := range instances {
+		w := ins.Weight()
+		if w <= 0 {
+			continue
+		}
+		r.list = append(r.list, ins)
+		addr := ins.Address().String()
+		for i := 0; i < w*factor; i++ {
+			h := crc32.ChecksumIEEE([]byte(addr + "#" + strconv.Itoa(i)))
+			// 哈希冲突时保留先加入的节点
+			if _, exist := r.nodes[h]; exist {
+				continue
+			}
+			r.nodes[h] = ins
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+func (r *hashRing) get(key string) discovery.Instance {
+	if len(r.hashes) == 0 {
+		return nil
+	}
+	if key == "" {
+		return r.list[fastrand.Intn(len(r.list))]
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.nodes[r.hashes[idx]]
+}
+
+// Pick 实现 Loadbalancer 接口。
+func (b *consistentHashBalancer) Pick(ctx context.Context, req *protocol.Request, e discovery.Result) discovery.Instance {
+	if len(e.Instances) == 0 {
+		return nil
+	}
+	r, ok := b.rings.Load(e.CacheKey)
+	if !ok {
+		r, _ = b.rings.LoadOrStore(e.CacheKey, newHashRing(e.Instances, b.opt.VirtualFactor))
+	}
+
+	var key string
+	if b.opt.KeyFunc != nil {
+		key = b.opt.KeyFunc(ctx, req)
+	}
+	return r.(*hashRing).get(key)
+}
+
+// Rebalance 实现 Loadbalancer 接口。
+func (b *consistentHashBalancer) Rebalance(e discovery.Result) {
+	b.rings.Store(e.CacheKey, newHashRing(e.Instances, b.opt.VirtualFactor))
+}
+
+// Delete 实现 Loadbalancer 接口。
+func (b *consistentHashBalancer) Delete(cacheKey string) {
+	b.rings.Delete(cacheKey)
+}
+
+// Name 实现 Loadbalancer 接口。
+func (b *consistentHashBalancer) Name() string {
+	return "consistent_hash"
+}
@@ -0,0 +1,180 @@
+package loadbalance
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+var errNoInstance = errors.NewPublic("服务发现：无可用的服务实例")
+
+// Config 负载均衡工厂的配置。
+type Config struct {
+	Resolver discovery.Resolver
+	Balancer Loadbalancer
+	LbOpts   Options
+}
+
+// BalancerFactory 缓存各目标服务的解析结果，并按需刷新、过期，为请求选取实例。
+type BalancerFactory struct {
+	Config
+
+	mu    sync.Mutex
+	cache sync.Map // desc -> *cachedResult
+}
+
+type cachedResult struct {
+	factory    *BalancerFactory
+	desc       string
+	res        atomic.Value // discovery.Result
+	lastAccess int64
+	cancel     context.CancelFunc
+}
+
+// NewBalancerFactory 创建负载均衡工厂。
+func NewBalancerFactory(config Config) *BalancerFactory {
+	config.LbOpts.Check()
+	return &BalancerFactory{Config: config}
+}
+
+// GetInstance 解析请求主机对应的服务，并选取一个实例。
+//
+// 请求选项中的标签会作为目标服务的过滤条件传给解析器。
+func (f *BalancerFactory) GetInstance(ctx context.Context, req *protocol.Request) (discovery.Instance, error) {
+	desc := f.Resolver.Target(ctx, &discovery.TargetInfo{
+		Host: string(req.Host()),
+		Tags: req.Options().Tags(),
+	})
+
+	cr, err := f.get(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := f.Balancer.Pick(ctx, req, cr.result())
+	if ins == nil {
+		return nil, errNoInstance
+	}
+	return ins, nil
+}
+
+// Close 停止所有后台刷新，并清空缓存。
+func (f *BalancerFactory) Close() {
+	f.cache.Range(func(key, value any) bool {
+		cr := value.(*cachedResult)
+		cr.cancel()
+		f.cache.Delete(key)
+		f.Balancer.Delete(cr.result().CacheKey)
+		return true
+	})
+}
+
+func (f *BalancerFactory) get(ctx context.Context, desc string) (*cachedResult, error) {
+	if v, ok := f.cache.Load(desc); ok {
+		cr := v.(*cachedResult)
+		cr.touch()
+		return cr, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// 双重检查，避免并发请求重复解析
+	if v, ok := f.cache.Load(desc); ok {
+		cr := v.(*cachedResult)
+		cr.touch()
+		return cr, nil
+	}
+
+	res, err := f.Resolver.Resolve(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	if res.CacheKey == "" {
+		res.CacheKey = desc
+	}
+	f.Balancer.Rebalance(res)
+
+	// 先订阅再返回，确保之后的实例变化不会遗漏
+	watchCtx, cancel := context.WithCancel(context.Background())
+	updates, err := f.Resolver.Watch(watchCtx, desc)
+	if err != nil {
+		hlog.SystemLogger().Warnf("[服务发现] 监听 %s 出错，仅按间隔刷新：%v", desc, err)
+		updates = nil
+	}
+
+	cr := &cachedResult{
+		factory: f,
+		desc:    desc,
+		cancel:  cancel,
+	}
+	cr.res.Store(res)
+	cr.touch()
+	f.cache.Store(desc, cr)
+
+	go cr.run(watchCtx, updates)
+
+	return cr, nil
+}
+
+func (cr *cachedResult) result() discovery.Result {
+	return cr.res.Load().(discovery.Result)
+}
+
+func (cr *cachedResult) touch() {
+	atomic.StoreInt64(&cr.lastAccess, time.Now().UnixNano())
+}
+
+func (cr *cachedResult) update(res discovery.Result) {
+	if res.CacheKey == "" {
+		res.CacheKey = cr.desc
+	}
+	cr.res.Store(res)
+	cr.factory.Balancer.Rebalance(res)
+}
+
+// 后台刷新解析结果：优先接收解析器推送，同时按间隔主动刷新，长时间未使用则过期。
+func (cr *cachedResult) run(ctx context.Context, updates <-chan discovery.Result) {
+	f := cr.factory
+
+	refresh := time.NewTicker(f.LbOpts.RefreshInterval)
+	expire := time.NewTicker(f.LbOpts.ExpireInterval)
+	defer func() {
+		refresh.Stop()
+		expire.Stop()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case res, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			cr.update(res)
+		case <-refresh.C:
+			res, err := f.Resolver.Resolve(ctx, cr.desc)
+			if err != nil {
+				hlog.SystemLogger().Warnf("[服务发现] 刷新 %s 出错：%v", cr.desc, err)
+				continue
+			}
+			cr.update(res)
+		case <-expire.C:
+			if time.Since(time.Unix(0, atomic.LoadInt64(&cr.lastAccess))) < f.LbOpts.ExpireInterval {
+				continue
+			}
+			f.cache.Delete(cr.desc)
+			f.Balancer.Delete(cr.result().CacheKey)
+			cr.cancel()
+			return
+		}
+	}
+}
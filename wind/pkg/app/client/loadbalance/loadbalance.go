@@ -0,0 +1,49 @@
+package loadbalance
+
+import (
+	"context"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// Loadbalancer 定义负载均衡器所需实现的接口。
+type Loadbalancer interface {
+	// Pick 从解析结果中为请求选取一个实例，无可用实例时返回 nil。
+	Pick(ctx context.Context, req *protocol.Request, e discovery.Result) discovery.Instance
+
+	// Rebalance 在实例变化后重建解析结果对应的均衡状态。
+	Rebalance(e discovery.Result)
+
+	// Delete 删除给定缓存键对应的均衡状态。
+	Delete(cacheKey string)
+
+	// Name 返回负载均衡器名称。
+	Name() string
+}
+
+// DefaultOptions 默认的负载均衡选项。
+var DefaultOptions = Options{
+	RefreshInterval: 5 * time.Second,
+	ExpireInterval:  15 * time.Second,
+}
+
+// Options 负载均衡的选项。
+type Options struct {
+	// 定期调用 Resolve 刷新实例的间隔时长
+	RefreshInterval time.Duration
+
+	// 缓存的解析结果超过此时长未被使用则过期删除
+	ExpireInterval time.Duration
+}
+
+// Check 校验选项，非法值将替换为默认值。
+func (o *Options) Check() {
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = DefaultOptions.RefreshInterval
+	}
+	if o.ExpireInterval <= 0 {
+		o.ExpireInterval = DefaultOptions.ExpireInterval
+	}
+}
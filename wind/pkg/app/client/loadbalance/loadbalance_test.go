@@ -0,0 +1,133 @@
+package loadbalance
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+func TestWeightedRoundRobinBalancer(t *testing.T) {
+	b := NewWeightedRoundRobinBalancer()
+	res := discovery.Result{
+		CacheKey: "echo",
+		Instances: []discovery.Instance{
+			discovery.NewInstance("tcp", "a", 5, nil),
+			discovery.NewInstance("tcp", "b", 1, nil),
+			discovery.NewInstance("tcp", "c", 1, nil),
+		},
+	}
+
+	var picked []string
+	for i := 0; i < 7; i++ {
+		picked = append(picked, b.Pick(context.Background(), nil, res).Address().String())
+	}
+	// 平滑加权轮询：a 出现 5 次且被 b、c 打散
+	assert.DeepEqual(t, []string{"a", "a", "b", "a", "c", "a", "a"}, picked)
+
+	b.Rebalance(discovery.Result{CacheKey: "echo", Instances: res.Instances[1:2]})
+	assert.DeepEqual(t, "b", b.Pick(context.Background(), nil, res).Address().String())
+
+	b.Delete("echo")
+	assert.Nil(t, b.Pick(context.Background(), nil, discovery.Result{CacheKey: "echo"}))
+	assert.DeepEqual(t, "weight_round_robin", b.Name())
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	b := NewConsistentHashBalancer(ConsistentHashOption{
+		KeyFunc: func(ctx context.Context, req *protocol.Request) string {
+			return req.Header.Get("X-User")
+		},
+	})
+	var instances []discovery.Instance
+	for i := 0; i < 5; i++ {
+		instances = append(instances, discovery.NewInstance("tcp", "10.0.0."+strconv.Itoa(i), 10, nil))
+	}
+	res := discovery.Result{CacheKey: "echo", Instances: instances}
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+
+	before := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		user := "user" + strconv.Itoa(i)
+		req.Header.Set("X-User", user)
+		first := b.Pick(context.Background(), req, res).Address().String()
+		assert.DeepEqual(t, first, b.Pick(context.Background(), req, res).Address().String())
+		before[user] = first
+	}
+
+	// 移除一个实例后，仅原本落在该实例上的键会迁移
+	removed := instances[0].Address().String()
+	b.Rebalance(discovery.Result{CacheKey: "echo", Instances: instances[1:]})
+	for user, addr := range before {
+		req.Header.Set("X-User", user)
+		now := b.Pick(context.Background(), req, res).Address().String()
+		if addr != removed {
+			assert.DeepEqual(t, addr, now)
+		} else {
+			assert.NotEqual(t, removed, now)
+		}
+	}
+}
+
+func TestBalancerFactory(t *testing.T) {
+	r := discovery.NewStaticResolver(map[string][]discovery.Instance{
+		"echo": {discovery.NewInstance("tcp", "127.0.0.1:8001", 10, map[string]string{"env": "prod"})},
+	})
+	f := NewBalancerFactory(Config{
+		Resolver: r,
+		Balancer: NewWeightedRoundRobinBalancer(),
+		LbOpts:   Options{RefreshInterval: time.Hour, ExpireInterval: time.Hour},
+	})
+	defer f.Close()
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://echo/ping")
+	req.SetOptions(config.WithSD(true), config.WithTag("env", "prod"))
+
+	ins, err := f.GetInstance(context.Background(), req)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "127.0.0.1:8001", ins.Address().String())
+
+	// 解析器推送的变化会更新缓存
+	r.Update("echo", []discovery.Instance{discovery.NewInstance("tcp", "127.0.0.1:8002", 10, map[string]string{"env": "prod"})})
+	time.Sleep(50 * time.Millisecond)
+	ins, err = f.GetInstance(context.Background(), req)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "127.0.0.1:8002", ins.Address().String())
+
+	req.SetOptions(config.WithTag("env", "test"))
+	_, err = f.GetInstance(context.Background(), req)
+	assert.NotNil(t, err)
+}
+
+func TestBalancerFactoryExpire(t *testing.T) {
+	r := discovery.NewStaticResolver(map[string][]discovery.Instance{
+		"echo": {discovery.NewInstance("tcp", "127.0.0.1:8001", 10, nil)},
+	})
+	f := NewBalancerFactory(Config{
+		Resolver: r,
+		Balancer: NewWeightedRoundRobinBalancer(),
+		LbOpts:   Options{RefreshInterval: 10 * time.Millisecond, ExpireInterval: 30 * time.Millisecond},
+	})
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://echo/ping")
+
+	_, err := f.GetInstance(context.Background(), req)
+	assert.Nil(t, err)
+	_, ok := f.cache.Load("echo")
+	assert.True(t, ok)
+
+	time.Sleep(100 * time.Millisecond)
+	_, ok = f.cache.Load("echo")
+	assert.False(t, ok)
+}
@@ -0,0 +1,93 @@
+package loadbalance
+
+import (
+	"context"
+	"sync"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+var _ Loadbalancer = (*weightedRoundRobinBalancer)(nil)
+
+// NewWeightedRoundRobinBalancer 创建平滑加权轮询负载均衡器。
+//
+// 实例按权重比例被选中，且高权重实例的选取会被打散而不是连续出现。
+func NewWeightedRoundRobinBalancer() Loadbalancer {
+	return &weightedRoundRobinBalancer{}
+}
+
+type weightedRoundRobinBalancer struct {
+	pickers sync.Map // cacheKey -> *wrrPicker
+}
+
+type wrrNode struct {
+	ins     discovery.Instance
+	weight  int
+	current int
+}
+
+type wrrPicker struct {
+	mu          sync.Mutex
+	nodes       []*wrrNode
+	totalWeight int
+}
+
+func newWrrPicker(instances []discovery.Instance) *wrrPicker {
+	p := &wrrPicker{nodes: make([]*wrrNode, 0, len(instances))}
+	for _, ins := range instances {
+		w := ins.Weight()
+		if w <= 0 {
+			continue
+		}
+		p.nodes = append(p.nodes, &wrrNode{ins: ins, weight: w})
+		p.totalWeight += w
+	}
+	return p
+}
+
+// 选取当前权重最大的节点，并将其当前权重减去总权重。
+func (p *wrrPicker) next() discovery.Instance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *wrrNode
+	for _, n := range p.nodes {
+		n.current += n.weight
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.current -= p.totalWeight
+	return best.ins
+}
+
+// Pick 实现 Loadbalancer 接口。
+func (b *weightedRoundRobinBalancer) Pick(_ context.Context, _ *protocol.Request, e discovery.Result) discovery.Instance {
+	if len(e.Instances) == 0 {
+		return nil
+	}
+	p, ok := b.pickers.Load(e.CacheKey)
+	if !ok {
+		p, _ = b.pickers.LoadOrStore(e.CacheKey, newWrrPicker(e.Instances))
+	}
+	return p.(*wrrPicker).next()
+}
+
+// Rebalance 实现 Loadbalancer 接口。
+func (b *weightedRoundRobinBalancer) Rebalance(e discovery.Result) {
+	b.pickers.Store(e.CacheKey, newWrrPicker(e.Instances))
+}
+
+// Delete 实现 Loadbalancer 接口。
+func (b *weightedRoundRobinBalancer) Delete(cacheKey string) {
+	b.pickers.Delete(cacheKey)
+}
+
+// Name 实现 Loadbalancer 接口。
+func (b *weightedRoundRobinBalancer) Name() string {
+	return "weight_round_robin"
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// Endpoint 表示一次客户端请求的执行函数。
+type Endpoint func(ctx context.Context, req *protocol.Request, resp *protocol.Response) (err error)
+
+// Middleware 是客户端中间件，用于包装 Endpoint。
+//
+// 中间件可在调用 next 前后处理请求和响应，如签名、链路追踪、日志和指标等；
+// 也可不调用 next 直接填充 resp 并返回，以短路后续中间件和真实请求。
+type Middleware func(next Endpoint) Endpoint
+
+// 新建客户端时预先注册的中间件
+var defaultMiddlewares []Middleware
+
+// AddDefaultMiddleware 添加默认中间件，之后经 NewClient 新建的客户端会预先注册这些中间件，
+// 它们位于 Use 添加的中间件之外。
+//
+// 如请求标识中间件借此使客户端自动携带上下文中的请求标识。
+// 注意：该方法非并发安全，应在初始化阶段调用。
+func AddDefaultMiddleware(mws ...Middleware) {
+	defaultMiddlewares = append(defaultMiddlewares, mws...)
+}
+
+// chain 将一组中间件组合为一个。
+//
+// 先添加的中间件位于外层，即请求时先执行，响应时后执行。
+func chain(mws ...Middleware) Middleware {
+	return func(next Endpoint) Endpoint {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/common/test/mock"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+func TestChain(t *testing.T) {
+	var trace []string
+	mw := func(name string) Middleware {
+		return func(next Endpoint) Endpoint {
+			return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+				trace = append(trace, name+"-前")
+				err := next(ctx, req, resp)
+				trace = append(trace, name+"-后")
+				return err
+			}
+		}
+	}
+
+	ep := chain(mw("a"), mw("b"))(func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+		trace = append(trace, "请求")
+		return nil
+	})
+	assert.Nil(t, ep(context.Background(), nil, nil))
+	assert.DeepEqual(t, []string{"a-前", "b-前", "请求", "b-后", "a-后"}, trace)
+}
+
+func TestClientUse(t *testing.T) {
+	c, _ := NewClient(WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})))
+
+	var trace []string
+	c.Use(func(next Endpoint) Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			trace = append(trace, "first")
+			req.Header.Set("X-Sign", "abc")
+			return next(ctx, req, resp)
+		}
+	})
+	c.Use(func(next Endpoint) Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			trace = append(trace, "second:"+req.Header.Get("X-Sign"))
+			return next(ctx, req, resp)
+		}
+	})
+	assert.Nil(t, c.UseAsLast(func(next Endpoint) Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			trace = append(trace, "last")
+			return next(ctx, req, resp)
+		}
+	}))
+	assert.NotNil(t, c.UseAsLast(func(next Endpoint) Endpoint { return next }))
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	assert.Nil(t, c.Do(context.Background(), req, resp))
+	assert.DeepEqual(t, []string{"first", "second:abc", "last"}, trace)
+	assert.DeepEqual(t, "0123456789", string(resp.Body()))
+
+	assert.NotNil(t, c.TakeOutLastMiddleware())
+	assert.Nil(t, c.TakeOutLastMiddleware())
+}
+
+func TestClientMiddlewareShortCircuit(t *testing.T) {
+	var dialed bool
+	c, _ := NewClient(WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		dialed = true
+		return mock.NewConn(okResponse), nil
+	})))
+	c.Use(func(next Endpoint) Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			resp.SetStatusCode(consts.StatusTeapot)
+			resp.SetBodyString("cached")
+			return nil
+		}
+	})
+
+	statusCode, body, err := c.Get(context.Background(), nil, "http://foobar/baz")
+	assert.Nil(t, err)
+	assert.False(t, dialed)
+	assert.DeepEqual(t, consts.StatusTeapot, statusCode)
+	assert.DeepEqual(t, "cached", string(body))
+}
+
+func TestAddDefaultMiddleware(t *testing.T) {
+	defer func() { defaultMiddlewares = nil }()
+
+	var trace []string
+	AddDefaultMiddleware(func(next Endpoint) Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			trace = append(trace, "default")
+			return next(ctx, req, resp)
+		}
+	})
+	c, _ := NewClient(WithDialer(newMockDialer(func(addr string) (network.Conn, error) {
+		return mock.NewConn(okResponse), nil
+	})))
+	c.Use(func(next Endpoint) Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			trace = append(trace, "use")
+			return next(ctx, req, resp)
+		}
+	})
+
+	req, resp := protocol.AcquireRequest(), protocol.AcquireResponse()
+	req.SetRequestURI("http://foobar/baz")
+	assert.Nil(t, c.Do(context.Background(), req, resp))
+	assert.DeepEqual(t, []string{"default", "use"}, trace)
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/retry"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// WithDialTimeout 设置拨号超时时长。
+func WithDialTimeout(dialTimeout time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.DialTimeout = dialTimeout
+	}}
+}
+
+// WithMaxConnsPerHost 设置每个主机的最大连接数。
+func WithMaxConnsPerHost(mc int) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.MaxConnsPerHost = mc
+	}}
+}
+
+// WithMaxIdleConnDuration 设置闲置连接的最大保持时长。
+func WithMaxIdleConnDuration(t time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.MaxIdleConnDuration = t
+	}}
+}
+
+// WithMaxConnDuration 设置长连接的最大存活时长。
+func WithMaxConnDuration(t time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.MaxConnDuration = t
+	}}
+}
+
+// WithMaxConnWaitTimeout 设置等待闲置连接的最大时长。
+func WithMaxConnWaitTimeout(t time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.MaxConnWaitTimeout = t
+	}}
+}
+
+// WithKeepAlive 设置是否启用连接保活。
+func WithKeepAlive(b bool) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.KeepAlive = b
+	}}
+}
+
+// WithClientReadTimeout 设置完整响应的最大读取时长。
+func WithClientReadTimeout(t time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.ReadTimeout = t
+	}}
+}
+
+// WithWriteTimeout 设置完整请求的最大写入时长。
+func WithWriteTimeout(t time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.WriteTimeout = t
+	}}
+}
+
+// WithTLSConfig 设置连接主机所用的 TLS 配置。
+func WithTLSConfig(cfg *tls.Config) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.TLSConfig = cfg
+	}}
+}
+
+// WithDialer 设置建立主机连接的拨号器。
+func WithDialer(d network.Dialer) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.Dialer = d
+	}}
+}
+
+// WithResponseBodyStream 设置是否启用响应的正文流。
+func WithResponseBodyStream(b bool) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.ResponseBodyStream = b
+	}}
+}
+
+// WithDisableHeaderNamesNormalizing 设置是否禁用标头名称的规范化。
+func WithDisableHeaderNamesNormalizing(disable bool) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.DisableHeaderNamesNormalizing = disable
+	}}
+}
+
+// WithName 设置客户端名称，用于 User-Agent 请求标头。
+func WithName(name string) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.Name = name
+	}}
+}
+
+// WithNoDefaultUserAgentHeader 设置是否在请求时排除默认的 User-Agent 标头。
+func WithNoDefaultUserAgentHeader(isNoDefaultUserAgentHeader bool) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.NoDefaultUserAgentHeader = isNoDefaultUserAgentHeader
+	}}
+}
+
+// WithDisablePathNormalizing 设置是否禁用路径的规范化。
+func WithDisablePathNormalizing(isDisablePathNormalizing bool) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.DisablePathNormalizing = isDisablePathNormalizing
+	}}
+}
+
+// WithRetryConfig 设置与重试相关的配置。
+func WithRetryConfig(opts ...retry.Option) config.ClientOption {
+	retryCfg := &retry.Config{
+		MaxAttemptTimes: consts.DefaultMaxRetryTimes,
+		Delay:           1 * time.Millisecond,
+		MaxDelay:        100 * time.Millisecond,
+		MaxJitter:       20 * time.Millisecond,
+		DelayPolicy:     retry.CombineDelay(retry.DefaultDelayPolicy),
+	}
+	retryCfg.Apply(opts)
+
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.RetryConfig = retryCfg
+	}}
+}
+
+// WithMaxResponseBodySize 设置响应正文的最大字节数。
+func WithMaxResponseBodySize(maxResponseBodySize int) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.MaxResponseBodySize = maxResponseBodySize
+	}}
+}
+
+// WithDialDualStack 设置是否同时尝试连接 ipv4 和 ipv6 的主机地址。
+func WithDialDualStack(b bool) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.DialDualStack = b
+	}}
+}
+
+// WithConnStateObserve 设置观察主机客户端状态的函数及观察间隔时长。
+func WithConnStateObserve(hs config.HostClientStateFunc, interval ...time.Duration) config.ClientOption {
+	return config.ClientOption{F: func(o *config.ClientOptions) {
+		o.HostClientStateObserve = hs
+		if len(interval) > 0 {
+			o.ObservationInterval = interval[0]
+		}
+	}}
+}
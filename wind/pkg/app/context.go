@@ -11,6 +11,7 @@ import (
 
 	"github.com/favbox/gosky/wind/internal/bytesconv"
 	"github.com/favbox/gosky/wind/internal/bytestr"
+	"github.com/favbox/gosky/wind/pkg/app/server/binding"
 	"github.com/favbox/gosky/wind/pkg/app/server/render"
 	"github.com/favbox/gosky/wind/pkg/common/errors"
 	"github.com/favbox/gosky/wind/pkg/common/tracer/traceinfo"
@@ -64,6 +65,12 @@ type RequestContext struct {
 
 	// 通过自定义函数获取表单值
 	formValueFunc FormValueFunc
+
+	// 请求参数绑定器
+	binder binding.Binder
+
+	// 结构体校验器
+	validator binding.StructValidator
 }
 
 // Abort 中止处理，并防止调用挂起的处理器。
@@ -74,6 +81,11 @@ func (ctx *RequestContext) Abort() {
 	ctx.index = rConsts.AbortIndex
 }
 
+// IsAborted 返回当前处理是否已被中止。
+func (ctx *RequestContext) IsAborted() bool {
+	return ctx.index >= rConsts.AbortIndex
+}
+
 // AbortWithStatus 设置状态码并中止处理。
 //
 // 例如，对于身份鉴权失败的请求可使用：ctx.AbortWithStatus(401)
@@ -225,6 +237,16 @@ func (ctx *RequestContext) SetFormValueFunc(f FormValueFunc) {
 	ctx.formValueFunc = f
 }
 
+// SetBinder 设置请求参数绑定器。
+func (ctx *RequestContext) SetBinder(binder binding.Binder) {
+	ctx.binder = binder
+}
+
+// SetValidator 设置结构体校验器。
+func (ctx *RequestContext) SetValidator(validator binding.StructValidator) {
+	ctx.validator = validator
+}
+
 // QueryArgs 返回请求 URL 中的查询参数。
 //
 // 不会返回 POST 请求的参数 - 请使用 PostArgs()。
@@ -384,11 +406,62 @@ func (ctx *RequestContext) Next(c context.Context) {
 	}
 }
 
+// Handlers 返回当前请求上下文的处理链。
+func (ctx *RequestContext) Handlers() HandlersChain {
+	return ctx.handlers
+}
+
 // SetHandlers 设置当前请求上下文的处理链。
 func (ctx *RequestContext) SetHandlers(handlers HandlersChain) {
 	ctx.handlers = handlers
 }
 
+// GetIndex 返回当前处理器在处理链中的索引。
+func (ctx *RequestContext) GetIndex() int8 {
+	return ctx.index
+}
+
+// SetIndex 设置当前处理器在处理链中的索引。
+func (ctx *RequestContext) SetIndex(index int8) {
+	ctx.index = index
+}
+
+// Copy 返回当前请求上下文的副本，可在请求处理结束后或其他协程中安全使用。
+//
+// 副本拥有独立的请求、响应和键值对，处理链为空，与原上下文共享连接。
+// 副本不会放回上下文池，因此原上下文被复用后副本依然有效。
+func (ctx *RequestContext) Copy() *RequestContext {
+	cp := &RequestContext{
+		conn:          ctx.conn,
+		Params:        make(param.Params, len(ctx.Params)),
+		fullPath:      ctx.fullPath,
+		index:         rConsts.AbortIndex,
+		HTMLRender:    ctx.HTMLRender,
+		clientIPFunc:  ctx.clientIPFunc,
+		formValueFunc: ctx.formValueFunc,
+		binder:        ctx.binder,
+		validator:     ctx.validator,
+	}
+	copy(cp.Params, ctx.Params)
+	ctx.Request.CopyTo(&cp.Request)
+	ctx.Response.CopyTo(&cp.Response)
+
+	ctx.mu.RLock()
+	if ctx.Keys != nil {
+		cp.Keys = make(map[string]any, len(ctx.Keys))
+		for k, v := range ctx.Keys {
+			cp.Keys[k] = v
+		}
+	}
+	ctx.mu.RUnlock()
+	return cp
+}
+
+// FullPath 返回匹配路由的完整路径，如 "/user/:name"，未匹配路由时返回空串。
+func (ctx *RequestContext) FullPath() string {
+	return ctx.fullPath
+}
+
 // SetFullPath 设置当前请求上下文的完整路径。
 func (ctx *RequestContext) SetFullPath(p string) {
 	ctx.fullPath = p
@@ -512,6 +585,48 @@ func (ctx *RequestContext) Param(key string) string {
 	return ctx.Params.ByName(key)
 }
 
+// Bind 依据结构体标签将请求参数绑定到 obj，obj 必须是非空的结构体指针。
+//
+// 支持的标签有 path、form、query、cookie、header、json、protobuf 和 default，
+// 绑定失败时返回 *binding.BindError，其中包含出错的字段与参数来源。
+//
+//	type Req struct {
+//		ID   int64     `path:"id"`
+//		Page int       `query:"page" default:"1"`
+//		From time.Time `query:"from"`
+//		Auth string    `header:"Authorization,required"`
+//	}
+func (ctx *RequestContext) Bind(obj any) error {
+	return ctx.getBinder().Bind(&ctx.Request, obj, ctx.Params)
+}
+
+// Validate 依据 vd 标签校验 obj，校验失败时返回 *binding.ValidateError。
+func (ctx *RequestContext) Validate(obj any) error {
+	return ctx.getValidator().ValidateStruct(obj)
+}
+
+// BindAndValidate 绑定请求参数到 obj 并校验。
+func (ctx *RequestContext) BindAndValidate(obj any) error {
+	if err := ctx.Bind(obj); err != nil {
+		return err
+	}
+	return ctx.Validate(obj)
+}
+
+func (ctx *RequestContext) getBinder() binding.Binder {
+	if ctx.binder != nil {
+		return ctx.binder
+	}
+	return binding.DefaultBinder()
+}
+
+func (ctx *RequestContext) getValidator() binding.StructValidator {
+	if ctx.validator != nil {
+		return ctx.validator
+	}
+	return binding.DefaultValidator()
+}
+
 // RemoteAddr 获取客户端的网址。
 //
 // 若为空则返回 zeroTCPAddr。
@@ -1,17 +1,21 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/favbox/gosky/wind/internal/bytestr"
+	"github.com/favbox/gosky/wind/pkg/app/server/binding"
 	"github.com/favbox/gosky/wind/pkg/common/test/assert"
 	"github.com/favbox/gosky/wind/pkg/common/test/mock"
 	"github.com/favbox/gosky/wind/pkg/common/testdata/proto"
 	"github.com/favbox/gosky/wind/pkg/common/utils"
 	"github.com/favbox/gosky/wind/pkg/protocol"
 	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route/param"
 )
 
 func TestRequestContext_ClientIP(t *testing.T) {
@@ -184,3 +188,80 @@ func TestStatus(t *testing.T) {
 		t.Fatalf("expected get consts.StatusOK, but not")
 	}
 }
+
+func TestRequestContext_BindAndValidate(t *testing.T) {
+	type Req struct {
+		ID   int    `path:"id"`
+		Name string `query:"name" vd:"len($)>0; msg:'名称不能为空'"`
+	}
+
+	c := NewContext(1)
+	c.Params = append(c.Params, param.Param{Key: "id", Value: "7"})
+	c.Request.SetRequestURI("/users/7?name=wind")
+
+	var r Req
+	assert.Nil(t, c.BindAndValidate(&r))
+	assert.DeepEqual(t, Req{ID: 7, Name: "wind"}, r)
+
+	c.Request.SetRequestURI("/users/7")
+	r = Req{}
+	assert.Nil(t, c.Bind(&r))
+	err := c.Validate(&r)
+	var ve *binding.ValidateError
+	assert.True(t, errors.As(err, &ve))
+	assert.DeepEqual(t, "名称不能为空", ve.Msg)
+
+	c.Request.SetRequestURI("/users/7?name=wind")
+	c.Params[0].Value = "abc"
+	err = c.BindAndValidate(&r)
+	var be *binding.BindError
+	assert.True(t, errors.As(err, &be))
+	assert.DeepEqual(t, "ID", be.Field)
+	assert.DeepEqual(t, binding.SourcePath, be.Source)
+}
+
+func TestRequestContext_Copy(t *testing.T) {
+	c := NewContext(1)
+	c.Request.SetRequestURI("/user/tom")
+	c.Request.SetBodyString("body")
+	c.Response.Header.Set("X-A", "a")
+	c.Params = append(c.Params, param.Param{Key: "name", Value: "tom"})
+	c.SetFullPath("/user/:name")
+	c.Set("k", "v")
+
+	cp := c.Copy()
+	assert.DeepEqual(t, "/user/tom", string(cp.Request.URI().Path()))
+	assert.DeepEqual(t, "body", string(cp.Request.Body()))
+	assert.DeepEqual(t, "a", string(cp.Response.Header.Peek("X-A")))
+	assert.DeepEqual(t, "tom", cp.Param("name"))
+	assert.DeepEqual(t, "/user/:name", cp.FullPath())
+	assert.DeepEqual(t, "v", cp.GetString("k"))
+	assert.True(t, cp.IsAborted())
+
+	// 副本与原上下文互不影响
+	c.Reset()
+	cp.Set("k2", "v2")
+	assert.DeepEqual(t, "tom", cp.Param("name"))
+	assert.DeepEqual(t, "body", string(cp.Request.Body()))
+	_, exists := c.Get("k2")
+	assert.False(t, exists)
+}
+
+func TestRequestContext_Index(t *testing.T) {
+	c := NewContext(0)
+	var calls int
+	c.SetHandlers(HandlersChain{
+		func(_ context.Context, ctx *RequestContext) { calls++ },
+		func(_ context.Context, ctx *RequestContext) { calls++ },
+	})
+	assert.DeepEqual(t, 2, len(c.Handlers()))
+	assert.DeepEqual(t, int8(-1), c.GetIndex())
+	assert.False(t, c.IsAborted())
+
+	c.SetIndex(0)
+	c.Next(context.Background())
+	assert.DeepEqual(t, 1, calls)
+
+	c.Abort()
+	assert.True(t, c.IsAborted())
+}
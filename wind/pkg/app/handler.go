@@ -31,6 +31,22 @@ func GetHandlerName(handler HandlerFunc) string {
 	return handlerNames[getFuncAddr(handler)]
 }
 
+var preflightHandlers = make(map[uintptr]struct{})
+
+// SetPreflightHandler 标记该处理器会自行应答跨域预检请求。
+//
+// 路由组通过 Use 或 Group 注册被标记的中间件时，会为该组补充一个 OPTIONS 通配路由，
+// 使没有对应 OPTIONS 路由的预检请求也能经过该中间件。
+func SetPreflightHandler(handler HandlerFunc) {
+	preflightHandlers[getFuncAddr(handler)] = struct{}{}
+}
+
+// IsPreflightHandler 汇报该处理器是否会自行应答跨域预检请求。
+func IsPreflightHandler(handler HandlerFunc) bool {
+	_, ok := preflightHandlers[getFuncAddr(handler)]
+	return ok
+}
+
 func getFuncAddr(v any) uintptr {
 	return reflect.ValueOf(reflect.ValueOf(v)).Field(1).Pointer()
 }
@@ -0,0 +1,36 @@
+package sd
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/app/client"
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/app/client/loadbalance"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// Discovery 返回一个服务发现的客户端中间件。
+//
+// 对于设置了 config.WithSD(true) 的请求，将网址中的主机视为服务名，
+// 经解析器和负载均衡器选出实例后，把主机改写为实例地址。其他请求原样放行。
+func Discovery(resolver discovery.Resolver, opts ...Option) client.Middleware {
+	cfg := newOptions(opts...)
+	factory := loadbalance.NewBalancerFactory(loadbalance.Config{
+		Resolver: resolver,
+		Balancer: cfg.balancer,
+		LbOpts:   cfg.lbOpts,
+	})
+
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			if req.Options() != nil && req.Options().IsSD() {
+				ins, err := factory.GetInstance(ctx, req)
+				if err != nil {
+					return err
+				}
+				req.SetHost(ins.Address().String())
+			}
+			return next(ctx, req, resp)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/discovery"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+func TestDiscovery(t *testing.T) {
+	r := discovery.NewStaticResolver(map[string][]discovery.Instance{
+		"echo": {
+			discovery.NewInstance("tcp", "127.0.0.1:8001", 10, nil),
+			discovery.NewInstance("tcp", "127.0.0.1:8002", 10, nil),
+		},
+	})
+
+	var hosts []string
+	ep := Discovery(r)(func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+		hosts = append(hosts, string(req.Host()))
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		req := protocol.AcquireRequest()
+		req.SetRequestURI("http://echo/ping")
+		req.SetOptions(config.WithSD(true))
+		assert.Nil(t, ep(context.Background(), req, nil))
+		protocol.ReleaseRequest(req)
+	}
+	assert.DeepEqual(t, []string{"127.0.0.1:8001", "127.0.0.1:8002"}, hosts)
+
+	// 未启用服务发现的请求保持原样
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/ping")
+	assert.Nil(t, ep(context.Background(), req, nil))
+	assert.DeepEqual(t, "example.com", hosts[2])
+
+	// 未知服务返回错误
+	req.SetRequestURI("http://unknown/ping")
+	req.SetOptions(config.WithSD(true))
+	assert.NotNil(t, ep(context.Background(), req, nil))
+}
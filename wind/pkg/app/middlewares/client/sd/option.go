@@ -0,0 +1,45 @@
+package sd
+
+import (
+	"github.com/favbox/gosky/wind/pkg/app/client/loadbalance"
+)
+
+// 表示一个服务发现的自定义选项结构体。
+type options struct {
+	// 负载均衡器
+	balancer loadbalance.Loadbalancer
+
+	// 负载均衡选项
+	lbOpts loadbalance.Options
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义服务发现的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		balancer: loadbalance.NewWeightedRoundRobinBalancer(),
+		lbOpts:   loadbalance.DefaultOptions,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithLoadBalancer 自定义负载均衡器，默认为平滑加权轮询。
+func WithLoadBalancer(lb loadbalance.Loadbalancer) Option {
+	return func(o *options) {
+		o.balancer = lb
+	}
+}
+
+// WithLoadBalanceOptions 自定义负载均衡的刷新及过期时长。
+func WithLoadBalanceOptions(lbOpts loadbalance.Options) Option {
+	return func(o *options) {
+		o.lbOpts = lbOpts
+	}
+}
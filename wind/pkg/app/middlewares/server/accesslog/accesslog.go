@@ -0,0 +1,215 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/bytebufferpool"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/tracer"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/stats"
+)
+
+// 待写出的一条日志。
+type entry struct {
+	c   context.Context
+	buf *bytebufferpool.ByteBuffer
+}
+
+// Logger 按模板格式化并写出访问日志。
+//
+// 日志在请求中格式化到池化的缓冲区，再交由后台协程写出，格式化过程不产生内存分配。
+// 可通过 Handler 以中间件方式记录，或通过 Tracer 以跟踪器方式记录。
+type Logger struct {
+	tmpl   *template
+	opts   *options
+	queue  chan entry
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// New 返回一个访问日志中间件，等同于 NewLogger(opts...).Handler()。
+func New(opts ...Option) app.HandlerFunc {
+	return NewLogger(opts...).Handler()
+}
+
+// NewLogger 创建一个访问日志记录器，模板有误时引发恐慌。
+//
+// 启用异步写出时，应在程序退出前调用 Close 以写出队列中剩余的日志。
+func NewLogger(opts ...Option) *Logger {
+	o := newOptions(opts...)
+	segments, err := parseTemplate(o.format)
+	if err != nil {
+		panic(err)
+	}
+
+	l := &Logger{
+		tmpl: &template{
+			segments:   segments,
+			json:       o.json,
+			timeFormat: o.timeFormat,
+			timeZone:   o.timeZone,
+		},
+		opts: o,
+	}
+	if o.queueSize > 0 {
+		l.queue = make(chan entry, o.queueSize)
+		l.done = make(chan struct{})
+		go l.run()
+	}
+	return l
+}
+
+// Handler 返回以中间件方式记录访问日志的处理器。
+//
+// 启用链路跟踪时，耗时自 HTTPStart 事件起算，接收字节数取自 traceinfo.HTTPStats；
+// 发送字节数在响应写出后才能确定，中间件中为响应正文的长度，流式正文未知长度时为零。
+// 如需包含标头的完整发送字节数，请使用 Tracer。
+func (l *Logger) Handler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+		ctx.Next(c)
+
+		r := record{start: start, recv: requestSize(ctx), sent: bodySize(ctx)}
+		if ctx.IsEnableTrace() {
+			st := ctx.GetTraceInfo().Stats()
+			if e := st.GetEvent(stats.HTTPStart); e != nil {
+				r.start = e.Time()
+			}
+			r.recv = st.RecvSize()
+		}
+		r.latency = time.Since(r.start)
+		l.log(c, ctx, &r)
+	}
+}
+
+// Tracer 返回以跟踪器方式记录访问日志的 tracer.Tracer，需经 server.WithTracer 注册。
+//
+// 跟踪器在响应写出后记录，耗时及收发字节数均取自 traceinfo.HTTPStats。
+func (l *Logger) Tracer() tracer.Tracer {
+	return (*logTracer)(l)
+}
+
+type logTracer Logger
+
+func (t *logTracer) Start(c context.Context, ctx *app.RequestContext) context.Context {
+	return c
+}
+
+func (t *logTracer) Finish(c context.Context, ctx *app.RequestContext) {
+	st := ctx.GetTraceInfo().Stats()
+	start, finish := st.GetEvent(stats.HTTPStart), st.GetEvent(stats.HTTPFinish)
+	if start == nil {
+		return
+	}
+	r := record{start: start.Time(), sent: st.SendSize(), recv: st.RecvSize()}
+	if finish != nil {
+		r.latency = finish.Time().Sub(r.start)
+	} else {
+		r.latency = time.Since(r.start)
+	}
+	(*Logger)(t).log(c, ctx, &r)
+}
+
+// Close 写出队列中剩余的日志并停止后台协程，之后的日志将同步写出。
+func (l *Logger) Close() error {
+	if l.queue == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+	<-l.done
+	return nil
+}
+
+func (l *Logger) log(c context.Context, ctx *app.RequestContext, r *record) {
+	if l.opts.skip != nil && l.opts.skip(ctx.Response.StatusCode(), ctx.Request.URI().Path()) {
+		return
+	}
+
+	buf := bytebufferpool.Get()
+	buf.B = l.tmpl.appendTo(buf.B, ctx, r)
+
+	if l.queue != nil {
+		l.mu.RLock()
+		if !l.closed {
+			l.queue <- entry{c: c, buf: buf}
+			l.mu.RUnlock()
+			return
+		}
+		l.mu.RUnlock()
+	}
+	l.write(c, buf)
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	for e := range l.queue {
+		l.write(e.c, e.buf)
+	}
+}
+
+func (l *Logger) write(c context.Context, buf *bytebufferpool.ByteBuffer) {
+	if l.opts.writer != nil {
+		buf.B = append(buf.B, '\n')
+		if _, err := l.opts.writer.Write(buf.B); err != nil {
+			hlog.SystemLogger().Errorf("访问日志写出失败：%v", err)
+		}
+	} else {
+		// CtxLogger 仅有格式化方法，以日志行为格式串且不传参数，可免去参数装箱的内存分配，
+		// 为此需将其中的 % 转义。调用返回后缓冲区即归还池中，记录器不得持有该格式串。
+		buf.B = escapePercent(buf.B)
+		l.opts.logger.CtxInfof(c, bytesconv.B2s(buf.B))
+	}
+	bytebufferpool.Put(buf)
+}
+
+// 将 % 转义为 %%，在原缓冲区上原地扩展，容量足够时不产生内存分配。
+func escapePercent(b []byte) []byte {
+	n := bytes.Count(b, percent)
+	if n == 0 {
+		return b
+	}
+	end := len(b)
+	b = append(b, make([]byte, n)...)
+	for i, j := end-1, len(b)-1; i >= 0; i-- {
+		b[j] = b[i]
+		j--
+		if b[i] == '%' {
+			b[j] = '%'
+			j--
+		}
+	}
+	return b
+}
+
+var percent = []byte("%")
+
+// 接收的字节数，即原始标头与正文长度之和。
+func requestSize(ctx *app.RequestContext) int {
+	n := len(ctx.Request.Header.RawHeaders())
+	if cl := ctx.Request.Header.ContentLength(); cl > 0 {
+		n += cl
+	}
+	return n
+}
+
+// 响应正文的长度，流式正文未知长度时为零。
+func bodySize(ctx *app.RequestContext) int {
+	if ctx.Response.IsBodyStream() {
+		if cl := ctx.Response.Header.ContentLength(); cl > 0 {
+			return cl
+		}
+		return 0
+	}
+	return len(ctx.Response.Body())
+}
@@ -0,0 +1,219 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/stats"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/traceinfo"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// 协程安全的输出器。
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestEngine(h app.HandlerFunc) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(h)
+	engine.GET("/user/:name", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "hello")
+	})
+	engine.GET("/health", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Status(consts.StatusNoContent)
+	})
+	return engine
+}
+
+func TestAccessLogSync(t *testing.T) {
+	w := &syncBuffer{}
+	engine := newTestEngine(New(
+		WithWriter(w),
+		WithQueueSize(0),
+		WithFormat("${status} ${method} ${path} ${route} ${bytesSent}"),
+	))
+
+	ut.PerformRequest(engine, consts.MethodGet, "/user/tom", nil)
+	ut.PerformRequest(engine, consts.MethodGet, "/missing", nil)
+	// 默认的 404 正文在中间件返回后才写入
+	assert.Equal(t, "200 GET /user/tom /user/:name 5\n404 GET /missing /missing 0\n", w.String())
+}
+
+func TestAccessLogAsync(t *testing.T) {
+	w := &syncBuffer{}
+	l := NewLogger(WithWriter(w), WithQueueSize(2), WithFormat("${route}"))
+	engine := newTestEngine(l.Handler())
+
+	for i := 0; i < 10; i++ {
+		ut.PerformRequest(engine, consts.MethodGet, "/user/tom", nil)
+	}
+	assert.Nil(t, l.Close())
+	assert.Equal(t, strings.Repeat("/user/:name\n", 10), w.String())
+
+	// 关闭后同步写出
+	ut.PerformRequest(engine, consts.MethodGet, "/user/tom", nil)
+	assert.Equal(t, strings.Repeat("/user/:name\n", 11), w.String())
+	assert.Nil(t, l.Close())
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	w := &syncBuffer{}
+	engine := newTestEngine(New(
+		WithWriter(w),
+		WithQueueSize(0),
+		WithJSON(true),
+		WithFormat("${status} ${route} ${reqHeader:X-Request-ID}"),
+	))
+
+	ut.PerformRequest(engine, consts.MethodGet, "/user/tom", nil, ut.Header{Key: "X-Request-ID", Value: "abc"})
+	assert.Equal(t, `{"status":200,"route":"/user/:name","reqHeader:X-Request-ID":"abc"}`+"\n", w.String())
+}
+
+func TestAccessLogSkip(t *testing.T) {
+	w := &syncBuffer{}
+	engine := newTestEngine(New(
+		WithWriter(w),
+		WithQueueSize(0),
+		WithFormat("${path}"),
+		WithSkip(func(status int, path []byte) bool { return string(path) == "/health" }),
+	))
+
+	ut.PerformRequest(engine, consts.MethodGet, "/health", nil)
+	ut.PerformRequest(engine, consts.MethodGet, "/user/tom", nil)
+	assert.Equal(t, "/user/tom\n", w.String())
+}
+
+type testKey struct{}
+
+// 仅记录日志内容的 CtxLogger。
+type testLogger struct {
+	syncBuffer
+}
+
+func (l *testLogger) CtxTracef(ctx context.Context, format string, v ...any)  {}
+func (l *testLogger) CtxDebugf(ctx context.Context, format string, v ...any)  {}
+func (l *testLogger) CtxNoticef(ctx context.Context, format string, v ...any) {}
+func (l *testLogger) CtxWarnf(ctx context.Context, format string, v ...any)   {}
+func (l *testLogger) CtxErrorf(ctx context.Context, format string, v ...any)  {}
+func (l *testLogger) CtxFatalf(ctx context.Context, format string, v ...any)  {}
+func (l *testLogger) CtxInfof(ctx context.Context, format string, v ...any) {
+	_, _ = l.Write([]byte(ctx.Value(testKey{}).(string) + ":"))
+	_, _ = l.Write([]byte(fmt.Sprintf(format, v...)))
+}
+
+func TestAccessLogLogger(t *testing.T) {
+	logger := &testLogger{}
+	l := NewLogger(WithLogger(logger), WithFormat("${status}"))
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(func(c context.Context, ctx *app.RequestContext) {
+		ctx.Next(context.WithValue(c, testKey{}, "v"))
+	}, l.Handler())
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {})
+
+	ut.PerformRequest(engine, consts.MethodGet, "/", nil)
+	assert.Nil(t, l.Close())
+	assert.Equal(t, "v:200", logger.String())
+
+	// 日志中的 % 原样输出
+	logger = &testLogger{}
+	l = NewLogger(WithLogger(logger), WithFormat("${status} 100% %s %d"), WithQueueSize(0))
+	engine = route.NewEngine(config.NewOptions(nil))
+	engine.Use(func(c context.Context, ctx *app.RequestContext) {
+		ctx.Next(context.WithValue(c, testKey{}, "v"))
+	}, l.Handler())
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {})
+	ut.PerformRequest(engine, consts.MethodGet, "/", nil)
+	assert.Equal(t, "v:200 100% %s %d", logger.String())
+}
+
+// 丢弃日志的 CtxLogger。
+type discardLogger struct {
+	testLogger
+}
+
+func (l *discardLogger) CtxInfof(ctx context.Context, format string, v ...any) {}
+
+func TestAccessLogNoAlloc(t *testing.T) {
+	if raceEnabled {
+		t.Skip("竞态检测下无法统计内存分配")
+	}
+	ctx := newTestContext()
+	c := context.Background()
+	for name, opt := range map[string]Option{
+		"writer": WithWriter(io.Discard),
+		"logger": WithLogger(&discardLogger{}),
+	} {
+		l := NewLogger(opt, WithQueueSize(0), WithFormat("${status} ${latency} ${method} ${path} 100%"))
+		allocs := testing.AllocsPerRun(100, func() {
+			l.log(c, ctx, &testRecord)
+		})
+		assert.Equal(t, float64(0), allocs, name)
+	}
+}
+
+func TestEscapePercent(t *testing.T) {
+	for in, want := range map[string]string{
+		"":       "",
+		"abc":    "abc",
+		"%":      "%%",
+		"a%b%%c": "a%%b%%%%c",
+		"%a%":    "%%a%%",
+	} {
+		assert.Equal(t, want, string(escapePercent([]byte(in))), in)
+	}
+}
+
+func TestAccessLogTracer(t *testing.T) {
+	w := &syncBuffer{}
+	l := NewLogger(WithWriter(w), WithQueueSize(0), WithFormat("${route} ${bytesSent} ${bytesReceived}"))
+	tr := l.Tracer()
+
+	ctx := app.NewContext(0)
+	ctx.Request.SetRequestURI("/user/tom")
+	ctx.SetFullPath("/user/:name")
+	ti := traceinfo.NewTraceInfo()
+	ti.Stats().SetLevel(stats.LevelBase)
+	ctx.SetTraceInfo(ti)
+
+	// 没有开始事件时不记录
+	tr.Finish(context.Background(), ctx)
+	assert.Equal(t, "", w.String())
+
+	c := tr.Start(context.Background(), ctx)
+	ti.Stats().Record(stats.HTTPStart, stats.StatusInfo, "")
+	ti.Stats().SetRecvSize(64)
+	ti.Stats().SetSendSize(256)
+	ti.Stats().Record(stats.HTTPFinish, stats.StatusInfo, "")
+	tr.Finish(c, ctx)
+	assert.Equal(t, "/user/:name 256 64\n", w.String())
+}
+
+func TestNewLoggerPanic(t *testing.T) {
+	assert.Panics(t, func() {
+		NewLogger(WithFormat("${unknown}"))
+	})
+}
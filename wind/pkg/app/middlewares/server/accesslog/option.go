@@ -0,0 +1,149 @@
+package accesslog
+
+import (
+	"io"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+)
+
+const (
+	// 默认的日志模板。
+	defaultFormat = "[${time}] ${status} - ${latency} ${method} ${path}"
+
+	// 默认的时间格式。
+	defaultTimeFormat = "2006-01-02 15:04:05.000"
+
+	// 默认的异步队列长度。
+	defaultQueueSize = 1024
+)
+
+// 表示一个访问日志的自定义选项结构体。
+type options struct {
+	// 日志模板。
+	format string
+
+	// 是否以 JSON 格式输出。
+	json bool
+
+	// ${time} 的时间格式。
+	timeFormat string
+
+	// ${time} 的时区。
+	timeZone *time.Location
+
+	// 日志输出器，优先于 logger。
+	writer io.Writer
+
+	// 日志记录器，默认为 hlog 的默认记录器。
+	logger hlog.CtxLogger
+
+	// 异步队列长度，为零时同步写出。
+	queueSize int
+
+	// 跳过记录的请求。
+	skip func(status int, path []byte) bool
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义访问日志的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		format:     defaultFormat,
+		timeFormat: defaultTimeFormat,
+		timeZone:   time.Local,
+		logger:     hlog.DefaultLogger(),
+		queueSize:  defaultQueueSize,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithFormat 设置日志模板，默认为 "[${time}] ${status} - ${latency} ${method} ${path}"。
+//
+// 支持的变量：
+//
+//	${time}           请求开始时间
+//	${status}         响应状态码
+//	${latency}        处理耗时
+//	${method}         请求方法
+//	${path}           请求路径
+//	${route}          匹配的路由，如 /user/:name，未匹配时为请求路径
+//	${query}          查询字符串
+//	${protocol}       协议版本
+//	${host}           请求主机
+//	${clientIP}       客户端 IP
+//	${userAgent}      用户代理
+//	${referer}        来源页面
+//	${bytesSent}      发送的字节数
+//	${bytesReceived}  接收的字节数
+//	${error}          处理链中的错误
+//	${reqHeader:X}    请求标头 X
+//	${respHeader:X}   响应标头 X
+//	${key:X}          RequestContext.Keys 中的键 X
+//
+// 模板中未知的变量将导致 New 引发恐慌。
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithJSON 设置是否以 JSON 格式输出，默认否。
+//
+// JSON 格式仅输出模板中的变量，以变量名为键，忽略其余文本。
+func WithJSON(b bool) Option {
+	return func(o *options) {
+		o.json = b
+	}
+}
+
+// WithTimeFormat 设置 ${time} 的时间格式，默认为 "2006-01-02 15:04:05.000"。
+func WithTimeFormat(layout string) Option {
+	return func(o *options) {
+		o.timeFormat = layout
+	}
+}
+
+// WithTimeZone 设置 ${time} 的时区，默认为本地时区。
+func WithTimeZone(loc *time.Location) Option {
+	return func(o *options) {
+		o.timeZone = loc
+	}
+}
+
+// WithWriter 设置日志输出器，每条日志后追加换行符。设置后忽略 WithLogger。
+func WithWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.writer = w
+	}
+}
+
+// WithLogger 设置日志记录器，以 CtxInfof 输出日志，默认为 hlog 的默认记录器。
+func WithLogger(l hlog.CtxLogger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithQueueSize 设置异步队列长度，默认为 1024，为零时同步写出。
+//
+// 队列已满时请求将等待，而不会丢弃日志。
+func WithQueueSize(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithSkip 设置跳过记录的请求，如健康检查。
+func WithSkip(f func(status int, path []byte) bool) Option {
+	return func(o *options) {
+		o.skip = f
+	}
+}
@@ -0,0 +1,46 @@
+package accesslog
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, defaultFormat, opts.format)
+	assert.False(t, opts.json)
+	assert.Equal(t, defaultTimeFormat, opts.timeFormat)
+	assert.Equal(t, time.Local, opts.timeZone)
+	assert.Nil(t, opts.writer)
+	assert.Equal(t, hlog.DefaultLogger(), opts.logger)
+	assert.Equal(t, defaultQueueSize, opts.queueSize)
+	assert.Nil(t, opts.skip)
+}
+
+func TestOption(t *testing.T) {
+	w := &bytes.Buffer{}
+	skip := func(status int, path []byte) bool { return false }
+	opts := newOptions(
+		WithFormat("${status}"),
+		WithJSON(true),
+		WithTimeFormat(time.RFC3339),
+		WithTimeZone(time.UTC),
+		WithWriter(w),
+		WithLogger(hlog.SystemLogger()),
+		WithQueueSize(0),
+		WithSkip(skip),
+	)
+	assert.Equal(t, "${status}", opts.format)
+	assert.True(t, opts.json)
+	assert.Equal(t, time.RFC3339, opts.timeFormat)
+	assert.Equal(t, time.UTC, opts.timeZone)
+	assert.Equal(t, w, opts.writer)
+	assert.Equal(t, hlog.SystemLogger(), opts.logger)
+	assert.Equal(t, 0, opts.queueSize)
+	assert.Equal(t, fmt.Sprintf("%p", skip), fmt.Sprintf("%p", opts.skip))
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package accesslog
+
+// 竞态检测下 sync.Pool 会随机丢弃对象，无法统计内存分配。
+const raceEnabled = true
@@ -0,0 +1,293 @@
+package accesslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 模板变量的种类。
+type tag uint8
+
+const (
+	tagText tag = iota
+	tagTime
+	tagStatus
+	tagLatency
+	tagMethod
+	tagPath
+	tagRoute
+	tagQuery
+	tagProtocol
+	tagHost
+	tagClientIP
+	tagUserAgent
+	tagReferer
+	tagBytesSent
+	tagBytesReceived
+	tagError
+	tagReqHeader
+	tagRespHeader
+	tagKey
+)
+
+var tags = map[string]tag{
+	"time":          tagTime,
+	"status":        tagStatus,
+	"latency":       tagLatency,
+	"method":        tagMethod,
+	"path":          tagPath,
+	"route":         tagRoute,
+	"query":         tagQuery,
+	"protocol":      tagProtocol,
+	"host":          tagHost,
+	"clientIP":      tagClientIP,
+	"userAgent":     tagUserAgent,
+	"referer":       tagReferer,
+	"bytesSent":     tagBytesSent,
+	"bytesReceived": tagBytesReceived,
+	"error":         tagError,
+}
+
+// 带参数的变量，形如 ${reqHeader:X-Request-ID}。
+var paramTags = map[string]tag{
+	"reqHeader":  tagReqHeader,
+	"respHeader": tagRespHeader,
+	"key":        tagKey,
+}
+
+// 模板中的一段，为纯文本或变量。
+type segment struct {
+	tag   tag
+	text  string // 纯文本内容，或变量名（作为 JSON 的键）
+	param string
+}
+
+// 一次请求待记录的度量。
+type record struct {
+	start   time.Time
+	latency time.Duration
+	sent    int
+	recv    int
+}
+
+// 已解析的日志模板。
+type template struct {
+	segments   []segment
+	json       bool
+	timeFormat string
+	timeZone   *time.Location
+}
+
+// 解析日志模板，未知或未闭合的变量返回错误。
+func parseTemplate(format string) ([]segment, error) {
+	var segments []segment
+	for format != "" {
+		i := strings.Index(format, "${")
+		if i < 0 {
+			segments = append(segments, segment{tag: tagText, text: format})
+			break
+		}
+		if i > 0 {
+			segments = append(segments, segment{tag: tagText, text: format[:i]})
+		}
+		format = format[i+2:]
+		j := strings.IndexByte(format, '}')
+		if j < 0 {
+			return nil, fmt.Errorf("accesslog：模板变量未闭合 %q", "${"+format)
+		}
+		name := format[:j]
+		format = format[j+1:]
+
+		if t, ok := tags[name]; ok {
+			segments = append(segments, segment{tag: t, text: name})
+			continue
+		}
+		if prefix, param, ok := strings.Cut(name, ":"); ok && param != "" {
+			if t, ok := paramTags[prefix]; ok {
+				segments = append(segments, segment{tag: t, text: name, param: param})
+				continue
+			}
+		}
+		return nil, fmt.Errorf("accesslog：未知的模板变量 %q", name)
+	}
+	return segments, nil
+}
+
+// 将一次请求的日志追加到 dst。
+func (t *template) appendTo(dst []byte, ctx *app.RequestContext, r *record) []byte {
+	if t.json {
+		return t.appendJSON(dst, ctx, r)
+	}
+	for i := range t.segments {
+		s := &t.segments[i]
+		if s.tag == tagText {
+			dst = append(dst, s.text...)
+			continue
+		}
+		dst = t.appendValue(dst, s, ctx, r, appendText)
+	}
+	return dst
+}
+
+func (t *template) appendJSON(dst []byte, ctx *app.RequestContext, r *record) []byte {
+	dst = append(dst, '{')
+	first := true
+	for i := range t.segments {
+		s := &t.segments[i]
+		if s.tag == tagText {
+			continue
+		}
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		dst = appendJSONString(dst, s.text)
+		dst = append(dst, ':')
+		dst = t.appendValue(dst, s, ctx, r, appendJSONString)
+	}
+	return append(dst, '}')
+}
+
+// 追加变量的值，字符串经由 appendString 转义。
+func (t *template) appendValue(dst []byte, s *segment, ctx *app.RequestContext, r *record, appendString func([]byte, string) []byte) []byte {
+	switch s.tag {
+	case tagTime:
+		if t.json {
+			dst = append(dst, '"')
+			dst = r.start.In(t.timeZone).AppendFormat(dst, t.timeFormat)
+			return append(dst, '"')
+		}
+		return r.start.In(t.timeZone).AppendFormat(dst, t.timeFormat)
+	case tagStatus:
+		return strconv.AppendInt(dst, int64(ctx.Response.StatusCode()), 10)
+	case tagLatency:
+		if t.json {
+			dst = append(dst, '"')
+			dst = appendDuration(dst, r.latency)
+			return append(dst, '"')
+		}
+		return appendDuration(dst, r.latency)
+	case tagMethod:
+		return appendString(dst, bytesconv.B2s(ctx.Request.Header.Method()))
+	case tagPath:
+		return appendString(dst, bytesconv.B2s(ctx.Request.URI().Path()))
+	case tagRoute:
+		if route := ctx.FullPath(); route != "" {
+			return appendString(dst, route)
+		}
+		return appendString(dst, bytesconv.B2s(ctx.Request.URI().Path()))
+	case tagQuery:
+		return appendString(dst, bytesconv.B2s(ctx.Request.URI().QueryString()))
+	case tagProtocol:
+		return appendString(dst, ctx.Request.Header.GetProtocol())
+	case tagHost:
+		return appendString(dst, bytesconv.B2s(ctx.Host()))
+	case tagClientIP:
+		return appendString(dst, ctx.ClientIP())
+	case tagUserAgent:
+		return appendString(dst, bytesconv.B2s(ctx.UserAgent()))
+	case tagReferer:
+		return appendString(dst, bytesconv.B2s(ctx.Request.Header.Peek(consts.HeaderReferer)))
+	case tagBytesSent:
+		return strconv.AppendInt(dst, int64(r.sent), 10)
+	case tagBytesReceived:
+		return strconv.AppendInt(dst, int64(r.recv), 10)
+	case tagError:
+		if len(ctx.Errors) == 0 {
+			return appendString(dst, "")
+		}
+		return appendString(dst, ctx.Errors.Last().Error())
+	case tagReqHeader:
+		return appendString(dst, bytesconv.B2s(ctx.Request.Header.Peek(s.param)))
+	case tagRespHeader:
+		return appendString(dst, bytesconv.B2s(ctx.Response.Header.Peek(s.param)))
+	case tagKey:
+		v, _ := ctx.Get(s.param)
+		switch v := v.(type) {
+		case nil:
+			return appendString(dst, "")
+		case string:
+			return appendString(dst, v)
+		case []byte:
+			return appendString(dst, bytesconv.B2s(v))
+		case fmt.Stringer:
+			return appendString(dst, v.String())
+		default:
+			return appendString(dst, fmt.Sprint(v))
+		}
+	}
+	return dst
+}
+
+// 以至多三位小数追加耗时，如 512µs、1.234ms、2.5s，不产生内存分配。
+func appendDuration(dst []byte, d time.Duration) []byte {
+	switch {
+	case d < time.Millisecond:
+		return append(strconv.AppendInt(dst, d.Microseconds(), 10), "µs"...)
+	case d < time.Second:
+		return append(appendMilli(dst, d.Microseconds()), "ms"...)
+	default:
+		return append(appendMilli(dst, d.Milliseconds()), 's')
+	}
+}
+
+// 以至多三位小数追加 n/1000，末尾的零被省略。
+func appendMilli(dst []byte, n int64) []byte {
+	return strconv.AppendFloat(dst, float64(n)/1000, 'f', -1, 64)
+}
+
+const hex = "0123456789abcdef"
+
+// 追加文本，控制字符以 \xNN 转义以防日志注入。
+func appendText(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c == 0x7f {
+			dst = append(dst, '\\', 'x', hex[c>>4], hex[c&0xf])
+			continue
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// 追加带引号的 JSON 字符串。
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c == '\n':
+				dst = append(dst, '\\', 'n')
+			case c == '\r':
+				dst = append(dst, '\\', 'r')
+			case c == '\t':
+				dst = append(dst, '\\', 't')
+			case c < ' ' || c == 0x7f:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				dst = append(dst, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, "\ufffd"...)
+		} else {
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(dst, '"')
+}
@@ -0,0 +1,122 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContext() *app.RequestContext {
+	ctx := app.NewContext(0)
+	ctx.Request.SetRequestURI("http://example.com/user/tom?a=1")
+	ctx.Request.Header.SetMethod(consts.MethodPost)
+	ctx.Request.Header.SetProtocol(consts.HTTP11)
+	ctx.Request.Header.Set("X-Request-ID", "abc")
+	ctx.Request.Header.SetUserAgentBytes([]byte("wind-test"))
+	ctx.SetFullPath("/user/:name")
+	ctx.Set("uid", "42")
+	ctx.Response.SetStatusCode(consts.StatusCreated)
+	ctx.Response.Header.Set("X-Cache", "HIT")
+	return ctx
+}
+
+var testRecord = record{
+	start:   time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC),
+	latency: 1500 * time.Microsecond,
+	sent:    128,
+	recv:    64,
+}
+
+func newTestTemplate(t *testing.T, format string, json bool) *template {
+	segments, err := parseTemplate(format)
+	assert.Nil(t, err)
+	return &template{segments: segments, json: json, timeFormat: defaultTimeFormat, timeZone: time.UTC}
+}
+
+func TestParseTemplate(t *testing.T) {
+	segments, err := parseTemplate("a${status}b${reqHeader:X-ID}")
+	assert.Nil(t, err)
+	assert.Equal(t, []segment{
+		{tag: tagText, text: "a"},
+		{tag: tagStatus, text: "status"},
+		{tag: tagText, text: "b"},
+		{tag: tagReqHeader, text: "reqHeader:X-ID", param: "X-ID"},
+	}, segments)
+
+	for _, format := range []string{"${unknown}", "${status", "${reqHeader:}", "${foo:bar}"} {
+		_, err = parseTemplate(format)
+		assert.NotNil(t, err, format)
+	}
+}
+
+func TestTemplateText(t *testing.T) {
+	ctx := newTestContext()
+	tmpl := newTestTemplate(t, "[${time}] ${status} ${latency} ${method} ${path} ${route} ${query} "+
+		"${host} ${userAgent} ${bytesSent} ${bytesReceived} ${reqHeader:X-Request-ID} "+
+		"${respHeader:X-Cache} ${key:uid} ${key:none}|${error}|${protocol}", false)
+	assert.Equal(t, "[2024-01-02 03:04:05.006] 201 1.5ms POST /user/tom /user/:name a=1 "+
+		"example.com wind-test 128 64 abc HIT 42 ||HTTP/1.1",
+		string(tmpl.appendTo(nil, ctx, &testRecord)))
+
+	// 未匹配路由时使用请求路径
+	ctx.SetFullPath("")
+	tmpl = newTestTemplate(t, "${route}", false)
+	assert.Equal(t, "/user/tom", string(tmpl.appendTo(nil, ctx, &testRecord)))
+
+	// 控制字符被转义
+	ctx.Request.Header.Set("X-Evil", "a\nb")
+	tmpl = newTestTemplate(t, "${reqHeader:X-Evil}", false)
+	assert.Equal(t, `a\x0ab`, string(tmpl.appendTo(nil, ctx, &testRecord)))
+}
+
+func TestTemplateJSON(t *testing.T) {
+	ctx := newTestContext()
+	ctx.Request.Header.Set("X-Evil", "\"a\"\n\x01")
+	tmpl := newTestTemplate(t, "${time} - ${status} ${latency} ${route} ${bytesSent} ${reqHeader:X-Evil}", true)
+	out := tmpl.appendTo(nil, ctx, &testRecord)
+
+	var m map[string]any
+	assert.Nil(t, json.Unmarshal(out, &m), string(out))
+	assert.Equal(t, map[string]any{
+		"time":             "2024-01-02 03:04:05.006",
+		"status":           float64(201),
+		"latency":          "1.5ms",
+		"route":            "/user/:name",
+		"bytesSent":        float64(128),
+		"reqHeader:X-Evil": "\"a\"\n\x01",
+	}, m)
+}
+
+func TestTemplateNoAlloc(t *testing.T) {
+	ctx := newTestContext()
+	tmpl := newTestTemplate(t, "[${time}] ${status} ${latency} ${method} ${path} ${route} ${query} "+
+		"${userAgent} ${bytesSent} ${reqHeader:X-Request-ID} ${key:uid}", false)
+	jsonTmpl := newTestTemplate(t, "${time} ${status} ${latency} ${route} ${reqHeader:X-Request-ID}", true)
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = tmpl.appendTo(buf[:0], ctx, &testRecord)
+		buf = jsonTmpl.appendTo(buf[:0], ctx, &testRecord)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestAppendDuration(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0µs"},
+		{512 * time.Microsecond, "512µs"},
+		{1234 * time.Microsecond, "1.234ms"},
+		{1234567 * time.Nanosecond, "1.234ms"},
+		{20 * time.Millisecond, "20ms"},
+		{2500 * time.Millisecond, "2.5s"},
+		{61234567 * time.Microsecond, "61.234s"},
+	} {
+		assert.Equal(t, tt.want, string(appendDuration(nil, tt.d)))
+	}
+}
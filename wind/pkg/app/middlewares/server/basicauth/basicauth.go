@@ -0,0 +1,57 @@
+package basicauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"strconv"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// KeyUser 是认证通过的用户名在 RequestContext.Keys 中的键。
+const KeyUser = "basicauth_user"
+
+// New 返回一个 HTTP 基本认证中间件，未设置账户时引发恐慌。
+//
+// 认证通过时，将用户名存入 Keys 的 KeyUser 中，可通过 GetUser 获取；
+// 否则交由认证失败处理器处理，并以 WWW-Authenticate 标头提示客户端认证。
+// 基本认证以明文传输密码，应仅在 HTTPS 下使用。
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	if o.lookup == nil {
+		panic("basicauth：未设置账户")
+	}
+	challenge := `Basic realm=` + strconv.Quote(o.realm) + `, charset="UTF-8"`
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		username, password, ok := ctx.Request.BasicAuth()
+		if ok && verify(c, o.lookup, username, password) {
+			ctx.Set(KeyUser, username)
+			ctx.Next(c)
+			return
+		}
+
+		// 处理器可能重置响应，标头在其后设置
+		o.unauthorized(c, ctx)
+		ctx.Abort()
+		if ctx.Response.StatusCode() == consts.StatusUnauthorized {
+			ctx.Response.Header.Set(consts.HeaderWWWAuthenticate, challenge)
+		}
+	}
+}
+
+// 校验账户密码，比较摘要以免耗时随密码长度和内容变化。
+func verify(c context.Context, lookup LookupFunc, username, password string) bool {
+	expected, found := lookup(c, username)
+	// 账户不存在时同样进行比较，避免据耗时探测用户名
+	a := sha256.Sum256([]byte(password))
+	b := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1 && found
+}
+
+// GetUser 返回认证通过的用户名，未经认证时返回空串。
+func GetUser(ctx *app.RequestContext) string {
+	return ctx.GetString(KeyUser)
+}
@@ -0,0 +1,95 @@
+package basicauth
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEngine(opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	engine.GET("/admin", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "hello "+GetUser(ctx))
+	})
+	return engine
+}
+
+func basic(username, password string) ut.Header {
+	return ut.Header{
+		Key:   consts.HeaderAuthorization,
+		Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	engine := newTestEngine(WithAccounts(Accounts{"tom": "123456", "jerry": ""}), WithRealm(`wind "admin"`))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/admin", nil, basic("tom", "123456")).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "hello tom", string(resp.Body()))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderWWWAuthenticate))
+
+	// 空密码的账户
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/admin", nil, basic("jerry", "")).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	for _, h := range []ut.Header{
+		basic("tom", "12345"),
+		basic("tom", "1234567"),
+		basic("TOM", "123456"),
+		basic("spike", ""),
+		{Key: consts.HeaderAuthorization, Value: "Basic !"},
+		{Key: consts.HeaderAuthorization, Value: "Bearer token"},
+		{Key: "X-Other", Value: "1"},
+	} {
+		resp = ut.PerformRequest(engine, consts.MethodGet, "/admin", nil, h).Result()
+		assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode(), h.Value)
+		assert.Equal(t, "Unauthorized", string(resp.Body()))
+		assert.Equal(t, `Basic realm="wind \"admin\"", charset="UTF-8"`, string(resp.Header.Peek(consts.HeaderWWWAuthenticate)))
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var got string
+	engine := newTestEngine(WithLookup(func(c context.Context, username string) (string, bool) {
+		got = username
+		return "secret", username == "admin"
+	}))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/admin", nil, basic("admin", "secret")).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "hello admin", string(resp.Body()))
+
+	// 账户不存在时即使密码一致也不通过
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/admin", nil, basic("guest", "secret")).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, "guest", got)
+}
+
+func TestUnauthorized(t *testing.T) {
+	var reached bool
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithAccounts(Accounts{"tom": "123"}), WithUnauthorized(func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusForbidden, "禁止访问")
+	})))
+	engine.GET("/admin", func(c context.Context, ctx *app.RequestContext) {
+		reached = true
+	})
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/admin", nil).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+	assert.Equal(t, "禁止访问", string(resp.Body()))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderWWWAuthenticate))
+	assert.False(t, reached)
+
+	assert.Panics(t, func() { New() })
+	assert.Empty(t, GetUser(app.NewContext(0)))
+}
@@ -0,0 +1,89 @@
+package basicauth
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 默认的认证领域。
+const defaultRealm = "Authorization Required"
+
+// Accounts 表示用户名到密码的映射。
+type Accounts map[string]string
+
+// LookupFunc 按用户名查找账户的密码，账户不存在时 ok 为假。
+type LookupFunc func(c context.Context, username string) (password string, ok bool)
+
+// 表示一个基本认证的自定义选项结构体。
+type options struct {
+	// 认证领域，随 WWW-Authenticate 标头发送。
+	realm string
+
+	// 账户查找函数。
+	lookup LookupFunc
+
+	// 认证失败时的处理器。
+	unauthorized app.HandlerFunc
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认的认证失败处理器，以 401 终止请求。
+func defaultUnauthorized(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg(consts.StatusMessage(consts.StatusUnauthorized), consts.StatusUnauthorized)
+}
+
+// 创建一个自定义基本认证的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		realm:        defaultRealm,
+		unauthorized: defaultUnauthorized,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithRealm 设置认证领域，默认为 "Authorization Required"。
+func WithRealm(realm string) Option {
+	return func(o *options) {
+		o.realm = realm
+	}
+}
+
+// WithAccounts 以固定的账户认证，等同于以查找 accounts 的函数调用 WithLookup。
+func WithAccounts(accounts Accounts) Option {
+	// 复制一份，避免调用方后续修改引起并发读写
+	m := make(Accounts, len(accounts))
+	for user, password := range accounts {
+		m[user] = password
+	}
+	return WithLookup(func(c context.Context, username string) (string, bool) {
+		password, ok := m[username]
+		return password, ok
+	})
+}
+
+// WithLookup 自定义账户查找函数，如从数据库或配置中心读取账户。
+//
+// 中间件以恒定时间比较请求的密码与查找到的密码。
+func WithLookup(f LookupFunc) Option {
+	return func(o *options) {
+		o.lookup = f
+	}
+}
+
+// WithUnauthorized 自定义认证失败时的处理器，默认以 401 终止请求。
+//
+// 处理器返回后请求即被中止；响应状态码为 401 时将补充 WWW-Authenticate 标头。
+func WithUnauthorized(f app.HandlerFunc) Option {
+	return func(o *options) {
+		o.unauthorized = f
+	}
+}
@@ -0,0 +1,37 @@
+package basicauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, "Authorization Required", opts.realm)
+	assert.Nil(t, opts.lookup)
+	assert.Equal(t, fmt.Sprintf("%p", defaultUnauthorized), fmt.Sprintf("%p", opts.unauthorized))
+}
+
+func TestOption(t *testing.T) {
+	unauthorized := func(c context.Context, ctx *app.RequestContext) {}
+	opts := newOptions(WithRealm("admin"), WithUnauthorized(unauthorized))
+	assert.Equal(t, "admin", opts.realm)
+	assert.Equal(t, fmt.Sprintf("%p", unauthorized), fmt.Sprintf("%p", opts.unauthorized))
+
+	accounts := Accounts{"tom": "123"}
+	opts = newOptions(WithAccounts(accounts))
+	accounts["tom"] = "456"
+	password, ok := opts.lookup(context.Background(), "tom")
+	assert.True(t, ok)
+	assert.Equal(t, "123", password)
+	_, ok = opts.lookup(context.Background(), "jerry")
+	assert.False(t, ok)
+
+	lookup := func(c context.Context, username string) (string, bool) { return "", false }
+	opts = newOptions(WithLookup(lookup))
+	assert.Equal(t, fmt.Sprintf("%p", lookup), fmt.Sprintf("%p", opts.lookup))
+}
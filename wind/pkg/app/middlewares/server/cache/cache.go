@@ -0,0 +1,371 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// HeaderXCache 标示响应是否来自缓存，值为 HIT 或 MISS。
+	HeaderXCache = "X-Cache"
+
+	// KeyTags 是处理器为响应设置的标签在 RequestContext.Keys 中的键。
+	KeyTags = "cache_tags"
+)
+
+// 存储中标签的前缀，区分用户标签与缓存键自动生成的标签。
+const (
+	tagPrefix = "tag:"
+	keyPrefix = "key:"
+)
+
+// 缓存响应时不保存的标头。
+var skipHeaders = map[string]bool{
+	consts.HeaderContentLength:    true,
+	consts.HeaderConnection:       true,
+	consts.HeaderTransferEncoding: true,
+	consts.HeaderTrailer:          true,
+	consts.HeaderDate:             true,
+	consts.HeaderAge:              true,
+	consts.HeaderSetCookie:        true,
+	HeaderXCache:                  true,
+}
+
+// Cache 实现共享的 HTTP 响应缓存，语义参见 RFC 9111。
+//
+// 仅缓存 GET 和 HEAD 请求的完整响应（状态码、标头和正文），缓存键由 WithKeyFunc 计算的键
+// 及 WithVaryHeaders 设置的请求标头组成。响应依据 Cache-Control 决定是否缓存及有效期：
+//
+//   - no-store、private 和 no-cache 的响应不缓存，设置了 Set-Cookie 的响应也不缓存；
+//   - s-maxage 优先于 max-age，均未设置时依次使用 Expires 和 WithDefaultTTL；
+//   - stale-while-revalidate 期间返回陈旧的响应，同时在后台重新执行处理链以更新缓存；
+//   - 携带 Authorization 的请求仅在响应含 public、s-maxage 或 must-revalidate 时缓存。
+//
+// 请求的 Cache-Control 为 no-store 时不使用也不更新缓存，为 no-cache 或 max-age=0 时跳过缓存但更新缓存。
+// 同一缓存键的并发未命中请求仅执行一次处理链，其余请求等待其结果。
+//
+// 仅缓存后续处理链设置的标头，先于缓存中间件执行的中间件（如 requestid）设置的标头不缓存，
+// 命中时保留当前请求的这些标头，仅以缓存的状态码、标头和正文覆盖。
+// 命中时响应附加 Age 标头，所有经过中间件的响应均附加 X-Cache: HIT 或 MISS。
+// 通过 SetBodyStream 设置的正文流及已劫持响应编写器的响应不缓存。
+// 与 etag 同时使用时应先注册 etag，以便由其处理命中缓存的条件请求。
+type Cache struct {
+	opts *options
+	now  func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// 一次进行中的处理链执行。
+type call struct {
+	done chan struct{}
+}
+
+// New 返回一个响应缓存中间件，等同于 NewCache(opts...).Handler()。
+func New(opts ...Option) app.HandlerFunc {
+	return NewCache(opts...).Handler()
+}
+
+// NewCache 创建一个响应缓存。
+func NewCache(opts ...Option) *Cache {
+	return &Cache{
+		opts:  newOptions(opts...),
+		now:   time.Now,
+		calls: make(map[string]*call),
+	}
+}
+
+// Handler 返回缓存响应的中间件。
+func (m *Cache) Handler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !ctx.IsGet() && !ctx.IsHead() {
+			ctx.Next(c)
+			return
+		}
+
+		cc := parseCacheControl(ctx.Request.Header.Peek(consts.HeaderCacheControl))
+		if cc == nil && bytes.Contains(ctx.Request.Header.Peek(consts.HeaderPragma), []byte("no-cache")) {
+			cc = directives{"no-cache": ""}
+		}
+		if cc.has("no-store") {
+			ctx.Next(c)
+			ctx.Response.Header.Set(HeaderXCache, "MISS")
+			return
+		}
+
+		outer := outerHeaders(ctx)
+		base := m.opts.keyFunc(ctx)
+		key := m.variantKey(base, ctx)
+		maxAge, hasMaxAge := cc.seconds("max-age")
+		lookup := !cc.has("no-cache") && (!hasMaxAge || maxAge > 0)
+		if lookup && m.serve(c, ctx, key) {
+			return
+		}
+
+		m.mu.Lock()
+		if cl, ok := m.calls[key]; ok {
+			m.mu.Unlock()
+			// 等待进行中的请求，其响应可缓存时直接使用
+			select {
+			case <-cl.done:
+				if lookup && m.serve(c, ctx, key) {
+					return
+				}
+			case <-c.Done():
+			}
+			ctx.Next(c)
+			ctx.Response.Header.Set(HeaderXCache, "MISS")
+			return
+		}
+		cl := &call{done: make(chan struct{})}
+		m.calls[key] = cl
+		m.mu.Unlock()
+
+		defer m.finish(key, cl)
+		ctx.Next(c)
+		m.store(c, ctx, base, key, outer)
+		ctx.Response.Header.Set(HeaderXCache, "MISS")
+	}
+}
+
+// 返回处理链执行前响应已有的标头，即外层中间件设置的标头。
+func outerHeaders(ctx *app.RequestContext) map[HeaderField]bool {
+	var outer map[HeaderField]bool
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		if outer == nil {
+			outer = make(map[HeaderField]bool)
+		}
+		outer[HeaderField{Key: string(k), Value: string(v)}] = true
+	})
+	return outer
+}
+
+// Invalidate 使缓存键为 key 的所有响应失效，key 为 WithKeyFunc 计算的键，不含 Vary 部分。
+func (m *Cache) Invalidate(c context.Context, key string) error {
+	return m.opts.store.DeleteTag(c, keyPrefix+key)
+}
+
+// InvalidateTag 使带有该标签的所有响应失效，标签由处理器通过 AddTags 设置。
+func (m *Cache) InvalidateTag(c context.Context, tag string) error {
+	return m.opts.store.DeleteTag(c, tagPrefix+tag)
+}
+
+// 从缓存中读取并写入响应，未命中或已过期时返回假。陈旧但可用的响应将在后台更新。
+func (m *Cache) serve(c context.Context, ctx *app.RequestContext, key string) bool {
+	e, err := m.opts.store.Get(c, key)
+	if err != nil {
+		hlog.SystemLogger().CtxWarnf(c, "读取响应缓存失败：%v", err)
+		return false
+	}
+	if e == nil {
+		return false
+	}
+	now := m.now()
+	if !now.Before(e.StaleUntil) {
+		return false
+	}
+	if !now.Before(e.Expires) {
+		m.revalidate(ctx, key)
+	}
+
+	// 不重置响应，以保留外层中间件为当前请求设置的标头
+	resp := &ctx.Response
+	resp.SetStatusCode(e.StatusCode)
+	for _, h := range e.Header {
+		resp.Header.Del(h.Key)
+	}
+	for _, h := range e.Header {
+		resp.Header.Add(h.Key, h.Value)
+	}
+	resp.SetBody(e.Body)
+	age := now.Sub(e.Date) / time.Second
+	if age < 0 {
+		age = 0
+	}
+	resp.Header.Set(consts.HeaderAge, strconv.FormatInt(int64(age), 10))
+	resp.Header.Set(HeaderXCache, "HIT")
+	ctx.Abort()
+	return true
+}
+
+// 在后台以请求副本重新执行后续处理链并更新缓存，同一缓存键同时仅更新一次。
+func (m *Cache) revalidate(ctx *app.RequestContext, key string) {
+	m.mu.Lock()
+	if _, ok := m.calls[key]; ok {
+		m.mu.Unlock()
+		return
+	}
+	cl := &call{done: make(chan struct{})}
+	m.calls[key] = cl
+	m.mu.Unlock()
+
+	cp := ctx.Copy()
+	cp.SetHandlers(ctx.Handlers()[ctx.GetIndex()+1:])
+	cp.SetIndex(-1)
+	cp.Response.Reset()
+	base := m.opts.keyFunc(ctx)
+	// 去除条件标头，以便获得完整的响应
+	for _, h := range []string{consts.HeaderIfNoneMatch, consts.HeaderIfModifiedSince, consts.HeaderIfMatch, consts.HeaderIfUnmodifiedSince, consts.HeaderIfRange, consts.HeaderRange} {
+		cp.Request.Header.DelBytes([]byte(h))
+	}
+
+	go func() {
+		c := context.Background()
+		defer m.finish(key, cl)
+		defer func() {
+			if v := recover(); v != nil {
+				hlog.SystemLogger().CtxErrorf(c, "后台更新响应缓存时发生恐慌：%v", v)
+			}
+		}()
+		cp.Next(c)
+		m.store(c, cp, base, key, nil)
+	}()
+}
+
+func (m *Cache) finish(key string, cl *call) {
+	m.mu.Lock()
+	delete(m.calls, key)
+	m.mu.Unlock()
+	close(cl.done)
+}
+
+// 保存可缓存的响应，outer 为外层中间件设置的标头，不予保存。
+func (m *Cache) store(c context.Context, ctx *app.RequestContext, base, key string, outer map[HeaderField]bool) {
+	resp := &ctx.Response
+	if !cacheableStatus[resp.StatusCode()] || resp.IsBodyStream() || resp.GetHijackWriter() != nil ||
+		resp.SkipBody || len(resp.Body()) > m.opts.maxBodySize {
+		return
+	}
+	hasCookie := false
+	resp.Header.VisitAllCookie(func(k, v []byte) {
+		hasCookie = true
+	})
+	if hasCookie || !m.varyCovered(resp.Header.PeekAll(consts.HeaderVary)) {
+		return
+	}
+	now := m.now()
+	authorized := len(ctx.Request.Header.Peek(consts.HeaderAuthorization)) > 0
+	ttl, stale, ok := freshness(resp, authorized, m.opts.defaultTTL, now)
+	if !ok {
+		return
+	}
+
+	e := &Entry{
+		StatusCode: resp.StatusCode(),
+		Body:       append([]byte(nil), resp.Body()...),
+		Tags:       []string{keyPrefix + base},
+		Date:       now,
+		Expires:    now.Add(ttl),
+		StaleUntil: now.Add(ttl + stale),
+	}
+	resp.Header.VisitAll(func(k, v []byte) {
+		h := HeaderField{Key: string(k), Value: string(v)}
+		if !skipHeaders[h.Key] && !outer[h] {
+			e.Header = append(e.Header, h)
+		}
+	})
+	for _, t := range GetTags(ctx) {
+		e.Tags = append(e.Tags, tagPrefix+t)
+	}
+	if err := m.opts.store.Set(c, key, e); err != nil {
+		hlog.SystemLogger().CtxWarnf(c, "保存响应缓存失败：%v", err)
+	}
+}
+
+// 判断响应的 Vary 标头是否均已包含在缓存键中。
+func (m *Cache) varyCovered(values [][]byte) bool {
+	for _, v := range values {
+		for _, h := range strings.Split(string(v), ",") {
+			h = strings.TrimSpace(h)
+			if h == "" {
+				continue
+			}
+			if h == "*" {
+				return false
+			}
+			found := false
+			for _, vh := range m.opts.varyHeaders {
+				if strings.EqualFold(h, vh) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 在缓存键后附加参与缓存键的请求标头。
+func (m *Cache) variantKey(base string, ctx *app.RequestContext) string {
+	if len(m.opts.varyHeaders) == 0 {
+		return base
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	for _, h := range m.opts.varyHeaders {
+		b.WriteByte('\n')
+		b.WriteString(h)
+		b.WriteByte(':')
+		b.Write(ctx.Request.Header.Peek(h))
+	}
+	return b.String()
+}
+
+// DefaultKey 返回请求默认的缓存键，形如 "GET /path?a=1&b=2"，查询参数按名称和值排序。
+func DefaultKey(ctx *app.RequestContext) string {
+	type arg struct{ k, v string }
+	var args []arg
+	ctx.QueryArgs().VisitAll(func(k, v []byte) {
+		args = append(args, arg{string(k), string(v)})
+	})
+	sort.Slice(args, func(i, j int) bool {
+		if args[i].k != args[j].k {
+			return args[i].k < args[j].k
+		}
+		return args[i].v < args[j].v
+	})
+
+	var b strings.Builder
+	b.Write(ctx.Request.Header.Method())
+	b.WriteByte(' ')
+	b.Write(ctx.Request.URI().Path())
+	for i, a := range args {
+		if i == 0 {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(a.k))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(a.v))
+	}
+	return b.String()
+}
+
+// AddTags 为当前响应添加标签，以便通过 Cache.InvalidateTag 使其失效。
+func AddTags(ctx *app.RequestContext, tags ...string) {
+	ctx.Set(KeyTags, append(GetTags(ctx), tags...))
+}
+
+// GetTags 返回处理器为当前响应设置的标签。
+func GetTags(ctx *app.RequestContext) []string {
+	if v, ok := ctx.Get(KeyTags); ok {
+		tags, _ := v.([]string)
+		return tags
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/middlewares/server/requestid"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+type testServer struct {
+	engine *route.Engine
+	cache  *Cache
+	store  *MemoryStore
+	calls  atomic.Int32
+	now    time.Time
+	mu     sync.Mutex
+}
+
+func (s *testServer) setNow(now time.Time) {
+	s.mu.Lock()
+	s.now = now
+	s.mu.Unlock()
+}
+
+func (s *testServer) getNow() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// 创建测试服务，响应正文为处理器的累计调用次数，Cache-Control 取自查询参数 cc。
+func newTestServer(opts ...Option) *testServer {
+	s := &testServer{store: NewMemoryStore(1 << 20), now: time.Now()}
+	s.store.now = s.getNow
+	s.cache = NewCache(append([]Option{WithStore(s.store)}, opts...)...)
+	s.cache.now = s.getNow
+
+	handler := func(c context.Context, ctx *app.RequestContext) {
+		n := s.calls.Add(1)
+		if cc := ctx.Query("cc"); cc != "" {
+			ctx.Response.Header.Set(consts.HeaderCacheControl, cc)
+		}
+		if vary := ctx.Query("vary"); vary != "" {
+			ctx.Response.Header.Set(consts.HeaderVary, vary)
+		}
+		if tag := ctx.Query("tag"); tag != "" {
+			AddTags(ctx, tag)
+		}
+		if ctx.Query("cookie") != "" {
+			cookie := protocol.AcquireCookie()
+			cookie.SetKey("id")
+			cookie.SetValue("1")
+			ctx.Response.Header.SetCookie(cookie)
+			protocol.ReleaseCookie(cookie)
+		}
+		status := consts.StatusOK
+		if v := ctx.Query("status"); v != "" {
+			status, _ = strconv.Atoi(v)
+		}
+		ctx.Response.Header.Set("X-Custom", "custom")
+		ctx.String(status, strconv.Itoa(int(n)))
+	}
+
+	s.engine = route.NewEngine(config.NewOptions(nil))
+	s.engine.Use(s.cache.Handler())
+	s.engine.GET("/", handler)
+	s.engine.HEAD("/", handler)
+	s.engine.POST("/", handler)
+	s.engine.GET("/stream", func(c context.Context, ctx *app.RequestContext) {
+		s.calls.Add(1)
+		ctx.Response.Header.Set(consts.HeaderCacheControl, "max-age=60")
+		ctx.SetBodyStream(strings.NewReader("stream"), -1)
+	})
+	return s
+}
+
+func (s *testServer) do(method, url string, headers ...ut.Header) *protocol.Response {
+	return ut.PerformRequest(s.engine, method, url, nil, headers...).Result()
+}
+
+func (s *testServer) get(url string, headers ...ut.Header) *protocol.Response {
+	return s.do(consts.MethodGet, url, headers...)
+}
+
+func assertHit(t *testing.T, resp *protocol.Response, body string, msgs ...any) {
+	assert.Equal(t, "HIT", string(resp.Header.Peek(HeaderXCache)), msgs...)
+	assert.Equal(t, body, string(resp.Body()), msgs...)
+}
+
+func assertMiss(t *testing.T, resp *protocol.Response, body string, msgs ...any) {
+	assert.Equal(t, "MISS", string(resp.Header.Peek(HeaderXCache)), msgs...)
+	assert.Equal(t, body, string(resp.Body()), msgs...)
+}
+
+func TestCache(t *testing.T) {
+	s := newTestServer()
+	resp := s.get("/?cc=max-age=60")
+	assertMiss(t, resp, "1")
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAge))
+
+	s.setNow(s.now.Add(10 * time.Second))
+	resp = s.get("/?cc=max-age=60")
+	assertHit(t, resp, "1")
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "10", string(resp.Header.Peek(consts.HeaderAge)))
+	assert.Equal(t, "max-age=60", string(resp.Header.Peek(consts.HeaderCacheControl)))
+	assert.Equal(t, "custom", string(resp.Header.Peek("X-Custom")))
+	assert.Equal(t, "text/plain; charset=utf-8", string(resp.Header.ContentType()))
+
+	// 查询参数的顺序不影响缓存键
+	assertMiss(t, s.get("/?cc=max-age=60&b=2&a=1"), "2")
+	assertHit(t, s.get("/?a=1&cc=max-age=60&b=2"), "2")
+
+	// 方法参与缓存键，非 GET 和 HEAD 请求不缓存
+	assertMiss(t, s.do(consts.MethodHead, "/?cc=max-age=60"), "3")
+	assertHit(t, s.do(consts.MethodHead, "/?cc=max-age=60"), "3")
+	resp = s.do(consts.MethodPost, "/?cc=max-age=60")
+	assert.Empty(t, resp.Header.Peek(HeaderXCache))
+	assert.Equal(t, "4", string(resp.Body()))
+
+	// 过期后重新执行处理链
+	s.setNow(s.now.Add(time.Minute))
+	assertMiss(t, s.get("/?cc=max-age=60"), "5")
+	assertHit(t, s.get("/?cc=max-age=60"), "5")
+	assert.Equal(t, int32(5), s.calls.Load())
+}
+
+func TestNotCacheable(t *testing.T) {
+	for _, url := range []string{
+		"/",
+		"/?cc=no-store,max-age=60",
+		"/?cc=private,max-age=60",
+		"/?cc=no-cache,max-age=60",
+		"/?cc=max-age=0",
+		"/?cc=max-age=60&cookie=1",
+		"/?cc=max-age=60&status=500",
+		"/?cc=max-age=60&vary=Accept-Language",
+		"/?cc=max-age=60&vary=*",
+		"/stream",
+	} {
+		s := newTestServer()
+		s.get(url)
+		s.get(url)
+		assert.Equal(t, int32(2), s.calls.Load(), url)
+		assert.Zero(t, s.store.Len(), url)
+	}
+
+	// 超出大小的正文
+	s := newTestServer(WithMaxBodySize(0))
+	s.get("/?cc=max-age=60")
+	assert.Zero(t, s.store.Len())
+
+	// 携带凭证的请求
+	s = newTestServer()
+	auth := ut.Header{Key: consts.HeaderAuthorization, Value: "Bearer token"}
+	s.get("/?cc=max-age=60", auth)
+	assert.Zero(t, s.store.Len())
+	s.get("/?cc=public,max-age=60", auth)
+	assertHit(t, s.get("/?cc=public,max-age=60"), "2")
+}
+
+func TestCacheableStatus(t *testing.T) {
+	s := newTestServer()
+	assertMiss(t, s.get("/?cc=max-age=60&status=404"), "1")
+	resp := s.get("/?cc=max-age=60&status=404")
+	assertHit(t, resp, "1")
+	assert.Equal(t, consts.StatusNotFound, resp.StatusCode())
+}
+
+func TestDefaultTTL(t *testing.T) {
+	s := newTestServer(WithDefaultTTL(time.Minute))
+	assertMiss(t, s.get("/"), "1")
+	assertHit(t, s.get("/"), "1")
+	s.setNow(s.now.Add(time.Minute))
+	assertMiss(t, s.get("/"), "2")
+}
+
+func TestRequestCacheControl(t *testing.T) {
+	s := newTestServer()
+	s.get("/?cc=max-age=60")
+
+	// no-cache 和 max-age=0 跳过缓存但更新缓存
+	for i, h := range []ut.Header{
+		{Key: consts.HeaderCacheControl, Value: "no-cache"},
+		{Key: consts.HeaderCacheControl, Value: "max-age=0"},
+		{Key: consts.HeaderPragma, Value: "no-cache"},
+	} {
+		body := strconv.Itoa(i + 2)
+		assertMiss(t, s.get("/?cc=max-age=60", h), body, h)
+		assertHit(t, s.get("/?cc=max-age=60"), body, h)
+	}
+
+	// no-store 既不使用也不更新缓存
+	assertMiss(t, s.get("/?cc=max-age=60", ut.Header{Key: consts.HeaderCacheControl, Value: "no-store"}), "5")
+	assertHit(t, s.get("/?cc=max-age=60"), "4")
+
+	// 其他指令不影响
+	assertHit(t, s.get("/?cc=max-age=60", ut.Header{Key: consts.HeaderCacheControl, Value: "max-age=10"}), "4")
+}
+
+func TestVaryHeaders(t *testing.T) {
+	s := newTestServer(WithVaryHeaders("Accept-Language"))
+	en := ut.Header{Key: consts.HeaderAcceptLanguage, Value: "en"}
+	zh := ut.Header{Key: consts.HeaderAcceptLanguage, Value: "zh"}
+	url := "/?cc=max-age=60&vary=accept-language"
+
+	assertMiss(t, s.get(url, en), "1")
+	assertMiss(t, s.get(url, zh), "2")
+	assertMiss(t, s.get(url), "3")
+	assertHit(t, s.get(url, en), "1")
+	assertHit(t, s.get(url, zh), "2")
+	assertHit(t, s.get(url), "3")
+	assert.Equal(t, "accept-language", string(s.get(url, en).Header.Peek(consts.HeaderVary)))
+
+	// 按键失效时删除所有变体
+	assert.Nil(t, s.cache.Invalidate(context.Background(), "GET /?cc=max-age%3D60&vary=accept-language"))
+	assert.Zero(t, s.store.Len())
+	assertMiss(t, s.get(url, en), "4")
+}
+
+func TestInvalidate(t *testing.T) {
+	s := newTestServer()
+	c := context.Background()
+	s.get("/?cc=max-age=60&tag=a&id=1")
+	s.get("/?cc=max-age=60&tag=a&id=2")
+	s.get("/?cc=max-age=60&tag=b&id=3")
+	assert.Equal(t, 3, s.store.Len())
+
+	assert.Nil(t, s.cache.InvalidateTag(c, "a"))
+	assert.Equal(t, 1, s.store.Len())
+	assertHit(t, s.get("/?cc=max-age=60&tag=b&id=3"), "3")
+	assertMiss(t, s.get("/?cc=max-age=60&tag=a&id=1"), "4")
+
+	// 标签与缓存键互不干扰
+	assert.Nil(t, s.cache.Invalidate(c, "b"))
+	assert.Nil(t, s.cache.InvalidateTag(c, "GET /?cc=max-age%3D60&id=3&tag=b"))
+	assert.Equal(t, 2, s.store.Len())
+	assert.Nil(t, s.cache.Invalidate(c, "GET /?cc=max-age%3D60&id=3&tag=b"))
+	assert.Equal(t, 1, s.store.Len())
+}
+
+func TestCollapse(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New())
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		calls.Add(1)
+		<-release
+		if ctx.Query("cc") != "" {
+			ctx.Response.Header.Set(consts.HeaderCacheControl, ctx.Query("cc"))
+		}
+		ctx.String(consts.StatusOK, "ok")
+	})
+
+	for cc, expected := range map[string]int32{"max-age=60": 1, "no-store": 10} {
+		calls.Store(0)
+		release = make(chan struct{})
+		var wg sync.WaitGroup
+		results := make(chan *protocol.Response, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results <- ut.PerformRequest(engine, consts.MethodGet, "/?cc="+cc, nil).Result()
+			}()
+		}
+		// 等待首个请求进入处理器，其余请求在等待
+		assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, int32(1), calls.Load(), cc)
+		close(release)
+		wg.Wait()
+		close(results)
+
+		// 可缓存时其余请求直接使用其响应，否则各自执行处理链
+		hits := 0
+		for resp := range results {
+			assert.Equal(t, "ok", string(resp.Body()))
+			if string(resp.Header.Peek(HeaderXCache)) == "HIT" {
+				hits++
+			}
+		}
+		assert.Equal(t, expected, calls.Load(), cc)
+		assert.Equal(t, int(10-expected), hits, cc)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	s := newTestServer()
+	url := "/?cc=max-age=60,stale-while-revalidate=30"
+	assertMiss(t, s.get(url), "1")
+
+	// 陈旧期内返回陈旧的响应并在后台更新
+	s.setNow(s.now.Add(70 * time.Second))
+	resp := s.get(url)
+	assertHit(t, resp, "1")
+	assert.Equal(t, "70", string(resp.Header.Peek(consts.HeaderAge)))
+	assert.Eventually(t, func() bool {
+		resp := s.get(url)
+		return string(resp.Body()) == "2" && string(resp.Header.Peek(HeaderXCache)) == "HIT"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), s.calls.Load())
+
+	// 超出陈旧期后同步执行处理链
+	s.setNow(s.now.Add(200 * time.Second))
+	assertMiss(t, s.get(url), "3")
+}
+
+func TestOuterHeaders(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(requestid.New(), func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set("X-Outer", "outer")
+		ctx.Next(c)
+	}, New())
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set(consts.HeaderCacheControl, "max-age=60")
+		ctx.Response.Header.Set("X-Outer", "handler")
+		ctx.String(consts.StatusOK, "ok")
+	})
+
+	first := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assertMiss(t, first, "ok")
+	id1 := string(first.Header.Peek("X-Request-ID"))
+	assert.NotEmpty(t, id1)
+
+	// 命中时保留当前请求的标识，处理器覆盖的标头取自缓存
+	second := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assertHit(t, second, "ok")
+	id2 := string(second.Header.Peek("X-Request-ID"))
+	assert.NotEmpty(t, id2)
+	assert.NotEqual(t, id1, id2)
+	assert.Equal(t, []string{"handler"}, peekAll(second, "X-Outer"))
+	assert.Equal(t, "max-age=60", string(second.Header.Peek(consts.HeaderCacheControl)))
+}
+
+func peekAll(resp *protocol.Response, key string) []string {
+	var vs []string
+	for _, v := range resp.Header.PeekAll(key) {
+		vs = append(vs, string(v))
+	}
+	return vs
+}
+
+func TestDefaultKey(t *testing.T) {
+	for url, key := range map[string]string{
+		"/":                   "GET /",
+		"/a/b?x=1":            "GET /a/b?x=1",
+		"/?b=2&a=3&a=1":       "GET /?a=1&a=3&b=2",
+		"/?q=a b&r=%26":       "GET /?q=a+b&r=%26",
+		"/path%20with?empty=": "GET /path with?empty=",
+	} {
+		ctx := app.NewContext(0)
+		ctx.Request.SetRequestURI(url)
+		assert.Equal(t, key, DefaultKey(ctx), url)
+	}
+}
+
+func TestAddTags(t *testing.T) {
+	ctx := app.NewContext(0)
+	assert.Nil(t, GetTags(ctx))
+	AddTags(ctx, "a")
+	AddTags(ctx, "b", "c")
+	assert.Equal(t, []string{"a", "b", "c"}, GetTags(ctx))
+}
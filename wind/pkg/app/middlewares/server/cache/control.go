@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 可启发式缓存的状态码，参见 RFC 9110 第 15.1 节。
+var cacheableStatus = map[int]bool{
+	consts.StatusOK:                   true,
+	consts.StatusNonAuthoritativeInfo: true,
+	consts.StatusNoContent:            true,
+	consts.StatusMultipleChoices:      true,
+	consts.StatusMovedPermanently:     true,
+	consts.StatusPermanentRedirect:    true,
+	consts.StatusNotFound:             true,
+	consts.StatusMethodNotAllowed:     true,
+	consts.StatusGone:                 true,
+	consts.StatusRequestURITooLong:    true,
+	consts.StatusNotImplemented:       true,
+}
+
+// 缓存指令，键为小写的指令名。
+type directives map[string]string
+
+// 解析 Cache-Control 标头，如 "public, max-age=60, no-cache="Set-Cookie""。
+func parseCacheControl(v []byte) directives {
+	if len(v) == 0 {
+		return nil
+	}
+	d := make(directives)
+	s := string(v)
+	for s != "" {
+		var part string
+		// 带引号的值中可含逗号
+		if i := strings.IndexAny(s, ",\""); i >= 0 && s[i] == '"' {
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				part, s = s, ""
+			} else {
+				j := i + 1 + end + 1
+				part, s = s[:j], s[j:]
+				if k := strings.IndexByte(s, ','); k >= 0 {
+					s = s[k+1:]
+				} else {
+					s = ""
+				}
+			}
+		} else if i >= 0 {
+			part, s = s[:i], s[i+1:]
+		} else {
+			part, s = s, ""
+		}
+
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		d[name] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return d
+}
+
+func (d directives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// 返回以秒为单位的时长指令，不存在或无效时 ok 为假。
+func (d directives) seconds(name string) (time.Duration, bool) {
+	v, ok := d[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// 计算响应作为共享缓存的新鲜期和可陈旧使用的时长，不可缓存时 ok 为假。
+func freshness(resp *protocol.Response, authorized bool, defaultTTL time.Duration, now time.Time) (ttl, stale time.Duration, ok bool) {
+	cc := parseCacheControl(resp.Header.Peek(consts.HeaderCacheControl))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return 0, 0, false
+	}
+	// 携带凭证的请求仅在响应明确允许时缓存，参见 RFC 9111 第 3.5 节
+	if authorized && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return 0, 0, false
+	}
+
+	if d, ok := cc.seconds("s-maxage"); ok {
+		ttl = d
+	} else if d, ok := cc.seconds("max-age"); ok {
+		ttl = d
+	} else if v := resp.Header.Peek(consts.HeaderExpires); len(v) > 0 {
+		// 无效的 Expires 表示已过期
+		if t, err := bytesconv.ParseHTTPDate(v); err == nil {
+			ttl = t.Sub(now)
+		}
+	} else {
+		ttl = defaultTTL
+	}
+	if ttl <= 0 {
+		return 0, 0, false
+	}
+
+	// must-revalidate 等指令禁止使用陈旧的响应
+	if !cc.has("must-revalidate") && !cc.has("proxy-revalidate") {
+		stale, _ = cc.seconds("stale-while-revalidate")
+	}
+	return ttl, stale, true
+}
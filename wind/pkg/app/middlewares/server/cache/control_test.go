@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	assert.Nil(t, parseCacheControl(nil))
+	d := parseCacheControl([]byte(`Public, MAX-AGE=60 , s-maxage="120", no-cache="Set-Cookie, X-Foo", ,private`))
+	assert.Equal(t, directives{
+		"public":   "",
+		"max-age":  "60",
+		"s-maxage": "120",
+		"no-cache": "Set-Cookie, X-Foo",
+		"private":  "",
+	}, d)
+
+	ttl, ok := d.seconds("s-maxage")
+	assert.True(t, ok)
+	assert.Equal(t, 120*time.Second, ttl)
+	_, ok = d.seconds("public")
+	assert.False(t, ok)
+	_, ok = parseCacheControl([]byte("max-age=-1")).seconds("max-age")
+	assert.False(t, ok)
+	_, ok = parseCacheControl([]byte("max-age=abc")).seconds("max-age")
+	assert.False(t, ok)
+
+	// 未闭合的引号
+	assert.Equal(t, directives{"a": "1", "b": "x, c=2"}, parseCacheControl([]byte(`a=1, b="x, c=2`)))
+}
+
+func TestFreshness(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		cacheControl string
+		expires      string
+		authorized   bool
+		defaultTTL   time.Duration
+		ttl, stale   time.Duration
+		ok           bool
+	}{
+		{cacheControl: "max-age=60", ttl: time.Minute, ok: true},
+		{cacheControl: "max-age=60, s-maxage=10", ttl: 10 * time.Second, ok: true},
+		{cacheControl: "max-age=60, stale-while-revalidate=30", ttl: time.Minute, stale: 30 * time.Second, ok: true},
+		{cacheControl: "max-age=60, stale-while-revalidate=30, must-revalidate", ttl: time.Minute, ok: true},
+		{cacheControl: "max-age=0"},
+		{cacheControl: "max-age=60, no-store"},
+		{cacheControl: "max-age=60, private"},
+		{cacheControl: "max-age=60, no-cache"},
+		{cacheControl: "max-age=60", authorized: true},
+		{cacheControl: "max-age=60, public", authorized: true, ttl: time.Minute, ok: true},
+		{cacheControl: "s-maxage=60", authorized: true, ttl: time.Minute, ok: true},
+		{expires: string(bytesconv.AppendHTTPDate(nil, now.Add(time.Hour))), ttl: time.Hour, ok: true},
+		{expires: string(bytesconv.AppendHTTPDate(nil, now.Add(-time.Hour)))},
+		{expires: "0", defaultTTL: time.Hour},
+		{defaultTTL: time.Hour, ttl: time.Hour, ok: true},
+		{},
+	} {
+		resp := &protocol.Response{}
+		if tc.cacheControl != "" {
+			resp.Header.Set(consts.HeaderCacheControl, tc.cacheControl)
+		}
+		if tc.expires != "" {
+			resp.Header.Set(consts.HeaderExpires, tc.expires)
+		}
+		ttl, stale, ok := freshness(resp, tc.authorized, tc.defaultTTL, now)
+		assert.Equal(t, tc.ok, ok, tc)
+		assert.Equal(t, tc.ttl, ttl, tc)
+		assert.Equal(t, tc.stale, stale, tc)
+	}
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件存储中响应文件名的前缀。
+const filePrefix = "cache_"
+
+var errExpired = errors.New("cache：响应已过期")
+
+// 响应文件开头的元数据，便于不读取正文即可建立索引。
+type fileMeta struct {
+	Key        string
+	Tags       []string
+	StaleUntil time.Time
+}
+
+// FileStore 是将每个响应保存为一个文件的存储，适用于单实例部署。
+//
+// 标签索引保存在内存中，创建时扫描目录重建。过期的响应文件在读取时删除，也可定期调用 Cleanup 清理。
+type FileStore struct {
+	dir string
+	now func() time.Time
+
+	mu   sync.Mutex
+	tags tagIndex
+	keys map[string][]string
+}
+
+// NewFileStore 创建一个将响应保存在 dir 目录下的文件存储，目录不存在时自动创建。
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		dir:  dir,
+		now:  time.Now,
+		tags: make(tagIndex),
+		keys: make(map[string][]string),
+	}
+	if err := s.scan(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, filePrefix+hex.EncodeToString(sum[:]))
+}
+
+// Get 实现 Store 接口。
+func (s *FileStore) Get(_ context.Context, key string) (*Entry, error) {
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return nil, err
+	}
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	var meta fileMeta
+	e := new(Entry)
+	if err = dec.Decode(&meta); err == nil && meta.Key == key && s.now().Before(meta.StaleUntil) {
+		err = dec.Decode(e)
+	} else if err == nil && meta.Key == key {
+		err = errExpired
+	}
+	_ = f.Close()
+
+	switch {
+	case err == nil:
+		return e, nil
+	case err == errExpired:
+		_ = s.Delete(context.Background(), key)
+	}
+	// 损坏的文件及散列冲突均视为未命中
+	return nil, nil
+}
+
+// Set 实现 Store 接口。
+func (s *FileStore) Set(_ context.Context, key string, e *Entry) error {
+	// 先写入临时文件再重命名，避免并发读取到不完整的内容
+	f, err := os.CreateTemp(s.dir, ".tmp_")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	if err = enc.Encode(fileMeta{Key: key, Tags: e.Tags, StaleUntil: e.StaleUntil}); err == nil {
+		if err = enc.Encode(e); err == nil {
+			err = w.Flush()
+		}
+	}
+	if err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	s.index(key, e.Tags)
+	return nil
+}
+
+// Delete 实现 Store 接口。
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(key)
+}
+
+// DeleteTag 实现 Store 接口。
+func (s *FileStore) DeleteTag(_ context.Context, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.tags.keys(tag) {
+		if err := s.delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cleanup 删除过期的响应文件。
+func (s *FileStore) Cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.walk(func(name string, meta *fileMeta) {
+		if meta == nil {
+			_ = os.Remove(name)
+			return
+		}
+		if !s.now().Before(meta.StaleUntil) {
+			_ = s.delete(meta.Key)
+		}
+	})
+}
+
+// 扫描目录重建标签索引，并删除过期或损坏的文件。
+func (s *FileStore) scan() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	return s.walk(func(name string, meta *fileMeta) {
+		if meta == nil || !now.Before(meta.StaleUntil) {
+			_ = os.Remove(name)
+			return
+		}
+		s.index(meta.Key, meta.Tags)
+	})
+}
+
+// 遍历响应文件，元数据无法解析时 meta 为 nil。
+func (s *FileStore) walk(f func(name string, meta *fileMeta)) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), filePrefix) {
+			continue
+		}
+		name := filepath.Join(s.dir, e.Name())
+		meta, err := readMeta(name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			meta = nil
+		}
+		f(name, meta)
+	}
+	return nil
+}
+
+func readMeta(name string) (*fileMeta, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	meta := new(fileMeta)
+	if err = gob.NewDecoder(io.LimitReader(bufio.NewReader(f), 1<<20)).Decode(meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func (s *FileStore) index(key string, tags []string) {
+	s.tags.remove(key, s.keys[key])
+	s.tags.add(key, tags)
+	s.keys[key] = tags
+}
+
+func (s *FileStore) delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.tags.remove(key, s.keys[key])
+	delete(s.keys, key)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"net/textproto"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+)
+
+const (
+	// 默认内存存储的容量。
+	defaultMaxBytes = 64 << 20
+
+	// 默认可缓存的最大正文字节数。
+	defaultMaxBodySize = 1 << 20
+)
+
+// 表示一个响应缓存的自定义选项结构体。
+type options struct {
+	// 响应的存储。
+	store Store
+
+	// 响应未声明有效期时的默认有效期，为零时不缓存此类响应。
+	defaultTTL time.Duration
+
+	// 参与缓存键的请求标头，已规范化。
+	varyHeaders []string
+
+	// 计算缓存键的函数。
+	keyFunc func(ctx *app.RequestContext) string
+
+	// 可缓存的最大正文字节数。
+	maxBodySize int
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义响应缓存的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyFunc:     DefaultKey,
+		maxBodySize: defaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore(defaultMaxBytes)
+	}
+
+	return cfg
+}
+
+// WithStore 设置响应的存储，默认为容量 64 MiB 的内存存储。
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithDefaultTTL 设置响应未通过 Cache-Control 或 Expires 声明有效期时的默认有效期。
+//
+// 默认为零，即仅缓存明确声明了有效期的响应。
+func WithDefaultTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.defaultTTL = d
+	}
+}
+
+// WithVaryHeaders 设置参与缓存键的请求标头，如 Accept-Encoding、Accept-Language。
+//
+// 响应的 Vary 标头包含未设置的请求标头或为 * 时不缓存该响应。
+func WithVaryHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.varyHeaders = make([]string, len(headers))
+		for i, h := range headers {
+			o.varyHeaders[i] = textproto.CanonicalMIMEHeaderKey(h)
+		}
+	}
+}
+
+// WithKeyFunc 自定义计算缓存键的函数，默认为 DefaultKey。
+func WithKeyFunc(f func(ctx *app.RequestContext) string) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMaxBodySize 设置可缓存的最大正文字节数，默认为 1 MiB。
+func WithMaxBodySize(n int) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
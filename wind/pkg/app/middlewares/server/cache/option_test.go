@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	store, ok := opts.store.(*MemoryStore)
+	assert.True(t, ok)
+	assert.Equal(t, int64(defaultMaxBytes), store.maxBytes)
+	assert.Zero(t, opts.defaultTTL)
+	assert.Nil(t, opts.varyHeaders)
+	assert.Equal(t, fmt.Sprintf("%p", DefaultKey), fmt.Sprintf("%p", opts.keyFunc))
+	assert.Equal(t, defaultMaxBodySize, opts.maxBodySize)
+}
+
+func TestOption(t *testing.T) {
+	store := NewMemoryStore(1024)
+	keyFunc := func(ctx *app.RequestContext) string { return "key" }
+	opts := newOptions(
+		WithStore(store),
+		WithDefaultTTL(time.Minute),
+		WithVaryHeaders("accept-encoding", "X-Tenant"),
+		WithKeyFunc(keyFunc),
+		WithMaxBodySize(10),
+	)
+	assert.Same(t, store, opts.store)
+	assert.Equal(t, time.Minute, opts.defaultTTL)
+	assert.Equal(t, []string{"Accept-Encoding", "X-Tenant"}, opts.varyHeaders)
+	assert.Equal(t, fmt.Sprintf("%p", keyFunc), fmt.Sprintf("%p", opts.keyFunc))
+	assert.Equal(t, 10, opts.maxBodySize)
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Entry 表示一个缓存的响应。
+//
+// 存储返回的 Entry 可能被多个请求共享，调用方不得修改。
+type Entry struct {
+	// StatusCode 为响应的状态码。
+	StatusCode int
+
+	// Header 为响应标头，不含 Content-Length、Date 等逐跳或每次生成的标头。
+	Header []HeaderField
+
+	// Body 为响应正文。
+	Body []byte
+
+	// Tags 为响应的标签，用于按标签失效。
+	Tags []string
+
+	// Date 为响应的生成时间。
+	Date time.Time
+
+	// Expires 为响应的过期时间，此前的响应是新鲜的。
+	Expires time.Time
+
+	// StaleUntil 为陈旧响应可在后台重新验证期间继续使用的截止时间，存储可在此后丢弃该响应。
+	StaleUntil time.Time
+}
+
+// HeaderField 表示一个响应标头。
+type HeaderField struct {
+	Key   string
+	Value string
+}
+
+// 估算条目占用的字节数。
+func (e *Entry) size() int64 {
+	n := len(e.Body)
+	for _, h := range e.Header {
+		n += len(h.Key) + len(h.Value)
+	}
+	for _, t := range e.Tags {
+		n += len(t)
+	}
+	return int64(n)
+}
+
+// Store 保存缓存的响应。
+type Store interface {
+	// Get 返回键对应的响应，不存在或已超过 StaleUntil 时返回 nil。
+	Get(c context.Context, key string) (*Entry, error)
+
+	// Set 保存响应，替换键对应的已有响应。
+	Set(c context.Context, key string, e *Entry) error
+
+	// Delete 删除键对应的响应，不存在时不返回错误。
+	Delete(c context.Context, key string) error
+
+	// DeleteTag 删除带有该标签的所有响应。
+	DeleteTag(c context.Context, tag string) error
+}
+
+// 标签到键的索引。
+type tagIndex map[string]map[string]struct{}
+
+func (idx tagIndex) add(key string, tags []string) {
+	for _, t := range tags {
+		keys := idx[t]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			idx[t] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (idx tagIndex) remove(key string, tags []string) {
+	for _, t := range tags {
+		if keys := idx[t]; keys != nil {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(idx, t)
+			}
+		}
+	}
+}
+
+// 返回带有该标签的键。
+func (idx tagIndex) keys(tag string) []string {
+	keys := make([]string, 0, len(idx[tag]))
+	for k := range idx[tag] {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MemoryStore 是按最近最少使用（LRU）策略淘汰的内存存储，适用于单实例部署。
+type MemoryStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     tagIndex
+	now      func() time.Time
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// NewMemoryStore 创建一个容量为 maxBytes 字节的内存存储，超出容量时淘汰最久未使用的响应。
+func NewMemoryStore(maxBytes int64) *MemoryStore {
+	return &MemoryStore{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(tagIndex),
+		now:      time.Now,
+	}
+}
+
+// Get 实现 Store 接口。
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	item := el.Value.(*memoryItem)
+	if !s.now().Before(item.entry.StaleUntil) {
+		s.remove(el)
+		return nil, nil
+	}
+	s.ll.MoveToFront(el)
+	return item.entry, nil
+}
+
+// Set 实现 Store 接口，超出容量的单个响应不会保存。
+func (s *MemoryStore) Set(_ context.Context, key string, e *Entry) error {
+	item := &memoryItem{key: key, entry: e, size: int64(len(key)) + e.size()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	if item.size > s.maxBytes {
+		return nil
+	}
+	s.items[key] = s.ll.PushFront(item)
+	s.size += item.size
+	s.tags.add(key, e.Tags)
+	for s.size > s.maxBytes {
+		s.remove(s.ll.Back())
+	}
+	return nil
+}
+
+// Delete 实现 Store 接口。
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+// DeleteTag 实现 Store 接口。
+func (s *MemoryStore) DeleteTag(_ context.Context, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.tags.keys(tag) {
+		if el, ok := s.items[key]; ok {
+			s.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len 返回缓存的响应数。
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	item := el.Value.(*memoryItem)
+	s.ll.Remove(el)
+	delete(s.items, item.key)
+	s.tags.remove(item.key, item.entry.Tags)
+	s.size -= item.size
+}
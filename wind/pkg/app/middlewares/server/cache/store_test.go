@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 各存储共有的行为。
+func testStore(t *testing.T, s Store, setNow func(time.Time)) {
+	c := context.Background()
+	now := time.Now()
+	setNow(now)
+	newEntry := func(body string, tags ...string) *Entry {
+		return &Entry{
+			StatusCode: 200,
+			Header:     []HeaderField{{Key: "Content-Type", Value: "text/plain"}},
+			Body:       []byte(body),
+			Tags:       tags,
+			Date:       now,
+			Expires:    now.Add(time.Minute),
+			StaleUntil: now.Add(2 * time.Minute),
+		}
+	}
+
+	e, err := s.Get(c, "missing")
+	assert.Nil(t, err)
+	assert.Nil(t, e)
+
+	assert.Nil(t, s.Set(c, "a", newEntry("a", "key:a", "tag:x")))
+	assert.Nil(t, s.Set(c, "b", newEntry("b", "key:b", "tag:x", "tag:y")))
+	assert.Nil(t, s.Set(c, "c", newEntry("c", "key:c", "tag:y")))
+	e, err = s.Get(c, "a")
+	assert.Nil(t, err)
+	if assert.NotNil(t, e) {
+		assert.Equal(t, 200, e.StatusCode)
+		assert.Equal(t, []HeaderField{{Key: "Content-Type", Value: "text/plain"}}, e.Header)
+		assert.Equal(t, "a", string(e.Body))
+		assert.Equal(t, []string{"key:a", "tag:x"}, e.Tags)
+		assert.True(t, e.Expires.Equal(now.Add(time.Minute)))
+	}
+
+	// 替换后旧标签不再生效
+	assert.Nil(t, s.Set(c, "a", newEntry("a2", "key:a")))
+	assert.Nil(t, s.DeleteTag(c, "tag:x"))
+	e, _ = s.Get(c, "a")
+	if assert.NotNil(t, e) {
+		assert.Equal(t, "a2", string(e.Body))
+	}
+	e, _ = s.Get(c, "b")
+	assert.Nil(t, e)
+	e, _ = s.Get(c, "c")
+	assert.NotNil(t, e)
+
+	assert.Nil(t, s.DeleteTag(c, "tag:y"))
+	assert.Nil(t, s.DeleteTag(c, "tag:missing"))
+	e, _ = s.Get(c, "c")
+	assert.Nil(t, e)
+
+	assert.Nil(t, s.Delete(c, "a"))
+	assert.Nil(t, s.Delete(c, "a"))
+	e, _ = s.Get(c, "a")
+	assert.Nil(t, e)
+
+	// 过期的响应在陈旧期内仍可读取，其后丢弃
+	assert.Nil(t, s.Set(c, "d", newEntry("d")))
+	setNow(now.Add(90 * time.Second))
+	e, _ = s.Get(c, "d")
+	assert.NotNil(t, e)
+	setNow(now.Add(2 * time.Minute))
+	e, _ = s.Get(c, "d")
+	assert.Nil(t, e)
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(1 << 20)
+	testStore(t, s, func(now time.Time) { s.now = func() time.Time { return now } })
+	assert.Zero(t, s.Len())
+	assert.Zero(t, s.size)
+	assert.Empty(t, s.tags)
+}
+
+func TestMemoryStoreLRU(t *testing.T) {
+	c := context.Background()
+	newEntry := func(n int) *Entry {
+		return &Entry{Body: make([]byte, n), Tags: []string{"t"}, StaleUntil: time.Now().Add(time.Hour)}
+	}
+	// 每个条目占用 1 字节的键、1 字节的标签和 98 字节的正文
+	s := NewMemoryStore(300)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, s.Set(c, strconv.Itoa(i), newEntry(98)))
+	}
+	assert.Equal(t, 3, s.Len())
+
+	// 访问后不再是最久未使用的
+	e, _ := s.Get(c, "0")
+	assert.NotNil(t, e)
+	assert.Nil(t, s.Set(c, "3", newEntry(98)))
+	assert.Equal(t, 3, s.Len())
+	e, _ = s.Get(c, "1")
+	assert.Nil(t, e)
+	for _, k := range []string{"0", "2", "3"} {
+		e, _ = s.Get(c, k)
+		assert.NotNil(t, e, k)
+	}
+	assert.Equal(t, int64(300), s.size)
+
+	// 超出容量的单个响应不保存，并移除同键的旧响应
+	assert.Nil(t, s.Set(c, "0", newEntry(300)))
+	e, _ = s.Get(c, "0")
+	assert.Nil(t, e)
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, int64(200), s.size)
+
+	assert.Nil(t, s.DeleteTag(c, "t"))
+	assert.Zero(t, s.Len())
+	assert.Zero(t, s.size)
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	testStore(t, s, func(now time.Time) { s.now = func() time.Time { return now } })
+	assert.Empty(t, s.tags)
+	assert.Empty(t, s.keys)
+
+	_, err = NewFileStore(filepath.Join(dir, ".tmp_missing", string([]byte{0})))
+	assert.NotNil(t, err)
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	c := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	now := time.Now()
+	assert.Nil(t, s.Set(c, "a", &Entry{Body: []byte("a"), Tags: []string{"tag:x"}, StaleUntil: now.Add(time.Hour)}))
+	assert.Nil(t, s.Set(c, "b", &Entry{Body: []byte("b"), Tags: []string{"tag:x"}, StaleUntil: now.Add(time.Hour)}))
+	assert.Nil(t, s.Set(c, "expired", &Entry{StaleUntil: now.Add(time.Second)}))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, filePrefix+"corrupt"), []byte("corrupt"), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "other"), []byte("other"), 0o600))
+
+	// 重新打开时重建标签索引，并清理过期及损坏的文件
+	s.now = func() time.Time { return now.Add(time.Minute) }
+	assert.Nil(t, s.Cleanup())
+	s, err = NewFileStore(dir)
+	assert.Nil(t, err)
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 3)
+	e, _ := s.Get(c, "a")
+	if assert.NotNil(t, e) {
+		assert.Equal(t, "a", string(e.Body))
+	}
+	assert.Nil(t, s.DeleteTag(c, "tag:x"))
+	entries, _ = os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+
+	// 损坏的文件视为未命中
+	assert.Nil(t, os.WriteFile(s.path("bad"), []byte("bad"), 0o600))
+	e, err = s.Get(c, "bad")
+	assert.Nil(t, err)
+	assert.Nil(t, e)
+}
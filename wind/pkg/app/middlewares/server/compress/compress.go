@@ -0,0 +1,156 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/bytebufferpool"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 已解析的压缩配置。
+type compressor struct {
+	encodings          []string
+	level              Level
+	minLength          int
+	contentTypes       []string
+	excludedPaths      []string
+	excludedExtensions []string
+}
+
+// New 返回一个压缩响应正文的中间件。
+//
+// 中间件依据请求的 Accept-Encoding（含 q 权重）协商编码，在处理链返回后压缩响应，
+// 支持 br、zstd、gzip 和 deflate。缓冲的正文小于最小字节数时不压缩；
+// 通过 SetBodyStream 设置的正文流则边读边压缩，并改用分块传输。
+//
+// 以下响应不压缩：HEAD 请求、已设置 Content-Encoding、状态码不允许正文或为 206、
+// 内容类型不可压缩、已劫持响应编写器（如 SSE），以及排除的路径和扩展名。
+// 可压缩的响应均附加 Vary: Accept-Encoding，压缩后强 ETag 将转为弱 ETag。
+func New(opts ...Option) app.HandlerFunc {
+	cfg := newOptions(opts...)
+	cp := &compressor{
+		level:              cfg.level,
+		minLength:          cfg.minLength,
+		contentTypes:       cfg.contentTypes,
+		excludedPaths:      cfg.excludedPaths,
+		excludedExtensions: cfg.excludedExtensions,
+	}
+	for _, e := range cfg.encodings {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if _, ok := encoders[e]; ok {
+			cp.encodings = append(cp.encodings, e)
+		}
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		if ctx.IsHead() || cp.isExcluded(bytesconv.B2s(ctx.Path())) {
+			ctx.Next(c)
+			return
+		}
+		encoding := negotiate(string(ctx.Request.Header.Peek(consts.HeaderAcceptEncoding)), cp.encodings)
+
+		ctx.Next(c)
+
+		cp.compress(&ctx.Response, encoding)
+	}
+}
+
+func (cp *compressor) compress(resp *protocol.Response, encoding string) {
+	status := resp.StatusCode()
+	if resp.GetHijackWriter() != nil ||
+		resp.MustSkipBody() ||
+		status == consts.StatusPartialContent ||
+		len(resp.Header.ContentEncoding()) > 0 ||
+		!cp.isCompressible(resp.Header.ContentType()) {
+		return
+	}
+
+	addVary(&resp.Header)
+	if encoding == "" {
+		return
+	}
+	e := encoders[encoding]
+
+	if resp.IsBodyStream() {
+		resp.SetBodyStreamNoReset(newCompressReader(resp.BodyStream(), e, cp.level), -1)
+	} else {
+		body := resp.BodyBytes()
+		if len(body) < cp.minLength {
+			return
+		}
+		buf := bytebufferpool.Get()
+		buf.B = e.appendBytes(buf.B, body, cp.level)
+		if len(buf.B) >= len(body) {
+			bytebufferpool.Put(buf)
+			return
+		}
+		resp.SetBody(buf.B)
+		bytebufferpool.Put(buf)
+	}
+
+	resp.Header.SetContentEncoding(encoding)
+	// 压缩后的表示与原表示逐字节不同，强校验器不再成立
+	if etag := resp.Header.Peek(consts.HeaderETag); len(etag) > 0 && !bytes.HasPrefix(etag, []byte("W/")) {
+		resp.Header.Set(consts.HeaderETag, "W/"+string(etag))
+	}
+}
+
+// 添加 Vary: Accept-Encoding，已存在时不重复添加。
+func addVary(h *protocol.ResponseHeader) {
+	for _, v := range h.PeekAll(consts.HeaderVary) {
+		for _, field := range strings.Split(bytesconv.B2s(v), ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, consts.HeaderAcceptEncoding) {
+				return
+			}
+		}
+	}
+	h.Add(consts.HeaderVary, consts.HeaderAcceptEncoding)
+}
+
+func (cp *compressor) isExcluded(path string) bool {
+	for _, prefix := range cp.excludedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	if len(cp.excludedExtensions) > 0 {
+		if i := strings.LastIndexByte(path, '.'); i >= 0 && strings.LastIndexByte(path, '/') < i {
+			ext := path[i:]
+			for _, excluded := range cp.excludedExtensions {
+				if strings.EqualFold(ext, excluded) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (cp *compressor) isCompressible(contentType []byte) bool {
+	mediaType, _, _ := strings.Cut(bytesconv.B2s(contentType), ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		return false
+	}
+	for _, t := range cp.contentTypes {
+		switch {
+		case strings.HasSuffix(t, "/"):
+			if strings.HasPrefix(mediaType, t) {
+				return true
+			}
+		case strings.HasPrefix(t, "+"):
+			if strings.HasSuffix(mediaType, t) {
+				return true
+			}
+		case mediaType == t:
+			return true
+		}
+	}
+	return false
+}
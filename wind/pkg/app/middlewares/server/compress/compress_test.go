@@ -0,0 +1,202 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBody = strings.Repeat("hello wind compress middleware ", 100)
+
+func newTestEngine(opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	engine.GET("/text", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set(consts.HeaderETag, `"v1"`)
+		ctx.String(consts.StatusOK, testBody)
+	})
+	engine.GET("/small", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "small")
+	})
+	engine.GET("/image.png", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Data(consts.StatusOK, consts.MIMEImagePNG, []byte(testBody))
+	})
+	engine.GET("/static/app.js", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Data(consts.StatusOK, consts.MIMETextJavascript, []byte(testBody))
+	})
+	engine.GET("/encoded", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.SetContentEncoding("gzip")
+		ctx.String(consts.StatusOK, testBody)
+	})
+	engine.GET("/stream", func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetContentType(consts.MIMEApplicationJSONUTF8)
+		ctx.SetBodyStream(strings.NewReader(testBody), len(testBody))
+	})
+	return engine
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		assert.Nil(t, err)
+		r = zr
+	case EncodingDeflate:
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		assert.Nil(t, err)
+		r = zr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		assert.Nil(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	b, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func get(engine *route.Engine, path, acceptEncoding string) *protocol.Response {
+	return ut.PerformRequest(engine, consts.MethodGet, path, nil,
+		ut.Header{Key: consts.HeaderAcceptEncoding, Value: acceptEncoding}).Result()
+}
+
+func TestNegotiate(t *testing.T) {
+	all := []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+	cases := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"x-gzip", EncodingGzip},
+		{"gzip, deflate, br, zstd", EncodingBrotli},
+		{"gzip;q=1.0, br;q=0.5", EncodingGzip},
+		{"GZIP ; Q=0.2, deflate;q=0.3", EncodingDeflate},
+		{"*", EncodingBrotli},
+		{"br;q=0, *;q=0.1", EncodingZstd},
+		{"gzip;q=0", ""},
+		{"*;q=0", ""},
+		{"identity", ""},
+		{"gzip;q=abc, deflate", EncodingDeflate},
+		{"compress, gzip;q=1.5", ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, negotiate(c.accept, all), c.accept)
+	}
+	assert.Equal(t, EncodingGzip, negotiate("br, gzip", []string{EncodingGzip}))
+}
+
+func TestCompressBuffered(t *testing.T) {
+	engine := newTestEngine()
+	for _, encoding := range []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate} {
+		resp := get(engine, "/text", encoding)
+		assert.Equal(t, consts.StatusOK, resp.StatusCode())
+		assert.Equal(t, encoding, string(resp.Header.ContentEncoding()))
+		assert.Equal(t, consts.HeaderAcceptEncoding, string(resp.Header.Peek(consts.HeaderVary)))
+		assert.Equal(t, `W/"v1"`, string(resp.Header.Peek(consts.HeaderETag)))
+		assert.Less(t, len(resp.Body()), len(testBody))
+		assert.Equal(t, testBody, decode(t, encoding, resp.Body()))
+	}
+
+	// 客户端不接受压缩时依然声明 Vary
+	resp := get(engine, "/text", "identity")
+	assert.Empty(t, resp.Header.ContentEncoding())
+	assert.Equal(t, consts.HeaderAcceptEncoding, string(resp.Header.Peek(consts.HeaderVary)))
+	assert.Equal(t, `"v1"`, string(resp.Header.Peek(consts.HeaderETag)))
+	assert.Equal(t, testBody, string(resp.Body()))
+}
+
+func TestCompressStream(t *testing.T) {
+	engine := newTestEngine()
+	for _, encoding := range []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate} {
+		resp := get(engine, "/stream", encoding)
+		assert.Equal(t, encoding, string(resp.Header.ContentEncoding()))
+		assert.Equal(t, testBody, decode(t, encoding, resp.Body()))
+	}
+
+	// 正文流压缩后长度未知，改用分块传输
+	var resp protocol.Response
+	resp.Header.SetContentType(consts.MIMETextPlainUTF8)
+	resp.SetBodyStream(strings.NewReader(testBody), len(testBody))
+	cp := &compressor{contentTypes: defaultContentTypes, minLength: 1 << 20}
+	cp.compress(&resp, EncodingGzip)
+	assert.True(t, resp.IsBodyStream())
+	assert.Equal(t, -1, resp.Header.ContentLength())
+	assert.Equal(t, testBody, decode(t, EncodingGzip, resp.Body()))
+}
+
+func TestCompressSkip(t *testing.T) {
+	engine := newTestEngine(WithExcludedPaths("/static/"), WithExcludedExtensions(".PNG"))
+	for _, path := range []string{"/image.png", "/static/app.js"} {
+		resp := get(engine, path, "gzip")
+		assert.Empty(t, resp.Header.ContentEncoding(), path)
+		assert.Empty(t, resp.Header.Peek(consts.HeaderVary), path)
+	}
+
+	// 正文过小时不压缩，但响应依然因 Accept-Encoding 而异
+	resp := get(engine, "/small", "gzip")
+	assert.Empty(t, resp.Header.ContentEncoding())
+	assert.Equal(t, consts.HeaderAcceptEncoding, string(resp.Header.Peek(consts.HeaderVary)))
+	assert.Equal(t, "small", string(resp.Body()))
+
+	// 已编码的响应保持原样
+	resp = get(engine, "/encoded", "br")
+	assert.Equal(t, "gzip", string(resp.Header.ContentEncoding()))
+	assert.Equal(t, testBody, string(resp.Body()))
+
+	// 按内容类型跳过
+	engine = newTestEngine(WithContentTypes("application/json"), WithMinLength(0))
+	resp = get(engine, "/text", "gzip")
+	assert.Empty(t, resp.Header.ContentEncoding())
+	resp = get(engine, "/stream", "gzip")
+	assert.Equal(t, "gzip", string(resp.Header.ContentEncoding()))
+}
+
+func TestIsCompressible(t *testing.T) {
+	cp := &compressor{contentTypes: defaultContentTypes}
+	for _, ct := range []string{"text/html; charset=utf-8", "application/json", "application/problem+json", "image/svg+xml", "Application/XML"} {
+		assert.True(t, cp.isCompressible([]byte(ct)), ct)
+	}
+	for _, ct := range []string{"", "image/png", "application/octet-stream", "application/zip"} {
+		assert.False(t, cp.isCompressible([]byte(ct)), ct)
+	}
+}
+
+func TestCompressReaderFlushes(t *testing.T) {
+	pr, pw := io.Pipe()
+	r := newCompressReader(pr, encoders[EncodingGzip], LevelDefault)
+	go pw.Write([]byte("part1"))
+
+	// 源数据尚未结束，已读到的数据也应立即压缩输出
+	p := make([]byte, 1024)
+	n, err := r.Read(p)
+	assert.Nil(t, err)
+	assert.Greater(t, n, 0)
+	out := append([]byte(nil), p[:n]...)
+
+	pw.Close()
+	rest, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Close())
+	assert.Equal(t, "part1", decode(t, EncodingGzip, append(out, rest...)))
+}
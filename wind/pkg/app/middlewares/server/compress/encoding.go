@@ -0,0 +1,142 @@
+package compress
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	hcompress "github.com/favbox/gosky/wind/pkg/common/compress"
+)
+
+// 流式压缩使用的编写器。
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// 一种内容编码的实现。
+type encoder struct {
+	// 压缩 src 并附加到 dst。
+	appendBytes func(dst, src []byte, level Level) []byte
+
+	// 获取写入 w 的编写器，release 关闭编写器（写出剩余数据）并放回池中。
+	acquire func(w io.Writer, level Level) (zw flushWriter, release func())
+}
+
+// 各编码按 LevelDefault、LevelBestSpeed、LevelBestCompression 排列的级别。
+var (
+	gzipLevels   = [...]int{hcompress.CompressDefaultCompression, 1, 9}
+	brotliLevels = [...]int{5, hcompress.BrotliBestSpeed, hcompress.BrotliBestCompression}
+	zstdLevels   = [...]int{hcompress.ZstdSpeedDefault, hcompress.ZstdSpeedFastest, hcompress.ZstdSpeedBestCompression}
+)
+
+func levelIndex(level Level) int {
+	if level < LevelDefault || level > LevelBestCompression {
+		return int(LevelDefault)
+	}
+	return int(level)
+}
+
+var encoders = map[string]encoder{
+	EncodingGzip: {
+		appendBytes: func(dst, src []byte, level Level) []byte {
+			return hcompress.AppendGzipBytesLevel(dst, src, gzipLevels[levelIndex(level)])
+		},
+		acquire: func(w io.Writer, level Level) (flushWriter, func()) {
+			l := gzipLevels[levelIndex(level)]
+			zw := hcompress.AcquireStacklessGzipWriter(w, l)
+			return zw, func() { hcompress.ReleaseStacklessGzipWriter(zw, l) }
+		},
+	},
+	EncodingDeflate: {
+		appendBytes: func(dst, src []byte, level Level) []byte {
+			return hcompress.AppendDeflateBytesLevel(dst, src, gzipLevels[levelIndex(level)])
+		},
+		acquire: func(w io.Writer, level Level) (flushWriter, func()) {
+			l := gzipLevels[levelIndex(level)]
+			zw := hcompress.AcquireDeflateWriter(w, l)
+			return zw, func() { hcompress.ReleaseDeflateWriter(zw, l) }
+		},
+	},
+	EncodingBrotli: {
+		appendBytes: func(dst, src []byte, level Level) []byte {
+			return hcompress.AppendBrotliBytesLevel(dst, src, brotliLevels[levelIndex(level)])
+		},
+		acquire: func(w io.Writer, level Level) (flushWriter, func()) {
+			l := brotliLevels[levelIndex(level)]
+			zw := hcompress.AcquireBrotliWriter(w, l)
+			return zw, func() { hcompress.ReleaseBrotliWriter(zw, l) }
+		},
+	},
+	EncodingZstd: {
+		appendBytes: func(dst, src []byte, level Level) []byte {
+			return hcompress.AppendZstdBytesLevel(dst, src, zstdLevels[levelIndex(level)])
+		},
+		acquire: func(w io.Writer, level Level) (flushWriter, func()) {
+			l := zstdLevels[levelIndex(level)]
+			zw := hcompress.AcquireZstdWriter(w, l)
+			return zw, func() { hcompress.ReleaseZstdWriter(zw, l) }
+		},
+	},
+}
+
+// 根据 Accept-Encoding 从 supported 中选出权重最高的编码，权重相同时按 supported 的顺序。
+// 客户端不接受任何编码时返回空串。
+func negotiate(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	var (
+		weights  = make(map[string]float64, 4)
+		wildcard = -1.0
+	)
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(item, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q, ok := parseQuality(params)
+		if !ok {
+			continue
+		}
+		switch coding {
+		case "*":
+			wildcard = q
+		case "x-gzip":
+			coding = EncodingGzip
+			fallthrough
+		default:
+			weights[coding] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range supported {
+		q, ok := weights[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// 解析 ";q=0.8" 形式的权重参数，缺省为 1。
+func parseQuality(params string) (float64, bool) {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, false
+		}
+		return q, true
+	}
+	return 1, true
+}
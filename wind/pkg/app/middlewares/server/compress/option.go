@@ -0,0 +1,121 @@
+package compress
+
+// Level 表示与具体编码无关的压缩级别。
+type Level int
+
+// 各压缩级别会映射为每种编码各自的级别。
+const (
+	// LevelDefault 兼顾速度与压缩率，适合动态内容。
+	LevelDefault Level = iota
+	// LevelBestSpeed 压缩最快。
+	LevelBestSpeed
+	// LevelBestCompression 压缩率最高，适合可缓存的内容。
+	LevelBestCompression
+)
+
+// 支持的内容编码。
+const (
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// 表示一个响应压缩的自定义选项结构体。
+type options struct {
+	// 启用的编码，按服务端偏好排列，客户端权重相同时优先选用靠前的编码。
+	encodings []string
+
+	// 压缩级别。
+	level Level
+
+	// 缓冲响应的最小压缩字节数，正文流不受此限制。
+	minLength int
+
+	// 可压缩的内容类型。
+	contentTypes []string
+
+	// 不压缩的路径前缀。
+	excludedPaths []string
+
+	// 不压缩的路径扩展名。
+	excludedExtensions []string
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认可压缩的内容类型。
+var defaultContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/x-javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+	"+json",
+	"+xml",
+}
+
+// 创建一个自定义响应压缩的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		encodings:    []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate},
+		level:        LevelDefault,
+		minLength:    1024,
+		contentTypes: defaultContentTypes,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithEncodings 设置启用的编码及服务端偏好顺序，默认为 br、zstd、gzip、deflate。
+//
+// 不支持的编码将被忽略。
+func WithEncodings(encodings ...string) Option {
+	return func(o *options) {
+		o.encodings = encodings
+	}
+}
+
+// WithLevel 设置压缩级别，默认为 LevelDefault。
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithMinLength 设置缓冲响应的最小压缩字节数，默认为 1024。
+func WithMinLength(n int) Option {
+	return func(o *options) {
+		o.minLength = n
+	}
+}
+
+// WithContentTypes 设置可压缩的内容类型，覆盖默认列表。
+//
+// 以 "/" 结尾的按前缀匹配（如 "text/"），以 "+" 开头的按后缀匹配（如 "+json"），其余按媒体类型精确匹配。
+func WithContentTypes(types ...string) Option {
+	return func(o *options) {
+		o.contentTypes = types
+	}
+}
+
+// WithExcludedPaths 设置不压缩的路径前缀。
+func WithExcludedPaths(paths ...string) Option {
+	return func(o *options) {
+		o.excludedPaths = paths
+	}
+}
+
+// WithExcludedExtensions 设置不压缩的路径扩展名，如 ".png"。
+func WithExcludedExtensions(exts ...string) Option {
+	return func(o *options) {
+		o.excludedExtensions = exts
+	}
+}
@@ -0,0 +1,34 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}, opts.encodings)
+	assert.Equal(t, LevelDefault, opts.level)
+	assert.Equal(t, 1024, opts.minLength)
+	assert.Equal(t, defaultContentTypes, opts.contentTypes)
+	assert.Nil(t, opts.excludedPaths)
+	assert.Nil(t, opts.excludedExtensions)
+}
+
+func TestOption(t *testing.T) {
+	opts := newOptions(
+		WithEncodings(EncodingGzip),
+		WithLevel(LevelBestSpeed),
+		WithMinLength(10),
+		WithContentTypes("text/plain"),
+		WithExcludedPaths("/metrics"),
+		WithExcludedExtensions(".png"),
+	)
+	assert.Equal(t, []string{EncodingGzip}, opts.encodings)
+	assert.Equal(t, LevelBestSpeed, opts.level)
+	assert.Equal(t, 10, opts.minLength)
+	assert.Equal(t, []string{"text/plain"}, opts.contentTypes)
+	assert.Equal(t, []string{"/metrics"}, opts.excludedPaths)
+	assert.Equal(t, []string{".png"}, opts.excludedExtensions)
+}
@@ -0,0 +1,71 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+)
+
+// 源数据的单次读取大小。
+const streamChunkSize = 8 << 10
+
+// 边读边压缩的正文流。
+//
+// 每次读到源数据即压缩并冲刷，使流式响应的数据能及时到达客户端。
+type compressReader struct {
+	src     io.Reader
+	zw      flushWriter
+	release func()
+
+	buf   bytes.Buffer
+	chunk []byte
+	err   error
+}
+
+func newCompressReader(src io.Reader, e encoder, level Level) *compressReader {
+	r := &compressReader{src: src, chunk: make([]byte, streamChunkSize)}
+	r.zw, r.release = e.acquire(&r.buf, level)
+	return r
+}
+
+func (r *compressReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := r.src.Read(r.chunk)
+		if n > 0 {
+			if _, werr := r.zw.Write(r.chunk[:n]); werr != nil {
+				r.err = werr
+				continue
+			}
+			if ferr := r.zw.Flush(); ferr != nil {
+				r.err = ferr
+				continue
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				// 写出压缩流的结尾
+				r.releaseWriter()
+			}
+			r.err = err
+		}
+	}
+	return r.buf.Read(p)
+}
+
+// Close 释放编写器，并关闭源数据流。
+func (r *compressReader) Close() error {
+	r.releaseWriter()
+	if c, ok := r.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (r *compressReader) releaseWriter() {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+}
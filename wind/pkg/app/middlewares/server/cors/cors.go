@@ -0,0 +1,212 @@
+package cors
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 已解析的跨域配置。
+type cors struct {
+	allowAllOrigins bool
+	origins         map[string]struct{}
+	wildcards       []wildcard
+	originFunc      func(origin string) bool
+
+	methods      map[string]struct{}
+	allowMethods string
+
+	allowAllHeaders bool
+	headers         map[string]struct{}
+	allowHeaders    string
+
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+
+	// 响应是否因来源而异
+	varyOrigin bool
+}
+
+// 子域名通配的来源，如 https://*.example.com。
+type wildcard struct {
+	prefix string
+	suffix string
+}
+
+func (w wildcard) match(origin string) bool {
+	return len(origin) > len(w.prefix)+len(w.suffix) &&
+		strings.HasPrefix(origin, w.prefix) &&
+		strings.HasSuffix(origin, w.suffix)
+}
+
+// New 返回一个处理跨域资源共享的中间件。
+//
+// 预检请求（携带 Access-Control-Request-Method 的 OPTIONS 请求）由中间件以 204 直接响应，
+// 不会进入后续处理链；其他跨域请求则附加相应的响应标头后继续处理。
+//
+// 预检请求通常没有对应的路由：通过 Engine.Use 全局注册时，中间件经由路由未找到或方法不允许的处理链生效；
+// 通过路由组的 Use 或 Group 注册时，路由组会自动补充 OPTIONS 通配路由，无需另行注册。
+func New(opts ...Option) app.HandlerFunc {
+	cr := newCors(newOptions(opts...))
+
+	h := func(c context.Context, ctx *app.RequestContext) {
+		origin := string(ctx.Request.Header.Peek(consts.HeaderOrigin))
+		preflight := ctx.Request.Header.IsOptions() &&
+			len(ctx.Request.Header.Peek(consts.HeaderAccessControlRequestMethod)) > 0
+
+		if cr.varyOrigin {
+			ctx.Response.Header.Add(consts.HeaderVary, consts.HeaderOrigin)
+		}
+		if origin == "" {
+			ctx.Next(c)
+			return
+		}
+
+		if preflight {
+			ctx.Response.Header.Add(consts.HeaderVary, consts.HeaderAccessControlRequestMethod)
+			ctx.Response.Header.Add(consts.HeaderVary, consts.HeaderAccessControlRequestHeaders)
+			cr.handlePreflight(ctx, origin)
+			ctx.AbortWithStatus(consts.StatusNoContent)
+			return
+		}
+
+		if cr.isOriginAllowed(origin) {
+			cr.setOrigin(ctx, origin)
+			if cr.exposeHeaders != "" {
+				ctx.Response.Header.Set(consts.HeaderAccessControlExposeHeaders, cr.exposeHeaders)
+			}
+		}
+		ctx.Next(c)
+	}
+	app.SetPreflightHandler(h)
+	return h
+}
+
+func newCors(o *options) *cors {
+	cr := &cors{
+		origins:     make(map[string]struct{}),
+		originFunc:  o.allowOriginFunc,
+		methods:     make(map[string]struct{}),
+		headers:     make(map[string]struct{}),
+		credentials: o.allowCredentials,
+	}
+
+	for _, origin := range o.allowOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			cr.allowAllOrigins = true
+		case i >= 0:
+			cr.wildcards = append(cr.wildcards, wildcard{prefix: origin[:i], suffix: origin[i+1:]})
+		default:
+			cr.origins[origin] = struct{}{}
+		}
+	}
+	// 仅在统一响应 "*" 时，响应才与来源无关
+	cr.varyOrigin = cr.originFunc != nil || !cr.allowAllOrigins || cr.credentials
+
+	methods := make([]string, 0, len(o.allowMethods))
+	for _, m := range o.allowMethods {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		cr.methods[m] = struct{}{}
+		methods = append(methods, m)
+	}
+	cr.allowMethods = strings.Join(methods, ", ")
+
+	headers := make([]string, 0, len(o.allowHeaders))
+	for _, h := range o.allowHeaders {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "*" {
+			cr.allowAllHeaders = true
+			continue
+		}
+		cr.headers[h] = struct{}{}
+		headers = append(headers, h)
+	}
+	cr.allowHeaders = strings.Join(headers, ", ")
+
+	cr.exposeHeaders = strings.Join(o.exposeHeaders, ", ")
+	if o.maxAge > 0 {
+		cr.maxAge = strconv.FormatInt(int64(o.maxAge.Seconds()), 10)
+	}
+	return cr
+}
+
+// 处理预检请求，不允许时不写入任何跨域标头，由浏览器拒绝后续请求。
+func (cr *cors) handlePreflight(ctx *app.RequestContext, origin string) {
+	if !cr.isOriginAllowed(origin) {
+		return
+	}
+	method := strings.ToUpper(string(ctx.Request.Header.Peek(consts.HeaderAccessControlRequestMethod)))
+	if _, ok := cr.methods[method]; !ok {
+		return
+	}
+	requestHeaders := string(ctx.Request.Header.Peek(consts.HeaderAccessControlRequestHeaders))
+	if !cr.areHeadersAllowed(requestHeaders) {
+		return
+	}
+
+	cr.setOrigin(ctx, origin)
+	ctx.Response.Header.Set(consts.HeaderAccessControlAllowMethods, cr.allowMethods)
+	if cr.allowAllHeaders {
+		if requestHeaders != "" {
+			ctx.Response.Header.Set(consts.HeaderAccessControlAllowHeaders, requestHeaders)
+		}
+	} else if cr.allowHeaders != "" {
+		ctx.Response.Header.Set(consts.HeaderAccessControlAllowHeaders, cr.allowHeaders)
+	}
+	if cr.maxAge != "" {
+		ctx.Response.Header.Set(consts.HeaderAccessControlMaxAge, cr.maxAge)
+	}
+}
+
+func (cr *cors) setOrigin(ctx *app.RequestContext, origin string) {
+	if cr.varyOrigin {
+		ctx.Response.Header.Set(consts.HeaderAccessControlAllowOrigin, origin)
+	} else {
+		ctx.Response.Header.Set(consts.HeaderAccessControlAllowOrigin, "*")
+	}
+	if cr.credentials {
+		ctx.Response.Header.Set(consts.HeaderAccessControlAllowCredentials, "true")
+	}
+}
+
+func (cr *cors) isOriginAllowed(origin string) bool {
+	if cr.originFunc != nil {
+		return cr.originFunc(origin)
+	}
+	if cr.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := cr.origins[origin]; ok {
+		return true
+	}
+	for _, w := range cr.wildcards {
+		if w.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// 校验以逗号分隔的请求标头是否均被允许。
+func (cr *cors) areHeadersAllowed(requestHeaders string) bool {
+	if cr.allowAllHeaders || requestHeaders == "" {
+		return true
+	}
+	for _, h := range strings.Split(requestHeaders, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if _, ok := cr.headers[h]; !ok {
+			return false
+		}
+	}
+	return true
+}
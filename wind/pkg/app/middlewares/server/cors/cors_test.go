@@ -0,0 +1,182 @@
+package cors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEngine(opts ...Option) (*route.Engine, *int) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	calls := new(int)
+	api := engine.Group("/api")
+	api.GET("/users", func(c context.Context, ctx *app.RequestContext) {
+		*calls++
+		ctx.String(consts.StatusOK, "ok")
+	})
+	return engine, calls
+}
+
+func preflight(engine *route.Engine, origin, method, headers string) *protocol.Response {
+	h := []ut.Header{
+		{Key: consts.HeaderOrigin, Value: origin},
+		{Key: consts.HeaderAccessControlRequestMethod, Value: method},
+	}
+	if headers != "" {
+		h = append(h, ut.Header{Key: consts.HeaderAccessControlRequestHeaders, Value: headers})
+	}
+	return ut.PerformRequest(engine, consts.MethodOptions, "/api/users", nil, h...).Result()
+}
+
+func vary(resp *protocol.Response) string {
+	var values []string
+	for _, v := range resp.Header.PeekAll(consts.HeaderVary) {
+		values = append(values, string(v))
+	}
+	return strings.Join(values, ", ")
+}
+
+func TestPreflight(t *testing.T) {
+	engine, calls := newTestEngine(
+		WithAllowOrigins("https://example.com", "https://*.example.org"),
+		WithAllowHeaders("X-Token", "Content-Type"),
+		WithAllowCredentials(true),
+		WithMaxAge(10*time.Minute),
+	)
+
+	resp := preflight(engine, "https://example.com", consts.MethodPut, "x-token, content-type")
+	assert.Equal(t, consts.StatusNoContent, resp.StatusCode())
+	assert.Equal(t, "https://example.com", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, "true", string(resp.Header.Peek(consts.HeaderAccessControlAllowCredentials)))
+	assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, HEAD", string(resp.Header.Peek(consts.HeaderAccessControlAllowMethods)))
+	assert.Equal(t, "x-token, content-type", string(resp.Header.Peek(consts.HeaderAccessControlAllowHeaders)))
+	assert.Equal(t, "600", string(resp.Header.Peek(consts.HeaderAccessControlMaxAge)))
+	assert.Equal(t, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers", vary(resp))
+	assert.Equal(t, 0, *calls)
+
+	// 子域名通配
+	resp = preflight(engine, "https://api.example.org", consts.MethodGet, "")
+	assert.Equal(t, "https://api.example.org", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	resp = preflight(engine, "https://example.org", consts.MethodGet, "")
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowOrigin))
+
+	// 来源、方法或标头不允许时，仍以 204 结束预检但不附加跨域标头
+	for _, c := range [][3]string{
+		{"https://evil.com", consts.MethodGet, ""},
+		{"https://example.com", "PROPFIND", ""},
+		{"https://example.com", consts.MethodGet, "X-Other"},
+	} {
+		resp = preflight(engine, c[0], c[1], c[2])
+		assert.Equal(t, consts.StatusNoContent, resp.StatusCode())
+		assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowOrigin))
+		assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowMethods))
+	}
+	assert.Equal(t, 0, *calls)
+}
+
+func TestActualRequest(t *testing.T) {
+	engine, calls := newTestEngine(
+		WithAllowOriginFunc(func(origin string) bool { return strings.HasSuffix(origin, ".test") }),
+		WithExposeHeaders("X-Total", "X-Page"),
+	)
+
+	w := ut.PerformRequest(engine, consts.MethodGet, "/api/users", nil, ut.Header{Key: consts.HeaderOrigin, Value: "http://a.test"})
+	resp := w.Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "ok", string(resp.Body()))
+	assert.Equal(t, "http://a.test", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, "X-Total, X-Page", string(resp.Header.Peek(consts.HeaderAccessControlExposeHeaders)))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowCredentials))
+	assert.Equal(t, "Origin", vary(resp))
+
+	// 来源不允许时不附加跨域标头，但请求照常处理
+	w = ut.PerformRequest(engine, consts.MethodGet, "/api/users", nil, ut.Header{Key: consts.HeaderOrigin, Value: "http://evil.com"})
+	resp = w.Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "Origin", vary(resp))
+
+	// 非跨域请求
+	w = ut.PerformRequest(engine, consts.MethodGet, "/api/users", nil)
+	resp = w.Result()
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "Origin", vary(resp))
+	assert.Equal(t, 3, *calls)
+}
+
+func TestAllowAll(t *testing.T) {
+	engine, _ := newTestEngine(WithAllowHeaders("*"))
+
+	resp := preflight(engine, "https://any.com", consts.MethodDelete, "X-Anything")
+	assert.Equal(t, "*", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, "X-Anything", string(resp.Header.Peek(consts.HeaderAccessControlAllowHeaders)))
+	assert.Equal(t, "Access-Control-Request-Method, Access-Control-Request-Headers", vary(resp))
+
+	// 统一响应 "*" 时无需 Vary: Origin
+	w := ut.PerformRequest(engine, consts.MethodGet, "/api/users", nil, ut.Header{Key: consts.HeaderOrigin, Value: "https://any.com"})
+	resp = w.Result()
+	assert.Equal(t, "*", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	assert.Empty(t, vary(resp))
+
+	// 允许凭证时回写请求的来源
+	engine, _ = newTestEngine(WithAllowCredentials(true))
+	w = ut.PerformRequest(engine, consts.MethodGet, "/api/users", nil, ut.Header{Key: consts.HeaderOrigin, Value: "https://any.com"})
+	resp = w.Result()
+	assert.Equal(t, "https://any.com", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, "true", string(resp.Header.Peek(consts.HeaderAccessControlAllowCredentials)))
+	assert.Equal(t, "Origin", vary(resp))
+}
+
+func TestGroupPreflight(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	calls := 0
+	api := engine.Group("/api")
+	api.Use(New(WithAllowOrigins("https://example.com")))
+	api.Use(New(WithAllowOrigins("https://example.com"))) // 重复注册不会重复添加通配路由
+	api.GET("/users", func(c context.Context, ctx *app.RequestContext) {
+		calls++
+		ctx.String(consts.StatusOK, "ok")
+	})
+	api.OPTIONS("/items", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "items")
+	})
+	engine.GET("/public", func(c context.Context, ctx *app.RequestContext) {})
+
+	// 仅在路由组上注册时，预检请求同样由中间件应答
+	resp := preflight(engine, "https://example.com", consts.MethodGet, "")
+	assert.Equal(t, consts.StatusNoContent, resp.StatusCode())
+	assert.Equal(t, "https://example.com", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, 0, calls)
+
+	// 非预检的 OPTIONS 请求仍按路由未找到处理，已注册的 OPTIONS 路由优先
+	resp = ut.PerformRequest(engine, consts.MethodOptions, "/api/users", nil).Result()
+	assert.Equal(t, consts.StatusNotFound, resp.StatusCode())
+	resp = ut.PerformRequest(engine, consts.MethodOptions, "/api/items", nil).Result()
+	assert.Equal(t, "items", string(resp.Body()))
+
+	// 路由组外的路由不受影响
+	resp = ut.PerformRequest(engine, consts.MethodOptions, "/public", nil,
+		ut.Header{Key: consts.HeaderOrigin, Value: "https://example.com"},
+		ut.Header{Key: consts.HeaderAccessControlRequestMethod, Value: consts.MethodGet}).Result()
+	assert.Equal(t, consts.StatusNotFound, resp.StatusCode())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAccessControlAllowOrigin))
+
+	// 通过 Group 的处理器参数注册时同样生效
+	v2 := engine.Group("/v2", New(WithAllowOrigins("*")))
+	v2.GET("/users", func(c context.Context, ctx *app.RequestContext) {})
+	resp = ut.PerformRequest(engine, consts.MethodOptions, "/v2/users", nil,
+		ut.Header{Key: consts.HeaderOrigin, Value: "https://any.com"},
+		ut.Header{Key: consts.HeaderAccessControlRequestMethod, Value: consts.MethodGet}).Result()
+	assert.Equal(t, consts.StatusNoContent, resp.StatusCode())
+	assert.Equal(t, "*", string(resp.Header.Peek(consts.HeaderAccessControlAllowOrigin)))
+}
@@ -0,0 +1,115 @@
+package cors
+
+import (
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 表示一个跨域资源共享的自定义选项结构体。
+type options struct {
+	// 允许的来源，"*" 表示允许所有来源，"https://*.example.com" 表示允许其子域名。
+	allowOrigins []string
+
+	// 自定义来源校验函数，优先于 allowOrigins。
+	allowOriginFunc func(origin string) bool
+
+	// 预检请求允许的方法。
+	allowMethods []string
+
+	// 预检请求允许的标头，"*" 表示允许请求的所有标头。
+	allowHeaders []string
+
+	// 允许浏览器脚本读取的响应标头。
+	exposeHeaders []string
+
+	// 是否允许携带 Cookie 等凭证。
+	allowCredentials bool
+
+	// 预检结果的缓存时长，为零时不发送。
+	maxAge time.Duration
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义跨域资源共享的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		allowOrigins: []string{"*"},
+		allowMethods: []string{
+			consts.MethodGet,
+			consts.MethodPost,
+			consts.MethodPut,
+			consts.MethodPatch,
+			consts.MethodDelete,
+			consts.MethodHead,
+		},
+		allowHeaders: []string{
+			consts.HeaderOrigin,
+			consts.HeaderAccept,
+			consts.HeaderContentType,
+			consts.HeaderContentLength,
+			"X-Requested-With",
+		},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithAllowOrigins 设置允许的来源，默认允许所有来源。
+//
+// 支持精确匹配（https://example.com）、子域名通配（https://*.example.com）及 "*"。
+func WithAllowOrigins(origins ...string) Option {
+	return func(o *options) {
+		o.allowOrigins = origins
+	}
+}
+
+// WithAllowOriginFunc 自定义来源校验函数，设置后忽略 WithAllowOrigins。
+func WithAllowOriginFunc(f func(origin string) bool) Option {
+	return func(o *options) {
+		o.allowOriginFunc = f
+	}
+}
+
+// WithAllowMethods 设置预检请求允许的方法，默认为 GET、POST、PUT、PATCH、DELETE 和 HEAD。
+func WithAllowMethods(methods ...string) Option {
+	return func(o *options) {
+		o.allowMethods = methods
+	}
+}
+
+// WithAllowHeaders 设置预检请求允许的标头，"*" 表示允许请求的所有标头。
+func WithAllowHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.allowHeaders = headers
+	}
+}
+
+// WithExposeHeaders 设置允许浏览器脚本读取的响应标头。
+func WithExposeHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.exposeHeaders = headers
+	}
+}
+
+// WithAllowCredentials 设置是否允许携带 Cookie 等凭证。
+//
+// 允许凭证时规范禁止使用通配的来源，此时将回写请求的来源。
+func WithAllowCredentials(b bool) Option {
+	return func(o *options) {
+		o.allowCredentials = b
+	}
+}
+
+// WithMaxAge 设置预检结果的缓存时长，按秒发送。
+func WithMaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = d
+	}
+}
@@ -0,0 +1,41 @@
+package cors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, []string{"*"}, opts.allowOrigins)
+	assert.Nil(t, opts.allowOriginFunc)
+	assert.Contains(t, opts.allowMethods, consts.MethodPatch)
+	assert.Contains(t, opts.allowHeaders, consts.HeaderContentType)
+	assert.Nil(t, opts.exposeHeaders)
+	assert.False(t, opts.allowCredentials)
+	assert.Equal(t, time.Duration(0), opts.maxAge)
+}
+
+func TestOption(t *testing.T) {
+	originFunc := func(origin string) bool { return true }
+	opts := newOptions(
+		WithAllowOrigins("https://example.com"),
+		WithAllowOriginFunc(originFunc),
+		WithAllowMethods(consts.MethodGet),
+		WithAllowHeaders("X-Token"),
+		WithExposeHeaders("X-Total"),
+		WithAllowCredentials(true),
+		WithMaxAge(time.Hour),
+	)
+	assert.Equal(t, []string{"https://example.com"}, opts.allowOrigins)
+	assert.Equal(t, fmt.Sprintf("%p", originFunc), fmt.Sprintf("%p", opts.allowOriginFunc))
+	assert.Equal(t, []string{consts.MethodGet}, opts.allowMethods)
+	assert.Equal(t, []string{"X-Token"}, opts.allowHeaders)
+	assert.Equal(t, []string{"X-Total"}, opts.exposeHeaders)
+	assert.True(t, opts.allowCredentials)
+	assert.Equal(t, time.Hour, opts.maxAge)
+}
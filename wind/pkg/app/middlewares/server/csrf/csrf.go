@@ -0,0 +1,180 @@
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+)
+
+// KeyToken 是当前请求可用的令牌在 RequestContext.Keys 中的键。
+const KeyToken = "csrf_token"
+
+// 原始令牌的字节数。
+const tokenLength = 32
+
+var (
+	ErrMissingToken = errors.New("csrf：缺少令牌")
+	ErrInvalidToken = errors.New("csrf：令牌无效")
+)
+
+// Protector 实现跨站请求伪造防护。
+//
+// 每个客户端持有一个原始令牌，默认存于 Cookie 中。每次请求生成一个经随机掩码处理的令牌，
+// 供页面或脚本在后续的非安全方法（POST、PUT、PATCH、DELETE 等）请求中通过标头或表单字段提交，
+// 中间件去除掩码后与原始令牌比较。掩码使每次渲染的令牌各不相同，以抵御 BREACH 攻击。
+type Protector struct {
+	opts *options
+}
+
+// New 返回一个跨站请求伪造防护中间件，等同于 NewProtector(opts...).Handler()。
+func New(opts ...Option) app.HandlerFunc {
+	return NewProtector(opts...).Handler()
+}
+
+// NewProtector 创建一个跨站请求伪造防护器。
+func NewProtector(opts ...Option) *Protector {
+	return &Protector{opts: newOptions(opts...)}
+}
+
+// Handler 返回执行防护的中间件。
+//
+// 中间件为每个请求将令牌存入 Keys 的 KeyToken 中，可通过 GetToken 获取，
+// 或在模板中通过 FuncMap 提供的函数渲染。
+func (p *Protector) Handler() app.HandlerFunc {
+	o := p.opts
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		raw, err := o.store.Get(c, ctx)
+		if err != nil || len(raw) != tokenLength {
+			// 令牌缺失或已损坏时重新生成
+			if raw, err = newToken(); err == nil {
+				err = o.store.Save(c, ctx, raw)
+			}
+			if err != nil {
+				o.errorHandler(c, ctx, err)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Set(KeyToken, mask(raw))
+
+		if !isSafeMethod(ctx) && !p.isExempt(ctx) {
+			if err = p.verify(ctx, raw); err != nil {
+				o.errorHandler(c, ctx, err)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Next(c)
+	}
+}
+
+// FuncMap 返回模板函数，可通过 Engine.SetFuncMap 注册，须在加载模板前调用。
+//
+//   - csrfToken 返回当前请求的令牌，如 {{ csrfToken .ctx }}。
+//   - csrfField 返回携带令牌的隐藏表单字段，如 {{ csrfField .ctx }}。
+//
+// 函数的参数为当前请求的 *app.RequestContext，需由处理器传入模板数据中。
+func (p *Protector) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": GetToken,
+		"csrfField": func(ctx *app.RequestContext) template.HTML {
+			return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(p.opts.formField) +
+				`" value="` + GetToken(ctx) + `">`)
+		},
+	}
+}
+
+// 校验提交的令牌，标头优先于表单字段。
+func (p *Protector) verify(ctx *app.RequestContext, raw []byte) error {
+	sent := ctx.Request.Header.Peek(p.opts.header)
+	if len(sent) == 0 {
+		sent = formValue(ctx, p.opts.formField)
+	}
+	if len(sent) == 0 {
+		return ErrMissingToken
+	}
+	if !equal(sent, raw) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (p *Protector) isExempt(ctx *app.RequestContext) bool {
+	if len(p.opts.exemptPaths) == 0 {
+		return false
+	}
+	if _, ok := p.opts.exemptPaths[ctx.FullPath()]; ok {
+		return true
+	}
+	_, ok := p.opts.exemptPaths[string(ctx.Path())]
+	return ok
+}
+
+// GetToken 返回当前请求的令牌，未经中间件处理时返回空串。
+func GetToken(ctx *app.RequestContext) string {
+	return ctx.GetString(KeyToken)
+}
+
+// 安全方法不应改变服务端状态，无需校验。
+func isSafeMethod(ctx *app.RequestContext) bool {
+	h := &ctx.Request.Header
+	return h.IsGet() || h.IsHead() || h.IsOptions() || h.IsTrace()
+}
+
+// 读取正文中的表单字段，不读取查询参数，以免令牌经由网址泄露。
+func formValue(ctx *app.RequestContext, field string) []byte {
+	if v := ctx.PostArgs().Peek(field); len(v) > 0 {
+		return v
+	}
+	if form, err := ctx.MultipartForm(); err == nil {
+		if v := form.Value[field]; len(v) > 0 {
+			return []byte(v[0])
+		}
+	}
+	return nil
+}
+
+func newToken() ([]byte, error) {
+	token := make([]byte, tokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// 以随机的一次性密钥对原始令牌掩码，返回编码后的 密钥||密钥^令牌。
+func mask(raw []byte) string {
+	buf := make([]byte, 2*tokenLength)
+	pad := buf[:tokenLength]
+	if _, err := rand.Read(pad); err != nil {
+		panic(err)
+	}
+	subtle.XORBytes(buf[tokenLength:], pad, raw)
+	return encode(buf)
+}
+
+// 去除提交的令牌的掩码，并与原始令牌比较。
+func equal(sent, raw []byte) bool {
+	buf, err := decode(sent)
+	if err != nil || len(buf) != 2*tokenLength {
+		return false
+	}
+	subtle.XORBytes(buf[tokenLength:], buf[:tokenLength], buf[tokenLength:])
+	return subtle.ConstantTimeCompare(buf[tokenLength:], raw) == 1
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s []byte) ([]byte, error) {
+	buf := make([]byte, base64.RawURLEncoding.DecodedLen(len(s)))
+	n, err := base64.RawURLEncoding.Decode(buf, s)
+	return buf[:n], err
+}
@@ -0,0 +1,229 @@
+package csrf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/common/utils"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+var fieldRe = regexp.MustCompile(`<input type="hidden" name="_csrf" value="([\w-]+)">`)
+
+func newTestEngine(t *testing.T, opts ...Option) *route.Engine {
+	p := NewProtector(opts...)
+	src := `<form method="post">{{ csrfField .ctx }}</form><meta content="{{ csrfToken .ctx }}">`
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "form.tmpl"), []byte(src), 0o600))
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	// 模板函数须在加载模板前注册
+	engine.SetFuncMap(p.FuncMap())
+	engine.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	engine.Use(p.Handler())
+
+	// ut 构造的上下文不含引擎的 HTML 渲染器，这里直接执行模板
+	tmpl := template.Must(template.New("form").Funcs(p.FuncMap()).Parse(src))
+	engine.GET("/form", func(c context.Context, ctx *app.RequestContext) {
+		buf := &bytes.Buffer{}
+		assert.Nil(t, tmpl.Execute(buf, utils.H{"ctx": ctx}))
+		ctx.Data(consts.StatusOK, consts.MIMETextHtml, buf.Bytes())
+	})
+	submit := func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "ok")
+	}
+	engine.POST("/submit", submit)
+	engine.DELETE("/submit", submit)
+	engine.POST("/hooks/:id", submit)
+	return engine
+}
+
+// 获取表单页，返回令牌 Cookie 及表单中的令牌。
+func getForm(t *testing.T, engine *route.Engine, headers ...ut.Header) (*protocol.Cookie, string) {
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/form", nil, headers...).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	m := fieldRe.FindSubmatch(resp.Body())
+	assert.NotNil(t, m, string(resp.Body()))
+	assert.Contains(t, string(resp.Body()), `<meta content="`+string(m[1])+`">`)
+
+	cookie := protocol.AcquireCookie()
+	cookie.SetKey("_csrf")
+	if !resp.Header.Cookie(cookie) {
+		protocol.ReleaseCookie(cookie)
+		return nil, string(m[1])
+	}
+	return cookie, string(m[1])
+}
+
+func cookieHeader(cookie *protocol.Cookie) ut.Header {
+	return ut.Header{Key: consts.HeaderCookie, Value: "_csrf=" + string(cookie.Value())}
+}
+
+func form(values string) (*ut.Body, ut.Header) {
+	return &ut.Body{Body: strings.NewReader(values), Len: len(values)},
+		ut.Header{Key: consts.HeaderContentType, Value: consts.MIMEApplicationHTMLForm}
+}
+
+func TestCSRF(t *testing.T) {
+	engine := newTestEngine(t)
+	cookie, token := getForm(t, engine)
+	assert.NotNil(t, cookie)
+	assert.Equal(t, "/", string(cookie.Path()))
+	assert.Equal(t, 12*3600, cookie.MaxAge())
+	assert.True(t, cookie.HTTPOnly())
+	assert.False(t, cookie.Secure())
+	assert.Equal(t, protocol.CookieSameSiteLaxMode, cookie.SameSite())
+
+	// 已有令牌时不再下发 Cookie，但每次渲染的令牌均不同
+	again, token2 := getForm(t, engine, cookieHeader(cookie))
+	assert.Nil(t, again)
+	assert.NotEqual(t, token, token2)
+
+	// 以表单字段提交
+	body, ct := form("name=tom&_csrf=" + token)
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/submit", body, ct, cookieHeader(cookie)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	// 以标头提交
+	resp = ut.PerformRequest(engine, consts.MethodDelete, "/submit", nil, cookieHeader(cookie), ut.Header{Key: "X-CSRF-Token", Value: token2}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	// 以多部分表单提交
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	assert.Nil(t, w.WriteField("_csrf", token))
+	assert.Nil(t, w.Close())
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/submit", &ut.Body{Body: buf, Len: buf.Len()},
+		ut.Header{Key: consts.HeaderContentType, Value: w.FormDataContentType()}, cookieHeader(cookie)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+}
+
+func TestCSRFReject(t *testing.T) {
+	engine := newTestEngine(t)
+	cookie, token := getForm(t, engine)
+	other, otherToken := getForm(t, engine)
+
+	for name, c := range map[string]struct {
+		body    string
+		headers []ut.Header
+		err     error
+	}{
+		"缺少令牌":      {"name=tom", []ut.Header{cookieHeader(cookie)}, ErrMissingToken},
+		"令牌在查询中":    {"", []ut.Header{cookieHeader(cookie)}, ErrMissingToken},
+		"缺少 Cookie": {"_csrf=" + token, nil, ErrInvalidToken},
+		"令牌不匹配":     {"_csrf=" + otherToken, []ut.Header{cookieHeader(cookie)}, ErrInvalidToken},
+		"令牌被篡改":     {"_csrf=" + token[:len(token)-2] + "AA", []ut.Header{cookieHeader(cookie)}, ErrInvalidToken},
+		"令牌格式有误":    {"_csrf=%21%21", []ut.Header{cookieHeader(cookie)}, ErrInvalidToken},
+		"以原始令牌提交":   {"_csrf=" + string(other.Value()), []ut.Header{cookieHeader(other)}, ErrInvalidToken},
+	} {
+		body, ct := form(c.body)
+		url := "/submit"
+		if name == "令牌在查询中" {
+			url += "?_csrf=" + token
+		}
+		resp := ut.PerformRequest(engine, consts.MethodPost, url, body, append(c.headers, ct)...).Result()
+		assert.Equal(t, consts.StatusForbidden, resp.StatusCode(), name)
+		assert.Equal(t, c.err.Error(), string(resp.Body()), name)
+	}
+}
+
+func TestCSRFExempt(t *testing.T) {
+	engine := newTestEngine(t, WithExemptPaths("/hooks/:id"))
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/hooks/1", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/submit", nil).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+
+	engine = newTestEngine(t, WithExemptPaths("/hooks/2"))
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/hooks/2", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/hooks/1", nil).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+}
+
+func TestCSRFCustom(t *testing.T) {
+	var got error
+	engine := newTestEngine(t,
+		WithHeader("X-XSRF-Token"),
+		WithCookieSecure(true),
+		WithCookieSameSite(protocol.CookieSameSiteStrictMode),
+		WithCookieDomain("example.com"),
+		WithErrorHandler(func(c context.Context, ctx *app.RequestContext, err error) {
+			got = err
+			ctx.JSON(consts.StatusBadRequest, utils.H{"error": "csrf"})
+		}),
+	)
+	cookie, token := getForm(t, engine)
+	assert.True(t, cookie.Secure())
+	assert.Equal(t, protocol.CookieSameSiteStrictMode, cookie.SameSite())
+	assert.Equal(t, "example.com", string(cookie.Domain()))
+
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/submit", nil, cookieHeader(cookie), ut.Header{Key: "X-CSRF-Token", Value: token}).Result()
+	assert.Equal(t, consts.StatusBadRequest, resp.StatusCode())
+	assert.Equal(t, `{"error":"csrf"}`, string(resp.Body()))
+	assert.Equal(t, ErrMissingToken, got)
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/submit", nil, cookieHeader(cookie), ut.Header{Key: "X-XSRF-Token", Value: token}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+}
+
+// 存于服务端的存储，即同步器令牌模式。
+type memoryStore struct {
+	token []byte
+	err   error
+}
+
+func (s *memoryStore) Get(c context.Context, ctx *app.RequestContext) ([]byte, error) {
+	return s.token, s.err
+}
+
+func (s *memoryStore) Save(c context.Context, ctx *app.RequestContext, token []byte) error {
+	s.token = token
+	return s.err
+}
+
+func TestStore(t *testing.T) {
+	store := &memoryStore{}
+	engine := newTestEngine(t, WithStore(store))
+	cookie, token := getForm(t, engine)
+	assert.Nil(t, cookie)
+	assert.Len(t, store.token, tokenLength)
+
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/submit", nil, ut.Header{Key: "X-CSRF-Token", Value: token}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	store.err = errors.New("store error")
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/form", nil).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+	assert.Equal(t, "store error", string(resp.Body()))
+}
+
+func TestMask(t *testing.T) {
+	raw, err := newToken()
+	assert.Nil(t, err)
+	a, b := mask(raw), mask(raw)
+	assert.NotEqual(t, a, b)
+	assert.True(t, equal([]byte(a), raw))
+	assert.True(t, equal([]byte(b), raw))
+
+	other, err := newToken()
+	assert.Nil(t, err)
+	assert.False(t, equal([]byte(a), other))
+	assert.False(t, equal([]byte(encode(raw)), raw))
+	assert.Empty(t, GetToken(app.NewContext(0)))
+}
@@ -0,0 +1,183 @@
+package csrf
+
+import (
+	"context"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// 默认的令牌 Cookie 名称。
+	defaultCookieName = "_csrf"
+
+	// 默认的令牌标头。
+	defaultHeader = "X-CSRF-Token"
+
+	// 默认的令牌表单字段。
+	defaultFormField = "_csrf"
+
+	// 默认的 Cookie 有效期。
+	defaultCookieMaxAge = 12 * time.Hour
+)
+
+// 表示令牌 Cookie 的属性。
+type cookieConfig struct {
+	name     string
+	domain   string
+	path     string
+	maxAge   time.Duration
+	secure   bool
+	httpOnly bool
+	sameSite protocol.CookieSameSite
+}
+
+// 表示一个跨站请求伪造防护的自定义选项结构体。
+type options struct {
+	// 令牌 Cookie 的属性，默认存储使用。
+	cookie cookieConfig
+
+	// 原始令牌的存储，默认存于 Cookie 中，即双重提交 Cookie 模式。
+	store Store
+
+	// 提交令牌的标头。
+	header string
+
+	// 提交令牌的表单字段。
+	formField string
+
+	// 免于校验的路由。
+	exemptPaths map[string]struct{}
+
+	// 校验失败时的处理器。
+	errorHandler func(c context.Context, ctx *app.RequestContext, err error)
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认的错误处理器，以 403 终止请求。
+func defaultErrorHandler(c context.Context, ctx *app.RequestContext, err error) {
+	ctx.AbortWithMsg(err.Error(), consts.StatusForbidden)
+}
+
+// 创建一个自定义跨站请求伪造防护的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		cookie: cookieConfig{
+			name:     defaultCookieName,
+			path:     "/",
+			maxAge:   defaultCookieMaxAge,
+			httpOnly: true,
+			sameSite: protocol.CookieSameSiteLaxMode,
+		},
+		header:       defaultHeader,
+		formField:    defaultFormField,
+		exemptPaths:  make(map[string]struct{}),
+		errorHandler: defaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.store == nil {
+		cfg.store = &cookieStore{cfg: cfg.cookie}
+	}
+	return cfg
+}
+
+// WithStore 自定义原始令牌的存储。
+//
+// 默认存于 Cookie 中（双重提交 Cookie 模式）；存于服务端会话中即为同步器令牌模式。
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithHeader 设置提交令牌的标头，默认为 "X-CSRF-Token"。
+func WithHeader(header string) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// WithFormField 设置提交令牌的表单字段，默认为 "_csrf"。
+func WithFormField(field string) Option {
+	return func(o *options) {
+		o.formField = field
+	}
+}
+
+// WithExemptPaths 设置免于校验的路由，可为注册的完整路由（如 /hooks/:id）或请求路径，
+// 适用于以其他方式认证的回调等接口。
+func WithExemptPaths(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			o.exemptPaths[p] = struct{}{}
+		}
+	}
+}
+
+// WithErrorHandler 自定义校验失败时的处理器，默认以 403 终止请求。处理器返回后请求即被中止。
+//
+// 错误为 ErrMissingToken、ErrInvalidToken 或存储返回的错误。
+func WithErrorHandler(f func(c context.Context, ctx *app.RequestContext, err error)) Option {
+	return func(o *options) {
+		o.errorHandler = f
+	}
+}
+
+// WithCookieName 设置令牌 Cookie 的名称，默认为 "_csrf"。
+func WithCookieName(name string) Option {
+	return func(o *options) {
+		o.cookie.name = name
+	}
+}
+
+// WithCookieDomain 设置令牌 Cookie 的域名，默认为空，即仅限当前主机。
+func WithCookieDomain(domain string) Option {
+	return func(o *options) {
+		o.cookie.domain = domain
+	}
+}
+
+// WithCookiePath 设置令牌 Cookie 的路径，默认为 "/"。
+func WithCookiePath(path string) Option {
+	return func(o *options) {
+		o.cookie.path = path
+	}
+}
+
+// WithCookieMaxAge 设置令牌 Cookie 的有效期，默认为 12 小时，为零时为会话 Cookie。
+func WithCookieMaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.cookie.maxAge = d
+	}
+}
+
+// WithCookieSecure 设置令牌 Cookie 是否仅限 HTTPS 传输，默认为否。
+func WithCookieSecure(b bool) Option {
+	return func(o *options) {
+		o.cookie.secure = b
+	}
+}
+
+// WithCookieHTTPOnly 设置令牌 Cookie 是否禁止脚本读取，默认为是。
+//
+// 前端脚本需从 Cookie 读取令牌时设为否；也可将令牌渲染到页面中，由脚本读取后通过标头提交。
+func WithCookieHTTPOnly(b bool) Option {
+	return func(o *options) {
+		o.cookie.httpOnly = b
+	}
+}
+
+// WithCookieSameSite 设置令牌 Cookie 的 SameSite 属性，默认为 Lax。
+func WithCookieSameSite(mode protocol.CookieSameSite) Option {
+	return func(o *options) {
+		o.cookie.sameSite = mode
+	}
+}
@@ -0,0 +1,68 @@
+package csrf
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, cookieConfig{
+		name:     "_csrf",
+		path:     "/",
+		maxAge:   12 * time.Hour,
+		httpOnly: true,
+		sameSite: protocol.CookieSameSiteLaxMode,
+	}, opts.cookie)
+	assert.Equal(t, &cookieStore{cfg: opts.cookie}, opts.store)
+	assert.Equal(t, "X-CSRF-Token", opts.header)
+	assert.Equal(t, "_csrf", opts.formField)
+	assert.Empty(t, opts.exemptPaths)
+	assert.Equal(t, fmt.Sprintf("%p", defaultErrorHandler), fmt.Sprintf("%p", opts.errorHandler))
+}
+
+type nopStore struct{}
+
+func (nopStore) Get(c context.Context, ctx *app.RequestContext) ([]byte, error) { return nil, nil }
+
+func (nopStore) Save(c context.Context, ctx *app.RequestContext, token []byte) error { return nil }
+
+func TestOption(t *testing.T) {
+	errorHandler := func(c context.Context, ctx *app.RequestContext, err error) {}
+	opts := newOptions(
+		WithCookieName("token"),
+		WithCookieDomain("example.com"),
+		WithCookiePath("/app"),
+		WithCookieMaxAge(time.Hour),
+		WithCookieSecure(true),
+		WithCookieHTTPOnly(false),
+		WithCookieSameSite(protocol.CookieSameSiteStrictMode),
+		WithHeader("X-XSRF-Token"),
+		WithFormField("csrf"),
+		WithExemptPaths("/hooks/:id", "/ping"),
+		WithErrorHandler(errorHandler),
+	)
+	assert.Equal(t, cookieConfig{
+		name:     "token",
+		domain:   "example.com",
+		path:     "/app",
+		maxAge:   time.Hour,
+		secure:   true,
+		httpOnly: false,
+		sameSite: protocol.CookieSameSiteStrictMode,
+	}, opts.cookie)
+	assert.Equal(t, &cookieStore{cfg: opts.cookie}, opts.store)
+	assert.Equal(t, "X-XSRF-Token", opts.header)
+	assert.Equal(t, "csrf", opts.formField)
+	assert.Equal(t, map[string]struct{}{"/hooks/:id": {}, "/ping": {}}, opts.exemptPaths)
+	assert.Equal(t, fmt.Sprintf("%p", errorHandler), fmt.Sprintf("%p", opts.errorHandler))
+
+	opts = newOptions(WithStore(nopStore{}))
+	assert.Equal(t, nopStore{}, opts.store)
+}
@@ -0,0 +1,48 @@
+package csrf
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// Store 保存客户端的原始令牌。
+type Store interface {
+	// Get 返回客户端的原始令牌，不存在时返回空。
+	Get(c context.Context, ctx *app.RequestContext) ([]byte, error)
+
+	// Save 保存客户端的原始令牌。
+	Save(c context.Context, ctx *app.RequestContext, token []byte) error
+}
+
+// 将原始令牌存于 Cookie 中的存储。
+type cookieStore struct {
+	cfg cookieConfig
+}
+
+func (s *cookieStore) Get(c context.Context, ctx *app.RequestContext) ([]byte, error) {
+	v := ctx.Cookie(s.cfg.name)
+	if len(v) == 0 {
+		return nil, nil
+	}
+	return decode(v)
+}
+
+func (s *cookieStore) Save(c context.Context, ctx *app.RequestContext, token []byte) error {
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+
+	cookie.SetKey(s.cfg.name)
+	cookie.SetValue(encode(token))
+	cookie.SetDomain(s.cfg.domain)
+	cookie.SetPath(s.cfg.path)
+	if s.cfg.maxAge > 0 {
+		cookie.SetMaxAge(int(s.cfg.maxAge.Seconds()))
+	}
+	cookie.SetSecure(s.cfg.secure)
+	cookie.SetHTTPOnly(s.cfg.httpOnly)
+	cookie.SetSameSite(s.cfg.sameSite)
+	ctx.Response.Header.SetCookie(cookie)
+	return nil
+}
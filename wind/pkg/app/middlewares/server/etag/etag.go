@@ -0,0 +1,206 @@
+package etag
+
+import (
+	"bytes"
+	"context"
+	"hash/crc64"
+	"strconv"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// New 返回一个为动态响应生成 ETag 并处理条件请求的中间件。
+//
+// 中间件仅作用于 GET 和 HEAD 请求，在处理链返回后以 CRC-64 计算状态码为 200 的缓冲正文的 ETag，
+// 处理器已设置 ETag 时沿用其值。随后依据 ETag 及处理器设置的 Last-Modified，
+// 按 RFC 9110 的顺序评估 If-Match、If-Unmodified-Since、If-None-Match 和 If-Modified-Since，
+// 必要时以 412 或不含正文的 304 响应；Range 请求则依据 If-Range 返回部分或完整的正文。
+//
+// 通过 SetBodyStream 设置的正文流、已劫持响应编写器及跳过正文的响应不做处理。
+// 非安全方法的前置条件须由处理器在修改资源前自行校验。
+//
+// 与 compress 同时使用时应先注册 compress，使 ETag 基于原始正文计算，压缩后转为弱 ETag。
+func New(opts ...Option) app.HandlerFunc {
+	cfg := newOptions(opts...)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !ctx.IsGet() && !ctx.IsHead() {
+			ctx.Next(c)
+			return
+		}
+
+		ctx.Next(c)
+
+		resp := &ctx.Response
+		if resp.StatusCode() != consts.StatusOK || resp.IsBodyStream() || resp.GetHijackWriter() != nil {
+			return
+		}
+		etag := append([]byte(nil), resp.Header.Peek(consts.HeaderETag)...)
+		if len(etag) == 0 {
+			if resp.SkipBody {
+				return
+			}
+			etag = generate(resp.Body(), cfg.weak)
+			resp.Header.SetBytesV(consts.HeaderETag, etag)
+		}
+		lastModified, hasLastModified := parseDate(resp.Header.Peek(consts.HeaderLastModified))
+
+		switch evaluate(ctx, etag, lastModified, hasLastModified) {
+		case consts.StatusPreconditionFailed:
+			ctx.AbortWithMsg(consts.StatusMessage(consts.StatusPreconditionFailed), consts.StatusPreconditionFailed)
+			return
+		case consts.StatusNotModified:
+			notModified(resp)
+			return
+		}
+
+		if cfg.byteRanges && !resp.SkipBody {
+			serveRange(ctx, etag, lastModified, hasLastModified)
+		}
+	}
+}
+
+// 以正文的长度和 CRC-64 生成 ETag。
+func generate(body []byte, weak bool) []byte {
+	b := make([]byte, 0, 40)
+	if weak {
+		b = append(b, "W/"...)
+	}
+	b = append(b, '"')
+	b = strconv.AppendInt(b, int64(len(body)), 16)
+	b = append(b, '-')
+	b = strconv.AppendUint(b, crc64.Checksum(body, crcTable), 16)
+	return append(b, '"')
+}
+
+// 评估前置条件，返回应响应的状态码，条件均满足时返回 200。
+func evaluate(ctx *app.RequestContext, etag []byte, lastModified time.Time, hasLastModified bool) int {
+	h := &ctx.Request.Header
+	if v := h.Peek(consts.HeaderIfMatch); len(v) > 0 {
+		if !matchAny(v, etag, true) {
+			return consts.StatusPreconditionFailed
+		}
+	} else if v := h.Peek(consts.HeaderIfUnmodifiedSince); len(v) > 0 && hasLastModified {
+		if t, ok := parseDate(v); ok && lastModified.After(t) {
+			return consts.StatusPreconditionFailed
+		}
+	}
+
+	if v := h.Peek(consts.HeaderIfNoneMatch); len(v) > 0 {
+		if matchAny(v, etag, false) {
+			return consts.StatusNotModified
+		}
+	} else if hasLastModified && !ctx.IfModifiedSince(lastModified) {
+		return consts.StatusNotModified
+	}
+	return consts.StatusOK
+}
+
+// 以不含正文的 304 响应，保留 ETag、Cache-Control 和 Vary 等标头。
+func notModified(resp *protocol.Response) {
+	resp.ResetBody()
+	resp.Header.Del(consts.HeaderContentType)
+	resp.Header.Del(consts.HeaderContentEncoding)
+	resp.SetStatusCode(consts.StatusNotModified)
+}
+
+// 处理单个字节区间的 Range 请求。
+func serveRange(ctx *app.RequestContext, etag []byte, lastModified time.Time, hasLastModified bool) {
+	resp := &ctx.Response
+	resp.Header.Set(consts.HeaderAcceptRanges, "bytes")
+
+	byteRange := ctx.Request.Header.Peek(consts.HeaderRange)
+	if len(byteRange) == 0 || !ctx.IsGet() || bytes.IndexByte(byteRange, ',') >= 0 {
+		return
+	}
+	if v := ctx.Request.Header.Peek(consts.HeaderIfRange); len(v) > 0 {
+		var ok bool
+		if v[0] == '"' || bytes.HasPrefix(v, []byte("W/")) {
+			ok = match(v, etag, true)
+		} else if t, parsed := parseDate(v); parsed && hasLastModified {
+			// 日期须与 Last-Modified 完全一致
+			ok = t.Equal(lastModified)
+		}
+		if !ok {
+			return
+		}
+	}
+
+	body := resp.Body()
+	startPos, endPos, err := app.ParseByteRange(byteRange, len(body))
+	if err != nil {
+		ctx.AbortWithMsg(consts.StatusMessage(consts.StatusRequestedRangeNotSatisfiable), consts.StatusRequestedRangeNotSatisfiable)
+		resp.Header.Set(consts.HeaderContentRange, "bytes */"+strconv.Itoa(len(body)))
+		return
+	}
+	resp.Header.SetContentRange(startPos, endPos, len(body))
+	resp.SetBody(body[startPos : endPos+1])
+	resp.SetStatusCode(consts.StatusPartialContent)
+}
+
+// 判断以逗号分隔的实体标签列表中是否有与 etag 匹配的，"*" 匹配任意表示。
+func matchAny(list, etag []byte, strong bool) bool {
+	for len(list) > 0 {
+		var tag []byte
+		tag, list = nextTag(list)
+		if len(tag) == 1 && tag[0] == '*' {
+			return true
+		}
+		if len(tag) > 0 && match(tag, etag, strong) {
+			return true
+		}
+	}
+	return false
+}
+
+// 取出列表中的下一个实体标签，标签内可含逗号。
+func nextTag(list []byte) (tag, rest []byte) {
+	list = bytes.TrimLeft(list, " \t,")
+	start := 0
+	if bytes.HasPrefix(list, []byte("W/")) {
+		start = 2
+	}
+	if len(list) > start && list[start] == '"' {
+		if end := bytes.IndexByte(list[start+1:], '"'); end >= 0 {
+			n := start + end + 2
+			return list[:n], list[n:]
+		}
+		return nil, nil
+	}
+	if i := bytes.IndexByte(list, ','); i >= 0 {
+		return bytes.TrimSpace(list[:i]), list[i+1:]
+	}
+	return bytes.TrimSpace(list), nil
+}
+
+// 比较两个实体标签，强比较要求二者均为强标签。
+func match(a, b []byte, strong bool) bool {
+	aWeak, bWeak := bytes.HasPrefix(a, []byte("W/")), bytes.HasPrefix(b, []byte("W/"))
+	if strong && (aWeak || bWeak) {
+		return false
+	}
+	if aWeak {
+		a = a[2:]
+	}
+	if bWeak {
+		b = b[2:]
+	}
+	return bytes.Equal(a, b)
+}
+
+func parseDate(v []byte) (time.Time, bool) {
+	if len(v) == 0 {
+		return time.Time{}, false
+	}
+	t, err := bytesconv.ParseHTTPDate(v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,266 @@
+package etag
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/middlewares/server/compress"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+const body = `{"message":"hello world"}`
+
+var lastModified = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestEngine(opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set(consts.HeaderCacheControl, "no-cache")
+		ctx.Data(consts.StatusOK, consts.MIMEApplicationJSON, []byte(body))
+	})
+	engine.HEAD("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Data(consts.StatusOK, consts.MIMEApplicationJSON, []byte(body))
+	})
+	engine.POST("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Data(consts.StatusOK, consts.MIMEApplicationJSON, []byte(body))
+	})
+	engine.GET("/custom", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set(consts.HeaderETag, `"v1"`)
+		ctx.Response.Header.Set(consts.HeaderLastModified, string(bytesconv.AppendHTTPDate(nil, lastModified)))
+		ctx.String(consts.StatusOK, body)
+	})
+	engine.GET("/created", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusCreated, body)
+	})
+	engine.GET("/stream", func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetBodyStream(strings.NewReader(body), len(body))
+	})
+	return engine
+}
+
+func get(engine *route.Engine, url string, headers ...ut.Header) *protocol.Response {
+	return ut.PerformRequest(engine, consts.MethodGet, url, nil, headers...).Result()
+}
+
+func TestETag(t *testing.T) {
+	engine := newTestEngine()
+	resp := get(engine, "/")
+	etag := string(resp.Header.Peek(consts.HeaderETag))
+	assert.Regexp(t, `^"19-[0-9a-f]+"$`, etag)
+	assert.Equal(t, body, string(resp.Body()))
+	assert.Equal(t, "bytes", string(resp.Header.Peek(consts.HeaderAcceptRanges)))
+	// 相同的正文生成相同的 ETag
+	assert.Equal(t, etag, string(get(engine, "/").Header.Peek(consts.HeaderETag)))
+
+	resp = ut.PerformRequest(engine, consts.MethodHead, "/", nil).Result()
+	assert.Equal(t, etag, string(resp.Header.Peek(consts.HeaderETag)))
+
+	// 弱 ETag
+	resp = get(newTestEngine(WithWeak(true), WithByteRanges(false)), "/")
+	assert.Equal(t, "W/"+etag, string(resp.Header.Peek(consts.HeaderETag)))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderAcceptRanges))
+
+	// 沿用处理器设置的 ETag
+	resp = get(engine, "/custom")
+	assert.Equal(t, `"v1"`, string(resp.Header.Peek(consts.HeaderETag)))
+
+	// 不处理的响应
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/", nil, ut.Header{Key: consts.HeaderIfNoneMatch, Value: "*"}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderETag))
+	resp = get(engine, "/created")
+	assert.Equal(t, consts.StatusCreated, resp.StatusCode())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderETag))
+	resp = get(engine, "/stream", ut.Header{Key: consts.HeaderIfNoneMatch, Value: "*"})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderETag))
+	assert.Equal(t, body, string(resp.Body()))
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	engine := newTestEngine()
+	etag := string(get(engine, "/").Header.Peek(consts.HeaderETag))
+
+	for value, status := range map[string]int{
+		etag:                         consts.StatusNotModified,
+		"W/" + etag:                  consts.StatusNotModified,
+		`"a", ` + etag:               consts.StatusNotModified,
+		`"a,b",` + etag:              consts.StatusNotModified,
+		"*":                          consts.StatusNotModified,
+		`"other"`:                    consts.StatusOK,
+		strings.Trim(etag, `"`):      consts.StatusOK,
+		`"a", W/"b"`:                 consts.StatusOK,
+		etag[:len(etag)-1] + `x"`:    consts.StatusOK,
+		`"unterminated, ` + etag[1:]: consts.StatusOK,
+	} {
+		resp := get(engine, "/", ut.Header{Key: consts.HeaderIfNoneMatch, Value: value})
+		assert.Equal(t, status, resp.StatusCode(), value)
+		assert.Equal(t, etag, string(resp.Header.Peek(consts.HeaderETag)), value)
+		if status == consts.StatusNotModified {
+			assert.Empty(t, resp.Body(), value)
+			assert.Equal(t, "no-cache", string(resp.Header.Peek(consts.HeaderCacheControl)), value)
+		} else {
+			assert.Equal(t, body, string(resp.Body()), value)
+		}
+	}
+
+	// If-None-Match 优先于 If-Modified-Since
+	resp := get(engine, "/custom",
+		ut.Header{Key: consts.HeaderIfNoneMatch, Value: `"v0"`},
+		ut.Header{Key: consts.HeaderIfModifiedSince, Value: string(bytesconv.AppendHTTPDate(nil, lastModified))})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+}
+
+func TestIfModifiedSince(t *testing.T) {
+	engine := newTestEngine()
+	for date, status := range map[time.Time]int{
+		lastModified:                   consts.StatusNotModified,
+		lastModified.Add(time.Hour):    consts.StatusNotModified,
+		lastModified.Add(-time.Second): consts.StatusOK,
+	} {
+		resp := get(engine, "/custom", ut.Header{Key: consts.HeaderIfModifiedSince, Value: string(bytesconv.AppendHTTPDate(nil, date))})
+		assert.Equal(t, status, resp.StatusCode(), date)
+	}
+
+	// 未设置 Last-Modified 时忽略
+	resp := get(engine, "/", ut.Header{Key: consts.HeaderIfModifiedSince, Value: string(bytesconv.AppendHTTPDate(nil, time.Now()))})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+}
+
+func TestIfMatch(t *testing.T) {
+	engine := newTestEngine()
+	etag := string(get(engine, "/").Header.Peek(consts.HeaderETag))
+
+	for value, status := range map[string]int{
+		etag:           consts.StatusOK,
+		`"a", ` + etag: consts.StatusOK,
+		"*":            consts.StatusOK,
+		// If-Match 使用强比较
+		"W/" + etag: consts.StatusPreconditionFailed,
+		`"other"`:   consts.StatusPreconditionFailed,
+	} {
+		resp := get(engine, "/", ut.Header{Key: consts.HeaderIfMatch, Value: value})
+		assert.Equal(t, status, resp.StatusCode(), value)
+		if status == consts.StatusPreconditionFailed {
+			assert.Equal(t, consts.StatusMessage(consts.StatusPreconditionFailed), string(resp.Body()))
+		}
+	}
+
+	// If-Match 优先于 If-Unmodified-Since
+	resp := get(engine, "/custom",
+		ut.Header{Key: consts.HeaderIfMatch, Value: `"v1"`},
+		ut.Header{Key: consts.HeaderIfUnmodifiedSince, Value: string(bytesconv.AppendHTTPDate(nil, lastModified.Add(-time.Hour)))})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	// 前置条件失败优先于 304
+	resp = get(engine, "/", ut.Header{Key: consts.HeaderIfMatch, Value: `"other"`}, ut.Header{Key: consts.HeaderIfNoneMatch, Value: etag})
+	assert.Equal(t, consts.StatusPreconditionFailed, resp.StatusCode())
+}
+
+func TestIfUnmodifiedSince(t *testing.T) {
+	engine := newTestEngine()
+	for date, status := range map[time.Time]int{
+		lastModified:                 consts.StatusOK,
+		lastModified.Add(time.Hour):  consts.StatusOK,
+		lastModified.Add(-time.Hour): consts.StatusPreconditionFailed,
+	} {
+		resp := get(engine, "/custom", ut.Header{Key: consts.HeaderIfUnmodifiedSince, Value: string(bytesconv.AppendHTTPDate(nil, date))})
+		assert.Equal(t, status, resp.StatusCode(), date)
+	}
+
+	// 日期无效时忽略
+	resp := get(engine, "/custom", ut.Header{Key: consts.HeaderIfUnmodifiedSince, Value: "invalid"})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+}
+
+func TestRange(t *testing.T) {
+	engine := newTestEngine()
+	etag := string(get(engine, "/").Header.Peek(consts.HeaderETag))
+
+	resp := get(engine, "/", ut.Header{Key: consts.HeaderRange, Value: "bytes=2-8"})
+	assert.Equal(t, consts.StatusPartialContent, resp.StatusCode())
+	assert.Equal(t, body[2:9], string(resp.Body()))
+	assert.Equal(t, "bytes 2-8/25", string(resp.Header.Peek(consts.HeaderContentRange)))
+	assert.Equal(t, etag, string(resp.Header.Peek(consts.HeaderETag)))
+
+	resp = get(engine, "/", ut.Header{Key: consts.HeaderRange, Value: "bytes=-5"})
+	assert.Equal(t, consts.StatusPartialContent, resp.StatusCode())
+	assert.Equal(t, body[20:], string(resp.Body()))
+
+	// 无法满足的区间
+	resp = get(engine, "/", ut.Header{Key: consts.HeaderRange, Value: "bytes=100-"})
+	assert.Equal(t, consts.StatusRequestedRangeNotSatisfiable, resp.StatusCode())
+	assert.Equal(t, "bytes */25", string(resp.Header.Peek(consts.HeaderContentRange)))
+
+	// 多个区间返回完整的正文
+	resp = get(engine, "/", ut.Header{Key: consts.HeaderRange, Value: "bytes=0-1,3-4"})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, body, string(resp.Body()))
+
+	// If-Range
+	date := string(bytesconv.AppendHTTPDate(nil, lastModified))
+	for _, tc := range []struct {
+		url, ifRange string
+		partial      bool
+	}{
+		{"/", etag, true},
+		{"/", `"other"`, false},
+		{"/", "W/" + etag, false},
+		{"/custom", `"v1"`, true},
+		{"/custom", date, true},
+		{"/custom", string(bytesconv.AppendHTTPDate(nil, lastModified.Add(time.Hour))), false},
+		{"/", date, false},
+		{"/", "invalid", false},
+	} {
+		resp = get(engine, tc.url, ut.Header{Key: consts.HeaderRange, Value: "bytes=0-3"}, ut.Header{Key: consts.HeaderIfRange, Value: tc.ifRange})
+		if tc.partial {
+			assert.Equal(t, consts.StatusPartialContent, resp.StatusCode(), tc)
+			assert.Equal(t, body[:4], string(resp.Body()), tc)
+		} else {
+			assert.Equal(t, consts.StatusOK, resp.StatusCode(), tc)
+			assert.Equal(t, body, string(resp.Body()), tc)
+		}
+	}
+
+	// 未开启时忽略 Range
+	resp = get(newTestEngine(WithByteRanges(false)), "/", ut.Header{Key: consts.HeaderRange, Value: "bytes=2-8"})
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, body, string(resp.Body()))
+}
+
+func TestWithCompress(t *testing.T) {
+	payload := strings.Repeat("hello world ", 200)
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(compress.New(), New())
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, payload)
+	})
+
+	gzip := ut.Header{Key: consts.HeaderAcceptEncoding, Value: "gzip"}
+	resp := get(engine, "/", gzip)
+	assert.Equal(t, "gzip", string(resp.Header.Peek(consts.HeaderContentEncoding)))
+	etag := string(resp.Header.Peek(consts.HeaderETag))
+	assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+	// 压缩后的弱 ETag 仍可用于 If-None-Match
+	resp = get(engine, "/", gzip, ut.Header{Key: consts.HeaderIfNoneMatch, Value: etag})
+	assert.Equal(t, consts.StatusNotModified, resp.StatusCode())
+	assert.Empty(t, resp.Body())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderContentEncoding))
+
+	// 区间基于未压缩的正文，且部分响应不再压缩
+	resp = get(engine, "/", gzip, ut.Header{Key: consts.HeaderRange, Value: "bytes=0-4"})
+	assert.Equal(t, consts.StatusPartialContent, resp.StatusCode())
+	assert.Equal(t, "hello", string(resp.Body()))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderContentEncoding))
+}
@@ -0,0 +1,45 @@
+package etag
+
+// 表示一个实体标签中间件的自定义选项结构体。
+type options struct {
+	// 是否生成弱 ETag。
+	weak bool
+
+	// 是否为缓冲的正文处理单个字节区间的 Range 请求。
+	byteRanges bool
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义实体标签中间件的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		byteRanges: true,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithWeak 设置是否生成弱 ETag（W/"..."），默认生成强 ETag。
+//
+// 正文在语义上等价但逐字节可能不同时（如字段顺序不固定的 JSON）应使用弱 ETag。
+// 弱 ETag 不能用于 If-Match 和 If-Range 的比较。
+func WithWeak(b bool) Option {
+	return func(o *options) {
+		o.weak = b
+	}
+}
+
+// WithByteRanges 设置是否为缓冲的正文处理 Range 请求，默认为真。
+//
+// 开启时仅支持单个字节区间，If-Range 与当前表示不符时返回完整的正文；多个区间的请求返回完整的正文。
+func WithByteRanges(b bool) Option {
+	return func(o *options) {
+		o.byteRanges = b
+	}
+}
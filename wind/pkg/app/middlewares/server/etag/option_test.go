@@ -0,0 +1,22 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.False(t, opts.weak)
+	assert.True(t, opts.byteRanges)
+}
+
+func TestOption(t *testing.T) {
+	opts := newOptions(
+		WithWeak(true),
+		WithByteRanges(false),
+	)
+	assert.True(t, opts.weak)
+	assert.False(t, opts.byteRanges)
+}
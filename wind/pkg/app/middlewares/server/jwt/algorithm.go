@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// Algorithm 表示令牌的签名算法。
+type Algorithm string
+
+// 支持的签名算法。
+const (
+	// HS256 以 []byte 类型的密钥进行 HMAC-SHA256 签名。
+	HS256 Algorithm = "HS256"
+
+	// RS256 以 *rsa.PrivateKey 签名，以 *rsa.PublicKey 验签。
+	RS256 Algorithm = "RS256"
+
+	// ES256 以 P-256 曲线的 *ecdsa.PrivateKey 签名，以 *ecdsa.PublicKey 验签。
+	ES256 Algorithm = "ES256"
+
+	// EdDSA 以 ed25519.PrivateKey 签名，以 ed25519.PublicKey 验签。
+	EdDSA Algorithm = "EdDSA"
+)
+
+// ES256 签名中 r、s 各自的字节数。
+const es256KeySize = 32
+
+// 判断算法是否受支持。
+func (a Algorithm) valid() bool {
+	switch a {
+	case HS256, RS256, ES256, EdDSA:
+		return true
+	}
+	return false
+}
+
+// 以 key 对 input 签名。
+func (a Algorithm) sign(key any, input []byte) ([]byte, error) {
+	switch a {
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok || len(k) == 0 {
+			return nil, keyTypeError(a, key)
+		}
+		m := hmac.New(sha256.New, k)
+		m.Write(input)
+		return m.Sum(nil), nil
+	case RS256:
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, keyTypeError(a, key)
+		}
+		h := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h[:])
+	case ES256:
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok || k.Curve != elliptic.P256() {
+			return nil, keyTypeError(a, key)
+		}
+		h := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, k, h[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS 要求以定长的 r||s 表示签名
+		sig := make([]byte, 2*es256KeySize)
+		r.FillBytes(sig[:es256KeySize])
+		s.FillBytes(sig[es256KeySize:])
+		return sig, nil
+	case EdDSA:
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok || len(k) != ed25519.PrivateKeySize {
+			return nil, keyTypeError(a, key)
+		}
+		return ed25519.Sign(k, input), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// 以 key 校验 input 的签名 sig，私钥将以其公钥校验。
+func (a Algorithm) verify(key any, input, sig []byte) error {
+	switch a {
+	case HS256:
+		expected, err := a.sign(key, input)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal(sig, expected) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case RS256:
+		var k *rsa.PublicKey
+		switch v := key.(type) {
+		case *rsa.PublicKey:
+			k = v
+		case *rsa.PrivateKey:
+			k = &v.PublicKey
+		default:
+			return keyTypeError(a, key)
+		}
+		h := sha256.Sum256(input)
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case ES256:
+		var k *ecdsa.PublicKey
+		switch v := key.(type) {
+		case *ecdsa.PublicKey:
+			k = v
+		case *ecdsa.PrivateKey:
+			k = &v.PublicKey
+		default:
+			return keyTypeError(a, key)
+		}
+		if k.Curve != elliptic.P256() {
+			return keyTypeError(a, key)
+		}
+		if len(sig) != 2*es256KeySize {
+			return ErrInvalidSignature
+		}
+		h := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(sig[:es256KeySize])
+		s := new(big.Int).SetBytes(sig[es256KeySize:])
+		if !ecdsa.Verify(k, h[:], r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case EdDSA:
+		var k ed25519.PublicKey
+		switch v := key.(type) {
+		case ed25519.PublicKey:
+			k = v
+		case ed25519.PrivateKey:
+			k, _ = v.Public().(ed25519.PublicKey)
+		default:
+			return keyTypeError(a, key)
+		}
+		if len(k) != ed25519.PublicKeySize {
+			return keyTypeError(a, key)
+		}
+		if !ed25519.Verify(k, input, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlgorithm
+}
+
+func keyTypeError(a Algorithm, key any) error {
+	return fmt.Errorf("jwt：%s 算法不支持 %T 类型的密钥", a, key)
+}
@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 各算法的测试密钥。
+func testKeys(t *testing.T) map[Algorithm]any {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	return map[Algorithm]any{
+		HS256: []byte("secret"),
+		RS256: rsaKey,
+		ES256: ecKey,
+		EdDSA: edKey,
+	}
+}
+
+func TestAlgorithm(t *testing.T) {
+	input := []byte("header.payload")
+	for alg, key := range testKeys(t) {
+		t.Run(string(alg), func(t *testing.T) {
+			sig, err := alg.sign(key, input)
+			assert.Nil(t, err)
+			assert.Nil(t, alg.verify(key, input, sig))
+			assert.Equal(t, ErrInvalidSignature, alg.verify(key, []byte("header.other"), sig))
+
+			tampered := append([]byte(nil), sig...)
+			tampered[0] ^= 0xff
+			assert.Equal(t, ErrInvalidSignature, alg.verify(key, input, tampered))
+
+			// 公钥仅可验签
+			var public any
+			switch k := key.(type) {
+			case *rsa.PrivateKey:
+				public = &k.PublicKey
+			case *ecdsa.PrivateKey:
+				public = &k.PublicKey
+			case ed25519.PrivateKey:
+				public = k.Public()
+			}
+			if public != nil {
+				assert.Nil(t, alg.verify(public, input, sig))
+				_, err = alg.sign(public, input)
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestAlgorithmKeyType(t *testing.T) {
+	keys := testKeys(t)
+	// 防止以公钥作为 HMAC 密钥伪造令牌
+	rsaKey := keys[RS256].(*rsa.PrivateKey)
+	_, err := HS256.sign(&rsaKey.PublicKey, nil)
+	assert.NotNil(t, err)
+	assert.NotNil(t, HS256.verify(&rsaKey.PublicKey, nil, nil))
+	_, err = HS256.sign([]byte{}, nil)
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, RS256.verify(keys[ES256], nil, nil))
+	assert.NotNil(t, ES256.verify(keys[EdDSA], nil, nil))
+	assert.NotNil(t, EdDSA.verify(keys[HS256], nil, nil))
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+	_, err = ES256.sign(p384, nil)
+	assert.NotNil(t, err)
+	assert.NotNil(t, ES256.verify(&p384.PublicKey, nil, make([]byte, 64)))
+
+	assert.Equal(t, ErrUnsupportedAlgorithm, Algorithm("none").verify(nil, nil, nil))
+	_, err = Algorithm("HS512").sign([]byte("secret"), nil)
+	assert.Equal(t, ErrUnsupportedAlgorithm, err)
+}
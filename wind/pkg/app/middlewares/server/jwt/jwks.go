@@ -0,0 +1,309 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/json"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 两次重新加载密钥集的最小间隔，避免未知密钥标识的令牌频繁触发加载。
+const minReloadInterval = 30 * time.Second
+
+// Doer 执行 HTTP 请求，*client.Client 即实现了该接口。
+type Doer interface {
+	Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error
+}
+
+// KeySet 表示 JSON Web 密钥集（JWKS，参见 RFC 7517），用于按密钥标识查找验签密钥。
+//
+// 支持 oct（HS256）、RSA（RS256）、P-256 曲线的 EC（ES256）和 Ed25519 曲线的 OKP（EdDSA）密钥，
+// 用途为加密（use 为 enc）的密钥及其他类型的密钥将被忽略。
+//
+// 从文件或网址加载的密钥集会缓存指定时长，过期后在下次查找时重新加载；
+// 令牌的密钥标识在缓存中不存在时也会重新加载，以便及时获取轮换后的新密钥。
+// 重新加载失败时记录日志并继续使用已缓存的密钥。
+type KeySet struct {
+	load func(c context.Context) ([]byte, error)
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu       sync.RWMutex
+	keys     []*jwk
+	expireAt time.Time
+	loadedAt time.Time
+
+	// 确保同一时刻仅有一个加载过程
+	loadMu sync.Mutex
+}
+
+// 已解析的单个密钥。
+type jwk struct {
+	kid string
+	alg Algorithm
+	key any
+}
+
+// ParseKeySet 解析 JWKS 格式的 data，得到不会重新加载的密钥集。
+func ParseKeySet(data []byte) (*KeySet, error) {
+	keys, err := parseKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	return &KeySet{keys: keys, now: time.Now}, nil
+}
+
+// NewFileKeySet 从 path 文件加载密钥集，缓存 ttl 后重新读取文件，ttl 为零时仅在遇到未知密钥标识时重新读取。
+//
+// 首次加载失败时返回错误。
+func NewFileKeySet(path string, ttl time.Duration) (*KeySet, error) {
+	ks := newKeySet(func(c context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, ttl)
+	if err := ks.reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// NewRemoteKeySet 从 url 获取密钥集，缓存 ttl 后重新获取，ttl 为零时仅在遇到未知密钥标识时重新获取。
+//
+// 密钥集在首次查找时才获取。doer 为空时使用默认配置的 *client.Client。
+func NewRemoteKeySet(url string, ttl time.Duration, doer Doer) *KeySet {
+	if doer == nil {
+		cli, err := client.NewClient()
+		if err != nil {
+			panic(err)
+		}
+		doer = cli
+	}
+	return newKeySet(func(c context.Context) ([]byte, error) {
+		req := protocol.AcquireRequest()
+		resp := protocol.AcquireResponse()
+		defer func() {
+			protocol.ReleaseRequest(req)
+			protocol.ReleaseResponse(resp)
+		}()
+
+		req.SetRequestURI(url)
+		req.Header.SetMethod(consts.MethodGet)
+		req.Header.Set(consts.HeaderAccept, consts.MIMEApplicationJSON)
+		if err := doer.Do(c, req, resp); err != nil {
+			return nil, err
+		}
+		if status := resp.StatusCode(); status != consts.StatusOK {
+			return nil, fmt.Errorf("jwt：获取密钥集时响应状态码为 %d", status)
+		}
+		return append([]byte(nil), resp.Body()...), nil
+	}, ttl)
+}
+
+func newKeySet(load func(c context.Context) ([]byte, error), ttl time.Duration) *KeySet {
+	return &KeySet{load: load, ttl: ttl, now: time.Now}
+}
+
+// Key 返回算法为 alg、标识为 kid 的密钥，可作为 KeyFunc 使用。
+//
+// kid 为空时返回首个算法匹配的密钥。
+func (ks *KeySet) Key(c context.Context, alg Algorithm, kid string) (any, error) {
+	ks.mu.RLock()
+	k := find(ks.keys, alg, kid)
+	stale := ks.ttl > 0 && !ks.now().Before(ks.expireAt)
+	ks.mu.RUnlock()
+
+	if ks.load == nil || (k != nil && !stale) {
+		return keyOf(k)
+	}
+
+	if k != nil {
+		// 已缓存的密钥仍可使用，若已有其他请求在加载则无需等待
+		if !ks.loadMu.TryLock() {
+			return keyOf(k)
+		}
+	} else {
+		ks.loadMu.Lock()
+	}
+	ks.mu.RLock()
+	retry := ks.loadedAt.IsZero() || ks.now().Sub(ks.loadedAt) >= minReloadInterval
+	ks.mu.RUnlock()
+	if retry {
+		if err := ks.reload(c); err != nil {
+			hlog.SystemLogger().CtxWarnf(c, "加载 JWT 密钥集失败：%v", err)
+		}
+	}
+	ks.loadMu.Unlock()
+
+	ks.mu.RLock()
+	k = find(ks.keys, alg, kid)
+	ks.mu.RUnlock()
+	return keyOf(k)
+}
+
+// 重新加载并替换密钥集。
+func (ks *KeySet) reload(c context.Context) error {
+	data, err := ks.load(c)
+	if err == nil {
+		var keys []*jwk
+		if keys, err = parseKeys(data); err == nil {
+			now := ks.now()
+			ks.mu.Lock()
+			ks.keys = keys
+			ks.loadedAt = now
+			ks.expireAt = now.Add(ks.ttl)
+			ks.mu.Unlock()
+			return nil
+		}
+	}
+
+	ks.mu.Lock()
+	ks.loadedAt = ks.now()
+	ks.mu.Unlock()
+	return err
+}
+
+func keyOf(k *jwk) (any, error) {
+	if k == nil {
+		return nil, ErrKeyNotFound
+	}
+	return k.key, nil
+}
+
+func find(keys []*jwk, alg Algorithm, kid string) *jwk {
+	for _, k := range keys {
+		if k.alg == alg && (kid == "" || k.kid == kid) {
+			return k
+		}
+	}
+	return nil
+}
+
+// JWKS 中单个密钥的原始字段。
+type rawKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseKeys(data []byte) ([]*jwk, error) {
+	var set struct {
+		Keys []rawKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt：密钥集格式有误：%w", err)
+	}
+
+	keys := make([]*jwk, 0, len(set.Keys))
+	for i := range set.Keys {
+		raw := &set.Keys[i]
+		if raw.Use == "enc" {
+			continue
+		}
+		k, err := parseKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("jwt：密钥 %q 有误：%w", raw.Kid, err)
+		}
+		// 忽略不支持的密钥及声明了其他算法的密钥
+		if k == nil || (raw.Alg != "" && Algorithm(raw.Alg) != k.alg) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func parseKey(raw *rawKey) (*jwk, error) {
+	k := &jwk{kid: raw.Kid}
+	switch raw.Kty {
+	case "oct":
+		secret, err := decodeField(raw.K)
+		if err != nil {
+			return nil, err
+		}
+		k.alg, k.key = HS256, secret
+	case "RSA":
+		n, err := decodeField(raw.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeField(raw.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) > 4 {
+			return nil, errors.New("公钥指数过大")
+		}
+		k.alg, k.key = RS256, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "EC":
+		if raw.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeField(raw.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeField(raw.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != es256KeySize || len(y) != es256KeySize {
+			return nil, errors.New("坐标长度有误")
+		}
+		// 借助 ecdh 校验点是否在曲线上
+		point := append(append([]byte{4}, x...), y...)
+		if _, err = ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		k.alg, k.key = ES256, &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+	case "OKP":
+		if raw.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeField(raw.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("公钥长度有误")
+		}
+		k.alg, k.key = EdDSA, ed25519.PublicKey(x)
+	default:
+		return nil, nil
+	}
+	return k, nil
+}
+
+func decodeField(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("缺少必需的字段")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,259 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/stretchr/testify/assert"
+)
+
+// 将密钥编码为 JWK。
+func toJWK(t *testing.T, kid string, key any) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch k := key.(type) {
+	case []byte:
+		return fmt.Sprintf(`{"kty":"oct","kid":%q,"k":%q}`, kid, enc(k))
+	case *rsa.PrivateKey:
+		return fmt.Sprintf(`{"kty":"RSA","kid":%q,"alg":"RS256","use":"sig","n":%q,"e":%q}`,
+			kid, enc(k.N.Bytes()), enc(big.NewInt(int64(k.E)).Bytes()))
+	case *ecdsa.PrivateKey:
+		return fmt.Sprintf(`{"kty":"EC","kid":%q,"crv":"P-256","x":%q,"y":%q}`,
+			kid, enc(k.X.FillBytes(make([]byte, 32))), enc(k.Y.FillBytes(make([]byte, 32))))
+	case ed25519.PrivateKey:
+		return fmt.Sprintf(`{"kty":"OKP","kid":%q,"crv":"Ed25519","x":%q}`, kid, enc(k.Public().(ed25519.PublicKey)))
+	}
+	t.Fatalf("unexpected key type %T", key)
+	return ""
+}
+
+func jwks(keys ...string) []byte {
+	return []byte(`{"keys":[` + strings.Join(keys, ",") + `]}`)
+}
+
+func TestParseKeySet(t *testing.T) {
+	keys := testKeys(t)
+	var jwkList []string
+	for alg, key := range keys {
+		jwkList = append(jwkList, toJWK(t, string(alg), key))
+	}
+	jwkList = append(jwkList,
+		`{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"}`,
+		`{"kty":"oct","kid":"other","alg":"HS512","k":"c2VjcmV0"}`,
+		`{"kty":"EC","kid":"p384","crv":"P-384","x":"AA","y":"AA"}`,
+		`{"kty":"OKP","kid":"x25519","crv":"X25519","x":"AA"}`,
+		`{"kty":"unknown","kid":"unknown"}`,
+	)
+	ks, err := ParseKeySet(jwks(jwkList...))
+	assert.Nil(t, err)
+	assert.Len(t, ks.keys, 4)
+
+	for alg, key := range keys {
+		token, err := Sign(alg, key, string(alg), Claims{"sub": "tom"})
+		assert.Nil(t, err)
+		claims, err := Parse(context.Background(), token, alg, ks.Key)
+		assert.Nil(t, err, alg)
+		assert.Equal(t, "tom", claims.Subject())
+
+		// 未指定标识时按算法查找
+		k, err := ks.Key(context.Background(), alg, "")
+		assert.Nil(t, err)
+		assert.NotNil(t, k)
+	}
+
+	for _, kid := range []string{"enc", "other", "p384", "x25519", "unknown", "missing"} {
+		_, err = ks.Key(context.Background(), HS256, kid)
+		assert.Equal(t, ErrKeyNotFound, err)
+	}
+	// 算法须与密钥类型相符
+	_, err = ks.Key(context.Background(), HS256, string(RS256))
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestParseKeySetError(t *testing.T) {
+	for _, data := range []string{
+		`{`,
+		`{"keys":[{"kty":"oct"}]}`,
+		`{"keys":[{"kty":"oct","k":"!"}]}`,
+		`{"keys":[{"kty":"RSA","n":"AQAB"}]}`,
+		`{"keys":[{"kty":"RSA","n":"AQAB","e":"AQIDBAU"}]}`,
+		`{"keys":[{"kty":"EC","crv":"P-256","x":"AQAB","y":"AQAB"}]}`,
+		`{"keys":[{"kty":"EC","crv":"P-256","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}]}`,
+		`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"AQAB"}]}`,
+	} {
+		_, err := ParseKeySet([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+}
+
+func TestFileKeySet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, jwks(toJWK(t, "k1", []byte("one"))), 0o600))
+
+	_, err := NewFileKeySet(filepath.Join(t.TempDir(), "missing.json"), time.Minute)
+	assert.NotNil(t, err)
+
+	ks, err := NewFileKeySet(path, time.Minute)
+	assert.Nil(t, err)
+	now := time.Now()
+	ks.now = func() time.Time { return now }
+
+	k, err := ks.Key(context.Background(), HS256, "k1")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("one"), k)
+
+	// 缓存期内不重新读取
+	assert.Nil(t, os.WriteFile(path, jwks(toJWK(t, "k1", []byte("two"))), 0o600))
+	k, _ = ks.Key(context.Background(), HS256, "k1")
+	assert.Equal(t, []byte("one"), k)
+
+	// 过期后重新读取
+	now = now.Add(time.Minute)
+	k, _ = ks.Key(context.Background(), HS256, "k1")
+	assert.Equal(t, []byte("two"), k)
+
+	// 重新读取失败时继续使用已缓存的密钥
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0o600))
+	now = now.Add(time.Minute)
+	k, err = ks.Key(context.Background(), HS256, "k1")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("two"), k)
+}
+
+// 记录请求次数的 Doer。
+type fakeDoer struct {
+	mu     sync.Mutex
+	calls  int
+	status int
+	body   []byte
+	err    error
+}
+
+func (d *fakeDoer) Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if d.err != nil {
+		return d.err
+	}
+	if string(req.URI().FullURI()) != "https://example.com/jwks.json" || !req.Header.IsGet() {
+		return errors.New("unexpected request")
+	}
+	resp.SetStatusCode(d.status)
+	resp.SetBody(d.body)
+	return nil
+}
+
+func (d *fakeDoer) set(status int, body []byte, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status, d.body, d.err = status, body, err
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	doer := &fakeDoer{}
+	doer.set(consts.StatusOK, jwks(toJWK(t, "k1", []byte("one"))), nil)
+	ks := NewRemoteKeySet("https://example.com/jwks.json", 0, doer)
+	now := time.Now()
+	ks.now = func() time.Time { return now }
+	c := context.Background()
+
+	// 首次查找时获取
+	assert.Equal(t, 0, doer.calls)
+	k, err := ks.Key(c, HS256, "k1")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("one"), k)
+	k, _ = ks.Key(c, HS256, "k1")
+	assert.Equal(t, []byte("one"), k)
+	assert.Equal(t, 1, doer.calls)
+
+	// 未知的密钥标识触发重新获取，但受最小间隔限制
+	doer.set(consts.StatusOK, jwks(toJWK(t, "k1", []byte("one")), toJWK(t, "k2", []byte("two"))), nil)
+	_, err = ks.Key(c, HS256, "k2")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, 1, doer.calls)
+
+	now = now.Add(minReloadInterval)
+	k, err = ks.Key(c, HS256, "k2")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("two"), k)
+	assert.Equal(t, 2, doer.calls)
+
+	_, err = ks.Key(c, HS256, "k3")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, 2, doer.calls)
+
+	// 获取失败时继续使用已缓存的密钥
+	now = now.Add(minReloadInterval)
+	doer.set(consts.StatusInternalServerError, nil, nil)
+	_, err = ks.Key(c, HS256, "k3")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, 3, doer.calls)
+	k, err = ks.Key(c, HS256, "k1")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("one"), k)
+}
+
+func TestRemoteKeySetTTL(t *testing.T) {
+	doer := &fakeDoer{}
+	doer.set(consts.StatusOK, jwks(toJWK(t, "k1", []byte("one"))), nil)
+	ks := NewRemoteKeySet("https://example.com/jwks.json", time.Hour, doer)
+	now := time.Now()
+	ks.now = func() time.Time { return now }
+	c := context.Background()
+
+	_, err := ks.Key(c, HS256, "k1")
+	assert.Nil(t, err)
+
+	doer.set(consts.StatusOK, jwks(toJWK(t, "k1", []byte("rotated"))), nil)
+	now = now.Add(time.Hour - time.Second)
+	k, _ := ks.Key(c, HS256, "k1")
+	assert.Equal(t, []byte("one"), k)
+	assert.Equal(t, 1, doer.calls)
+
+	now = now.Add(time.Second)
+	k, _ = ks.Key(c, HS256, "k1")
+	assert.Equal(t, []byte("rotated"), k)
+	assert.Equal(t, 2, doer.calls)
+
+	// 请求失败且无缓存
+	doer = &fakeDoer{}
+	doer.set(0, nil, errors.New("dial error"))
+	ks = NewRemoteKeySet("https://example.com/jwks.json", time.Hour, doer)
+	_, err = ks.Key(c, HS256, "k1")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+func TestRemoteKeySetConcurrent(t *testing.T) {
+	doer := &fakeDoer{}
+	doer.set(consts.StatusOK, jwks(toJWK(t, "k1", []byte("one"))), nil)
+	ks := NewRemoteKeySet("https://example.com/jwks.json", time.Millisecond, doer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				k, err := ks.Key(context.Background(), HS256, "k1")
+				assert.Nil(t, err)
+				assert.Equal(t, []byte("one"), k)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, doer.calls)
+}
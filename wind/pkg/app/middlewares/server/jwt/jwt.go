@@ -0,0 +1,331 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// KeyClaims 是令牌声明集在 RequestContext.Keys 中的键。
+	KeyClaims = "jwt_claims"
+
+	// KeyToken 是原始令牌在 RequestContext.Keys 中的键。
+	KeyToken = "jwt_token"
+)
+
+var errNoSigningKey = errors.New("jwt：未设置签名密钥")
+
+// 令牌的来源。
+type lookup struct {
+	source string
+	name   string
+}
+
+// Middleware 实现基于 JWT 的认证，提供校验令牌的中间件及登录、刷新和注销的处理器。
+type Middleware struct {
+	opts    *options
+	lookups []lookup
+	keyFunc KeyFunc
+}
+
+// New 返回一个校验令牌的中间件，等同于 NewMiddleware(opts...).Handler()。
+func New(opts ...Option) app.HandlerFunc {
+	return NewMiddleware(opts...).Handler()
+}
+
+// NewMiddleware 创建一个 JWT 认证中间件，算法不受支持、未设置密钥或令牌来源有误时引发恐慌。
+func NewMiddleware(opts ...Option) *Middleware {
+	o := newOptions(opts...)
+	if !o.algorithm.valid() {
+		panic(fmt.Errorf("jwt：不支持的签名算法 %q", o.algorithm))
+	}
+	if o.key == nil && o.keyFunc == nil {
+		panic("jwt：未设置密钥")
+	}
+
+	m := &Middleware{opts: o, keyFunc: o.keyFunc}
+	if m.keyFunc == nil {
+		m.keyFunc = func(c context.Context, alg Algorithm, kid string) (any, error) {
+			return o.key, nil
+		}
+	}
+	for _, s := range strings.Split(o.tokenLookup, ",") {
+		source, name, _ := strings.Cut(strings.TrimSpace(s), ":")
+		source, name = strings.TrimSpace(source), strings.TrimSpace(name)
+		switch {
+		case name == "":
+			panic(fmt.Errorf("jwt：令牌来源 %q 有误", s))
+		case source != "header" && source != "query" && source != "cookie":
+			panic(fmt.Errorf("jwt：不支持的令牌来源 %q", source))
+		}
+		m.lookups = append(m.lookups, lookup{source: source, name: name})
+	}
+	return m
+}
+
+// Handler 返回校验令牌的中间件。
+//
+// 令牌有效时，将声明集和原始令牌分别存入 Keys 的 KeyClaims 和 KeyToken 中，
+// 否则交由认证失败处理器处理，默认以 401 终止请求。
+func (m *Middleware) Handler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		token := m.extract(ctx)
+		if token == "" {
+			m.unauthorized(c, ctx, ErrMissingToken)
+			return
+		}
+		claims, err := m.Parse(c, token)
+		if err != nil {
+			m.unauthorized(c, ctx, err)
+			return
+		}
+
+		ctx.Set(KeyClaims, claims)
+		ctx.Set(KeyToken, token)
+		ctx.Next(c)
+	}
+}
+
+// LoginHandler 返回登录的处理器，未设置认证函数时引发恐慌。
+//
+// 认证函数返回的声明集将补充 iat、orig_iat、exp 以及配置的 iss 和 aud 后签发为令牌。
+func (m *Middleware) LoginHandler() app.HandlerFunc {
+	if m.opts.authenticator == nil {
+		panic("jwt：未设置认证函数")
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		claims, err := m.opts.authenticator(c, ctx)
+		if err != nil {
+			m.unauthorized(c, ctx, err)
+			return
+		}
+		m.issue(c, ctx, claims, m.opts.now())
+	}
+}
+
+// RefreshHandler 返回刷新令牌的处理器。
+//
+// 令牌须签名有效；过期的令牌仅在首次登录起 WithMaxRefresh 设置的期限内可刷新。
+// 新令牌保留原声明及首次登录的时间，并重新计算有效期。
+func (m *Middleware) RefreshHandler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		token := m.extract(ctx)
+		if token == "" {
+			m.unauthorized(c, ctx, ErrMissingToken)
+			return
+		}
+		claims, err := Parse(c, token, m.opts.algorithm, m.keyFunc)
+		if err == nil {
+			err = m.validate(claims, m.opts.maxRefresh == 0)
+		}
+		if err != nil {
+			m.unauthorized(c, ctx, err)
+			return
+		}
+
+		now := m.opts.now()
+		if m.opts.maxRefresh > 0 {
+			orig, ok := claims.Time(ClaimOrigIssuedAt)
+			if !ok {
+				orig, ok = claims.IssuedAt()
+			}
+			if !ok || now.After(orig.Add(m.opts.maxRefresh)) {
+				m.unauthorized(c, ctx, ErrRefreshExpired)
+				return
+			}
+		}
+		m.issue(c, ctx, claims, now)
+	}
+}
+
+// LogoutHandler 返回注销的处理器，下发了 Cookie 时将其删除。
+//
+// JWT 是无状态的，注销不会使已签发的令牌失效，如有需要可结合 jti 声明自行维护吊销列表。
+func (m *Middleware) LogoutHandler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if m.opts.cookieName != "" {
+			m.setCookie(ctx, "", protocol.CookieExpireDelete)
+		}
+		m.opts.logoutResponse(c, ctx)
+	}
+}
+
+// Sign 签发令牌，返回令牌及其过期时间。
+//
+// 除 claims 外，令牌还包含 iat、exp 以及配置的 iss 和 aud；claims 中未设置 orig_iat 时以当前时间补充。
+func (m *Middleware) Sign(claims Claims) (token string, expire time.Time, err error) {
+	return m.sign(claims, m.opts.now())
+}
+
+// Parse 解析令牌，校验签名、有效期及配置的签发者和受众，返回其声明集。
+func (m *Middleware) Parse(c context.Context, token string) (Claims, error) {
+	claims, err := Parse(c, token, m.opts.algorithm, m.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.validate(claims, true); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (m *Middleware) sign(claims Claims, now time.Time) (string, time.Time, error) {
+	if m.opts.key == nil {
+		return "", time.Time{}, errNoSigningKey
+	}
+
+	expire := now.Add(m.opts.timeout)
+	c := make(Claims, len(claims)+5)
+	for k, v := range claims {
+		c[k] = v
+	}
+	c[ClaimIssuedAt] = now.Unix()
+	c[ClaimExpiresAt] = expire.Unix()
+	if _, ok := c[ClaimOrigIssuedAt]; !ok {
+		c[ClaimOrigIssuedAt] = now.Unix()
+	}
+	if m.opts.issuer != "" {
+		c[ClaimIssuer] = m.opts.issuer
+	}
+	if m.opts.audience != "" {
+		if _, ok := c[ClaimAudience]; !ok {
+			c[ClaimAudience] = m.opts.audience
+		}
+	}
+
+	token, err := Sign(m.opts.algorithm, m.opts.key, m.opts.keyID, c)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// 令牌中的时间精确到秒
+	return token, time.Unix(expire.Unix(), 0), nil
+}
+
+// 签发令牌并响应。
+func (m *Middleware) issue(c context.Context, ctx *app.RequestContext, claims Claims, now time.Time) {
+	token, expire, err := m.sign(claims, now)
+	if err != nil {
+		hlog.SystemLogger().CtxErrorf(c, "签发 JWT 失败：%v", err)
+		ctx.AbortWithMsg(consts.StatusMessage(consts.StatusInternalServerError), consts.StatusInternalServerError)
+		return
+	}
+	if m.opts.cookieName != "" {
+		m.setCookie(ctx, token, expire)
+	}
+	m.opts.tokenResponse(c, ctx, token, expire)
+}
+
+// 校验声明的有效期、签发者和受众，checkExp 为假时不校验是否过期。
+func (m *Middleware) validate(claims Claims, checkExp bool) error {
+	now := m.opts.now()
+	if _, ok := claims[ClaimExpiresAt]; ok && checkExp {
+		exp, ok := claims.ExpiresAt()
+		if !ok {
+			return ErrMalformedToken
+		}
+		if !now.Before(exp.Add(m.opts.leeway)) {
+			return ErrTokenExpired
+		}
+	}
+	if _, ok := claims[ClaimNotBefore]; ok {
+		nbf, ok := claims.NotBefore()
+		if !ok {
+			return ErrMalformedToken
+		}
+		if now.Add(m.opts.leeway).Before(nbf) {
+			return ErrTokenNotValidYet
+		}
+	}
+	if m.opts.issuer != "" && claims.Issuer() != m.opts.issuer {
+		return ErrInvalidIssuer
+	}
+	if m.opts.audience != "" {
+		found := false
+		for _, aud := range claims.Audience() {
+			if aud == m.opts.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrInvalidAudience
+		}
+	}
+	return nil
+}
+
+// 按顺序从各来源提取令牌，均未找到时返回空串。
+func (m *Middleware) extract(ctx *app.RequestContext) string {
+	for _, l := range m.lookups {
+		var token string
+		switch l.source {
+		case "header":
+			token = string(ctx.Request.Header.Peek(l.name))
+			if head := m.opts.tokenHeadName; head != "" {
+				if len(token) <= len(head) || !strings.EqualFold(token[:len(head)], head) || token[len(head)] != ' ' {
+					continue
+				}
+				token = strings.TrimSpace(token[len(head)+1:])
+			}
+		case "query":
+			token = ctx.Query(l.name)
+		case "cookie":
+			token = string(ctx.Cookie(l.name))
+		}
+		if token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// 交由认证失败处理器处理并中止请求。
+func (m *Middleware) unauthorized(c context.Context, ctx *app.RequestContext, err error) {
+	// 处理器可能重置响应，标头在其后设置
+	m.opts.unauthorized(c, ctx, err)
+	ctx.Abort()
+	if ctx.Response.StatusCode() == consts.StatusUnauthorized && len(ctx.Response.Header.Peek(consts.HeaderWWWAuthenticate)) == 0 {
+		if errors.Is(err, ErrMissingToken) || errors.Is(err, ErrFailedAuthentication) {
+			ctx.Response.Header.Set(consts.HeaderWWWAuthenticate, "Bearer")
+		} else {
+			ctx.Response.Header.Set(consts.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		}
+	}
+}
+
+func (m *Middleware) setCookie(ctx *app.RequestContext, token string, expire time.Time) {
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+
+	cookie.SetKey(m.opts.cookieName)
+	cookie.SetValue(token)
+	cookie.SetPath("/")
+	cookie.SetExpire(expire)
+	cookie.SetHTTPOnly(true)
+	cookie.SetSecure(m.opts.secureCookie)
+	cookie.SetSameSite(protocol.CookieSameSiteLaxMode)
+	ctx.Response.Header.SetCookie(cookie)
+}
+
+// GetClaims 返回中间件存入的令牌声明集，未经认证时返回空。
+func GetClaims(ctx *app.RequestContext) Claims {
+	if v, ok := ctx.Get(KeyClaims); ok {
+		claims, _ := v.(Claims)
+		return claims
+	}
+	return nil
+}
+
+// GetToken 返回中间件存入的原始令牌，未经认证时返回空串。
+func GetToken(ctx *app.RequestContext) string {
+	return ctx.GetString(KeyToken)
+}
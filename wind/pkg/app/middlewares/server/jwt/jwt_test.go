@@ -0,0 +1,315 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/json"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+var secret = []byte("secret")
+
+func authenticator(c context.Context, ctx *app.RequestContext) (Claims, error) {
+	if ctx.Query("password") != "123456" {
+		return nil, ErrFailedAuthentication
+	}
+	return Claims{"sub": ctx.Query("user"), "role": "admin"}, nil
+}
+
+func newTestEngine(m *Middleware) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.POST("/login", m.LoginHandler())
+	engine.POST("/refresh", m.RefreshHandler())
+	engine.POST("/logout", m.LogoutHandler())
+	engine.GET("/me", m.Handler(), func(c context.Context, ctx *app.RequestContext) {
+		claims := GetClaims(ctx)
+		ctx.String(consts.StatusOK, claims.Subject()+":"+claims["role"].(string)+":"+GetToken(ctx)[:10])
+	})
+	return engine
+}
+
+func bearer(token string) ut.Header {
+	return ut.Header{Key: consts.HeaderAuthorization, Value: "Bearer " + token}
+}
+
+func tokenOf(t *testing.T, resp *protocol.Response) (string, time.Time) {
+	var r struct {
+		Token  string `json:"token"`
+		Expire string `json:"expire"`
+	}
+	assert.Nil(t, json.Unmarshal(resp.Body(), &r))
+	expire, err := time.Parse(time.RFC3339, r.Expire)
+	assert.Nil(t, err)
+	return r.Token, expire
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMiddleware(WithKey(secret), WithAuthenticator(authenticator), WithIssuer("wind"), WithAudience("api"))
+	m.opts.now = func() time.Time { return now }
+	engine := newTestEngine(m)
+
+	// 登录失败
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/login?user=tom&password=bad", nil).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrFailedAuthentication.Error(), string(resp.Body()))
+	assert.Equal(t, "Bearer", string(resp.Header.Peek(consts.HeaderWWWAuthenticate)))
+
+	// 登录成功
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/login?user=tom&password=123456", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	token, expire := tokenOf(t, resp)
+	assert.True(t, now.Add(time.Hour).Equal(expire))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderSetCookie))
+
+	claims, err := Parse(context.Background(), token, HS256, staticKey(secret))
+	assert.Nil(t, err)
+	assert.Equal(t, Claims{
+		"sub": "tom", "role": "admin", "iss": "wind", "aud": "api",
+		"iat": float64(now.Unix()), "orig_iat": float64(now.Unix()), "exp": float64(expire.Unix()),
+	}, claims)
+
+	// 访问受保护的路由
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "tom:admin:"+token[:10], string(resp.Body()))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, ut.Header{Key: consts.HeaderAuthorization, Value: "bearer " + token}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	// 缺少或无效的令牌
+	for _, h := range []ut.Header{
+		{Key: "X-Other", Value: "1"},
+		{Key: consts.HeaderAuthorization, Value: token},
+		{Key: consts.HeaderAuthorization, Value: "Basic " + token},
+		{Key: consts.HeaderAuthorization, Value: "Bearer"},
+	} {
+		resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, h).Result()
+		assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+		assert.Equal(t, ErrMissingToken.Error(), string(resp.Body()))
+		assert.Equal(t, "Bearer", string(resp.Header.Peek(consts.HeaderWWWAuthenticate)))
+	}
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, bearer(token+"x")).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, `Bearer error="invalid_token"`, string(resp.Header.Peek(consts.HeaderWWWAuthenticate)))
+
+	// 过期
+	now = expire
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrTokenExpired.Error(), string(resp.Body()))
+
+	// 未设置可刷新期限时，过期的令牌不可刷新
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/refresh", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrTokenExpired.Error(), string(resp.Body()))
+
+	now = expire.Add(-time.Minute)
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/refresh", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	refreshed, refreshedExpire := tokenOf(t, resp)
+	assert.True(t, now.Add(time.Hour).Equal(refreshedExpire))
+	claims, err = Parse(context.Background(), refreshed, HS256, staticKey(secret))
+	assert.Nil(t, err)
+	assert.Equal(t, "tom", claims.Subject())
+	assert.Equal(t, float64(expire.Add(-time.Hour).Unix()), claims[ClaimOrigIssuedAt])
+	assert.Equal(t, float64(now.Unix()), claims[ClaimIssuedAt])
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/logout", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Empty(t, resp.Header.Peek(consts.HeaderSetCookie))
+}
+
+func TestMaxRefresh(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMiddleware(WithKey(secret), WithAuthenticator(authenticator), WithTimeout(time.Minute), WithMaxRefresh(time.Hour))
+	m.opts.now = func() time.Time { return now }
+	engine := newTestEngine(m)
+
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/login?user=tom&password=123456", nil).Result()
+	token, _ := tokenOf(t, resp)
+
+	// 期限内可刷新已过期的令牌，首次登录时间保持不变
+	now = now.Add(30 * time.Minute)
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/refresh", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	token, _ = tokenOf(t, resp)
+
+	now = now.Add(30*time.Minute + time.Second)
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/refresh", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrRefreshExpired.Error(), string(resp.Body()))
+
+	// 签名无效的令牌不可刷新
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/refresh", nil, bearer(token+"x")).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/refresh", nil).Result()
+	assert.Equal(t, ErrMissingToken.Error(), string(resp.Body()))
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMiddleware(WithKey(secret), WithIssuer("wind"), WithAudience("api"), WithLeeway(time.Minute))
+	m.opts.now = func() time.Time { return now }
+
+	base := func(kv ...any) Claims {
+		c := Claims{"iss": "wind", "aud": []any{"web", "api"}}
+		for i := 0; i < len(kv); i += 2 {
+			c[kv[i].(string)] = kv[i+1]
+		}
+		return c
+	}
+	for name, c := range map[string]struct {
+		claims Claims
+		err    error
+	}{
+		"有效":     {base(), nil},
+		"宽限内过期":  {base("exp", float64(now.Unix()-59)), nil},
+		"已过期":    {base("exp", float64(now.Unix()-60)), ErrTokenExpired},
+		"宽限内生效":  {base("nbf", float64(now.Unix()+60)), nil},
+		"尚未生效":   {base("nbf", float64(now.Unix()+61)), ErrTokenNotValidYet},
+		"过期格式有误": {base("exp", "tomorrow"), ErrMalformedToken},
+		"生效格式有误": {base("nbf", true), ErrMalformedToken},
+		"签发者不符":  {base("iss", "other"), ErrInvalidIssuer},
+		"缺少签发者":  {Claims{"aud": "api"}, ErrInvalidIssuer},
+		"受众不符":   {base("aud", "web"), ErrInvalidAudience},
+		"缺少受众":   {Claims{"iss": "wind"}, ErrInvalidAudience},
+	} {
+		token, err := Sign(HS256, secret, "", c.claims)
+		assert.Nil(t, err)
+		_, err = m.Parse(context.Background(), token)
+		assert.Equal(t, c.err, err, name)
+	}
+}
+
+func TestTokenLookup(t *testing.T) {
+	m := NewMiddleware(WithKey(secret), WithTokenLookup("header: X-Token, query: token, cookie: jwt"), WithTokenHeadName(""))
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.GET("/me", m.Handler(), func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, GetClaims(ctx).Subject())
+	})
+	token, _, err := m.Sign(Claims{"sub": "tom"})
+	assert.Nil(t, err)
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/me", nil, ut.Header{Key: "X-Token", Value: token}).Result()
+	assert.Equal(t, "tom", string(resp.Body()))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me?token="+token, nil).Result()
+	assert.Equal(t, "tom", string(resp.Body()))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, ut.Header{Key: consts.HeaderCookie, Value: "jwt=" + token}).Result()
+	assert.Equal(t, "tom", string(resp.Body()))
+
+	// 按顺序取首个非空的来源
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me?token="+token, nil, ut.Header{Key: "X-Token", Value: "bad"}).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+
+	assert.Panics(t, func() { NewMiddleware(WithKey(secret), WithTokenLookup("header")) })
+	assert.Panics(t, func() { NewMiddleware(WithKey(secret), WithTokenLookup("form:token")) })
+}
+
+func TestCookie(t *testing.T) {
+	m := NewMiddleware(WithKey(secret), WithAuthenticator(authenticator), WithSendCookie("token"), WithSecureCookie(true))
+	engine := newTestEngine(m)
+
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/login?user=tom&password=123456", nil).Result()
+	token, expire := tokenOf(t, resp)
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+	cookie.SetKey("token")
+	assert.True(t, resp.Header.Cookie(cookie))
+	assert.Equal(t, token, string(cookie.Value()))
+	assert.Equal(t, expire.Unix(), cookie.Expire().Unix())
+	assert.Equal(t, "/", string(cookie.Path()))
+	assert.True(t, cookie.HTTPOnly())
+	assert.True(t, cookie.Secure())
+	assert.Equal(t, protocol.CookieSameSiteLaxMode, cookie.SameSite())
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/logout", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	cookie.Reset()
+	cookie.SetKey("token")
+	assert.True(t, resp.Header.Cookie(cookie))
+	assert.Empty(t, cookie.Value())
+	assert.True(t, cookie.Expire().Before(time.Now()))
+}
+
+func TestCustomResponse(t *testing.T) {
+	m := NewMiddleware(
+		WithKey(secret),
+		WithAuthenticator(authenticator),
+		WithUnauthorized(func(c context.Context, ctx *app.RequestContext, err error) {
+			ctx.AbortWithMsg("请先登录", consts.StatusForbidden)
+		}),
+		WithTokenResponse(func(c context.Context, ctx *app.RequestContext, token string, expire time.Time) {
+			ctx.String(consts.StatusCreated, token)
+		}),
+		WithLogoutResponse(func(c context.Context, ctx *app.RequestContext) {
+			ctx.SetStatusCode(consts.StatusNoContent)
+		}),
+	)
+	engine := newTestEngine(m)
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/me", nil).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+	assert.Equal(t, "请先登录", string(resp.Body()))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderWWWAuthenticate))
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/login?user=tom&password=123456", nil).Result()
+	assert.Equal(t, consts.StatusCreated, resp.StatusCode())
+	token := string(resp.Body())
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/logout", nil).Result()
+	assert.Equal(t, consts.StatusNoContent, resp.StatusCode())
+}
+
+func TestKeySetMiddleware(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	ks, err := ParseKeySet(jwks(toJWK(t, "k1", private)))
+	assert.Nil(t, err)
+
+	// 以私钥签发，以密钥集中的公钥校验
+	issuer := NewMiddleware(WithAlgorithm(EdDSA), WithKey(private), WithKeyID("k1"))
+	token, _, err := issuer.Sign(Claims{"sub": "tom"})
+	assert.Nil(t, err)
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.GET("/me", New(WithAlgorithm(EdDSA), WithKeySet(ks)), func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, GetClaims(ctx).Subject())
+	})
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/me", nil, bearer(token)).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "tom", string(resp.Body()))
+
+	other, _, err := NewMiddleware(WithAlgorithm(EdDSA), WithKey(private), WithKeyID("k2")).Sign(Claims{"sub": "tom"})
+	assert.Nil(t, err)
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/me", nil, bearer(other)).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrKeyNotFound.Error(), string(resp.Body()))
+
+	// 仅有验签密钥时无法签发令牌
+	_, _, err = NewMiddleware(WithAlgorithm(EdDSA), WithKeySet(ks)).Sign(Claims{})
+	assert.Equal(t, errNoSigningKey, err)
+	m := NewMiddleware(WithAlgorithm(EdDSA), WithKeySet(ks), WithAuthenticator(authenticator))
+	engine = newTestEngine(m)
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/login?user=tom&password=123456", nil).Result()
+	assert.Equal(t, consts.StatusInternalServerError, resp.StatusCode())
+}
+
+func TestNewMiddlewarePanic(t *testing.T) {
+	assert.Panics(t, func() { NewMiddleware() })
+	assert.Panics(t, func() { NewMiddleware(WithKey(secret), WithAlgorithm("none")) })
+	assert.Panics(t, func() { NewMiddleware(WithKey(secret)).LoginHandler() })
+	assert.Nil(t, GetClaims(app.NewContext(0)))
+	assert.Empty(t, GetToken(app.NewContext(0)))
+}
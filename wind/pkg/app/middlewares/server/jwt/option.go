@@ -0,0 +1,261 @@
+package jwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/utils"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// 默认的令牌来源。
+	defaultTokenLookup = "header:Authorization"
+
+	// 默认的令牌前缀。
+	defaultTokenHeadName = "Bearer"
+
+	// 默认的令牌有效期。
+	defaultTimeout = time.Hour
+
+	// 默认的令牌 Cookie 名称。
+	defaultCookieName = "jwt"
+)
+
+// 表示一个 JWT 认证的自定义选项结构体。
+type options struct {
+	// 签名算法，令牌的算法须与之一致。
+	algorithm Algorithm
+
+	// 签名和验签的密钥。
+	key any
+
+	// 签发令牌时写入标头的密钥标识。
+	keyID string
+
+	// 按令牌标头查找验签密钥的函数，设置后 key 仅用于签发令牌。
+	keyFunc KeyFunc
+
+	// 令牌来源，形如 "header:Authorization,query:token,cookie:jwt"。
+	tokenLookup string
+
+	// 标头中令牌的前缀。
+	tokenHeadName string
+
+	// 期望的签发者和受众，为空时不校验。
+	issuer   string
+	audience string
+
+	// 校验有效期时容许的时钟偏差。
+	leeway time.Duration
+
+	// 签发令牌的有效期。
+	timeout time.Duration
+
+	// 自首次登录起可刷新令牌的期限，为零时仅可刷新未过期的令牌。
+	maxRefresh time.Duration
+
+	// 登录时的认证函数。
+	authenticator func(c context.Context, ctx *app.RequestContext) (Claims, error)
+
+	// 认证失败时的处理器。
+	unauthorized func(c context.Context, ctx *app.RequestContext, err error)
+
+	// 登录及刷新成功时的响应。
+	tokenResponse func(c context.Context, ctx *app.RequestContext, token string, expire time.Time)
+
+	// 注销时的响应。
+	logoutResponse func(c context.Context, ctx *app.RequestContext)
+
+	// 登录及刷新时以 Cookie 下发令牌，为空时不下发。
+	cookieName string
+
+	// Cookie 是否仅限 HTTPS 传输。
+	secureCookie bool
+
+	// 当前时间，便于测试。
+	now func() time.Time
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认的认证失败处理器，以 401 终止请求。
+func defaultUnauthorized(c context.Context, ctx *app.RequestContext, err error) {
+	ctx.AbortWithMsg(err.Error(), consts.StatusUnauthorized)
+}
+
+// 默认的令牌响应。
+func defaultTokenResponse(c context.Context, ctx *app.RequestContext, token string, expire time.Time) {
+	ctx.JSON(consts.StatusOK, utils.H{
+		"token":  token,
+		"expire": expire.Format(time.RFC3339),
+	})
+}
+
+// 默认的注销响应。
+func defaultLogoutResponse(c context.Context, ctx *app.RequestContext) {
+	ctx.SetStatusCode(consts.StatusOK)
+}
+
+// 创建一个自定义 JWT 认证的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		algorithm:      HS256,
+		tokenLookup:    defaultTokenLookup,
+		tokenHeadName:  defaultTokenHeadName,
+		timeout:        defaultTimeout,
+		unauthorized:   defaultUnauthorized,
+		tokenResponse:  defaultTokenResponse,
+		logoutResponse: defaultLogoutResponse,
+		now:            time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithAlgorithm 设置签名算法，默认为 HS256。令牌标头中的算法须与之一致。
+func WithAlgorithm(alg Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = alg
+	}
+}
+
+// WithKey 设置签名和验签的密钥，类型须与算法相符。
+//
+// HS256 使用 []byte；非对称算法使用私钥时可签发和校验令牌，使用公钥时仅可校验令牌。
+func WithKey(key any) Option {
+	return func(o *options) {
+		o.key = key
+	}
+}
+
+// WithKeyID 设置签发令牌时写入标头的密钥标识（kid）。
+func WithKeyID(kid string) Option {
+	return func(o *options) {
+		o.keyID = kid
+	}
+}
+
+// WithKeySet 以密钥集校验令牌，等同于 WithKeyFunc(ks.Key)。
+func WithKeySet(ks *KeySet) Option {
+	return func(o *options) {
+		o.keyFunc = ks.Key
+	}
+}
+
+// WithKeyFunc 自定义查找验签密钥的函数，设置后 WithKey 的密钥仅用于签发令牌。
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithTokenLookup 设置令牌来源，按顺序查找，默认为 "header:Authorization"。
+//
+// 以逗号分隔多个来源，来源可为 header、query 或 cookie，如 "header:Authorization,query:token,cookie:jwt"。
+func WithTokenLookup(lookup string) Option {
+	return func(o *options) {
+		o.tokenLookup = lookup
+	}
+}
+
+// WithTokenHeadName 设置标头中令牌的前缀，默认为 "Bearer"，为空时整个标头值即为令牌。
+func WithTokenHeadName(name string) Option {
+	return func(o *options) {
+		o.tokenHeadName = name
+	}
+}
+
+// WithIssuer 设置签发者，签发令牌时写入 iss 声明，校验令牌时要求 iss 与之一致。
+func WithIssuer(iss string) Option {
+	return func(o *options) {
+		o.issuer = iss
+	}
+}
+
+// WithAudience 设置受众，签发令牌时写入 aud 声明，校验令牌时要求 aud 包含该受众。
+func WithAudience(aud string) Option {
+	return func(o *options) {
+		o.audience = aud
+	}
+}
+
+// WithLeeway 设置校验 exp 和 nbf 时容许的时钟偏差，默认为零。
+func WithLeeway(d time.Duration) Option {
+	return func(o *options) {
+		o.leeway = d
+	}
+}
+
+// WithTimeout 设置签发令牌的有效期，默认为 1 小时。
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithMaxRefresh 设置自首次登录起可刷新令牌的期限，期限内已过期的令牌也可刷新。
+//
+// 默认为零，此时仅可刷新未过期的令牌。
+func WithMaxRefresh(d time.Duration) Option {
+	return func(o *options) {
+		o.maxRefresh = d
+	}
+}
+
+// WithAuthenticator 设置登录时的认证函数，认证成功时返回待签发的声明。
+//
+// 返回的错误将交由认证失败处理器，凭据有误时可返回 ErrFailedAuthentication。
+func WithAuthenticator(f func(c context.Context, ctx *app.RequestContext) (Claims, error)) Option {
+	return func(o *options) {
+		o.authenticator = f
+	}
+}
+
+// WithUnauthorized 自定义认证失败时的处理器，默认以 401 终止请求，错误信息作为响应正文。
+//
+// 处理器返回后请求即被中止；响应状态码为 401 时将补充 WWW-Authenticate 标头。
+func WithUnauthorized(f func(c context.Context, ctx *app.RequestContext, err error)) Option {
+	return func(o *options) {
+		o.unauthorized = f
+	}
+}
+
+// WithTokenResponse 自定义登录及刷新成功时的响应，默认以 JSON 返回令牌及其过期时间。
+func WithTokenResponse(f func(c context.Context, ctx *app.RequestContext, token string, expire time.Time)) Option {
+	return func(o *options) {
+		o.tokenResponse = f
+	}
+}
+
+// WithLogoutResponse 自定义注销时的响应，默认以 200 响应。
+func WithLogoutResponse(f func(c context.Context, ctx *app.RequestContext)) Option {
+	return func(o *options) {
+		o.logoutResponse = f
+	}
+}
+
+// WithSendCookie 设置登录及刷新时以名为 name 的 HttpOnly Cookie 下发令牌，注销时将其删除。
+//
+// name 为空时使用 "jwt"。若需从该 Cookie 读取令牌，须在 WithTokenLookup 中包含对应的来源。
+func WithSendCookie(name string) Option {
+	return func(o *options) {
+		if name == "" {
+			name = defaultCookieName
+		}
+		o.cookieName = name
+	}
+}
+
+// WithSecureCookie 设置下发的 Cookie 是否仅限 HTTPS 传输。
+func WithSecureCookie(b bool) Option {
+	return func(o *options) {
+		o.secureCookie = b
+	}
+}
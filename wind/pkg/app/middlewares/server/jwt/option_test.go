@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, HS256, opts.algorithm)
+	assert.Nil(t, opts.key)
+	assert.Nil(t, opts.keyFunc)
+	assert.Equal(t, "header:Authorization", opts.tokenLookup)
+	assert.Equal(t, "Bearer", opts.tokenHeadName)
+	assert.Equal(t, time.Hour, opts.timeout)
+	assert.Zero(t, opts.maxRefresh)
+	assert.Zero(t, opts.leeway)
+	assert.Empty(t, opts.issuer)
+	assert.Empty(t, opts.audience)
+	assert.Empty(t, opts.cookieName)
+	assert.Nil(t, opts.authenticator)
+	assert.Equal(t, fmt.Sprintf("%p", defaultUnauthorized), fmt.Sprintf("%p", opts.unauthorized))
+	assert.Equal(t, fmt.Sprintf("%p", defaultTokenResponse), fmt.Sprintf("%p", opts.tokenResponse))
+	assert.Equal(t, fmt.Sprintf("%p", defaultLogoutResponse), fmt.Sprintf("%p", opts.logoutResponse))
+}
+
+func TestOption(t *testing.T) {
+	keyFunc := func(c context.Context, alg Algorithm, kid string) (any, error) { return nil, nil }
+	authenticator := func(c context.Context, ctx *app.RequestContext) (Claims, error) { return nil, nil }
+	unauthorized := func(c context.Context, ctx *app.RequestContext, err error) {}
+	tokenResponse := func(c context.Context, ctx *app.RequestContext, token string, expire time.Time) {}
+	logoutResponse := func(c context.Context, ctx *app.RequestContext) {}
+
+	opts := newOptions(
+		WithAlgorithm(EdDSA),
+		WithKey([]byte("secret")),
+		WithKeyID("k1"),
+		WithKeyFunc(keyFunc),
+		WithTokenLookup("query:token"),
+		WithTokenHeadName(""),
+		WithIssuer("wind"),
+		WithAudience("api"),
+		WithLeeway(time.Second),
+		WithTimeout(time.Minute),
+		WithMaxRefresh(time.Hour),
+		WithAuthenticator(authenticator),
+		WithUnauthorized(unauthorized),
+		WithTokenResponse(tokenResponse),
+		WithLogoutResponse(logoutResponse),
+		WithSendCookie(""),
+		WithSecureCookie(true),
+	)
+	assert.Equal(t, EdDSA, opts.algorithm)
+	assert.Equal(t, []byte("secret"), opts.key)
+	assert.Equal(t, "k1", opts.keyID)
+	assert.Equal(t, fmt.Sprintf("%p", keyFunc), fmt.Sprintf("%p", opts.keyFunc))
+	assert.Equal(t, "query:token", opts.tokenLookup)
+	assert.Empty(t, opts.tokenHeadName)
+	assert.Equal(t, "wind", opts.issuer)
+	assert.Equal(t, "api", opts.audience)
+	assert.Equal(t, time.Second, opts.leeway)
+	assert.Equal(t, time.Minute, opts.timeout)
+	assert.Equal(t, time.Hour, opts.maxRefresh)
+	assert.Equal(t, fmt.Sprintf("%p", authenticator), fmt.Sprintf("%p", opts.authenticator))
+	assert.Equal(t, fmt.Sprintf("%p", unauthorized), fmt.Sprintf("%p", opts.unauthorized))
+	assert.Equal(t, fmt.Sprintf("%p", tokenResponse), fmt.Sprintf("%p", opts.tokenResponse))
+	assert.Equal(t, fmt.Sprintf("%p", logoutResponse), fmt.Sprintf("%p", opts.logoutResponse))
+	assert.Equal(t, "jwt", opts.cookieName)
+	assert.True(t, opts.secureCookie)
+
+	ks, err := ParseKeySet([]byte(`{"keys":[]}`))
+	assert.Nil(t, err)
+	opts = newOptions(WithKeySet(ks), WithSendCookie("token"))
+	assert.NotNil(t, opts.keyFunc)
+	assert.Equal(t, "token", opts.cookieName)
+}
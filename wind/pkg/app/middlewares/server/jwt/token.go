@@ -0,0 +1,198 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/json"
+)
+
+// 注册的声明名称，参见 RFC 7519 第 4.1 节。
+const (
+	ClaimIssuer    = "iss"
+	ClaimSubject   = "sub"
+	ClaimAudience  = "aud"
+	ClaimExpiresAt = "exp"
+	ClaimNotBefore = "nbf"
+	ClaimIssuedAt  = "iat"
+	ClaimID        = "jti"
+
+	// ClaimOrigIssuedAt 为首次登录的签发时间，刷新令牌时保持不变，用于限制可刷新期限。
+	ClaimOrigIssuedAt = "orig_iat"
+)
+
+var (
+	ErrMissingToken         = errors.New("jwt：缺少令牌")
+	ErrMalformedToken       = errors.New("jwt：令牌格式有误")
+	ErrUnsupportedAlgorithm = errors.New("jwt：不支持的签名算法")
+	ErrInvalidSignature     = errors.New("jwt：签名无效")
+	ErrKeyNotFound          = errors.New("jwt：未找到验签密钥")
+	ErrTokenExpired         = errors.New("jwt：令牌已过期")
+	ErrTokenNotValidYet     = errors.New("jwt：令牌尚未生效")
+	ErrInvalidIssuer        = errors.New("jwt：签发者不匹配")
+	ErrInvalidAudience      = errors.New("jwt：受众不匹配")
+	ErrRefreshExpired       = errors.New("jwt：令牌已超出可刷新期限")
+	ErrFailedAuthentication = errors.New("jwt：用户名或密码错误")
+)
+
+// KeyFunc 按令牌标头中的算法和密钥标识返回验签密钥。
+type KeyFunc func(c context.Context, alg Algorithm, kid string) (any, error)
+
+// Claims 表示令牌的声明集。
+type Claims map[string]any
+
+// Issuer 返回签发者。
+func (c Claims) Issuer() string {
+	s, _ := c[ClaimIssuer].(string)
+	return s
+}
+
+// Subject 返回主题，通常为用户标识。
+func (c Claims) Subject() string {
+	s, _ := c[ClaimSubject].(string)
+	return s
+}
+
+// Audience 返回受众，单个字符串的受众也以切片返回。
+func (c Claims) Audience() []string {
+	switch v := c[ClaimAudience].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		aud := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	}
+	return nil
+}
+
+// ExpiresAt 返回过期时间，未设置时 ok 为假。
+func (c Claims) ExpiresAt() (t time.Time, ok bool) {
+	return c.Time(ClaimExpiresAt)
+}
+
+// NotBefore 返回生效时间，未设置时 ok 为假。
+func (c Claims) NotBefore() (t time.Time, ok bool) {
+	return c.Time(ClaimNotBefore)
+}
+
+// IssuedAt 返回签发时间，未设置时 ok 为假。
+func (c Claims) IssuedAt() (t time.Time, ok bool) {
+	return c.Time(ClaimIssuedAt)
+}
+
+// Time 将以秒为单位的数值声明解析为时间，未设置或类型不符时 ok 为假。
+func (c Claims) Time(name string) (t time.Time, ok bool) {
+	var sec float64
+	switch v := c[name].(type) {
+	case float64:
+		sec = v
+	case int64:
+		sec = float64(v)
+	case int:
+		sec = float64(v)
+	default:
+		return time.Time{}, false
+	}
+	if math.IsNaN(sec) || math.IsInf(sec, 0) {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(sec)
+	return time.Unix(int64(whole), int64(frac*1e9)), true
+}
+
+// 令牌的 JOSE 标头。
+type header struct {
+	Alg Algorithm `json:"alg"`
+	Typ string    `json:"typ,omitempty"`
+	Kid string    `json:"kid,omitempty"`
+}
+
+// Sign 以 alg 和 key 对 claims 签名，kid 非空时写入标头。
+func Sign(alg Algorithm, key any, kid string, claims Claims) (string, error) {
+	if !alg.valid() {
+		return "", ErrUnsupportedAlgorithm
+	}
+	h, err := json.Marshal(header{Alg: alg, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	buf := make([]byte, enc.EncodedLen(len(h))+1+enc.EncodedLen(len(p)))
+	enc.Encode(buf, h)
+	n := enc.EncodedLen(len(h))
+	buf[n] = '.'
+	enc.Encode(buf[n+1:], p)
+
+	sig, err := alg.sign(key, buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf) + "." + enc.EncodeToString(sig), nil
+}
+
+// Parse 解析令牌并校验签名，令牌的算法须为 alg。
+//
+// Parse 不校验声明的有效期、签发者和受众，中间件会在其后按选项校验。
+func Parse(c context.Context, token string, alg Algorithm, keyFunc KeyFunc) (Claims, error) {
+	i := strings.IndexByte(token, '.')
+	j := strings.LastIndexByte(token, '.')
+	if i <= 0 || j <= i+1 || j == len(token)-1 {
+		return nil, ErrMalformedToken
+	}
+
+	enc := base64.RawURLEncoding
+	h, err := enc.DecodeString(token[:i])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var hdr header
+	if err = json.Unmarshal(h, &hdr); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if hdr.Alg != alg {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	sig, err := enc.DecodeString(token[j+1:])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	key, err := keyFunc(c, hdr.Alg, hdr.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err = alg.verify(key, []byte(token[:j]), sig); err != nil {
+		return nil, err
+	}
+
+	p, err := enc.DecodeString(token[i+1 : j])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	// 声明集须为对象
+	if p = bytes.TrimSpace(p); len(p) == 0 || p[0] != '{' {
+		return nil, ErrMalformedToken
+	}
+	var claims Claims
+	if err = json.Unmarshal(p, &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	return claims, nil
+}
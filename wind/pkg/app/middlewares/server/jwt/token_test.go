@@ -0,0 +1,134 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func staticKey(key any) KeyFunc {
+	return func(c context.Context, alg Algorithm, kid string) (any, error) {
+		return key, nil
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	for alg, key := range testKeys(t) {
+		token, err := Sign(alg, key, "k1", Claims{"sub": "tom", "admin": true})
+		assert.Nil(t, err)
+
+		var gotAlg Algorithm
+		var gotKid string
+		claims, err := Parse(context.Background(), token, alg, func(c context.Context, alg Algorithm, kid string) (any, error) {
+			gotAlg, gotKid = alg, kid
+			return key, nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, alg, gotAlg)
+		assert.Equal(t, "k1", gotKid)
+		assert.Equal(t, Claims{"sub": "tom", "admin": true}, claims)
+	}
+}
+
+// 与其他实现签发的令牌互通。
+func TestParseKnownToken(t *testing.T) {
+	token := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9." +
+		"eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6IkpvaG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ." +
+		"SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c"
+	claims, err := Parse(context.Background(), token, HS256, staticKey([]byte("your-256-bit-secret")))
+	assert.Nil(t, err)
+	assert.Equal(t, "1234567890", claims.Subject())
+	assert.Equal(t, "John Doe", claims["name"])
+	iat, ok := claims.IssuedAt()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1516239022, 0), iat)
+
+	_, err = Parse(context.Background(), token, HS256, staticKey([]byte("other")))
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestParseError(t *testing.T) {
+	key := []byte("secret")
+	token, err := Sign(HS256, key, "", Claims{"sub": "tom"})
+	assert.Nil(t, err)
+	parts := strings.Split(token, ".")
+	enc := base64.RawURLEncoding
+
+	for name, c := range map[string]struct {
+		token string
+		err   error
+	}{
+		"空令牌":   {"", ErrMalformedToken},
+		"缺少分段":  {parts[0] + "." + parts[1], ErrMalformedToken},
+		"空签名":   {parts[0] + "." + parts[1] + ".", ErrMalformedToken},
+		"多余分段":  {token + ".x", ErrMalformedToken},
+		"标头非法":  {"!." + parts[1] + "." + parts[2], ErrMalformedToken},
+		"签名非法":  {parts[0] + "." + parts[1] + ".!", ErrMalformedToken},
+		"算法不符":  {enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + parts[1] + "." + parts[2], ErrUnsupportedAlgorithm},
+		"无签名算法": {enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "." + parts[2], ErrUnsupportedAlgorithm},
+		"篡改声明":  {parts[0] + "." + enc.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2], ErrInvalidSignature},
+	} {
+		_, err = Parse(context.Background(), c.token, HS256, staticKey(key))
+		assert.Equal(t, c.err, err, name)
+	}
+
+	// 声明集不是对象
+	for _, payload := range []string{`[1]`, `"tom"`, `{`} {
+		signed, err := Sign(HS256, key, "", nil)
+		assert.Nil(t, err)
+		p := strings.Split(signed, ".")
+		input := p[0] + "." + enc.EncodeToString([]byte(payload))
+		sig, err := HS256.sign(key, []byte(input))
+		assert.Nil(t, err)
+		_, err = Parse(context.Background(), input+"."+enc.EncodeToString(sig), HS256, staticKey(key))
+		assert.Equal(t, ErrMalformedToken, err)
+	}
+
+	errKey := errors.New("key error")
+	_, err = Parse(context.Background(), token, HS256, func(c context.Context, alg Algorithm, kid string) (any, error) {
+		return nil, errKey
+	})
+	assert.Equal(t, errKey, err)
+
+	_, err = Sign("none", nil, "", nil)
+	assert.Equal(t, ErrUnsupportedAlgorithm, err)
+}
+
+func TestClaims(t *testing.T) {
+	claims := Claims{
+		"iss": "wind",
+		"sub": "tom",
+		"aud": []any{"api", 1, "web"},
+		"exp": float64(1700000000.5),
+		"nbf": int64(1600000000),
+		"iat": 1500000000,
+		"bad": "1700000000",
+	}
+	assert.Equal(t, "wind", claims.Issuer())
+	assert.Equal(t, "tom", claims.Subject())
+	assert.Equal(t, []string{"api", "web"}, claims.Audience())
+
+	exp, ok := claims.ExpiresAt()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1700000000, 5e8), exp)
+	nbf, ok := claims.NotBefore()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1600000000, 0), nbf)
+	iat, ok := claims.IssuedAt()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1500000000, 0), iat)
+	_, ok = claims.Time("bad")
+	assert.False(t, ok)
+	_, ok = claims.Time("missing")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"api"}, Claims{"aud": "api"}.Audience())
+	assert.Equal(t, []string{"a", "b"}, Claims{"aud": []string{"a", "b"}}.Audience())
+	assert.Nil(t, Claims{}.Audience())
+	assert.Empty(t, Claims{"sub": 1}.Subject())
+}
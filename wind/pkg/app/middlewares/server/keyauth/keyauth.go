@@ -0,0 +1,180 @@
+package keyauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+)
+
+// KeyPrincipal 是认证通过的密钥持有者在 RequestContext.Keys 中的键。
+const KeyPrincipal = "keyauth_principal"
+
+var (
+	ErrMissingKey        = errors.New("keyauth：缺少 API 密钥")
+	ErrInvalidKey        = errors.New("keyauth：无效的 API 密钥")
+	ErrInsufficientScope = errors.New("keyauth：API 密钥权限不足")
+)
+
+// Principal 表示 API 密钥的持有者。
+type Principal struct {
+	// 持有者标识，如应用或用户的编号。
+	ID string
+
+	// 密钥被授予的权限范围。
+	Scopes []string
+
+	// 附加信息，可选。
+	Metadata any
+}
+
+// HasScopes 判断是否具备全部的权限范围。
+func (p *Principal) HasScopes(scopes ...string) bool {
+	for _, s := range scopes {
+		found := false
+		for _, granted := range p.Scopes {
+			if granted == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupFunc 查找密钥的持有者，密钥无效时返回空。
+//
+// 返回的错误将交由错误处理器，默认以 500 终止请求。
+type LookupFunc func(c context.Context, key string) (*Principal, error)
+
+// ErrorHandler 处理认证或授权失败的请求。
+type ErrorHandler func(c context.Context, ctx *app.RequestContext, err error)
+
+// StaticKeys 返回查找固定密钥的函数。
+//
+// 密钥以摘要为索引，查找耗时与密钥内容无关。
+func StaticKeys(keys map[string]Principal) LookupFunc {
+	m := make(map[[sha256.Size]byte]*Principal, len(keys))
+	for key, p := range keys {
+		p.Scopes = append([]string(nil), p.Scopes...)
+		m[sha256.Sum256([]byte(key))] = &p
+	}
+	return func(c context.Context, key string) (*Principal, error) {
+		return m[sha256.Sum256([]byte(key))], nil
+	}
+}
+
+// 密钥的来源。
+type lookup struct {
+	source string
+	name   string
+}
+
+// New 返回一个 API 密钥认证中间件，未设置查找函数或密钥来源有误时引发恐慌。
+//
+// 认证通过时，将密钥的持有者存入 Keys 的 KeyPrincipal 中，可通过 GetPrincipal 获取；
+// 否则交由错误处理器处理。
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+	if o.lookup == nil {
+		panic("keyauth：未设置密钥查找函数")
+	}
+	var lookups []lookup
+	for _, s := range strings.Split(o.keyLookup, ",") {
+		source, name, _ := strings.Cut(strings.TrimSpace(s), ":")
+		source, name = strings.TrimSpace(source), strings.TrimSpace(name)
+		switch {
+		case name == "":
+			panic(fmt.Errorf("keyauth：密钥来源 %q 有误", s))
+		case source != "header" && source != "query":
+			panic(fmt.Errorf("keyauth：不支持的密钥来源 %q", source))
+		}
+		lookups = append(lookups, lookup{source: source, name: name})
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		key := extract(ctx, lookups, o.authScheme)
+		if key == "" {
+			fail(c, ctx, o.errorHandler, ErrMissingKey)
+			return
+		}
+		p, err := o.lookup(c, key)
+		if err == nil && p == nil {
+			err = ErrInvalidKey
+		}
+		if err == nil && !p.HasScopes(o.scopes...) {
+			err = ErrInsufficientScope
+		}
+		if err != nil {
+			fail(c, ctx, o.errorHandler, err)
+			return
+		}
+
+		ctx.Set(KeyPrincipal, p)
+		ctx.Next(c)
+	}
+}
+
+// RequireScopes 返回校验权限范围的中间件，须注册在认证中间件之后。
+//
+// 未经认证或不具备全部的权限范围时，交由 handler 处理，handler 为空时使用默认的错误处理器。
+func RequireScopes(handler ErrorHandler, scopes ...string) app.HandlerFunc {
+	if handler == nil {
+		handler = defaultErrorHandler
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		p := GetPrincipal(ctx)
+		switch {
+		case p == nil:
+			fail(c, ctx, handler, ErrMissingKey)
+		case !p.HasScopes(scopes...):
+			fail(c, ctx, handler, ErrInsufficientScope)
+		default:
+			ctx.Next(c)
+		}
+	}
+}
+
+// GetPrincipal 返回认证通过的密钥持有者，未经认证时返回空。
+func GetPrincipal(ctx *app.RequestContext) *Principal {
+	if v, ok := ctx.Get(KeyPrincipal); ok {
+		p, _ := v.(*Principal)
+		return p
+	}
+	return nil
+}
+
+func fail(c context.Context, ctx *app.RequestContext, handler ErrorHandler, err error) {
+	handler(c, ctx, err)
+	ctx.Abort()
+}
+
+// 按顺序从各来源提取密钥，均未找到时返回空串。
+func extract(ctx *app.RequestContext, lookups []lookup, scheme string) string {
+	for _, l := range lookups {
+		var key string
+		switch l.source {
+		case "header":
+			key = string(ctx.Request.Header.Peek(l.name))
+			if scheme != "" {
+				if len(key) <= len(scheme) || !strings.EqualFold(key[:len(scheme)], scheme) || key[len(scheme)] != ' ' {
+					continue
+				}
+				key = strings.TrimSpace(key[len(scheme)+1:])
+			}
+		case "query":
+			key = ctx.Query(l.name)
+		}
+		if key != "" {
+			return key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package keyauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKeys = map[string]Principal{
+	"reader-key": {ID: "reader", Scopes: []string{"read"}},
+	"admin-key":  {ID: "admin", Scopes: []string{"read", "write"}, Metadata: "root"},
+}
+
+func newTestEngine(opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	engine.GET("/items", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, GetPrincipal(ctx).ID)
+	})
+	engine.POST("/items", RequireScopes(nil, "write"), func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusCreated, GetPrincipal(ctx).ID)
+	})
+	return engine
+}
+
+func apiKey(key string) ut.Header {
+	return ut.Header{Key: "X-API-Key", Value: key}
+}
+
+func TestKeyAuth(t *testing.T) {
+	engine := newTestEngine(WithKeys(testKeys))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/items", nil, apiKey("reader-key")).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "reader", string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items", nil).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrMissingKey.Error(), string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items", nil, apiKey("bad-key")).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, ErrInvalidKey.Error(), string(resp.Body()))
+
+	// 路由级的权限范围
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/items", nil, apiKey("reader-key")).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+	assert.Equal(t, ErrInsufficientScope.Error(), string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/items", nil, apiKey("admin-key")).Result()
+	assert.Equal(t, consts.StatusCreated, resp.StatusCode())
+	assert.Equal(t, "admin", string(resp.Body()))
+}
+
+func TestScopes(t *testing.T) {
+	engine := newTestEngine(WithKeys(testKeys), WithScopes("read", "write"))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/items", nil, apiKey("reader-key")).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items", nil, apiKey("admin-key")).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+
+	p := &Principal{Scopes: []string{"a", "b"}}
+	assert.True(t, p.HasScopes())
+	assert.True(t, p.HasScopes("b", "a"))
+	assert.False(t, p.HasScopes("a", "c"))
+}
+
+func TestKeyLookup(t *testing.T) {
+	engine := newTestEngine(WithKeys(testKeys), WithKeyLookup("header:Authorization, query:api_key"), WithAuthScheme("Bearer"))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/items", nil, ut.Header{Key: consts.HeaderAuthorization, Value: "bearer admin-key"}).Result()
+	assert.Equal(t, "admin", string(resp.Body()))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items?api_key=reader-key", nil).Result()
+	assert.Equal(t, "reader", string(resp.Body()))
+
+	// 前缀不符时继续查找下一个来源
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items?api_key=reader-key", nil, ut.Header{Key: consts.HeaderAuthorization, Value: "Basic admin-key"}).Result()
+	assert.Equal(t, "reader", string(resp.Body()))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items", nil, ut.Header{Key: consts.HeaderAuthorization, Value: "admin-key"}).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+
+	assert.Panics(t, func() { New(WithKeys(testKeys), WithKeyLookup("header")) })
+	assert.Panics(t, func() { New(WithKeys(testKeys), WithKeyLookup("cookie:key")) })
+	assert.Panics(t, func() { New() })
+}
+
+func TestLookupError(t *testing.T) {
+	errStore := errors.New("store error")
+	var got error
+	engine := newTestEngine(
+		WithLookup(func(c context.Context, key string) (*Principal, error) {
+			if key == "k" {
+				return &Principal{ID: "app"}, nil
+			}
+			return nil, errStore
+		}),
+	)
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/items", nil, apiKey("x")).Result()
+	assert.Equal(t, consts.StatusInternalServerError, resp.StatusCode())
+
+	engine = newTestEngine(
+		WithLookup(func(c context.Context, key string) (*Principal, error) { return nil, errStore }),
+		WithErrorHandler(func(c context.Context, ctx *app.RequestContext, err error) {
+			got = err
+			ctx.String(consts.StatusServiceUnavailable, "稍后重试")
+		}),
+	)
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/items", nil, apiKey("x")).Result()
+	assert.Equal(t, consts.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, "稍后重试", string(resp.Body()))
+	assert.Equal(t, errStore, got)
+}
+
+func TestRequireScopes(t *testing.T) {
+	var got error
+	h := RequireScopes(func(c context.Context, ctx *app.RequestContext, err error) {
+		got = err
+	}, "write")
+	ctx := app.NewContext(0)
+	h(context.Background(), ctx)
+	assert.Equal(t, ErrMissingKey, got)
+	assert.True(t, ctx.IsAborted())
+
+	ctx = app.NewContext(0)
+	ctx.Set(KeyPrincipal, &Principal{Scopes: []string{"read"}})
+	h(context.Background(), ctx)
+	assert.Equal(t, ErrInsufficientScope, got)
+	assert.Nil(t, GetPrincipal(app.NewContext(0)))
+}
+
+func TestStaticKeys(t *testing.T) {
+	keys := map[string]Principal{"k": {ID: "app", Scopes: []string{"read"}}}
+	lookup := StaticKeys(keys)
+	keys["k"].Scopes[0] = "write"
+
+	p, err := lookup(context.Background(), "k")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"read"}, p.Scopes)
+	p, err = lookup(context.Background(), "K")
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+}
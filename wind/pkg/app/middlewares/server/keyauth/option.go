@@ -0,0 +1,110 @@
+package keyauth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 默认的密钥来源。
+const defaultKeyLookup = "header:X-API-Key"
+
+// 表示一个 API 密钥认证的自定义选项结构体。
+type options struct {
+	// 密钥来源，形如 "header:X-API-Key,query:api_key"。
+	keyLookup string
+
+	// 标头中密钥的前缀，为空时整个标头值即为密钥。
+	authScheme string
+
+	// 密钥查找函数。
+	lookup LookupFunc
+
+	// 要求密钥具备的权限范围。
+	scopes []string
+
+	// 认证或授权失败时的处理器。
+	errorHandler ErrorHandler
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义 API 密钥认证的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		keyLookup:    defaultKeyLookup,
+		errorHandler: defaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// 默认的错误处理器，缺少或无效的密钥以 401、权限不足以 403、其他错误以 500 终止请求。
+func defaultErrorHandler(c context.Context, ctx *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, ErrMissingKey), errors.Is(err, ErrInvalidKey):
+		ctx.AbortWithMsg(err.Error(), consts.StatusUnauthorized)
+	case errors.Is(err, ErrInsufficientScope):
+		ctx.AbortWithMsg(err.Error(), consts.StatusForbidden)
+	default:
+		hlog.SystemLogger().CtxErrorf(c, "查找 API 密钥出错：%v", err)
+		ctx.AbortWithMsg(consts.StatusMessage(consts.StatusInternalServerError), consts.StatusInternalServerError)
+	}
+}
+
+// WithKeyLookup 设置密钥来源，按顺序查找，默认为 "header:X-API-Key"。
+//
+// 以逗号分隔多个来源，来源可为 header 或 query，如 "header:X-API-Key,query:api_key"。
+func WithKeyLookup(lookup string) Option {
+	return func(o *options) {
+		o.keyLookup = lookup
+	}
+}
+
+// WithAuthScheme 设置标头中密钥的前缀，如以 "Authorization: Bearer <key>" 传递密钥时设为 "Bearer"。
+//
+// 默认为空，此时整个标头值即为密钥。
+func WithAuthScheme(scheme string) Option {
+	return func(o *options) {
+		o.authScheme = scheme
+	}
+}
+
+// WithLookup 设置密钥查找函数，如从数据库读取密钥及其权限范围。
+func WithLookup(f LookupFunc) Option {
+	return func(o *options) {
+		o.lookup = f
+	}
+}
+
+// WithKeys 以固定的密钥认证，等同于 WithLookup(StaticKeys(keys))。
+func WithKeys(keys map[string]Principal) Option {
+	return WithLookup(StaticKeys(keys))
+}
+
+// WithScopes 设置要求密钥具备的权限范围，须全部具备才可通过。
+//
+// 也可在认证中间件之后为个别路由注册 RequireScopes。
+func WithScopes(scopes ...string) Option {
+	return func(o *options) {
+		o.scopes = scopes
+	}
+}
+
+// WithErrorHandler 自定义认证或授权失败时的处理器，处理器返回后请求即被中止。
+//
+// 错误为 ErrMissingKey、ErrInvalidKey、ErrInsufficientScope 或查找函数返回的错误。
+// 默认缺少或无效的密钥以 401、权限不足以 403、其他错误以 500 终止请求。
+func WithErrorHandler(f ErrorHandler) Option {
+	return func(o *options) {
+		o.errorHandler = f
+	}
+}
@@ -0,0 +1,41 @@
+package keyauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, "header:X-API-Key", opts.keyLookup)
+	assert.Empty(t, opts.authScheme)
+	assert.Nil(t, opts.lookup)
+	assert.Empty(t, opts.scopes)
+	assert.Equal(t, fmt.Sprintf("%p", defaultErrorHandler), fmt.Sprintf("%p", opts.errorHandler))
+}
+
+func TestOption(t *testing.T) {
+	lookup := func(c context.Context, key string) (*Principal, error) { return nil, nil }
+	errorHandler := func(c context.Context, ctx *app.RequestContext, err error) {}
+	opts := newOptions(
+		WithKeyLookup("query:api_key"),
+		WithAuthScheme("Bearer"),
+		WithLookup(lookup),
+		WithScopes("read", "write"),
+		WithErrorHandler(errorHandler),
+	)
+	assert.Equal(t, "query:api_key", opts.keyLookup)
+	assert.Equal(t, "Bearer", opts.authScheme)
+	assert.Equal(t, fmt.Sprintf("%p", lookup), fmt.Sprintf("%p", opts.lookup))
+	assert.Equal(t, []string{"read", "write"}, opts.scopes)
+	assert.Equal(t, fmt.Sprintf("%p", errorHandler), fmt.Sprintf("%p", opts.errorHandler))
+
+	opts = newOptions(WithKeys(map[string]Principal{"k1": {ID: "app"}}))
+	p, err := opts.lookup(context.Background(), "k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "app", p.ID)
+}
@@ -0,0 +1,106 @@
+package loadshed
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// CPU 使用率的采样间隔。
+	cpuInterval = 250 * time.Millisecond
+
+	// CPU 使用率的滑动平均系数。
+	cpuBeta = 0.95
+)
+
+var (
+	cpuOnce sync.Once
+	cpu     int64 // 滑动平均后的 CPU 使用率（千分比）
+)
+
+// 返回本进程的 CPU 使用率，以千分比表示，1000 即占满可用的 CPU。
+//
+// 首次调用时启动一个后台协程，每 250 毫秒采样一次本进程消耗的 CPU 时间，
+// 并以容器的 CPU 配额（cgroup v1/v2）或逻辑 CPU 数为可用的 CPU 数。
+// 不统计同一容器内其他进程的消耗，不支持读取进程 CPU 时间的平台上恒为零。
+func cpuUsage() int64 {
+	cpuOnce.Do(func() {
+		go sampleCPU()
+	})
+	return atomic.LoadInt64(&cpu)
+}
+
+func sampleCPU() {
+	cores := cpuQuota()
+	prevCPU, ok := processCPUTime()
+	if !ok {
+		return
+	}
+	prevTime := time.Now()
+
+	t := time.NewTicker(cpuInterval)
+	defer t.Stop()
+	for range t.C {
+		curCPU, _ := processCPUTime()
+		curTime := time.Now()
+		elapsed := curTime.Sub(prevTime)
+		if elapsed <= 0 {
+			continue
+		}
+		usage := float64(curCPU-prevCPU) / (float64(elapsed) * cores) * 1000
+		usage = math.Max(0, math.Min(1000, usage))
+		prevCPU, prevTime = curCPU, curTime
+
+		old := atomic.LoadInt64(&cpu)
+		atomic.StoreInt64(&cpu, int64(float64(old)*cpuBeta+usage*(1-cpuBeta)))
+	}
+}
+
+// 返回可用的 CPU 数，容器设置了 CPU 配额时以配额为准。
+func cpuQuota() float64 {
+	cores := float64(runtime.NumCPU())
+	if q, ok := cgroupQuota(); ok && q < cores {
+		cores = q
+	}
+	return cores
+}
+
+// 读取 cgroup 的 CPU 配额，未设置或不在容器中时返回假。
+func cgroupQuota() (float64, bool) {
+	// cgroup v2：cpu.max 的内容形如 "200000 100000" 或 "max 100000"
+	if b, err := os.ReadFile("/sys/fs/cgroup/cpu.max"); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) == 2 && fields[0] != "max" {
+			return parseQuota(fields[0], fields[1])
+		}
+		return 0, false
+	}
+	// cgroup v1：未设置配额时 cfs_quota_us 为 -1
+	quota, err := os.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	if err != nil {
+		return 0, false
+	}
+	period, err := os.ReadFile("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
+	if err != nil {
+		return 0, false
+	}
+	return parseQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func parseQuota(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
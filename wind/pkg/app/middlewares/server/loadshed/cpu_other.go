@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package loadshed
+
+import "time"
+
+// 不支持读取进程 CPU 时间的平台，CPU 使用率恒为零。
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
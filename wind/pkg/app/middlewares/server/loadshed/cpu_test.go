@@ -0,0 +1,31 @@
+package loadshed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessCPUTime(t *testing.T) {
+	before, ok := processCPUTime()
+	if !ok {
+		t.Skip("当前平台不支持读取进程 CPU 时间")
+	}
+	x := 0
+	for i := 0; i < 1e8; i++ {
+		x += i
+	}
+	after, _ := processCPUTime()
+	assert.Greater(t, after, before, x)
+}
+
+func TestParseQuota(t *testing.T) {
+	q, ok := parseQuota("200000", "100000")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, q)
+	_, ok = parseQuota("-1", "100000")
+	assert.False(t, ok)
+	_, ok = parseQuota("max", "100000")
+	assert.False(t, ok)
+	assert.Greater(t, cpuQuota(), 0.0)
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package loadshed
+
+import (
+	"syscall"
+	"time"
+)
+
+// 返回本进程累计消耗的 CPU 时间（用户态与内核态之和）。
+func processCPUTime() (time.Duration, bool) {
+	h, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, false
+	}
+	var creation, exit, kernel, user syscall.Filetime
+	if err = syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return 0, false
+	}
+	// Filetime 的单位为 100 纳秒
+	ticks := func(ft syscall.Filetime) int64 {
+		return int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime)
+	}
+	return time.Duration((ticks(kernel) + ticks(user)) * 100), true
+}
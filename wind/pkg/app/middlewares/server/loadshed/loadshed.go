@@ -0,0 +1,279 @@
+package loadshed
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+)
+
+// 平均并发数的滑动平均系数。
+const flyingBeta = 0.9
+
+// 没有响应时间样本时假定的最小响应时间（毫秒）。
+const defaultMinRT = 1000.0
+
+// Stats 表示过载保护的运行状态。
+type Stats struct {
+	// 处理中的请求数。
+	InFlight int64
+
+	// 累计放行的请求数。
+	Passed uint64
+
+	// 累计因过载（CPU 与吞吐量估算）丢弃的请求数。
+	Shed uint64
+
+	// 累计因超出最大并发数拒绝的请求数。
+	Overflow uint64
+
+	// 当前的 CPU 使用率（千分比）。
+	CPU int64
+
+	// 按窗口内最大吞吐量与最小响应时间估算的最大并发数。
+	MaxFlight int64
+
+	// 窗口内各分桶平均响应时间的最小值。
+	MinRT time.Duration
+
+	// 窗口内响应时间的 99 分位估算值，没有样本时为零。
+	P99 time.Duration
+}
+
+// Shedder 是类 BBR 的自适应过载保护器。
+//
+// 当 CPU 使用率超过阈值、响应时间的 99 分位超过 WithLatencyThreshold 设置的阈值
+// （或仍处于过载后的冷却期）时，以窗口内的最大吞吐量与最小响应时间之积
+// 估算系统能承载的并发数，处理中的请求数及其滑动平均均超出该估算时丢弃新请求。
+//
+// 最小响应时间反映无排队时的处理耗时，用于估算容量；99 分位反映排队造成的尾部延迟，用于判断过载。
+type Shedder struct {
+	opts     *options
+	windows  int64         // 每秒的分桶数
+	interval time.Duration // 每个分桶的时长
+
+	flying       int64
+	avgFlying    float64
+	avgFlyingMu  sync.Mutex
+	overloadTime int64 // 最近一次过载的时间（纳秒）
+	dropped      int32 // 冷却期内是否丢弃过请求
+
+	passCounter *rollingWindow
+	rtCounter   *rollingWindow
+
+	p99     int64 // 缓存的 99 分位响应时间（纳秒）
+	p99Time int64 // 上次计算 99 分位的时间（纳秒）
+
+	passed   uint64
+	shed     uint64
+	overflow uint64
+
+	now func() time.Time
+}
+
+// New 返回一个过载保护中间件，等同于 NewShedder(opts...).Handler()。
+func New(opts ...Option) app.HandlerFunc {
+	return NewShedder(opts...).Handler()
+}
+
+// NewShedder 创建一个过载保护器。
+func NewShedder(opts ...Option) *Shedder {
+	o := newOptions(opts...)
+	interval := o.window / time.Duration(o.buckets)
+	s := &Shedder{
+		opts:     o,
+		windows:  int64(max(1, time.Second/interval)),
+		interval: interval,
+		now:      time.Now,
+	}
+	now := func() time.Time { return s.now() }
+	s.passCounter = newRollingWindow(o.buckets, interval, now)
+	s.rtCounter = newLatencyWindow(o.buckets, interval, now)
+	return s
+}
+
+// Handler 返回执行过载保护的中间件。
+//
+// 应通过 Engine.Use 最先注册，以便在参数绑定和业务处理之前尽早拒绝请求。
+func (s *Shedder) Handler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if s.isExcluded(ctx) {
+			ctx.Next(c)
+			return
+		}
+		if !s.allow() {
+			s.opts.rejectHandler(c, ctx)
+			return
+		}
+
+		start := s.now()
+		defer s.done(start)
+		ctx.Next(c)
+	}
+}
+
+// Stats 返回当前的运行状态。
+func (s *Shedder) Stats() Stats {
+	return Stats{
+		InFlight:  atomic.LoadInt64(&s.flying),
+		Passed:    atomic.LoadUint64(&s.passed),
+		Shed:      atomic.LoadUint64(&s.shed),
+		Overflow:  atomic.LoadUint64(&s.overflow),
+		CPU:       s.opts.cpuUsage(),
+		MaxFlight: s.maxFlight(),
+		MinRT:     time.Duration(s.minRT() * float64(time.Millisecond)),
+		P99:       s.p99Latency(),
+	}
+}
+
+func (s *Shedder) isExcluded(ctx *app.RequestContext) bool {
+	if len(s.opts.excludedPaths) == 0 {
+		return false
+	}
+	if _, ok := s.opts.excludedPaths[ctx.FullPath()]; ok {
+		return true
+	}
+	_, ok := s.opts.excludedPaths[string(ctx.Path())]
+	return ok
+}
+
+// 判断是否放行请求，放行时计入处理中的请求。
+//
+// 先计入再检查，拒绝时撤回，以免并发请求同时通过检查而超出最大并发数。
+func (s *Shedder) allow() bool {
+	flying := atomic.AddInt64(&s.flying, 1)
+	if s.opts.maxInFlight > 0 && flying > s.opts.maxInFlight {
+		atomic.AddInt64(&s.flying, -1)
+		atomic.AddUint64(&s.overflow, 1)
+		return false
+	}
+	if s.shouldDrop() {
+		atomic.AddInt64(&s.flying, -1)
+		atomic.StoreInt32(&s.dropped, 1)
+		atomic.AddUint64(&s.shed, 1)
+		return false
+	}
+	atomic.AddUint64(&s.passed, 1)
+	return true
+}
+
+// 请求处理完成，记录吞吐量和响应时间。
+func (s *Shedder) done(start time.Time) {
+	flying := atomic.AddInt64(&s.flying, -1)
+	// 请求结束时才更新平均并发数，使其略滞后于实际并发数，以平滑突发
+	s.avgFlyingMu.Lock()
+	s.avgFlying = s.avgFlying*flyingBeta + float64(flying)*(1-flyingBeta)
+	s.avgFlyingMu.Unlock()
+
+	s.passCounter.add(1)
+	s.rtCounter.add(float64(s.now().Sub(start)) / float64(time.Millisecond))
+}
+
+func (s *Shedder) shouldDrop() bool {
+	if !s.overloaded() && !s.stillHot() {
+		return false
+	}
+	s.avgFlyingMu.Lock()
+	avgFlying := s.avgFlying
+	s.avgFlyingMu.Unlock()
+	maxFlight := s.maxFlight()
+	return int64(avgFlying) > maxFlight && atomic.LoadInt64(&s.flying) > maxFlight
+}
+
+func (s *Shedder) overloaded() bool {
+	if s.opts.cpuUsage() < s.opts.cpuThreshold &&
+		(s.opts.latencyThreshold <= 0 || s.p99Latency() < s.opts.latencyThreshold) {
+		return false
+	}
+	atomic.StoreInt64(&s.overloadTime, s.now().UnixNano())
+	return true
+}
+
+// 过载后的冷却期内丢弃过请求，视为仍然过热。
+func (s *Shedder) stillHot() bool {
+	if atomic.LoadInt32(&s.dropped) == 0 {
+		return false
+	}
+	overloadTime := atomic.LoadInt64(&s.overloadTime)
+	if overloadTime == 0 {
+		return false
+	}
+	if time.Duration(s.now().UnixNano()-overloadTime) < s.opts.coolOff {
+		return true
+	}
+	atomic.StoreInt32(&s.dropped, 0)
+	return false
+}
+
+// 估算的最大并发数：每秒最大吞吐量 × 最小响应时间。
+func (s *Shedder) maxFlight() int64 {
+	return int64(math.Max(1, float64(s.maxPass()*s.windows)*(s.minRT()/1e3)))
+}
+
+// 窗口内单个分桶的最大完成数。
+func (s *Shedder) maxPass() int64 {
+	var result float64 = 1
+	s.passCounter.reduce(func(b bucket) {
+		if b.sum > result {
+			result = b.sum
+		}
+	})
+	return int64(result)
+}
+
+// 窗口内各分桶平均响应时间的最小值（毫秒）。
+func (s *Shedder) minRT() float64 {
+	result := defaultMinRT
+	s.rtCounter.reduce(func(b bucket) {
+		if b.count <= 0 {
+			return
+		}
+		if avg := math.Round(b.sum / float64(b.count)); avg < result {
+			result = avg
+		}
+	})
+	return result
+}
+
+// 窗口内响应时间的 99 分位估算值。
+//
+// 合并各分桶的直方图开销较大，每个分桶时长内至多计算一次，期间返回缓存值。
+func (s *Shedder) p99Latency() time.Duration {
+	now := s.now().UnixNano()
+	if last := atomic.LoadInt64(&s.p99Time); last != 0 && time.Duration(now-last) < s.interval {
+		return time.Duration(atomic.LoadInt64(&s.p99))
+	}
+	p99 := time.Duration(s.percentile(0.99) * float64(time.Millisecond))
+	atomic.StoreInt64(&s.p99, int64(p99))
+	atomic.StoreInt64(&s.p99Time, now)
+	return p99
+}
+
+// 窗口内响应时间的 p 分位估算值（毫秒），取样本所在直方图档位的上界，没有样本时为零。
+func (s *Shedder) percentile(p float64) float64 {
+	var hist [histSize]uint64
+	var total uint64
+	s.rtCounter.reduce(func(b bucket) {
+		if b.count <= 0 {
+			return
+		}
+		for i, n := range b.hist {
+			hist[i] += uint64(n)
+		}
+		total += uint64(b.count)
+	})
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p * float64(total)))
+	var seen uint64
+	for i, n := range hist {
+		if seen += n; seen >= rank {
+			return histUpper(i)
+		}
+	}
+	return histUpper(histSize - 1)
+}
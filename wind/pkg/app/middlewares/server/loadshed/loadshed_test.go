@@ -0,0 +1,186 @@
+package loadshed
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// 返回 CPU 使用率可调、时间可控的过载保护器。
+func newTestShedder(opts ...Option) (s *Shedder, cpu *int64, now *time.Time) {
+	cpu, now = new(int64), new(time.Time)
+	*now = time.Unix(1700000000, 0)
+	opts = append(opts, func(o *options) {
+		o.cpuUsage = func() int64 { return atomic.LoadInt64(cpu) }
+	})
+	s = NewShedder(opts...)
+	s.now = func() time.Time { return *now }
+	return
+}
+
+func TestShedderAllow(t *testing.T) {
+	s, cpu, now := newTestShedder()
+
+	// CPU 未过载时不丢弃
+	for i := 0; i < 20; i++ {
+		assert.True(t, s.allow())
+	}
+	s.avgFlying = 20
+
+	// 过载且并发超出估算（无样本时为 1*10*1s=10）时丢弃
+	*cpu = 950
+	assert.Equal(t, int64(10), s.maxFlight())
+	assert.False(t, s.allow())
+	assert.Equal(t, uint64(1), s.Stats().Shed)
+
+	// CPU 回落但仍在冷却期内
+	*cpu = 100
+	*now = now.Add(500 * time.Millisecond)
+	assert.False(t, s.allow())
+
+	// 冷却期过后放行
+	*now = now.Add(time.Second)
+	assert.True(t, s.allow())
+
+	// 过载但并发未超出估算时放行
+	*cpu = 950
+	s.flying = 5
+	assert.True(t, s.allow())
+
+	st := s.Stats()
+	assert.Equal(t, uint64(22), st.Passed)
+	assert.Equal(t, uint64(2), st.Shed)
+	assert.Equal(t, int64(6), st.InFlight)
+	assert.Equal(t, int64(950), st.CPU)
+	assert.Equal(t, time.Second, st.MinRT)
+}
+
+func TestShedderDone(t *testing.T) {
+	s, _, now := newTestShedder(WithWindow(time.Second, 10))
+	assert.True(t, s.allow())
+	start := *now
+	*now = now.Add(20 * time.Millisecond)
+	s.done(start)
+	assert.Equal(t, int64(0), s.Stats().InFlight)
+	assert.InDelta(t, 0.0, s.avgFlying, 1e-9)
+}
+
+func TestShedderLatency(t *testing.T) {
+	feed := func(s *Shedder, now *time.Time) {
+		// 98 个 10 毫秒与 2 个 500 毫秒的样本
+		for i := 0; i < 100; i++ {
+			rt := 10.0
+			if i < 2 {
+				rt = 500
+			}
+			s.passCounter.add(1)
+			s.rtCounter.add(rt)
+		}
+		*now = now.Add(100 * time.Millisecond)
+	}
+
+	s, _, now := newTestShedder(WithWindow(time.Second, 10), WithLatencyThreshold(200*time.Millisecond))
+	assert.Equal(t, time.Duration(0), s.Stats().P99)
+	*now = now.Add(100 * time.Millisecond)
+	feed(s, now)
+
+	p99 := s.Stats().P99
+	assert.GreaterOrEqual(t, p99, 500*time.Millisecond)
+	assert.Less(t, p99, 625*time.Millisecond)
+
+	// 分桶时长内返回缓存值
+	s.rtCounter.add(1000)
+	assert.Equal(t, p99, s.Stats().P99)
+
+	// CPU 未过载，但 99 分位超过阈值且并发超出估算（100*10*20ms=20）时丢弃
+	assert.Equal(t, int64(20), s.maxFlight())
+	s.avgFlying, s.flying = 100, 100
+	assert.False(t, s.allow())
+	assert.Equal(t, uint64(1), s.Stats().Shed)
+
+	// 未设置阈值时只看 CPU
+	s, _, now = newTestShedder(WithWindow(time.Second, 10))
+	*now = now.Add(100 * time.Millisecond)
+	feed(s, now)
+	s.avgFlying, s.flying = 100, 100
+	assert.True(t, s.allow())
+}
+
+func TestShedderMaxInFlight(t *testing.T) {
+	s, _, _ := newTestShedder(WithMaxInFlight(2))
+	assert.True(t, s.allow())
+	assert.True(t, s.allow())
+	assert.False(t, s.allow())
+	assert.Equal(t, uint64(1), s.Stats().Overflow)
+}
+
+func TestShedderMaxInFlightConcurrent(t *testing.T) {
+	s, _, _ := newTestShedder(WithMaxInFlight(10))
+	var wg sync.WaitGroup
+	var passed int64
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.allow() {
+				atomic.AddInt64(&passed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(10), passed)
+	assert.Equal(t, int64(10), s.Stats().InFlight)
+	assert.Equal(t, uint64(90), s.Stats().Overflow)
+}
+
+func TestShedderHandler(t *testing.T) {
+	s, _, _ := newTestShedder(WithMaxInFlight(1), WithExcludedPaths("/health", "/user/:name"))
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(s.Handler())
+
+	var inFlight int64
+	ok := func(c context.Context, ctx *app.RequestContext) {
+		inFlight = s.Stats().InFlight
+		ctx.String(consts.StatusOK, "ok")
+	}
+	engine.GET("/", ok)
+	engine.GET("/health", ok)
+	engine.GET("/user/:name", ok)
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, int64(1), inFlight)
+	assert.Equal(t, int64(0), s.Stats().InFlight)
+
+	// 占满并发后拒绝
+	s.flying = 1
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, "1", string(resp.Header.Peek(consts.HeaderRetryAfter)))
+
+	// 排除的路由和路径不受限制
+	assert.Equal(t, consts.StatusOK, ut.PerformRequest(engine, consts.MethodGet, "/health", nil).Result().StatusCode())
+	assert.Equal(t, consts.StatusOK, ut.PerformRequest(engine, consts.MethodGet, "/user/tom", nil).Result().StatusCode())
+	assert.Equal(t, uint64(1), s.Stats().Overflow)
+}
+
+func TestShedderHandlerPanic(t *testing.T) {
+	s, _, _ := newTestShedder()
+	h := s.Handler()
+	ctx := app.NewContext(0)
+	ctx.SetHandlers(app.HandlersChain{h, func(c context.Context, ctx *app.RequestContext) {
+		panic("boom")
+	}})
+
+	assert.Panics(t, func() { ctx.Next(context.Background()) })
+	assert.Equal(t, int64(0), s.Stats().InFlight)
+}
@@ -0,0 +1,142 @@
+package loadshed
+
+import (
+	"context"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// 默认的 CPU 使用率阈值，以千分比表示，900 即 90%。
+	defaultCPUThreshold = 900
+
+	// 默认的统计窗口及其分桶数。
+	defaultWindow  = 5 * time.Second
+	defaultBuckets = 50
+
+	// 默认的过载冷却时间。
+	defaultCoolOff = time.Second
+)
+
+// 表示一个过载保护的自定义选项结构体。
+type options struct {
+	// CPU 使用率阈值（千分比），超过时开始按吞吐量估算丢弃请求。
+	cpuThreshold int64
+
+	// 响应时间 99 分位的阈值，超过时同样视为过载，为零时不启用。
+	latencyThreshold time.Duration
+
+	// 统计最大吞吐量、最小响应时间和响应时间分位数的窗口及分桶数。
+	window  time.Duration
+	buckets int
+
+	// 过载后持续丢弃的冷却时间。
+	coolOff time.Duration
+
+	// 同时处理的最大请求数，为零时不限。
+	maxInFlight int64
+
+	// 不受过载保护的路由或路径。
+	excludedPaths map[string]struct{}
+
+	// 拒绝请求时的处理器。
+	rejectHandler func(c context.Context, ctx *app.RequestContext)
+
+	// CPU 使用率（千分比），便于测试。
+	cpuUsage func() int64
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认的拒绝处理器，以 503 终止请求并建议一秒后重试。
+func defaultRejectHandler(c context.Context, ctx *app.RequestContext) {
+	ctx.AbortWithMsg(consts.StatusMessage(consts.StatusServiceUnavailable), consts.StatusServiceUnavailable)
+	ctx.Response.Header.Set(consts.HeaderRetryAfter, "1")
+}
+
+// 创建一个自定义过载保护的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		cpuThreshold:  defaultCPUThreshold,
+		window:        defaultWindow,
+		buckets:       defaultBuckets,
+		coolOff:       defaultCoolOff,
+		excludedPaths: make(map[string]struct{}),
+		rejectHandler: defaultRejectHandler,
+		cpuUsage:      cpuUsage,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.buckets <= 0 || cfg.window < time.Duration(cfg.buckets) {
+		panic("loadshed：统计窗口或分桶数无效")
+	}
+	return cfg
+}
+
+// WithCPUThreshold 设置 CPU 使用率阈值，以千分比表示，默认 900 即 90%。
+//
+// CPU 使用率为本进程的使用率，由首次使用时启动的后台协程每 250 毫秒采样一次，
+// 以容器的 CPU 配额或逻辑 CPU 数为满载。
+func WithCPUThreshold(threshold int64) Option {
+	return func(o *options) {
+		o.cpuThreshold = threshold
+	}
+}
+
+// WithLatencyThreshold 设置响应时间 99 分位的阈值，默认不启用。
+//
+// 窗口内响应时间的 99 分位超过阈值时，即使 CPU 使用率未超过阈值也视为过载，
+// 适用于瓶颈不在 CPU（如下游或锁竞争）的服务。
+func WithLatencyThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.latencyThreshold = d
+	}
+}
+
+// WithWindow 设置统计最大吞吐量、最小响应时间和响应时间分位数的窗口及分桶数，默认 5 秒 50 个分桶。
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(o *options) {
+		o.window = window
+		o.buckets = buckets
+	}
+}
+
+// WithCoolOff 设置过载后的冷却时间，默认 1 秒。
+//
+// CPU 使用率回落后，若冷却时间内曾丢弃请求，仍按吞吐量估算继续丢弃，以免负载反复抖动。
+func WithCoolOff(d time.Duration) Option {
+	return func(o *options) {
+		o.coolOff = d
+	}
+}
+
+// WithMaxInFlight 设置同时处理的最大请求数，超出时直接拒绝，默认不限。
+func WithMaxInFlight(n int64) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// WithExcludedPaths 设置不受过载保护的路由或路径，如健康检查。
+//
+// 既可匹配注册的路由（如 /user/:name），也可匹配请求路径。
+func WithExcludedPaths(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			o.excludedPaths[p] = struct{}{}
+		}
+	}
+}
+
+// WithRejectHandler 自定义拒绝请求时的处理器，默认以 503 终止请求。
+func WithRejectHandler(f func(c context.Context, ctx *app.RequestContext)) Option {
+	return func(o *options) {
+		o.rejectHandler = f
+	}
+}
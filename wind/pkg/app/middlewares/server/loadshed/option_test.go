@@ -0,0 +1,49 @@
+package loadshed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, int64(defaultCPUThreshold), opts.cpuThreshold)
+	assert.Equal(t, defaultWindow, opts.window)
+	assert.Equal(t, defaultBuckets, opts.buckets)
+	assert.Equal(t, defaultCoolOff, opts.coolOff)
+	assert.Equal(t, int64(0), opts.maxInFlight)
+	assert.Equal(t, time.Duration(0), opts.latencyThreshold)
+	assert.Empty(t, opts.excludedPaths)
+	assert.Equal(t, fmt.Sprintf("%p", defaultRejectHandler), fmt.Sprintf("%p", opts.rejectHandler))
+	assert.Equal(t, fmt.Sprintf("%p", cpuUsage), fmt.Sprintf("%p", opts.cpuUsage))
+}
+
+func TestOption(t *testing.T) {
+	handler := func(c context.Context, ctx *app.RequestContext) {}
+	opts := newOptions(
+		WithCPUThreshold(800),
+		WithWindow(time.Second, 10),
+		WithCoolOff(2*time.Second),
+		WithMaxInFlight(100),
+		WithLatencyThreshold(300*time.Millisecond),
+		WithExcludedPaths("/health", "/ready"),
+		WithRejectHandler(handler),
+	)
+	assert.Equal(t, int64(800), opts.cpuThreshold)
+	assert.Equal(t, time.Second, opts.window)
+	assert.Equal(t, 10, opts.buckets)
+	assert.Equal(t, 2*time.Second, opts.coolOff)
+	assert.Equal(t, int64(100), opts.maxInFlight)
+	assert.Equal(t, 300*time.Millisecond, opts.latencyThreshold)
+	assert.Equal(t, map[string]struct{}{"/health": {}, "/ready": {}}, opts.excludedPaths)
+	assert.Equal(t, fmt.Sprintf("%p", handler), fmt.Sprintf("%p", opts.rejectHandler))
+}
+
+func TestOptionPanic(t *testing.T) {
+	assert.Panics(t, func() { newOptions(WithWindow(time.Second, 0)) })
+}
@@ -0,0 +1,111 @@
+package loadshed
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 响应时间直方图：上界自 0.1 毫秒起按 1.25 倍递增，末档约 8 分钟，估算误差不超过 25%。
+const (
+	histBase   = 0.1
+	histFactor = 1.25
+	histSize   = 64
+)
+
+var logHistFactor = math.Log(histFactor)
+
+// 分桶内的累计值及样本数，响应时间窗口另记样本的直方图。
+type bucket struct {
+	sum   float64
+	count int64
+	hist  []uint32
+}
+
+func (b *bucket) reset() {
+	b.sum, b.count = 0, 0
+	clear(b.hist)
+}
+
+// 样本 v（毫秒）所在直方图档位的下标。
+func histIndex(v float64) int {
+	if v <= histBase {
+		return 0
+	}
+	return min(histSize-1, int(math.Ceil(math.Log(v/histBase)/logHistFactor)))
+}
+
+// 直方图第 i 档的上界（毫秒）。
+func histUpper(i int) float64 {
+	return histBase * math.Pow(histFactor, float64(i))
+}
+
+// 滑动窗口，将窗口按时间均分为若干分桶，统计时忽略尚未结束的当前分桶。
+type rollingWindow struct {
+	mu       sync.Mutex
+	buckets  []bucket
+	interval time.Duration
+	offset   int       // 当前分桶的下标
+	start    time.Time // 当前分桶的起始时间
+	now      func() time.Time
+}
+
+func newRollingWindow(size int, interval time.Duration, now func() time.Time) *rollingWindow {
+	return &rollingWindow{
+		buckets:  make([]bucket, size),
+		interval: interval,
+		now:      now,
+	}
+}
+
+// 创建记录样本直方图的滑动窗口，用于估算响应时间的分位数。
+func newLatencyWindow(size int, interval time.Duration, now func() time.Time) *rollingWindow {
+	w := newRollingWindow(size, interval, now)
+	for i := range w.buckets {
+		w.buckets[i].hist = make([]uint32, histSize)
+	}
+	return w
+}
+
+// 添加一个样本。
+func (w *rollingWindow) add(v float64) {
+	w.mu.Lock()
+	w.advance()
+	b := &w.buckets[w.offset]
+	b.sum += v
+	b.count++
+	if b.hist != nil {
+		b.hist[histIndex(v)]++
+	}
+	w.mu.Unlock()
+}
+
+// 依次遍历除当前分桶外的各分桶。
+func (w *rollingWindow) reduce(f func(b bucket)) {
+	w.mu.Lock()
+	w.advance()
+	n := len(w.buckets)
+	for i := 1; i < n; i++ {
+		f(w.buckets[(w.offset+i)%n])
+	}
+	w.mu.Unlock()
+}
+
+// 前进到当前时间所在的分桶，并清空期间经过的分桶。调用方需持有锁。
+func (w *rollingWindow) advance() {
+	now := w.now()
+	if w.start.IsZero() {
+		w.start = now
+		return
+	}
+	span := int(now.Sub(w.start) / w.interval)
+	if span <= 0 {
+		return
+	}
+	n := len(w.buckets)
+	for i := 1; i <= span && i <= n; i++ {
+		w.buckets[(w.offset+i)%n].reset()
+	}
+	w.offset = (w.offset + span) % n
+	w.start = w.start.Add(time.Duration(span) * w.interval)
+}
@@ -0,0 +1,71 @@
+package loadshed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	w := newRollingWindow(3, 100*time.Millisecond, func() time.Time { return now })
+	sums := func() []float64 {
+		var r []float64
+		w.reduce(func(b bucket) { r = append(r, b.sum) })
+		return r
+	}
+
+	// 当前分桶不参与统计
+	w.add(1)
+	w.add(2)
+	assert.Equal(t, []float64{0, 0}, sums())
+
+	now = now.Add(100 * time.Millisecond)
+	w.add(4)
+	assert.Equal(t, []float64{0, 3}, sums())
+
+	now = now.Add(100 * time.Millisecond)
+	assert.Equal(t, []float64{3, 4}, sums())
+
+	// 经过的分桶被清空
+	now = now.Add(200 * time.Millisecond)
+	assert.Equal(t, []float64{0, 0}, sums())
+
+	w.add(5)
+	now = now.Add(time.Second)
+	assert.Equal(t, []float64{0, 0}, sums())
+}
+
+func TestHistIndex(t *testing.T) {
+	assert.Equal(t, 0, histIndex(0))
+	assert.Equal(t, 0, histIndex(histBase))
+	assert.Equal(t, 1, histIndex(0.125))
+	assert.Equal(t, histSize-1, histIndex(1e9))
+
+	// 档位上界不小于样本，且误差不超过一档
+	for _, v := range []float64{0.3, 1, 7.5, 20, 130, 999, 30000} {
+		upper := histUpper(histIndex(v))
+		assert.GreaterOrEqual(t, upper*(1+1e-9), v)
+		assert.Less(t, upper, v*histFactor*(1+1e-9))
+	}
+}
+
+func TestLatencyWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	w := newLatencyWindow(2, 100*time.Millisecond, func() time.Time { return now })
+	w.add(1)
+	w.add(500)
+	now = now.Add(100 * time.Millisecond)
+
+	var hist []uint32
+	w.reduce(func(b bucket) { hist = append(hist, b.hist...) })
+	assert.Equal(t, uint32(1), hist[histIndex(1)])
+	assert.Equal(t, uint32(1), hist[histIndex(500)])
+
+	// 经过的分桶连同直方图一并清空
+	now = now.Add(200 * time.Millisecond)
+	hist = hist[:0]
+	w.reduce(func(b bucket) { hist = append(hist, b.hist...) })
+	assert.Equal(t, make([]uint32, histSize), hist)
+}
@@ -0,0 +1,64 @@
+package pprof
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/server"
+	"github.com/favbox/gosky/wind/pkg/common/adaptor"
+	"github.com/favbox/gosky/wind/pkg/route"
+)
+
+// DefaultPrefix 是调试路由的默认前缀。
+const DefaultPrefix = "/debug"
+
+// Register 在引擎上注册调试路由，前缀默认为 DefaultPrefix，参见 RouteRegister。
+func Register(engine *route.Engine, prefix ...string) {
+	RouteRegister(&engine.RouterGroup, prefix...)
+}
+
+// RouteRegister 在路由组上注册以下调试路由，前缀默认为 DefaultPrefix：
+//
+//   - {prefix}/pprof/ 为 net/http/pprof 的全部性能分析，如 heap、goroutine、profile?seconds=30、trace 等；
+//   - {prefix}/vars 为 expvar 发布的变量；
+//   - {prefix}/runtime 为 JSON 格式的运行时统计，参见 RuntimeStats。
+//
+// 调试路由会暴露进程的内部信息，应仅在内网开放，或通过带有认证中间件的路由组注册，如：
+//
+//	pprof.RouteRegister(h.Group("/admin", basicauth.New(...)))
+//
+// 互斥锁和阻塞分析需先通过 runtime.SetMutexProfileFraction 和 runtime.SetBlockProfileRate 开启。
+// CPU 分析和执行追踪的时长不应超过服务器的写超时。
+func RouteRegister(rg *route.RouterGroup, prefix ...string) {
+	p := DefaultPrefix
+	if len(prefix) > 0 {
+		p = prefix[0]
+	}
+	r := rg.Group(p)
+
+	pr := r.Group("/pprof")
+	pr.GET("/", adaptor.WindHandlerFunc(pprof.Index))
+	pr.GET("/cmdline", adaptor.WindHandlerFunc(pprof.Cmdline))
+	pr.GET("/profile", adaptor.WindHandlerFunc(pprof.Profile))
+	pr.GET("/symbol", adaptor.WindHandlerFunc(pprof.Symbol))
+	pr.POST("/symbol", adaptor.WindHandlerFunc(pprof.Symbol))
+	pr.GET("/trace", adaptor.WindHandlerFunc(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		pr.GET("/"+name, adaptor.WindHandler(pprof.Handler(name)))
+	}
+
+	r.GET("/vars", adaptor.WindHandler(expvar.Handler()))
+	r.GET("/runtime", Runtime)
+}
+
+// NewServer 创建一个仅提供调试路由的服务器，用于在独立的管理端口上监听，如：
+//
+//	go pprof.NewServer(":6060", basicauth.New(...)).Spin()
+//
+// handlers 在调试路由前执行，可用于认证。
+func NewServer(addr string, handlers ...app.HandlerFunc) *server.Wind {
+	w := server.New(server.WithHostPorts(addr), server.WithDisablePrintRoute(true))
+	RouteRegister(w.Group("/", handlers...))
+	return w
+}
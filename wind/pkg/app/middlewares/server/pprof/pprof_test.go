@@ -0,0 +1,110 @@
+package pprof
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	Register(engine)
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/debug/pprof/", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Contains(t, string(resp.Header.ContentType()), "text/html")
+	assert.Contains(t, string(resp.Body()), "goroutine?debug=1")
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/debug/pprof/goroutine?debug=1", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Contains(t, string(resp.Header.ContentType()), "text/plain")
+	assert.Contains(t, string(resp.Body()), "TestRegister")
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/debug/pprof/heap", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "application/octet-stream", string(resp.Header.ContentType()))
+	assert.Equal(t, `attachment; filename="heap"`, string(resp.Header.Peek("Content-Disposition")))
+	assert.NotEmpty(t, resp.Body())
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/debug/pprof/cmdline", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.NotEmpty(t, resp.Body())
+
+	resp = ut.PerformRequest(engine, consts.MethodPost, "/debug/pprof/symbol", &ut.Body{Body: strings.NewReader("0x0"), Len: 3}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Contains(t, string(resp.Body()), "num_symbols")
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/debug/pprof/profile?seconds=1", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, `attachment; filename="profile"`, string(resp.Header.Peek("Content-Disposition")))
+	assert.NotEmpty(t, resp.Body())
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/debug/vars", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	var vars map[string]any
+	assert.Nil(t, json.Unmarshal(resp.Body(), &vars))
+	assert.Contains(t, vars, "memstats")
+	assert.Contains(t, vars, "cmdline")
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/debug/runtime", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	var stats RuntimeStats
+	assert.Nil(t, json.Unmarshal(resp.Body(), &stats))
+	assert.NotEmpty(t, stats.GoVersion)
+	assert.Positive(t, stats.NumGoroutine)
+	assert.Positive(t, stats.Memory.HeapAlloc)
+}
+
+func TestRouteRegister(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	RouteRegister(engine.Group("/admin", func(c context.Context, ctx *app.RequestContext) {
+		if string(ctx.GetHeader("X-Token")) != "secret" {
+			ctx.AbortWithStatus(consts.StatusUnauthorized)
+			return
+		}
+		ctx.Next(c)
+	}), "/ops")
+
+	for _, path := range []string{"/admin/ops/pprof/", "/admin/ops/pprof/heap", "/admin/ops/vars", "/admin/ops/runtime"} {
+		resp := ut.PerformRequest(engine, consts.MethodGet, path, nil).Result()
+		assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode(), path)
+		resp = ut.PerformRequest(engine, consts.MethodGet, path, nil, ut.Header{Key: "X-Token", Value: "secret"}).Result()
+		assert.Equal(t, consts.StatusOK, resp.StatusCode(), path)
+	}
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/debug/pprof/", nil).Result()
+	assert.Equal(t, consts.StatusNotFound, resp.StatusCode())
+}
+
+func TestNewServer(t *testing.T) {
+	called := false
+	w := NewServer("127.0.0.1:0", func(c context.Context, ctx *app.RequestContext) {
+		called = true
+	})
+	assert.Equal(t, "127.0.0.1:0", w.GetOptions().Addr)
+
+	var paths []string
+	for _, r := range w.Routes() {
+		paths = append(paths, r.Method+" "+r.Path)
+	}
+	assert.Contains(t, paths, "GET /debug/pprof/")
+	assert.Contains(t, paths, "GET /debug/pprof/profile")
+	assert.Contains(t, paths, "GET /debug/pprof/trace")
+	assert.Contains(t, paths, "POST /debug/pprof/symbol")
+	assert.Contains(t, paths, "GET /debug/pprof/mutex")
+	assert.Contains(t, paths, "GET /debug/pprof/block")
+	assert.Contains(t, paths, "GET /debug/vars")
+	assert.Contains(t, paths, "GET /debug/runtime")
+
+	resp := ut.PerformRequest(w.Engine, consts.MethodGet, "/debug/runtime", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.True(t, called)
+}
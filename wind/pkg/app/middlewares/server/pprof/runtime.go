@@ -0,0 +1,93 @@
+package pprof
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 进程启动时间的近似值。
+var startTime = time.Now()
+
+// RuntimeStats 表示运行时统计。
+type RuntimeStats struct {
+	GoVersion    string      `json:"go_version"`
+	GOOS         string      `json:"goos"`
+	GOARCH       string      `json:"goarch"`
+	NumCPU       int         `json:"num_cpu"`
+	GOMAXPROCS   int         `json:"gomaxprocs"`
+	NumGoroutine int         `json:"num_goroutine"`
+	NumCgoCall   int64       `json:"num_cgo_call"`
+	StartTime    time.Time   `json:"start_time"`
+	Uptime       float64     `json:"uptime_seconds"`
+	Memory       MemoryStats `json:"memory"`
+}
+
+// MemoryStats 表示内存和垃圾回收统计，字段含义参见 runtime.MemStats。
+type MemoryStats struct {
+	Alloc        uint64    `json:"alloc"`
+	TotalAlloc   uint64    `json:"total_alloc"`
+	Sys          uint64    `json:"sys"`
+	Mallocs      uint64    `json:"mallocs"`
+	Frees        uint64    `json:"frees"`
+	HeapAlloc    uint64    `json:"heap_alloc"`
+	HeapSys      uint64    `json:"heap_sys"`
+	HeapIdle     uint64    `json:"heap_idle"`
+	HeapInuse    uint64    `json:"heap_inuse"`
+	HeapReleased uint64    `json:"heap_released"`
+	HeapObjects  uint64    `json:"heap_objects"`
+	StackInuse   uint64    `json:"stack_inuse"`
+	NextGC       uint64    `json:"next_gc"`
+	LastGC       time.Time `json:"last_gc"`
+	PauseTotalNs uint64    `json:"pause_total_ns"`
+	NumGC        uint32    `json:"num_gc"`
+}
+
+// ReadRuntimeStats 读取当前的运行时统计。
+//
+// 读取内存统计会短暂暂停所有协程，不宜频繁调用。
+func ReadRuntimeStats() *RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	s := &RuntimeStats{
+		GoVersion:    runtime.Version(),
+		GOOS:         runtime.GOOS,
+		GOARCH:       runtime.GOARCH,
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumGoroutine: runtime.NumGoroutine(),
+		NumCgoCall:   runtime.NumCgoCall(),
+		StartTime:    startTime,
+		Uptime:       time.Since(startTime).Seconds(),
+		Memory: MemoryStats{
+			Alloc:        m.Alloc,
+			TotalAlloc:   m.TotalAlloc,
+			Sys:          m.Sys,
+			Mallocs:      m.Mallocs,
+			Frees:        m.Frees,
+			HeapAlloc:    m.HeapAlloc,
+			HeapSys:      m.HeapSys,
+			HeapIdle:     m.HeapIdle,
+			HeapInuse:    m.HeapInuse,
+			HeapReleased: m.HeapReleased,
+			HeapObjects:  m.HeapObjects,
+			StackInuse:   m.StackInuse,
+			NextGC:       m.NextGC,
+			PauseTotalNs: m.PauseTotalNs,
+			NumGC:        m.NumGC,
+		},
+	}
+	if m.LastGC > 0 {
+		s.Memory.LastGC = time.Unix(0, int64(m.LastGC))
+	}
+	return s
+}
+
+// Runtime 以 JSON 格式响应运行时统计。
+func Runtime(_ context.Context, ctx *app.RequestContext) {
+	ctx.JSON(consts.StatusOK, ReadRuntimeStats())
+}
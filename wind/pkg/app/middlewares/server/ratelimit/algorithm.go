@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Algorithm 表示限流算法。
+type Algorithm int
+
+const (
+	// TokenBucket 令牌桶算法，以恒定速率补充令牌，允许不超过桶容量的突发请求。
+	TokenBucket Algorithm = iota
+
+	// SlidingWindow 滑动窗口算法，以上一窗口计数的加权值与当前窗口计数之和估算周期内的请求数。
+	SlidingWindow
+)
+
+// Result 表示一次限流判定的结果。
+type Result struct {
+	// 是否放行。
+	Allowed bool
+
+	// 周期内的配额，令牌桶算法为桶容量。
+	Limit int
+
+	// 剩余配额。
+	Remaining int
+
+	// 距配额完全恢复（令牌桶）或当前窗口结束（滑动窗口）的时间。
+	Reset time.Duration
+
+	// 被拒绝时距下次可能放行的时间。
+	RetryAfter time.Duration
+}
+
+// 限流配额：每 period 允许 limit 个请求，令牌桶最多积攒 burst 个令牌。
+type quota struct {
+	limit  int
+	period time.Duration
+	burst  int
+}
+
+// 在旧状态（不存在时为 nil）上消耗一个配额，返回新状态及其有效期。
+func (a Algorithm) take(q *quota, old []byte, now time.Time) (state []byte, ttl time.Duration, r Result) {
+	if a == SlidingWindow {
+		return slidingWindow(q, old, now)
+	}
+	return tokenBucket(q, old, now)
+}
+
+// 令牌桶的状态为剩余令牌数与上次更新时间，共 16 字节。
+func tokenBucket(q *quota, old []byte, now time.Time) ([]byte, time.Duration, Result) {
+	capacity := float64(q.burst)
+	interval := float64(q.period) / float64(q.limit) // 补充一个令牌的纳秒数
+	tokens, last := capacity, now.UnixNano()
+	if len(old) == 16 {
+		tokens = math.Float64frombits(binary.BigEndian.Uint64(old))
+		prev := int64(binary.BigEndian.Uint64(old[8:]))
+		// 多实例间的时钟偏差可能使上次更新时间晚于当前时间
+		if elapsed := last - prev; elapsed > 0 {
+			tokens = math.Min(capacity, tokens+float64(elapsed)/interval)
+		} else {
+			last = prev
+		}
+	}
+
+	r := Result{Limit: q.burst}
+	if tokens >= 1 {
+		tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = time.Duration(math.Ceil((1 - tokens) * interval))
+	}
+	r.Remaining = int(tokens)
+	r.Reset = time.Duration(math.Ceil((capacity - tokens) * interval))
+
+	state := make([]byte, 16)
+	binary.BigEndian.PutUint64(state, math.Float64bits(tokens))
+	binary.BigEndian.PutUint64(state[8:], uint64(last))
+	// 令牌桶装满后状态与不存在等价
+	return state, max(r.Reset, time.Millisecond), r
+}
+
+// 滑动窗口的状态为当前窗口的起始时间、上一窗口计数和当前窗口计数，共 24 字节。
+func slidingWindow(q *quota, old []byte, now time.Time) ([]byte, time.Duration, Result) {
+	period := int64(q.period)
+	ts := now.UnixNano()
+	start := ts - ts%period
+
+	var prev, cur int64
+	if len(old) == 24 {
+		switch s := int64(binary.BigEndian.Uint64(old)); s {
+		case start:
+			prev = int64(binary.BigEndian.Uint64(old[8:]))
+			cur = int64(binary.BigEndian.Uint64(old[16:]))
+		case start - period:
+			prev = int64(binary.BigEndian.Uint64(old[16:]))
+		}
+	}
+
+	limit := float64(q.limit)
+	elapsed := float64(ts-start) / float64(period)
+	count := float64(prev)*(1-elapsed) + float64(cur)
+
+	r := Result{Limit: q.limit, Reset: time.Duration(start + period - ts)}
+	if count+1 <= limit {
+		cur++
+		count++
+		r.Allowed = true
+	} else if float64(cur)+1 <= limit {
+		// 等待上一窗口的权重衰减到足以容纳一个请求
+		x := 1 - (limit-1-float64(cur))/float64(prev)
+		r.RetryAfter = time.Duration(math.Ceil((x - elapsed) * float64(period)))
+	} else {
+		// 等到下一窗口，当前窗口的计数成为上一窗口
+		x := 1 - (limit-1)/float64(cur)
+		r.RetryAfter = r.Reset + time.Duration(math.Ceil(x*float64(period)))
+	}
+	r.Remaining = max(0, q.limit-int(math.Ceil(count)))
+
+	state := make([]byte, 24)
+	binary.BigEndian.PutUint64(state, uint64(start))
+	binary.BigEndian.PutUint64(state[8:], uint64(prev))
+	binary.BigEndian.PutUint64(state[16:], uint64(cur))
+	// 两个窗口均已过去后状态不再有意义
+	return state, time.Duration(start + 2*period - ts), r
+}
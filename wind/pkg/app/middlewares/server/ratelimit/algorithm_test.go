@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	q := &quota{limit: 10, period: time.Second, burst: 3}
+	now := time.Unix(1700000000, 0)
+
+	var state []byte
+	var r Result
+	for i := 0; i < 3; i++ {
+		state, _, r = TokenBucket.take(q, state, now)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Limit)
+		assert.Equal(t, 2-i, r.Remaining)
+	}
+	assert.Equal(t, 300*time.Millisecond, r.Reset)
+
+	// 令牌耗尽，100ms 后补充一个
+	_, _, r = TokenBucket.take(q, state, now)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 100*time.Millisecond, r.RetryAfter)
+
+	now = now.Add(150 * time.Millisecond)
+	state, ttl, r := TokenBucket.take(q, state, now)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	assert.Equal(t, 250*time.Millisecond, r.Reset)
+	assert.Equal(t, r.Reset, ttl)
+
+	// 长时间空闲后不超过桶容量
+	now = now.Add(time.Hour)
+	_, _, r = TokenBucket.take(q, state, now)
+	assert.Equal(t, 2, r.Remaining)
+
+	// 时钟回拨时不增加令牌
+	_, _, r = TokenBucket.take(q, state, now.Add(-time.Hour))
+	assert.False(t, r.Allowed)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	q := &quota{limit: 4, period: time.Second, burst: 4}
+	start := time.Unix(1700000000, 0)
+
+	var state []byte
+	var r Result
+	for i := 0; i < 4; i++ {
+		state, _, r = SlidingWindow.take(q, state, start.Add(100*time.Millisecond))
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3-i, r.Remaining)
+	}
+	assert.Equal(t, 900*time.Millisecond, r.Reset)
+
+	// 当前窗口已满，需等到下一窗口上一窗口的权重降到 3/4
+	_, _, r = SlidingWindow.take(q, state, start.Add(100*time.Millisecond))
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 1150*time.Millisecond, r.RetryAfter)
+
+	// 下一窗口的 250ms 处，估算数为 4*0.75=3，可再放行一个
+	now := start.Add(1250 * time.Millisecond)
+	_, _, r = SlidingWindow.take(q, state, now.Add(-time.Millisecond))
+	assert.False(t, r.Allowed)
+	assert.InDelta(t, float64(time.Millisecond), float64(r.RetryAfter), float64(time.Microsecond))
+	state, ttl, r := SlidingWindow.take(q, state, now)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	assert.Equal(t, 1750*time.Millisecond, ttl)
+
+	// 隔了一个窗口以上，计数清零
+	_, _, r = SlidingWindow.take(q, state, start.Add(3*time.Second))
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 3, r.Remaining)
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// 默认每个周期允许的请求数。
+	defaultLimit = 100
+
+	// 默认的限流周期。
+	defaultPeriod = time.Minute
+
+	// 默认的存储键前缀。
+	defaultKeyPrefix = "ratelimit:"
+)
+
+// KeyFunc 返回限流的键，返回空串表示不限流。
+type KeyFunc func(c context.Context, ctx *app.RequestContext) string
+
+// 表示一个限流的自定义选项结构体。
+type options struct {
+	// 限流算法。
+	algorithm Algorithm
+
+	// 每 period 允许 limit 个请求。
+	limit  int
+	period time.Duration
+
+	// 令牌桶的容量，为零时等于 limit。
+	burst int
+
+	// 限流状态的存储。
+	store Store
+
+	// 限流的键。
+	keyFunc KeyFunc
+
+	// 存储键的前缀。
+	keyPrefix string
+
+	// 是否发送 RateLimit-* 响应标头。
+	headers bool
+
+	// 超出限流时的处理器。
+	limitReachedHandler func(c context.Context, ctx *app.RequestContext, r Result)
+
+	// 当前时间，便于测试。
+	now func() time.Time
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认的超限处理器，以 429 终止请求。
+func defaultLimitReachedHandler(c context.Context, ctx *app.RequestContext, r Result) {
+	ctx.AbortWithMsg(consts.StatusMessage(consts.StatusTooManyRequests), consts.StatusTooManyRequests)
+}
+
+// 创建一个自定义限流的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		algorithm:           TokenBucket,
+		limit:               defaultLimit,
+		period:              defaultPeriod,
+		keyFunc:             KeyByClientIP,
+		keyPrefix:           defaultKeyPrefix,
+		headers:             true,
+		limitReachedHandler: defaultLimitReachedHandler,
+		now:                 time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.limit <= 0 {
+		panic("ratelimit：配额须大于零")
+	}
+	if cfg.period <= 0 {
+		panic("ratelimit：周期须大于零")
+	}
+	if cfg.burst <= 0 {
+		cfg.burst = cfg.limit
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore()
+	}
+	return cfg
+}
+
+// WithAlgorithm 设置限流算法，默认为令牌桶。
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = a
+	}
+}
+
+// WithLimit 设置每 period 允许 limit 个请求，默认每分钟 100 个。
+func WithLimit(limit int, period time.Duration) Option {
+	return func(o *options) {
+		o.limit = limit
+		o.period = period
+	}
+}
+
+// WithBurst 设置令牌桶的容量，即允许的最大突发请求数，默认等于配额。仅对令牌桶算法有效。
+func WithBurst(n int) Option {
+	return func(o *options) {
+		o.burst = n
+	}
+}
+
+// WithStore 设置限流状态的存储，默认为每个中间件独占的内存存储。
+//
+// 多个中间件共享同一存储时，应通过 WithKeyPrefix 区分各自的配额。
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+// WithKeyFunc 设置限流的键，默认按客户端 IP 限流。
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithKeyPrefix 设置存储键的前缀，默认为 "ratelimit:"。
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.keyPrefix = prefix
+	}
+}
+
+// WithHeaders 设置是否发送 RateLimit-* 响应标头，默认是。超限时始终发送 Retry-After。
+func WithHeaders(b bool) Option {
+	return func(o *options) {
+		o.headers = b
+	}
+}
+
+// WithLimitReachedHandler 自定义超限时的处理器，默认以 429 终止请求。
+func WithLimitReachedHandler(f func(c context.Context, ctx *app.RequestContext, r Result)) Option {
+	return func(o *options) {
+		o.limitReachedHandler = f
+	}
+}
+
+// KeyByClientIP 按客户端 IP 限流。
+func KeyByClientIP(c context.Context, ctx *app.RequestContext) string {
+	return ctx.ClientIP()
+}
+
+// KeyByRoute 按匹配的路由限流，同一路由的所有请求共享配额。
+func KeyByRoute(c context.Context, ctx *app.RequestContext) string {
+	return string(ctx.Request.Header.Method()) + " " + ctx.FullPath()
+}
+
+// KeyByRouteAndClientIP 按路由和客户端 IP 限流。
+func KeyByRouteAndClientIP(c context.Context, ctx *app.RequestContext) string {
+	return KeyByRoute(c, ctx) + " " + ctx.ClientIP()
+}
+
+// KeyByHeader 按请求标头的值限流，如 API 密钥，标头缺失时按客户端 IP 限流。
+func KeyByHeader(header string) KeyFunc {
+	return func(c context.Context, ctx *app.RequestContext) string {
+		if v := ctx.Request.Header.Peek(header); len(v) > 0 {
+			return header + ":" + string(v)
+		}
+		return ctx.ClientIP()
+	}
+}
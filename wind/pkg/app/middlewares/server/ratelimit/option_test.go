@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, TokenBucket, opts.algorithm)
+	assert.Equal(t, defaultLimit, opts.limit)
+	assert.Equal(t, defaultPeriod, opts.period)
+	assert.Equal(t, defaultLimit, opts.burst)
+	assert.IsType(t, &MemoryStore{}, opts.store)
+	assert.Equal(t, fmt.Sprintf("%p", KeyByClientIP), fmt.Sprintf("%p", opts.keyFunc))
+	assert.Equal(t, defaultKeyPrefix, opts.keyPrefix)
+	assert.True(t, opts.headers)
+	assert.Equal(t, fmt.Sprintf("%p", defaultLimitReachedHandler), fmt.Sprintf("%p", opts.limitReachedHandler))
+}
+
+func TestOption(t *testing.T) {
+	store := NewMemoryStore()
+	handler := func(c context.Context, ctx *app.RequestContext, r Result) {}
+	opts := newOptions(
+		WithAlgorithm(SlidingWindow),
+		WithLimit(10, time.Second),
+		WithBurst(20),
+		WithStore(store),
+		WithKeyFunc(KeyByRoute),
+		WithKeyPrefix("rl:"),
+		WithHeaders(false),
+		WithLimitReachedHandler(handler),
+	)
+	assert.Equal(t, SlidingWindow, opts.algorithm)
+	assert.Equal(t, 10, opts.limit)
+	assert.Equal(t, time.Second, opts.period)
+	assert.Equal(t, 20, opts.burst)
+	assert.Equal(t, store, opts.store)
+	assert.Equal(t, fmt.Sprintf("%p", KeyByRoute), fmt.Sprintf("%p", opts.keyFunc))
+	assert.Equal(t, "rl:", opts.keyPrefix)
+	assert.False(t, opts.headers)
+	assert.Equal(t, fmt.Sprintf("%p", handler), fmt.Sprintf("%p", opts.limitReachedHandler))
+}
+
+func TestOptionPanic(t *testing.T) {
+	assert.Panics(t, func() { newOptions(WithLimit(0, time.Second)) })
+	assert.Panics(t, func() { newOptions(WithLimit(1, 0)) })
+}
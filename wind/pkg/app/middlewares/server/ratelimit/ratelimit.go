@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 限流响应标头，参见 IETF 草案 draft-ietf-httpapi-ratelimit-headers。
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// 比较并交换的最大尝试次数。
+const maxAttempts = 8
+
+var errTooManyConflicts = errors.New("ratelimit：并发更新冲突过多")
+
+// New 返回一个限流中间件。
+//
+// 中间件可通过 Engine.Use 全局注册，也可注册到路由组或单个路由上，为其设置独立的配额。
+// 超出配额时发送 Retry-After 标头并交由超限处理器处理，默认以 429 终止请求。
+// 存储出错时记录日志并放行请求。
+func New(opts ...Option) app.HandlerFunc {
+	l := newLimiter(newOptions(opts...))
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		key := l.opts.keyFunc(c, ctx)
+		if key == "" {
+			ctx.Next(c)
+			return
+		}
+
+		r, err := l.take(c, key)
+		if err != nil {
+			hlog.SystemLogger().CtxWarnf(c, "限流存储出错，已放行请求：%v", err)
+			ctx.Next(c)
+			return
+		}
+
+		if !r.Allowed {
+			// 超限处理器可能重置响应，标头在其后设置
+			l.opts.limitReachedHandler(c, ctx, r)
+			l.setHeaders(ctx, &r)
+			ctx.Response.Header.Set(consts.HeaderRetryAfter, strconv.FormatInt(seconds(r.RetryAfter), 10))
+			return
+		}
+		l.setHeaders(ctx, &r)
+		ctx.Next(c)
+	}
+}
+
+type limiter struct {
+	opts   *options
+	quota  quota
+	policy string
+}
+
+func newLimiter(o *options) *limiter {
+	l := &limiter{
+		opts:  o,
+		quota: quota{limit: o.limit, period: o.period, burst: o.burst},
+	}
+	// 如 "100;w=60"，令牌桶另附容量
+	l.policy = strconv.Itoa(o.limit) + ";w=" + strconv.FormatInt(seconds(o.period), 10)
+	if o.algorithm == TokenBucket && o.burst != o.limit {
+		l.policy += ";burst=" + strconv.Itoa(o.burst)
+	}
+	return l
+}
+
+func (l *limiter) setHeaders(ctx *app.RequestContext, r *Result) {
+	if !l.opts.headers {
+		return
+	}
+	ctx.Response.Header.Set(HeaderRateLimitLimit, strconv.Itoa(r.Limit))
+	ctx.Response.Header.Set(HeaderRateLimitRemaining, strconv.Itoa(r.Remaining))
+	ctx.Response.Header.Set(HeaderRateLimitReset, strconv.FormatInt(seconds(r.Reset), 10))
+	ctx.Response.Header.Set(HeaderRateLimitPolicy, l.policy)
+}
+
+// 在 key 上消耗一个配额，并发更新冲突时重试。
+func (l *limiter) take(c context.Context, key string) (Result, error) {
+	key = l.opts.keyPrefix + key
+	for i := 0; i < maxAttempts; i++ {
+		old, err := l.opts.store.Get(c, key)
+		if err != nil {
+			return Result{}, err
+		}
+		state, ttl, r := l.opts.algorithm.take(&l.quota, old, l.opts.now())
+		// 被拒绝的请求不消耗配额，无需写回
+		if !r.Allowed {
+			return r, nil
+		}
+		ok, err := l.opts.store.CompareAndSwap(c, key, old, state, ttl)
+		if err != nil {
+			return Result{}, err
+		}
+		if ok {
+			return r, nil
+		}
+	}
+	return Result{}, errTooManyConflicts
+}
+
+// 向上取整的秒数。
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
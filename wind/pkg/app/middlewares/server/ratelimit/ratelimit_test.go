@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func ok(c context.Context, ctx *app.RequestContext) {
+	ctx.String(consts.StatusOK, "ok")
+}
+
+func TestRateLimit(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithLimit(2, time.Minute)))
+	engine.GET("/", ok)
+
+	for i := 1; i >= 0; i-- {
+		resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+		assert.Equal(t, consts.StatusOK, resp.StatusCode())
+		assert.Equal(t, "2", string(resp.Header.Peek(HeaderRateLimitLimit)))
+		assert.Equal(t, string(rune('0'+i)), string(resp.Header.Peek(HeaderRateLimitRemaining)))
+		assert.Equal(t, "2;w=60", string(resp.Header.Peek(HeaderRateLimitPolicy)))
+		assert.Equal(t, 0, len(resp.Header.Peek(consts.HeaderRetryAfter)))
+	}
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, "30", string(resp.Header.Peek(consts.HeaderRetryAfter)))
+	assert.Equal(t, "60", string(resp.Header.Peek(HeaderRateLimitReset)))
+	assert.Equal(t, "0", string(resp.Header.Peek(HeaderRateLimitRemaining)))
+	assert.Equal(t, "Too Many Requests", string(resp.Body()))
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	store := NewMemoryStore()
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithStore(store), WithLimit(100, time.Minute), WithHeaders(false)))
+	engine.GET("/", ok)
+	auth := engine.Group("/auth", New(
+		WithStore(store),
+		WithKeyPrefix("ratelimit:auth:"),
+		WithAlgorithm(SlidingWindow),
+		WithLimit(1, time.Minute),
+	))
+	auth.POST("/login", ok)
+
+	assert.Equal(t, consts.StatusOK, ut.PerformRequest(engine, consts.MethodPost, "/auth/login", nil).Result().StatusCode())
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/auth/login", nil).Result()
+	assert.Equal(t, consts.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, "1", string(resp.Header.Peek(HeaderRateLimitLimit)))
+
+	// 全局配额不受路由组配额影响
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, 0, len(resp.Header.Peek(HeaderRateLimitLimit)))
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithLimit(1, time.Minute), WithKeyFunc(KeyByHeader("X-API-Key"))))
+	engine.GET("/", ok)
+
+	get := func(key string) int {
+		return ut.PerformRequest(engine, consts.MethodGet, "/", nil,
+			ut.Header{Key: "X-API-Key", Value: key}).Result().StatusCode()
+	}
+	assert.Equal(t, consts.StatusOK, get("a"))
+	assert.Equal(t, consts.StatusOK, get("b"))
+	assert.Equal(t, consts.StatusTooManyRequests, get("a"))
+
+	// 空键不限流
+	engine = route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithLimit(1, time.Minute), WithKeyFunc(func(c context.Context, ctx *app.RequestContext) string {
+		return ""
+	})))
+	engine.GET("/", ok)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, consts.StatusOK, ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result().StatusCode())
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod(consts.MethodGet)
+	ctx.SetFullPath("/user/:name")
+	ctx.Request.Header.Set("X-API-Key", "k")
+	c := context.Background()
+
+	assert.Equal(t, "GET /user/:name", KeyByRoute(c, ctx))
+	assert.Equal(t, "GET /user/:name "+ctx.ClientIP(), KeyByRouteAndClientIP(c, ctx))
+	assert.Equal(t, "X-API-Key:k", KeyByHeader("X-API-Key")(c, ctx))
+	assert.Equal(t, ctx.ClientIP(), KeyByHeader("X-Other")(c, ctx))
+}
+
+func TestRateLimitReachedHandler(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithLimit(1, time.Minute), WithLimitReachedHandler(func(c context.Context, ctx *app.RequestContext, r Result) {
+		ctx.JSON(consts.StatusTooManyRequests, map[string]any{"retry": seconds(r.RetryAfter)})
+		ctx.Abort()
+	})))
+	engine.GET("/", ok)
+
+	ut.PerformRequest(engine, consts.MethodGet, "/", nil)
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, `{"retry":60}`, string(resp.Body()))
+}
+
+type errStore struct{}
+
+func (errStore) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("down")
+}
+
+func (errStore) CompareAndSwap(context.Context, string, []byte, []byte, time.Duration) (bool, error) {
+	return false, errors.New("down")
+}
+
+// 总是冲突的存储。
+type conflictStore struct{}
+
+func (conflictStore) Get(context.Context, string) ([]byte, error) {
+	return nil, nil
+}
+
+func (conflictStore) CompareAndSwap(context.Context, string, []byte, []byte, time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	for _, s := range []Store{errStore{}, conflictStore{}} {
+		engine := route.NewEngine(config.NewOptions(nil))
+		engine.Use(New(WithStore(s), WithLimit(1, time.Minute)))
+		engine.GET("/", ok)
+
+		// 存储出错时放行
+		for i := 0; i < 2; i++ {
+			resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+			assert.Equal(t, consts.StatusOK, resp.StatusCode())
+			assert.Equal(t, 0, len(resp.Header.Peek(HeaderRateLimitLimit)))
+		}
+	}
+
+	_, err := newLimiter(newOptions(WithStore(conflictStore{}))).take(context.Background(), "k")
+	assert.Equal(t, errTooManyConflicts, err)
+}
+
+func TestRateLimitRedis(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	store := NewRedisStore(RedisOptions{Addr: srv.addr()})
+	defer store.Close()
+
+	// 两个实例共享同一配额
+	newEngine := func() *route.Engine {
+		engine := route.NewEngine(config.NewOptions(nil))
+		engine.Use(New(WithStore(store), WithLimit(3, time.Minute), WithBurst(2)))
+		engine.GET("/", ok)
+		return engine
+	}
+	e1, e2 := newEngine(), newEngine()
+
+	resp := ut.PerformRequest(e1, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "3;w=60;burst=2", string(resp.Header.Peek(HeaderRateLimitPolicy)))
+	assert.Equal(t, consts.StatusOK, ut.PerformRequest(e2, consts.MethodGet, "/", nil).Result().StatusCode())
+	assert.Equal(t, consts.StatusTooManyRequests, ut.PerformRequest(e1, consts.MethodGet, "/", nil).Result().StatusCode())
+}
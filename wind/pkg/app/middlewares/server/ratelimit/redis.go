@@ -0,0 +1,347 @@
+package ratelimit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrStoreClosed 表示存储已关闭。
+	ErrStoreClosed = errors.New("ratelimit：存储已关闭")
+
+	errUnexpectedReply = errors.New("ratelimit：意外的 Redis 响应")
+)
+
+// 默认的 Redis 连接池大小。
+const defaultRedisPoolSize = 16
+
+// RedisOptions 配置 Redis 存储。
+type RedisOptions struct {
+	// 服务器地址，如 127.0.0.1:6379。
+	Addr string
+
+	// 认证的用户名和密码，为空时不认证。
+	Username string
+	Password string
+
+	// 数据库编号。
+	DB int
+
+	// 空闲连接池的大小，默认 16。
+	PoolSize int
+
+	// 建立连接的超时时间，为零时不限。
+	DialTimeout time.Duration
+
+	// 单次命令读写的超时时间，为零时以上下文的截止时间为准。
+	Timeout time.Duration
+}
+
+// RedisStore 是基于 Redis 协议（RESP）的存储，适用于多实例共享配额。
+//
+// CompareAndSwap 以 WATCH/MULTI/EXEC 乐观事务实现，不依赖 Lua 脚本，
+// 因此也适用于兼容 Redis 协议但不支持脚本的服务。
+type RedisStore struct {
+	opts RedisOptions
+	pool chan *redisConn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewRedisStore 创建一个 Redis 存储，连接在首次使用时建立。
+func NewRedisStore(opts RedisOptions) *RedisStore {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultRedisPoolSize
+	}
+	return &RedisStore{opts: opts, pool: make(chan *redisConn, opts.PoolSize)}
+}
+
+// Get 实现 Store 接口。
+func (s *RedisStore) Get(c context.Context, key string) (value []byte, err error) {
+	err = s.do(c, func(cn *redisConn) error {
+		cn.writeCommand("GET", key)
+		if err := cn.flush(); err != nil {
+			return err
+		}
+		value, err = cn.readBulk()
+		return err
+	})
+	return
+}
+
+// CompareAndSwap 实现 Store 接口。
+func (s *RedisStore) CompareAndSwap(c context.Context, key string, old, new []byte, ttl time.Duration) (swapped bool, err error) {
+	err = s.do(c, func(cn *redisConn) error {
+		cn.writeCommand("WATCH", key)
+		cn.writeCommand("GET", key)
+		if err := cn.flush(); err != nil {
+			return err
+		}
+		if err := cn.readOK(); err != nil {
+			return err
+		}
+		cur, err := cn.readBulk()
+		if err != nil {
+			return err
+		}
+		if (cur == nil) != (old == nil) || !bytes.Equal(cur, old) {
+			cn.writeCommand("UNWATCH")
+			if err = cn.flush(); err != nil {
+				return err
+			}
+			return cn.readOK()
+		}
+
+		ms := strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+		cn.writeCommand("MULTI")
+		cn.writeCommand("SET", key, string(new), "PX", ms)
+		cn.writeCommand("EXEC")
+		if err = cn.flush(); err != nil {
+			return err
+		}
+		if err = cn.readOK(); err != nil {
+			return err
+		}
+		if _, err = cn.readReply(); err != nil { // QUEUED
+			return err
+		}
+		reply, err := cn.readReply()
+		if err != nil {
+			return err
+		}
+		// 被监视的键已被修改时 EXEC 返回空数组
+		swapped = reply != nil
+		return nil
+	})
+	return
+}
+
+// Close 关闭所有空闲连接，之后的调用将返回 ErrStoreClosed。
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.pool)
+	for cn := range s.pool {
+		_ = cn.conn.Close()
+	}
+	return nil
+}
+
+// 在池化的连接上执行 f，出错的连接将被丢弃。
+func (s *RedisStore) do(c context.Context, f func(cn *redisConn) error) error {
+	cn, err := s.get(c)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := c.Deadline()
+	if s.opts.Timeout > 0 {
+		if d := time.Now().Add(s.opts.Timeout); !ok || d.Before(deadline) {
+			deadline, ok = d, true
+		}
+	}
+	if ok {
+		_ = cn.conn.SetDeadline(deadline)
+	} else {
+		_ = cn.conn.SetDeadline(time.Time{})
+	}
+
+	// 出错时连接中可能残留未读的响应，不再复用
+	if err = f(cn); err != nil {
+		_ = cn.conn.Close()
+		return err
+	}
+	s.put(cn)
+	return nil
+}
+
+func (s *RedisStore) get(c context.Context) (*redisConn, error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, ErrStoreClosed
+	}
+
+	select {
+	case cn, ok := <-s.pool:
+		if ok {
+			return cn, nil
+		}
+		return nil, ErrStoreClosed
+	default:
+	}
+	return s.dial(c)
+}
+
+func (s *RedisStore) put(cn *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		select {
+		case s.pool <- cn:
+			return
+		default:
+		}
+	}
+	_ = cn.conn.Close()
+}
+
+func (s *RedisStore) dial(c context.Context) (*redisConn, error) {
+	d := net.Dialer{Timeout: s.opts.DialTimeout}
+	conn, err := d.DialContext(c, "tcp", s.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if s.opts.Password != "" || s.opts.DB != 0 {
+		if s.opts.Timeout > 0 {
+			_ = conn.SetDeadline(time.Now().Add(s.opts.Timeout))
+		}
+		n := 0
+		if s.opts.Password != "" {
+			if s.opts.Username != "" {
+				cn.writeCommand("AUTH", s.opts.Username, s.opts.Password)
+			} else {
+				cn.writeCommand("AUTH", s.opts.Password)
+			}
+			n++
+		}
+		if s.opts.DB != 0 {
+			cn.writeCommand("SELECT", strconv.Itoa(s.opts.DB))
+			n++
+		}
+		err = cn.flush()
+		for ; err == nil && n > 0; n-- {
+			err = cn.readOK()
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// Redis 返回的错误响应。
+type redisError string
+
+func (e redisError) Error() string { return "ratelimit：Redis 错误：" + string(e) }
+
+// 一个 RESP 协议连接。
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func (cn *redisConn) writeCommand(args ...string) {
+	cn.w.WriteByte('*')
+	cn.w.WriteString(strconv.Itoa(len(args)))
+	cn.w.WriteString("\r\n")
+	for _, arg := range args {
+		cn.w.WriteByte('$')
+		cn.w.WriteString(strconv.Itoa(len(arg)))
+		cn.w.WriteString("\r\n")
+		cn.w.WriteString(arg)
+		cn.w.WriteString("\r\n")
+	}
+}
+
+func (cn *redisConn) flush() error {
+	return cn.w.Flush()
+}
+
+func (cn *redisConn) readOK() error {
+	reply, err := cn.readReply()
+	if err != nil {
+		return err
+	}
+	if s, ok := reply.(string); !ok || s != "OK" {
+		return errUnexpectedReply
+	}
+	return nil
+}
+
+func (cn *redisConn) readBulk() ([]byte, error) {
+	reply, err := cn.readReply()
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	}
+	return nil, errUnexpectedReply
+}
+
+// 读取一个响应：简单字符串为 string，整数为 int64，批量字符串为 []byte，
+// 数组为 []any，空批量字符串和空数组为 nil，错误响应为 redisError。
+func (cn *redisConn) readReply() (any, error) {
+	line, err := cn.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errUnexpectedReply
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(cn.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = cn.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("%w：%q", errUnexpectedReply, line)
+}
+
+func (cn *redisConn) readLine() ([]byte, error) {
+	line, err := cn.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errUnexpectedReply
+	}
+	return line[:len(line)-2], nil
+}
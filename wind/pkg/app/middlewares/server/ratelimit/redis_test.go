@@ -0,0 +1,245 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 仅实现限流所需命令的 Redis 协议替身服务器。
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu    sync.Mutex
+	items map[string]fakeItem
+}
+
+type fakeItem struct {
+	value    string
+	expireAt time.Time
+	version  int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := &fakeRedis{ln: ln, password: password, items: make(map[string]fakeItem)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeRedis) addr() string { return s.ln.Addr().String() }
+
+// 模拟其他客户端修改键。
+func (s *fakeRedis) touch(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.items[key]
+	it.version++
+	s.items[key] = it
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	var (
+		watched map[string]int
+		queue   [][]string
+		inMulti bool
+	)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		var reply string
+		switch {
+		case cmd == "AUTH":
+			if args[len(args)-1] == s.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT" || cmd == "PING":
+			reply = "+OK\r\n"
+		case cmd == "WATCH":
+			s.mu.Lock()
+			if watched == nil {
+				watched = make(map[string]int)
+			}
+			for _, k := range args[1:] {
+				watched[k] = s.items[k].version
+			}
+			s.mu.Unlock()
+			reply = "+OK\r\n"
+		case cmd == "UNWATCH":
+			watched = nil
+			reply = "+OK\r\n"
+		case cmd == "MULTI":
+			inMulti = true
+			reply = "+OK\r\n"
+		case cmd == "EXEC":
+			s.mu.Lock()
+			aborted := false
+			for k, v := range watched {
+				if s.items[k].version != v {
+					aborted = true
+				}
+			}
+			if aborted {
+				reply = "*-1\r\n"
+			} else {
+				reply = "*" + strconv.Itoa(len(queue)) + "\r\n"
+				for _, q := range queue {
+					reply += s.exec(q)
+				}
+			}
+			s.mu.Unlock()
+			watched, queue, inMulti = nil, nil, false
+		case inMulti:
+			queue = append(queue, args)
+			reply = "+QUEUED\r\n"
+		default:
+			s.mu.Lock()
+			reply = s.exec(args)
+			s.mu.Unlock()
+		}
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) exec(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		it, ok := s.items[args[1]]
+		if !ok || it.value == "" || time.Now().After(it.expireAt) {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(it.value)) + "\r\n" + it.value + "\r\n"
+	case "SET":
+		it := s.items[args[1]]
+		it.value = args[2]
+		it.expireAt = time.Now().Add(time.Hour)
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			it.expireAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		it.version++
+		s.items[args[1]] = it
+		return "+OK\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisStore(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	s := NewRedisStore(RedisOptions{Addr: srv.addr(), Timeout: time.Second})
+	defer s.Close()
+
+	testStore(t, s)
+	testStoreConcurrent(t, s)
+}
+
+func TestRedisStoreAuth(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+
+	s := NewRedisStore(RedisOptions{Addr: srv.addr(), Password: "secret", DB: 1})
+	_, err := s.Get(context.Background(), "k")
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+	_, err = s.Get(context.Background(), "k")
+	assert.Equal(t, ErrStoreClosed, err)
+
+	s = NewRedisStore(RedisOptions{Addr: srv.addr(), Password: "wrong"})
+	defer s.Close()
+	_, err = s.Get(context.Background(), "k")
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
+
+func TestRedisStoreConflict(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	s := NewRedisStore(RedisOptions{Addr: srv.addr()})
+	defer s.Close()
+	c := context.Background()
+
+	// 在 WATCH 之后、EXEC 之前修改键，事务应被放弃
+	cn, err := s.get(c)
+	assert.Nil(t, err)
+	cn.writeCommand("WATCH", "k")
+	assert.Nil(t, cn.flush())
+	assert.Nil(t, cn.readOK())
+	srv.touch("k")
+	cn.writeCommand("MULTI")
+	cn.writeCommand("SET", "k", "v")
+	cn.writeCommand("EXEC")
+	assert.Nil(t, cn.flush())
+	assert.Nil(t, cn.readOK())
+	_, err = cn.readReply()
+	assert.Nil(t, err)
+	reply, err := cn.readReply()
+	assert.Nil(t, err)
+	assert.Nil(t, reply)
+	s.put(cn)
+
+	v, err := s.Get(c, "k")
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+}
+
+func TestRedisStoreDialError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	s := NewRedisStore(RedisOptions{Addr: addr, DialTimeout: time.Second})
+	defer s.Close()
+	_, err = s.Get(context.Background(), "k")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Store 保存限流状态，须保证 CompareAndSwap 的原子性，以便多个实例共享配额。
+type Store interface {
+	// Get 返回键的当前值，键不存在或已过期时返回 nil。
+	Get(c context.Context, key string) ([]byte, error)
+
+	// CompareAndSwap 仅当键的当前值等于 old（nil 表示不存在）时将其设为 new，
+	// 并在 ttl 后过期，返回是否设置成功。
+	CompareAndSwap(c context.Context, key string, old, new []byte, ttl time.Duration) (bool, error)
+}
+
+const (
+	// 内存存储的分片数。
+	memoryShards = 64
+
+	// 每个分片写入多少次后清理一次过期的键。
+	sweepInterval = 1024
+)
+
+// MemoryStore 是分片加锁的内存存储，适用于单实例部署。
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryShards]memoryShard
+}
+
+type memoryShard struct {
+	sync.Mutex
+	items  map[string]memoryItem
+	writes int
+}
+
+type memoryItem struct {
+	value    []byte
+	expireAt int64
+}
+
+// NewMemoryStore 创建一个内存存储。
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].items = make(map[string]memoryItem)
+	}
+	return s
+}
+
+func (s *MemoryStore) shard(key string) *memoryShard {
+	return &s.shards[maphash.String(s.seed, key)%memoryShards]
+}
+
+// Get 实现 Store 接口。
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	sh := s.shard(key)
+	now := time.Now().UnixNano()
+	sh.Lock()
+	defer sh.Unlock()
+	if it, ok := sh.items[key]; ok && it.expireAt > now {
+		return it.value, nil
+	}
+	return nil, nil
+}
+
+// CompareAndSwap 实现 Store 接口。
+func (s *MemoryStore) CompareAndSwap(_ context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	sh := s.shard(key)
+	now := time.Now().UnixNano()
+	sh.Lock()
+	defer sh.Unlock()
+
+	var cur []byte
+	if it, ok := sh.items[key]; ok && it.expireAt > now {
+		cur = it.value
+	}
+	if (cur == nil) != (old == nil) || !bytes.Equal(cur, old) {
+		return false, nil
+	}
+	sh.items[key] = memoryItem{value: new, expireAt: now + int64(ttl)}
+
+	if sh.writes++; sh.writes >= sweepInterval {
+		sh.writes = 0
+		for k, it := range sh.items {
+			if it.expireAt <= now {
+				delete(sh.items, k)
+			}
+		}
+	}
+	return true, nil
+}
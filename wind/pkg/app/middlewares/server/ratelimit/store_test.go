@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 对任一存储验证比较并交换的语义。
+func testStore(t *testing.T, s Store) {
+	c := context.Background()
+
+	v, err := s.Get(c, "k")
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+
+	ok, err := s.CompareAndSwap(c, "k", []byte("x"), []byte("a"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = s.CompareAndSwap(c, "k", nil, []byte("a"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = s.CompareAndSwap(c, "k", nil, []byte("b"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = s.CompareAndSwap(c, "k", []byte("a"), []byte("b"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	v, err = s.Get(c, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("b"), v)
+
+	// 过期后视为不存在
+	ok, err = s.CompareAndSwap(c, "e", nil, []byte("a"), 5*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	time.Sleep(20 * time.Millisecond)
+	v, err = s.Get(c, "e")
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+	ok, err = s.CompareAndSwap(c, "e", nil, []byte("c"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+// 并发请求下放行的数量不超过配额。
+func testStoreConcurrent(t *testing.T, s Store) {
+	l := newLimiter(newOptions(WithStore(s), WithLimit(50, time.Hour)))
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				r, err := l.take(context.Background(), "shared")
+				if err != nil {
+					continue
+				}
+				if r.Allowed {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, allowed, 50)
+	assert.Greater(t, allowed, 0)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+	testStoreConcurrent(t, NewMemoryStore())
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore()
+	c := context.Background()
+	for i := 0; i < sweepInterval*memoryShards; i++ {
+		_, _ = s.CompareAndSwap(c, fmt.Sprint(i), nil, []byte("a"), time.Nanosecond)
+	}
+	n := 0
+	for i := range s.shards {
+		n += len(s.shards[i].items)
+	}
+	assert.Less(t, n, sweepInterval*memoryShards)
+}
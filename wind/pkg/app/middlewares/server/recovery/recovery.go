@@ -28,6 +28,10 @@ func Recovery(opts ...Option) app.HandlerFunc {
 			if err := recover(); err != nil {
 				stack := stack(3)
 
+				// 供跟踪器统计恐慌
+				if ctx.IsEnableTrace() && ctx.GetTraceInfo() != nil {
+					ctx.GetTraceInfo().Stats().SetPanicked(err)
+				}
 				cfg.recoveryHandler(c, ctx, err, stack)
 			}
 		}()
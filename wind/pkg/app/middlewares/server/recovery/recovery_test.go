@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/traceinfo"
 	"github.com/favbox/gosky/wind/pkg/protocol/consts"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,3 +39,19 @@ func TestWithRecoveryHandler(t *testing.T) {
 	assert.Equal(t, consts.StatusNotImplemented, ctx.Response.StatusCode())
 	assert.Equal(t, `{"msg":"测试"}`, string(ctx.Response.Body()))
 }
+
+func TestRecoveryTrace(t *testing.T) {
+	ctx := app.NewContext(0)
+	ti := traceinfo.NewTraceInfo()
+	ctx.SetTraceInfo(ti)
+	ctx.SetEnableTrace(true)
+	ctx.SetHandlers(app.HandlersChain{func(c context.Context, ctx *app.RequestContext) {
+		panic("测试")
+	}})
+
+	Recovery()(context.Background(), ctx)
+
+	panicked, err := ti.Stats().Panicked()
+	assert.True(t, panicked)
+	assert.Equal(t, "测试", err)
+}
@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+)
+
+// 默认的请求标识标头。
+const defaultHeader = "X-Request-ID"
+
+// 传入标识的最大长度，超出时重新生成。
+const maxIDLength = 128
+
+// 表示一个请求标识的自定义选项结构体。
+type options struct {
+	// 读取和回写请求标识的标头。
+	header string
+
+	// 请求标识生成器。
+	generator func() string
+
+	// 是否采信请求中传入的标识。
+	trustIncoming bool
+
+	// 确定请求标识后的回调，可选。
+	handler func(c context.Context, ctx *app.RequestContext, id string)
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义请求标识的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		header:        defaultHeader,
+		generator:     NewUUIDv7,
+		trustIncoming: true,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithHeader 设置读取和回写请求标识的标头，默认为 X-Request-ID。
+func WithHeader(header string) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// WithGenerator 自定义请求标识生成器，默认生成 UUIDv7。
+func WithGenerator(f func() string) Option {
+	return func(o *options) {
+		o.generator = f
+	}
+}
+
+// WithTrustIncoming 设置是否采信请求中传入的标识，默认是。
+//
+// 即便采信，过长或含有不可见字符的传入标识也会被重新生成。
+func WithTrustIncoming(b bool) Option {
+	return func(o *options) {
+		o.trustIncoming = b
+	}
+}
+
+// WithHandler 设置确定请求标识后的回调，可用于写入链路追踪等。
+func WithHandler(f func(c context.Context, ctx *app.RequestContext, id string)) Option {
+	return func(o *options) {
+		o.handler = f
+	}
+}
@@ -0,0 +1,33 @@
+package requestid
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, defaultHeader, opts.header)
+	assert.Equal(t, fmt.Sprintf("%p", NewUUIDv7), fmt.Sprintf("%p", opts.generator))
+	assert.True(t, opts.trustIncoming)
+	assert.Nil(t, opts.handler)
+}
+
+func TestOption(t *testing.T) {
+	generator := func() string { return "id" }
+	handler := func(c context.Context, ctx *app.RequestContext, id string) {}
+	opts := newOptions(
+		WithHeader("X-Trace-ID"),
+		WithGenerator(generator),
+		WithTrustIncoming(false),
+		WithHandler(handler),
+	)
+	assert.Equal(t, "X-Trace-ID", opts.header)
+	assert.Equal(t, fmt.Sprintf("%p", generator), fmt.Sprintf("%p", opts.generator))
+	assert.False(t, opts.trustIncoming)
+	assert.Equal(t, fmt.Sprintf("%p", handler), fmt.Sprintf("%p", opts.handler))
+}
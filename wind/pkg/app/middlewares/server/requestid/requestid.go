@@ -0,0 +1,122 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/client"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// KeyRequestID 是请求标识在 RequestContext.Keys 中的键。
+const KeyRequestID = "requestid"
+
+type ctxKey struct{}
+
+// 上下文中的请求标识及其标头名称。
+type ctxValue struct {
+	id     string
+	header string
+}
+
+func init() {
+	hlog.AddCtxExtractor(func(c context.Context) string {
+		if id := FromContext(c); id != "" {
+			return "request_id=" + id
+		}
+		return ""
+	})
+	client.AddDefaultMiddleware(clientMiddleware(""))
+}
+
+// New 返回一个请求标识中间件。
+//
+// 中间件从请求标头读取标识，没有或不可用时生成新的标识，
+// 然后将其存入 RequestContext.Keys、回写到响应标头，
+// 并放入传给后续处理链的 context.Context 中。
+// 因此 hlog 默认记录器的 Ctx 系列方法会自动在日志中带上标识，
+// 以该上下文经 app/client 发出的请求也会自动以同名标头携带标识。
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		var id string
+		if o.trustIncoming {
+			if v := ctx.Request.Header.Peek(o.header); isValid(v) {
+				id = string(v)
+			}
+		}
+		if id == "" {
+			id = o.generator()
+			ctx.Request.Header.Set(o.header, id)
+		}
+
+		ctx.Set(KeyRequestID, id)
+		ctx.Response.Header.Set(o.header, id)
+		c = context.WithValue(c, ctxKey{}, ctxValue{id: id, header: o.header})
+		if o.handler != nil {
+			o.handler(c, ctx, id)
+		}
+		ctx.Next(c)
+	}
+}
+
+// Get 返回当前请求的标识，未经过中间件处理时返回空串。
+func Get(ctx *app.RequestContext) string {
+	return ctx.GetString(KeyRequestID)
+}
+
+// NewContext 返回携带请求标识 id 的上下文，客户端以默认标头携带该标识。
+func NewContext(c context.Context, id string) context.Context {
+	return context.WithValue(c, ctxKey{}, ctxValue{id: id, header: defaultHeader})
+}
+
+// FromContext 返回上下文中的请求标识，没有时返回空串。
+func FromContext(c context.Context) string {
+	v, _ := c.Value(ctxKey{}).(ctxValue)
+	return v.id
+}
+
+// ClientMiddleware 返回一个客户端中间件，将上下文中的请求标识写入发出请求的标头。
+//
+// 经 client.NewClient 新建的客户端已默认以服务端中间件所用的标头携带标识，
+// 仅在需要改用其他标头时才需注册，如：
+//
+//	cli.Use(requestid.ClientMiddleware(requestid.WithHeader("X-Trace-ID")))
+//
+// 请求已设置该标头或上下文中没有标识时不做处理。仅 WithHeader 选项对其生效。
+func ClientMiddleware(opts ...Option) client.Middleware {
+	return clientMiddleware(newOptions(opts...).header)
+}
+
+// 标头名称 header 为空时，使用上下文中记录的标头名称。
+func clientMiddleware(header string) client.Middleware {
+	return func(next client.Endpoint) client.Endpoint {
+		return func(c context.Context, req *protocol.Request, resp *protocol.Response) error {
+			if v, _ := c.Value(ctxKey{}).(ctxValue); v.id != "" {
+				h := header
+				if h == "" {
+					h = v.header
+				}
+				if len(req.Header.Peek(h)) == 0 {
+					req.Header.Set(h, v.id)
+				}
+			}
+			return next(c, req, resp)
+		}
+	}
+}
+
+// 传入的标识须为非空、不超长的可打印 ASCII 字符，以免被用于日志注入。
+func isValid(id []byte) bool {
+	if len(id) == 0 || len(id) > maxIDLength {
+		return false
+	}
+	for _, b := range id {
+		if b <= ' ' || b > '~' {
+			return false
+		}
+	}
+	return true
+}
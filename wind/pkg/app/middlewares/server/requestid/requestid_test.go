@@ -0,0 +1,174 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/client"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidv7 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func newTestEngine(opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	engine.GET("/ping", func(c context.Context, ctx *app.RequestContext) {
+		// 处理链中的上下文、Keys 和请求标头应一致
+		if FromContext(c) != Get(ctx) || string(ctx.Request.Header.Peek(defaultHeader)) != Get(ctx) {
+			ctx.String(consts.StatusInternalServerError, "mismatch")
+			return
+		}
+		ctx.String(consts.StatusOK, FromContext(c))
+	})
+	return engine
+}
+
+func TestRequestIDGenerate(t *testing.T) {
+	engine := newTestEngine()
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/ping", nil).Result()
+	id := string(resp.Header.Peek(defaultHeader))
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Regexp(t, uuidv7, id)
+	assert.Equal(t, id, string(resp.Body()))
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/ping", nil).Result()
+	assert.NotEqual(t, id, string(resp.Header.Peek(defaultHeader)))
+}
+
+func TestRequestIDIncoming(t *testing.T) {
+	engine := newTestEngine()
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/ping", nil,
+		ut.Header{Key: defaultHeader, Value: "abc-123"}).Result()
+	assert.Equal(t, "abc-123", string(resp.Header.Peek(defaultHeader)))
+	assert.Equal(t, "abc-123", string(resp.Body()))
+
+	// 非法的传入标识将被重新生成
+	for _, v := range []string{"a b", "a\x01", strings.Repeat("a", maxIDLength+1)} {
+		resp = ut.PerformRequest(engine, consts.MethodGet, "/ping", nil,
+			ut.Header{Key: defaultHeader, Value: v}).Result()
+		assert.Regexp(t, uuidv7, string(resp.Header.Peek(defaultHeader)))
+	}
+
+	// 不采信传入标识
+	engine = newTestEngine(WithTrustIncoming(false))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/ping", nil,
+		ut.Header{Key: defaultHeader, Value: "abc-123"}).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Regexp(t, uuidv7, string(resp.Header.Peek(defaultHeader)))
+}
+
+func TestRequestIDCustom(t *testing.T) {
+	var got string
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(
+		WithHeader("X-Trace-ID"),
+		WithGenerator(func() string { return "fixed" }),
+		WithHandler(func(c context.Context, ctx *app.RequestContext, id string) {
+			got = FromContext(c)
+		}),
+	))
+	engine.GET("/ping", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, Get(ctx))
+	})
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/ping", nil).Result()
+	assert.Equal(t, "fixed", string(resp.Header.Peek("X-Trace-ID")))
+	assert.Equal(t, "fixed", string(resp.Body()))
+	assert.Equal(t, "fixed", got)
+	assert.Equal(t, 0, len(resp.Header.Peek(defaultHeader)))
+}
+
+func TestClientMiddleware(t *testing.T) {
+	var sent string
+	endpoint := ClientMiddleware()(func(c context.Context, req *protocol.Request, resp *protocol.Response) error {
+		sent = string(req.Header.Peek(defaultHeader))
+		return nil
+	})
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	assert.Nil(t, endpoint(NewContext(context.Background(), "abc"), req, nil))
+	assert.Equal(t, "abc", sent)
+
+	// 已设置的标头不被覆盖
+	req.Header.Set(defaultHeader, "manual")
+	assert.Nil(t, endpoint(NewContext(context.Background(), "abc"), req, nil))
+	assert.Equal(t, "manual", sent)
+
+	// 上下文中没有标识
+	req.Reset()
+	assert.Nil(t, endpoint(context.Background(), req, nil))
+	assert.Equal(t, "", sent)
+}
+
+func TestAutoPropagation(t *testing.T) {
+	var buf bytes.Buffer
+	hlog.SetOutput(&buf)
+	defer hlog.SetOutput(os.Stderr)
+
+	// 新建的客户端无需注册中间件即会携带标识
+	cli, err := client.NewClient()
+	assert.Nil(t, err)
+	var sent string
+	cli.Use(func(next client.Endpoint) client.Endpoint {
+		return func(c context.Context, req *protocol.Request, resp *protocol.Response) error {
+			sent = string(req.Header.Peek("X-Trace-ID"))
+			return nil
+		}
+	})
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithHeader("X-Trace-ID"), WithGenerator(func() string { return "fixed" })))
+	engine.GET("/ping", func(c context.Context, ctx *app.RequestContext) {
+		hlog.CtxInfof(c, "处理请求")
+		req := protocol.AcquireRequest()
+		defer protocol.ReleaseRequest(req)
+		req.SetRequestURI("http://example.com/downstream")
+		_ = cli.Do(c, req, nil)
+	})
+
+	ut.PerformRequest(engine, consts.MethodGet, "/ping", nil)
+	assert.Equal(t, "fixed", sent)
+	assert.Contains(t, buf.String(), "[Info] request_id=fixed 处理请求")
+
+	// 上下文中没有标识时不做处理
+	sent = "unset"
+	buf.Reset()
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/downstream")
+	assert.Nil(t, cli.Do(context.Background(), req, nil))
+	hlog.CtxInfof(context.Background(), "无标识")
+	assert.Equal(t, "", sent)
+	assert.NotContains(t, buf.String(), "request_id=")
+}
+
+func TestNewUUIDv7(t *testing.T) {
+	prev := NewUUIDv7()
+	assert.Regexp(t, uuidv7, prev)
+	for i := 0; i < 100; i++ {
+		id := NewUUIDv7()
+		assert.Regexp(t, uuidv7, id)
+		assert.NotEqual(t, prev, id)
+		// 时间戳部分不减
+		assert.True(t, id[:13] >= prev[:13])
+		prev = id
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, "", FromContext(context.Background()))
+	assert.Equal(t, "x", FromContext(NewContext(context.Background(), "x")))
+}
@@ -0,0 +1,37 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// NewUUIDv7 生成一个 RFC 9562 定义的第 7 版 UUID。
+//
+// 前 48 位为毫秒级 Unix 时间戳，其余为随机位，生成的标识大致按时间排序。
+func NewUUIDv7() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+
+	ms := uint64(time.Now().UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = u[6]&0x0f | 0x70 // 版本 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 变体
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
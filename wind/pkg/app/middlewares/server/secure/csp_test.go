@@ -0,0 +1,50 @@
+package secure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSP(t *testing.T) {
+	csp := NewCSP()
+	assert.Empty(t, csp.String())
+
+	csp.Set("Default-Src", SourceSelf).
+		Set("script-src", SourceSelf, SourceNonce).
+		Set("upgrade-insecure-requests")
+	assert.Equal(t, "default-src 'self'; script-src 'self' 'nonce-{nonce}'; upgrade-insecure-requests", csp.String())
+
+	// 替换、追加和删除保持原有顺序
+	csp.Set("default-src", SourceNone).
+		Add("script-src", "https://cdn.example.com").
+		Add("img-src", SourceSelf, "data:").
+		Del("upgrade-insecure-requests").
+		Del("missing")
+	assert.Equal(t, "default-src 'none'; script-src 'self' 'nonce-{nonce}' https://cdn.example.com; img-src 'self' data:", csp.String())
+
+	sources, ok := csp.Get("SCRIPT-SRC")
+	assert.True(t, ok)
+	assert.Equal(t, []string{SourceSelf, SourceNonce, "https://cdn.example.com"}, sources)
+	sources[0] = "changed"
+	sources, _ = csp.Get("script-src")
+	assert.Equal(t, SourceSelf, sources[0])
+	_, ok = csp.Get("style-src")
+	assert.False(t, ok)
+
+	for _, s := range []string{"", "a;b", "a,b", "a b", "a\nb"} {
+		assert.Panics(t, func() { NewCSP().Set("default-src", s) }, s)
+		assert.Panics(t, func() { NewCSP().Set(s) }, s)
+	}
+}
+
+func TestDefaultCSP(t *testing.T) {
+	assert.Equal(t, "default-src 'self'; base-uri 'self'; font-src 'self' https: data:; form-action 'self'; "+
+		"frame-ancestors 'self'; img-src 'self' data:; object-src 'none'; script-src 'self'; "+
+		"script-src-attr 'none'; style-src 'self' https: 'unsafe-inline'; upgrade-insecure-requests", DefaultCSP().String())
+
+	// 每次返回新的策略
+	DefaultCSP().Del("default-src")
+	_, ok := DefaultCSP().Get("default-src")
+	assert.True(t, ok)
+}
@@ -0,0 +1,169 @@
+package secure
+
+import (
+	"time"
+)
+
+// 默认的 HSTS 有效期，即 180 天。
+const defaultHSTSMaxAge = 180 * 24 * time.Hour
+
+// Config 表示安全响应标头的全部设置，便于集中审阅。
+//
+// 字符串类型的标头值为空时不设置该标头。
+type Config struct {
+	// HSTSMaxAge 为 Strict-Transport-Security 的有效期，为零时不设置该标头。
+	//
+	// 该标头仅在 HTTPS 请求中设置：连接为 TLS 连接（即服务器配置了 TLS），
+	// 或 TrustForwardedProto 为真且 X-Forwarded-Proto 标头为 https。
+	HSTSMaxAge time.Duration
+
+	// HSTSIncludeSubdomains 表示 HSTS 是否同样作用于子域名。
+	HSTSIncludeSubdomains bool
+
+	// HSTSPreload 表示是否申请加入浏览器的 HSTS 预加载列表。
+	HSTSPreload bool
+
+	// TrustForwardedProto 表示是否依据 X-Forwarded-Proto 标头判断 HTTPS 请求，
+	// 适用于由反向代理终止 TLS 的部署。浏览器会忽略 HTTP 响应中的 HSTS，伪造该标头并无影响。
+	TrustForwardedProto bool
+
+	// ContentTypeNosniff 表示是否设置 X-Content-Type-Options: nosniff。
+	ContentTypeNosniff bool
+
+	// FrameOptions 为 X-Frame-Options 的值，如 DENY 或 SAMEORIGIN。
+	FrameOptions string
+
+	// ReferrerPolicy 为 Referrer-Policy 的值，如 no-referrer 或 strict-origin-when-cross-origin。
+	ReferrerPolicy string
+
+	// PermissionsPolicy 为 Permissions-Policy 的值，如 camera=(), geolocation=(self)。
+	PermissionsPolicy string
+
+	// CrossOriginOpenerPolicy 为 Cross-Origin-Opener-Policy 的值，如 same-origin。
+	CrossOriginOpenerPolicy string
+
+	// CrossOriginEmbedderPolicy 为 Cross-Origin-Embedder-Policy 的值，如 require-corp。
+	CrossOriginEmbedderPolicy string
+
+	// CrossOriginResourcePolicy 为 Cross-Origin-Resource-Policy 的值，如 same-origin 或 cross-origin。
+	CrossOriginResourcePolicy string
+
+	// ContentSecurityPolicy 为内容安全策略，为空时不设置该标头。
+	ContentSecurityPolicy *CSP
+
+	// CSPReportOnly 表示是否以 Content-Security-Policy-Report-Only 标头发送内容安全策略，
+	// 浏览器仅报告违规而不拦截，便于上线前验证策略。
+	CSPReportOnly bool
+}
+
+// Option 自定义选项的应用函数。
+type Option func(c *Config)
+
+// DefaultConfig 返回默认的设置。
+//
+// 默认设置 HSTS（180 天，含子域名）、nosniff、X-Frame-Options: SAMEORIGIN、
+// Referrer-Policy: no-referrer、Cross-Origin-Opener-Policy: same-origin
+// 和 Cross-Origin-Resource-Policy: same-origin，不设置其余标头。
+func DefaultConfig() Config {
+	return Config{
+		HSTSMaxAge:                defaultHSTSMaxAge,
+		HSTSIncludeSubdomains:     true,
+		TrustForwardedProto:       true,
+		ContentTypeNosniff:        true,
+		FrameOptions:              "SAMEORIGIN",
+		ReferrerPolicy:            "no-referrer",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// 以默认设置为基础应用自定义选项。
+func newConfig(opts ...Option) *Config {
+	cfg := DefaultConfig()
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &cfg
+}
+
+// WithConfig 以 cfg 替换全部设置，其后的选项仍可逐项调整。
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithHSTS 设置 HSTS 的有效期、是否作用于子域名及是否申请预加载，maxAge 为零时不设置该标头。
+func WithHSTS(maxAge time.Duration, includeSubdomains, preload bool) Option {
+	return func(c *Config) {
+		c.HSTSMaxAge = maxAge
+		c.HSTSIncludeSubdomains = includeSubdomains
+		c.HSTSPreload = preload
+	}
+}
+
+// WithTrustForwardedProto 设置是否依据 X-Forwarded-Proto 标头判断 HTTPS 请求，默认为真。
+func WithTrustForwardedProto(b bool) Option {
+	return func(c *Config) {
+		c.TrustForwardedProto = b
+	}
+}
+
+// WithContentTypeNosniff 设置是否发送 X-Content-Type-Options: nosniff，默认为真。
+func WithContentTypeNosniff(b bool) Option {
+	return func(c *Config) {
+		c.ContentTypeNosniff = b
+	}
+}
+
+// WithFrameOptions 设置 X-Frame-Options，默认为 SAMEORIGIN，为空时不设置。
+func WithFrameOptions(v string) Option {
+	return func(c *Config) {
+		c.FrameOptions = v
+	}
+}
+
+// WithReferrerPolicy 设置 Referrer-Policy，默认为 no-referrer，为空时不设置。
+func WithReferrerPolicy(v string) Option {
+	return func(c *Config) {
+		c.ReferrerPolicy = v
+	}
+}
+
+// WithPermissionsPolicy 设置 Permissions-Policy，默认不设置。
+func WithPermissionsPolicy(v string) Option {
+	return func(c *Config) {
+		c.PermissionsPolicy = v
+	}
+}
+
+// WithCrossOriginOpenerPolicy 设置 Cross-Origin-Opener-Policy，默认为 same-origin，为空时不设置。
+func WithCrossOriginOpenerPolicy(v string) Option {
+	return func(c *Config) {
+		c.CrossOriginOpenerPolicy = v
+	}
+}
+
+// WithCrossOriginEmbedderPolicy 设置 Cross-Origin-Embedder-Policy，默认不设置。
+func WithCrossOriginEmbedderPolicy(v string) Option {
+	return func(c *Config) {
+		c.CrossOriginEmbedderPolicy = v
+	}
+}
+
+// WithCrossOriginResourcePolicy 设置 Cross-Origin-Resource-Policy，默认为 same-origin，为空时不设置。
+func WithCrossOriginResourcePolicy(v string) Option {
+	return func(c *Config) {
+		c.CrossOriginResourcePolicy = v
+	}
+}
+
+// WithContentSecurityPolicy 设置内容安全策略，reportOnly 为真时仅报告违规，默认不设置。
+func WithContentSecurityPolicy(csp *CSP, reportOnly bool) Option {
+	return func(c *Config) {
+		c.ContentSecurityPolicy = csp
+		c.CSPReportOnly = reportOnly
+	}
+}
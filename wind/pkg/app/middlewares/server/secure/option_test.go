@@ -0,0 +1,59 @@
+package secure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	cfg := newConfig()
+	assert.Equal(t, DefaultConfig(), *cfg)
+	assert.Equal(t, 180*24*time.Hour, cfg.HSTSMaxAge)
+	assert.True(t, cfg.HSTSIncludeSubdomains)
+	assert.False(t, cfg.HSTSPreload)
+	assert.True(t, cfg.TrustForwardedProto)
+	assert.True(t, cfg.ContentTypeNosniff)
+	assert.Equal(t, "SAMEORIGIN", cfg.FrameOptions)
+	assert.Equal(t, "no-referrer", cfg.ReferrerPolicy)
+	assert.Empty(t, cfg.PermissionsPolicy)
+	assert.Equal(t, "same-origin", cfg.CrossOriginOpenerPolicy)
+	assert.Empty(t, cfg.CrossOriginEmbedderPolicy)
+	assert.Equal(t, "same-origin", cfg.CrossOriginResourcePolicy)
+	assert.Nil(t, cfg.ContentSecurityPolicy)
+	assert.False(t, cfg.CSPReportOnly)
+}
+
+func TestOption(t *testing.T) {
+	csp := NewCSP().Set("default-src", SourceSelf)
+	cfg := newConfig(
+		WithHSTS(time.Hour, false, true),
+		WithTrustForwardedProto(false),
+		WithContentTypeNosniff(false),
+		WithFrameOptions("DENY"),
+		WithReferrerPolicy("same-origin"),
+		WithPermissionsPolicy("camera=()"),
+		WithCrossOriginOpenerPolicy("same-origin-allow-popups"),
+		WithCrossOriginEmbedderPolicy("require-corp"),
+		WithCrossOriginResourcePolicy("cross-origin"),
+		WithContentSecurityPolicy(csp, true),
+	)
+	assert.Equal(t, time.Hour, cfg.HSTSMaxAge)
+	assert.False(t, cfg.HSTSIncludeSubdomains)
+	assert.True(t, cfg.HSTSPreload)
+	assert.False(t, cfg.TrustForwardedProto)
+	assert.False(t, cfg.ContentTypeNosniff)
+	assert.Equal(t, "DENY", cfg.FrameOptions)
+	assert.Equal(t, "same-origin", cfg.ReferrerPolicy)
+	assert.Equal(t, "camera=()", cfg.PermissionsPolicy)
+	assert.Equal(t, "same-origin-allow-popups", cfg.CrossOriginOpenerPolicy)
+	assert.Equal(t, "require-corp", cfg.CrossOriginEmbedderPolicy)
+	assert.Equal(t, "cross-origin", cfg.CrossOriginResourcePolicy)
+	assert.Same(t, csp, cfg.ContentSecurityPolicy)
+	assert.True(t, cfg.CSPReportOnly)
+
+	// WithConfig 替换全部设置，其后的选项仍生效
+	cfg = newConfig(WithConfig(Config{FrameOptions: "DENY"}), WithReferrerPolicy("origin"))
+	assert.Equal(t, Config{FrameOptions: "DENY", ReferrerPolicy: "origin"}, *cfg)
+}
@@ -0,0 +1,152 @@
+package secure
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// KeyNonce 是当前请求的内容安全策略随机数在 RequestContext.Keys 中的键。
+const KeyNonce = "csp_nonce"
+
+// 随机数的字节数。
+const nonceLength = 16
+
+// 标头及其取值。
+type header struct {
+	key   string
+	value string
+}
+
+// New 返回一个设置安全响应标头的中间件。
+//
+// 标头在调用后续处理器前设置，处理器可覆盖其值；若响应被重置（如 AbortWithMsg），
+// 中间件会在处理器返回后补充缺失的标头。
+func New(opts ...Option) app.HandlerFunc {
+	cfg := newConfig(opts...)
+
+	var headers []header
+	add := func(key, value string) {
+		if value != "" {
+			headers = append(headers, header{key: key, value: value})
+		}
+	}
+	if cfg.ContentTypeNosniff {
+		add(consts.HeaderXContentTypeOptions, "nosniff")
+	}
+	add(consts.HeaderXFrameOptions, cfg.FrameOptions)
+	add(consts.HeaderReferrerPolicy, cfg.ReferrerPolicy)
+	add(consts.HeaderPermissionsPolicy, cfg.PermissionsPolicy)
+	add(consts.HeaderCrossOriginOpenerPolicy, cfg.CrossOriginOpenerPolicy)
+	add(consts.HeaderCrossOriginEmbedderPolicy, cfg.CrossOriginEmbedderPolicy)
+	add(consts.HeaderCrossOriginResourcePolicy, cfg.CrossOriginResourcePolicy)
+
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge.Seconds()), 10)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	var csp, cspKey string
+	var useNonce bool
+	if cfg.ContentSecurityPolicy != nil {
+		csp = cfg.ContentSecurityPolicy.String()
+		useNonce = strings.Contains(csp, SourceNonce)
+		cspKey = consts.HeaderContentSecurityPolicy
+		if cfg.CSPReportOnly {
+			cspKey = consts.HeaderContentSecurityPolicyReportOnly
+		}
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		set := func() {
+			h := &ctx.Response.Header
+			for _, kv := range headers {
+				if len(h.Peek(kv.key)) == 0 {
+					h.Set(kv.key, kv.value)
+				}
+			}
+			if hsts != "" && len(h.Peek(consts.HeaderStrictTransportSecurity)) == 0 && isHTTPS(ctx, cfg.TrustForwardedProto) {
+				h.Set(consts.HeaderStrictTransportSecurity, hsts)
+			}
+			if csp != "" && len(h.Peek(cspKey)) == 0 {
+				value := csp
+				if useNonce {
+					value = strings.ReplaceAll(csp, SourceNonce, "'nonce-"+GetNonce(ctx)+"'")
+				}
+				h.Set(cspKey, value)
+			}
+		}
+
+		if useNonce {
+			nonce, err := newNonce()
+			if err != nil {
+				hlog.SystemLogger().CtxErrorf(c, "生成内容安全策略随机数失败：%v", err)
+				ctx.AbortWithMsg(consts.StatusMessage(consts.StatusInternalServerError), consts.StatusInternalServerError)
+				return
+			}
+			ctx.Set(KeyNonce, nonce)
+		}
+
+		set()
+		ctx.Next(c)
+		set()
+	}
+}
+
+// GetNonce 返回当前请求的内容安全策略随机数，策略未使用 SourceNonce 时返回空串。
+func GetNonce(ctx *app.RequestContext) string {
+	return ctx.GetString(KeyNonce)
+}
+
+// FuncMap 返回模板函数，可通过 Engine.SetFuncMap 注册，须在加载模板前调用。
+//
+//   - cspNonce 返回当前请求的随机数，如 <script nonce="{{ cspNonce .ctx }}">。
+//
+// 函数的参数为当前请求的 *app.RequestContext，需由处理器传入模板数据中。
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"cspNonce": GetNonce,
+	}
+}
+
+// 判断是否为 HTTPS 请求。
+func isHTTPS(ctx *app.RequestContext, trustForwardedProto bool) bool {
+	if _, ok := ctx.GetConn().(network.ConnTLSer); ok {
+		return true
+	}
+	if bytes.Equal(ctx.Request.URI().Scheme(), []byte("https")) {
+		return true
+	}
+	if trustForwardedProto {
+		// 多级代理时取最初的协议
+		proto := ctx.Request.Header.Peek(consts.HeaderXForwardedProto)
+		if i := bytes.IndexByte(proto, ','); i >= 0 {
+			proto = proto[:i]
+		}
+		return bytes.EqualFold(bytes.TrimSpace(proto), []byte("https"))
+	}
+	return false
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, nonceLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
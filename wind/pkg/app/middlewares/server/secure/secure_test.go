@@ -0,0 +1,185 @@
+package secure
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"html/template"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/test/mock"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEngine(opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(opts...))
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, GetNonce(ctx))
+	})
+	engine.GET("/override", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set(consts.HeaderXFrameOptions, "DENY")
+	})
+	engine.GET("/abort", func(c context.Context, ctx *app.RequestContext) {
+		ctx.AbortWithMsg("forbidden", consts.StatusForbidden)
+	})
+	return engine
+}
+
+func TestDefault(t *testing.T) {
+	engine := newTestEngine()
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, "nosniff", string(resp.Header.Peek(consts.HeaderXContentTypeOptions)))
+	assert.Equal(t, "SAMEORIGIN", string(resp.Header.Peek(consts.HeaderXFrameOptions)))
+	assert.Equal(t, "no-referrer", string(resp.Header.Peek(consts.HeaderReferrerPolicy)))
+	assert.Equal(t, "same-origin", string(resp.Header.Peek(consts.HeaderCrossOriginOpenerPolicy)))
+	assert.Equal(t, "same-origin", string(resp.Header.Peek(consts.HeaderCrossOriginResourcePolicy)))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderCrossOriginEmbedderPolicy))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderPermissionsPolicy))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderContentSecurityPolicy))
+	// 非 HTTPS 请求不设置 HSTS
+	assert.Empty(t, resp.Header.Peek(consts.HeaderStrictTransportSecurity))
+	assert.Empty(t, resp.Body())
+
+	// 处理器可覆盖标头
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/override", nil).Result()
+	assert.Equal(t, "DENY", string(resp.Header.Peek(consts.HeaderXFrameOptions)))
+
+	// 响应被重置后补充标头
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/abort", nil).Result()
+	assert.Equal(t, consts.StatusForbidden, resp.StatusCode())
+	assert.Equal(t, "nosniff", string(resp.Header.Peek(consts.HeaderXContentTypeOptions)))
+	assert.Equal(t, "SAMEORIGIN", string(resp.Header.Peek(consts.HeaderXFrameOptions)))
+}
+
+func TestHSTS(t *testing.T) {
+	engine := newTestEngine(WithHSTS(time.Hour, true, true))
+	for proto, expected := range map[string]string{
+		"https":       "max-age=3600; includeSubDomains; preload",
+		"HTTPS":       "max-age=3600; includeSubDomains; preload",
+		"https, http": "max-age=3600; includeSubDomains; preload",
+		"http":        "",
+		"http, https": "",
+	} {
+		resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil,
+			ut.Header{Key: consts.HeaderXForwardedProto, Value: proto}).Result()
+		assert.Equal(t, expected, string(resp.Header.Peek(consts.HeaderStrictTransportSecurity)), proto)
+	}
+
+	engine = newTestEngine(WithHSTS(time.Hour, false, false), WithTrustForwardedProto(false))
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil,
+		ut.Header{Key: consts.HeaderXForwardedProto, Value: "https"}).Result()
+	assert.Empty(t, resp.Header.Peek(consts.HeaderStrictTransportSecurity))
+
+	// TLS 连接
+	h := New(WithHSTS(time.Hour, false, false), WithTrustForwardedProto(false))
+	ctx := app.NewContext(0)
+	ctx.SetConn(&tlsConn{Conn: mock.NewConn("")})
+	h(context.Background(), ctx)
+	assert.Equal(t, "max-age=3600", string(ctx.Response.Header.Peek(consts.HeaderStrictTransportSecurity)))
+
+	// 标记为 TLS 的请求，如 HTTP/2
+	ctx = app.NewContext(0)
+	ctx.Request.SetIsTLS(true)
+	ctx.Request.SetRequestURI("/")
+	h(context.Background(), ctx)
+	assert.Equal(t, "max-age=3600", string(ctx.Response.Header.Peek(consts.HeaderStrictTransportSecurity)))
+
+	ctx = app.NewContext(0)
+	ctx.SetConn(&tlsConn{Conn: mock.NewConn("")})
+	New(WithHSTS(0, true, true))(context.Background(), ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(consts.HeaderStrictTransportSecurity))
+}
+
+type tlsConn struct {
+	*mock.Conn
+}
+
+func (c *tlsConn) Handshake() error {
+	return nil
+}
+
+func (c *tlsConn) ConnectionState() tls.ConnectionState {
+	return tls.ConnectionState{}
+}
+
+func TestDisable(t *testing.T) {
+	engine := newTestEngine(WithConfig(Config{}), WithPermissionsPolicy("geolocation=(self)"))
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil,
+		ut.Header{Key: consts.HeaderXForwardedProto, Value: "https"}).Result()
+	for _, key := range []string{
+		consts.HeaderStrictTransportSecurity,
+		consts.HeaderXContentTypeOptions,
+		consts.HeaderXFrameOptions,
+		consts.HeaderReferrerPolicy,
+		consts.HeaderCrossOriginOpenerPolicy,
+		consts.HeaderCrossOriginEmbedderPolicy,
+		consts.HeaderCrossOriginResourcePolicy,
+		consts.HeaderContentSecurityPolicy,
+	} {
+		assert.Empty(t, resp.Header.Peek(key), key)
+	}
+	assert.Equal(t, "geolocation=(self)", string(resp.Header.Peek(consts.HeaderPermissionsPolicy)))
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	csp := NewCSP().Set("default-src", SourceSelf).Set("script-src", SourceSelf, SourceNonce)
+	engine := newTestEngine(WithContentSecurityPolicy(csp, false))
+	// 创建后修改策略不再生效
+	csp.Set("default-src", SourceNone)
+
+	re := regexp.MustCompile(`^default-src 'self'; script-src 'self' 'nonce-([A-Za-z0-9_-]{22})'$`)
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+		m := re.FindStringSubmatch(string(resp.Header.Peek(consts.HeaderContentSecurityPolicy)))
+		if assert.Len(t, m, 2) {
+			assert.Equal(t, m[1], string(resp.Body()))
+			nonces = append(nonces, m[1])
+		}
+		assert.Empty(t, resp.Header.Peek(consts.HeaderContentSecurityPolicyReportOnly))
+	}
+	assert.Len(t, nonces, 2)
+	assert.NotEqual(t, nonces[0], nonces[1])
+
+	// 响应被重置后以同一随机数补充
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/abort", nil).Result()
+	assert.Regexp(t, re, string(resp.Header.Peek(consts.HeaderContentSecurityPolicy)))
+
+	// 未使用随机数时不生成
+	engine = newTestEngine(WithContentSecurityPolicy(DefaultCSP(), true))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, DefaultCSP().String(), string(resp.Header.Peek(consts.HeaderContentSecurityPolicyReportOnly)))
+	assert.Empty(t, resp.Header.Peek(consts.HeaderContentSecurityPolicy))
+	assert.Empty(t, resp.Body())
+}
+
+func TestFuncMap(t *testing.T) {
+	csp := NewCSP().Set("script-src", SourceNonce)
+	tmpl := template.Must(template.New("index").Funcs(FuncMap()).
+		Parse(`<script nonce="{{ cspNonce .ctx }}"></script>`))
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithContentSecurityPolicy(csp, false)))
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, map[string]any{"ctx": ctx}); err != nil {
+			ctx.String(consts.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.Data(consts.StatusOK, consts.MIMETextHtml, buf.Bytes())
+	})
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	header := string(resp.Header.Peek(consts.HeaderContentSecurityPolicy))
+	nonce := header[len("script-src 'nonce-") : len(header)-1]
+	assert.Equal(t, `<script nonce="`+nonce+`"></script>`, string(resp.Body()))
+}
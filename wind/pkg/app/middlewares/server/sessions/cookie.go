@@ -0,0 +1,110 @@
+package sessions
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// 浏览器可保存的单个 Cookie 的最大字节数。
+const maxCookieSize = 4096
+
+// ErrCookieTooLarge 表示会话数据编码后超出单个 Cookie 的大小限制。
+var ErrCookieTooLarge = errors.New("sessions：会话数据超出 Cookie 的大小限制")
+
+// CookieStore 是将会话数据加密后保存在客户端 Cookie 中的存储，适用于多实例部署。
+//
+// 数据以 AES-256-GCM 加密并认证，客户端无法读取或篡改。过期时间一并加密，
+// 服务端据此拒绝过期的 Cookie；但在有效期内，删除或更换标识前的旧 Cookie 仍可被重放，
+// 对此敏感时应使用服务端存储。会话数据编码后不能超过约 4KB。
+type CookieStore struct {
+	aeads []cipher.AEAD
+	now   func() time.Time
+}
+
+// NewCookieStore 以一个或多个密钥创建 Cookie 存储，密钥应为至少 32 字节的随机值。
+//
+// 首个密钥用于加密，所有密钥均可用于解密，轮换密钥时将新密钥置于首位并保留旧密钥一段时间。
+func NewCookieStore(secret []byte, oldSecrets ...[]byte) *CookieStore {
+	s := &CookieStore{now: time.Now}
+	for _, key := range append([][]byte{secret}, oldSecrets...) {
+		if len(key) == 0 {
+			panic("sessions：密钥不能为空")
+		}
+		// 由密钥派生出固定长度的加密密钥
+		m := hmac.New(sha256.New, key)
+		m.Write([]byte("wind sessions cookie store"))
+		block, err := aes.NewCipher(m.Sum(nil))
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s
+}
+
+// Load 实现 Store 接口，无法解密或已过期的 Cookie 视为不存在。
+func (s *CookieStore) Load(_ context.Context, cookie string) (string, []byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil {
+		return "", nil, nil
+	}
+	for _, aead := range s.aeads {
+		n := aead.NonceSize()
+		if len(b) < n+aead.Overhead() {
+			continue
+		}
+		plain, err := aead.Open(nil, b[:n], b[n:], nil)
+		if err != nil {
+			continue
+		}
+		// 明文为 8 字节的过期时间、编码后的会话标识及会话数据
+		if len(plain) < 8+base64IDLength {
+			return "", nil, nil
+		}
+		if !s.now().Before(time.Unix(0, int64(binary.BigEndian.Uint64(plain)))) {
+			return "", nil, nil
+		}
+		return string(plain[8 : 8+base64IDLength]), plain[8+base64IDLength:], nil
+	}
+	return "", nil, nil
+}
+
+// Save 实现 Store 接口。
+func (s *CookieStore) Save(_ context.Context, id string, data []byte, maxAge time.Duration) (string, error) {
+	if !validID(id) {
+		return "", errors.New("sessions：无效的会话标识")
+	}
+	plain := make([]byte, 8, 8+len(id)+len(data))
+	binary.BigEndian.PutUint64(plain, uint64(expireAt(s.now(), maxAge).UnixNano()))
+	plain = append(append(plain, id...), data...)
+
+	aead := s.aeads[0]
+	out := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return "", err
+	}
+	out = aead.Seal(out, out, plain, nil)
+
+	cookie := base64.RawURLEncoding.EncodeToString(out)
+	if len(cookie) > maxCookieSize {
+		return "", ErrCookieTooLarge
+	}
+	return cookie, nil
+}
+
+// Delete 实现 Store 接口，数据随 Cookie 一同删除，无需处理。
+func (s *CookieStore) Delete(context.Context, string) error {
+	return nil
+}
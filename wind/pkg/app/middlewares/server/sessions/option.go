@@ -0,0 +1,83 @@
+package sessions
+
+import (
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// 默认的会话有效期。
+const defaultMaxAge = 30 * 24 * time.Hour
+
+// 表示一个会话的自定义选项结构体，即会话 Cookie 的属性。
+type options struct {
+	domain   string
+	path     string
+	maxAge   time.Duration
+	secure   bool
+	httpOnly bool
+	sameSite protocol.CookieSameSite
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义会话的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		path:     "/",
+		maxAge:   defaultMaxAge,
+		httpOnly: true,
+		sameSite: protocol.CookieSameSiteLaxMode,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithCookieDomain 设置会话 Cookie 的域名，默认为空，即仅限当前主机。
+func WithCookieDomain(domain string) Option {
+	return func(o *options) {
+		o.domain = domain
+	}
+}
+
+// WithCookiePath 设置会话 Cookie 的路径，默认为 "/"。
+func WithCookiePath(path string) Option {
+	return func(o *options) {
+		o.path = path
+	}
+}
+
+// WithCookieMaxAge 设置会话的有效期，默认为 30 天。
+//
+// 为零时为会话 Cookie，浏览器关闭后失效，服务端数据由存储自行决定保留时长。
+func WithCookieMaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = d
+	}
+}
+
+// WithCookieSecure 设置会话 Cookie 是否仅限 HTTPS 传输，默认为否。
+func WithCookieSecure(b bool) Option {
+	return func(o *options) {
+		o.secure = b
+	}
+}
+
+// WithCookieHTTPOnly 设置会话 Cookie 是否禁止脚本读取，默认为是。
+func WithCookieHTTPOnly(b bool) Option {
+	return func(o *options) {
+		o.httpOnly = b
+	}
+}
+
+// WithCookieSameSite 设置会话 Cookie 的 SameSite 属性，默认为 Lax。
+func WithCookieSameSite(mode protocol.CookieSameSite) Option {
+	return func(o *options) {
+		o.sameSite = mode
+	}
+}
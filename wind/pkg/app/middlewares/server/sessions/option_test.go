@@ -0,0 +1,38 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, &options{
+		path:     "/",
+		maxAge:   30 * 24 * time.Hour,
+		httpOnly: true,
+		sameSite: protocol.CookieSameSiteLaxMode,
+	}, opts)
+}
+
+func TestOption(t *testing.T) {
+	opts := newOptions(
+		WithCookieDomain("example.com"),
+		WithCookiePath("/app"),
+		WithCookieMaxAge(time.Hour),
+		WithCookieSecure(true),
+		WithCookieHTTPOnly(false),
+		WithCookieSameSite(protocol.CookieSameSiteStrictMode),
+	)
+	assert.Equal(t, &options{
+		domain:   "example.com",
+		path:     "/app",
+		maxAge:   time.Hour,
+		secure:   true,
+		httpOnly: false,
+		sameSite: protocol.CookieSameSiteStrictMode,
+	}, opts)
+}
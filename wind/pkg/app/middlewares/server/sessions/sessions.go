@@ -0,0 +1,282 @@
+package sessions
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// KeySession 是默认会话在 RequestContext.Keys 中的键。
+const KeySession = "github.com/favbox/gosky/wind/sessions"
+
+const (
+	// 默认的闪存消息键。
+	flashKey = "_flash"
+
+	// 会话标识的随机字节数。
+	idLength = 32
+)
+
+func init() {
+	// 闪存消息以 []any 保存
+	gob.Register([]any(nil))
+}
+
+// Session 表示一个客户端的会话。
+//
+// 会话数据以 encoding/gob 编码，存入自定义类型的值前须先经 gob.Register 注册。
+// 修改会话后须在写出响应前调用 Save。
+type Session interface {
+	// ID 返回会话标识，新会话在首次保存前即已分配。
+	ID() string
+
+	// IsNew 判断会话是否为本次请求新建的。
+	IsNew() bool
+
+	// Get 返回键对应的值，不存在时返回 nil。
+	Get(key string) any
+
+	// Set 设置键值。
+	Set(key string, val any)
+
+	// Delete 删除键值。
+	Delete(key string)
+
+	// Clear 删除所有键值。
+	Clear()
+
+	// AddFlash 添加一条闪存消息，vars 可指定消息的键。
+	AddFlash(value any, vars ...string)
+
+	// Flashes 返回并删除闪存消息，vars 可指定消息的键。
+	Flashes(vars ...string) []any
+
+	// RenewID 为会话分配新的标识并保留其数据，保存时删除旧标识的数据。
+	//
+	// 应在登录、注销或权限变更时调用，以防范会话固定攻击。
+	RenewID() error
+
+	// Destroy 销毁会话，保存时删除存储中的数据及客户端的 Cookie。
+	Destroy()
+
+	// Save 保存会话，会话未修改时不做任何操作。
+	Save() error
+}
+
+// New 返回一个名为 name 的会话中间件，会话数据保存在 store 中。
+//
+// 处理器可通过 Default 获取会话。会话在首次访问时才从存储中加载。
+func New(name string, store Store, opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		s := &session{name: name, store: store, opts: o, c: c, ctx: ctx}
+		ctx.Set(KeySession, s)
+		ctx.Next(c)
+	}
+}
+
+// Default 返回中间件为当前请求创建的会话，未注册中间件时引发恐慌。
+func Default(ctx *app.RequestContext) Session {
+	return ctx.MustGet(KeySession).(Session)
+}
+
+type session struct {
+	name  string
+	store Store
+	opts  *options
+	c     context.Context
+	ctx   *app.RequestContext
+
+	loaded    bool
+	isNew     bool
+	id        string
+	values    map[string]any
+	oldID     string
+	modified  bool
+	destroyed bool
+}
+
+// 按需从存储中加载会话，出错时记录日志并以新会话继续。
+func (s *session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	if v := s.ctx.Cookie(s.name); len(v) > 0 {
+		id, data, err := s.store.Load(s.c, string(v))
+		if err == nil && data != nil {
+			values := make(map[string]any)
+			if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err == nil {
+				s.id, s.values = id, values
+				return
+			}
+		}
+		if err != nil {
+			hlog.SystemLogger().CtxWarnf(s.c, "加载会话失败：%v", err)
+		}
+	}
+
+	s.isNew = true
+	s.values = make(map[string]any)
+	s.id, _ = newID()
+}
+
+func (s *session) ID() string {
+	s.load()
+	return s.id
+}
+
+func (s *session) IsNew() bool {
+	s.load()
+	return s.isNew
+}
+
+func (s *session) Get(key string) any {
+	s.load()
+	return s.values[key]
+}
+
+func (s *session) Set(key string, val any) {
+	s.load()
+	s.values[key] = val
+	s.modified = true
+}
+
+func (s *session) Delete(key string) {
+	s.load()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+func (s *session) Clear() {
+	s.load()
+	if len(s.values) > 0 {
+		s.values = make(map[string]any)
+		s.modified = true
+	}
+}
+
+func (s *session) AddFlash(value any, vars ...string) {
+	key := flashKey
+	if len(vars) > 0 {
+		key = vars[0]
+	}
+	s.load()
+	flashes, _ := s.values[key].([]any)
+	s.values[key] = append(flashes, value)
+	s.modified = true
+}
+
+func (s *session) Flashes(vars ...string) []any {
+	key := flashKey
+	if len(vars) > 0 {
+		key = vars[0]
+	}
+	s.load()
+	flashes, ok := s.values[key].([]any)
+	if ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+	return flashes
+}
+
+func (s *session) RenewID() error {
+	s.load()
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	// 多次更换时仅需删除最初的标识
+	if !s.isNew && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = id
+	s.modified = true
+	return nil
+}
+
+func (s *session) Destroy() {
+	s.load()
+	s.values = make(map[string]any)
+	s.destroyed = true
+	s.modified = true
+}
+
+func (s *session) Save() error {
+	if !s.modified {
+		return nil
+	}
+
+	if s.oldID != "" {
+		if err := s.store.Delete(s.c, s.oldID); err != nil {
+			return err
+		}
+		s.oldID = ""
+	}
+	if s.destroyed {
+		if !s.isNew {
+			if err := s.store.Delete(s.c, s.id); err != nil {
+				return err
+			}
+		}
+		s.setCookie("", -1)
+		s.modified = false
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.values); err != nil {
+		return err
+	}
+	value, err := s.store.Save(s.c, s.id, buf.Bytes(), s.opts.maxAge)
+	if err != nil {
+		return err
+	}
+	s.setCookie(value, s.opts.maxAge)
+	s.isNew = false
+	s.modified = false
+	return nil
+}
+
+// 写入会话 Cookie，maxAge 为负时删除。
+func (s *session) setCookie(value string, maxAge time.Duration) {
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+
+	cookie.SetKey(s.name)
+	cookie.SetValue(value)
+	cookie.SetDomain(s.opts.domain)
+	cookie.SetPath(s.opts.path)
+	switch {
+	case maxAge < 0:
+		cookie.SetExpire(protocol.CookieExpireDelete)
+	case maxAge > 0:
+		cookie.SetMaxAge(int(maxAge.Seconds()))
+		cookie.SetExpire(time.Now().Add(maxAge))
+	}
+	cookie.SetSecure(s.opts.secure)
+	cookie.SetHTTPOnly(s.opts.httpOnly)
+	cookie.SetSameSite(s.opts.sameSite)
+	s.ctx.Response.Header.SetCookie(cookie)
+}
+
+// 生成随机的会话标识。
+func newID() (string, error) {
+	b := make([]byte, idLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,257 @@
+package sessions
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name  string
+	Admin bool
+}
+
+func init() {
+	gob.Register(user{})
+}
+
+func newTestEngine(store Store, opts ...Option) *route.Engine {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New("session", store, opts...))
+	engine.GET("/count", func(c context.Context, ctx *app.RequestContext) {
+		s := Default(ctx)
+		n, _ := s.Get("count").(int)
+		s.Set("count", n+1)
+		if err := s.Save(); err != nil {
+			ctx.String(consts.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.String(consts.StatusOK, strconv.Itoa(n+1))
+	})
+	engine.GET("/peek", func(c context.Context, ctx *app.RequestContext) {
+		s := Default(ctx)
+		n, _ := s.Get("count").(int)
+		_ = s.Save()
+		ctx.String(consts.StatusOK, strconv.Itoa(n)+":"+strconv.FormatBool(s.IsNew()))
+	})
+	engine.POST("/login", func(c context.Context, ctx *app.RequestContext) {
+		s := Default(ctx)
+		if err := s.RenewID(); err != nil {
+			ctx.String(consts.StatusInternalServerError, err.Error())
+			return
+		}
+		s.Set("user", user{Name: "tom", Admin: true})
+		s.AddFlash("欢迎回来")
+		if err := s.Save(); err != nil {
+			ctx.String(consts.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.String(consts.StatusOK, s.ID())
+	})
+	engine.GET("/me", func(c context.Context, ctx *app.RequestContext) {
+		s := Default(ctx)
+		u, _ := s.Get("user").(user)
+		flashes := s.Flashes()
+		_ = s.Save()
+		msg := u.Name
+		for _, f := range flashes {
+			msg += "|" + f.(string)
+		}
+		ctx.String(consts.StatusOK, msg)
+	})
+	engine.POST("/logout", func(c context.Context, ctx *app.RequestContext) {
+		s := Default(ctx)
+		s.Destroy()
+		_ = s.Save()
+	})
+	return engine
+}
+
+// 模拟浏览器，保存并回传会话 Cookie。
+type browser struct {
+	t      *testing.T
+	engine *route.Engine
+	cookie string
+}
+
+func (b *browser) do(method, url string) *protocol.Response {
+	var headers []ut.Header
+	if b.cookie != "" {
+		headers = append(headers, ut.Header{Key: consts.HeaderCookie, Value: "session=" + b.cookie})
+	}
+	resp := ut.PerformRequest(b.engine, method, url, nil, headers...).Result()
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+	cookie.SetKey("session")
+	if resp.Header.Cookie(cookie) {
+		// 删除时 Cookie 值为空
+		b.cookie = string(cookie.Value())
+	}
+	return resp
+}
+
+func TestSessions(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": NewMemoryStore(),
+		"file": func() Store {
+			s, err := NewFileStore(t.TempDir())
+			assert.Nil(t, err)
+			return s
+		}(),
+		"cookie": NewCookieStore([]byte("0123456789abcdef0123456789abcdef")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			b := &browser{t: t, engine: newTestEngine(store)}
+			for i := 1; i <= 3; i++ {
+				resp := b.do(consts.MethodGet, "/count")
+				assert.Equal(t, strconv.Itoa(i), string(resp.Body()))
+			}
+			resp := b.do(consts.MethodGet, "/peek")
+			assert.Equal(t, "3:false", string(resp.Body()))
+			// 会话未修改时不写入 Cookie
+			assert.Empty(t, resp.Header.Peek(consts.HeaderSetCookie))
+
+			// 登录后更换标识，数据保留
+			resp = b.do(consts.MethodPost, "/login")
+			assert.Equal(t, consts.StatusOK, resp.StatusCode())
+			resp = b.do(consts.MethodGet, "/me")
+			assert.Equal(t, "tom|欢迎回来", string(resp.Body()))
+			// 闪存消息只读取一次
+			resp = b.do(consts.MethodGet, "/me")
+			assert.Equal(t, "tom", string(resp.Body()))
+			resp = b.do(consts.MethodGet, "/peek")
+			assert.Equal(t, "3:false", string(resp.Body()))
+
+			// 注销后删除 Cookie
+			b.do(consts.MethodPost, "/logout")
+			assert.Empty(t, b.cookie)
+			resp = b.do(consts.MethodGet, "/peek")
+			assert.Equal(t, "0:true", string(resp.Body()))
+
+			// 其他客户端的会话互不影响
+			other := &browser{t: t, engine: b.engine}
+			resp = other.do(consts.MethodGet, "/count")
+			assert.Equal(t, "1", string(resp.Body()))
+
+			// 无效的 Cookie 视为新会话
+			other.cookie = "invalid"
+			resp = other.do(consts.MethodGet, "/peek")
+			assert.Equal(t, "0:true", string(resp.Body()))
+		})
+	}
+}
+
+func TestRenewID(t *testing.T) {
+	store := NewMemoryStore()
+	b := &browser{t: t, engine: newTestEngine(store)}
+	b.do(consts.MethodGet, "/count")
+	oldID := b.cookie
+	_, data, _ := store.Load(context.Background(), oldID)
+	assert.NotNil(t, data)
+
+	resp := b.do(consts.MethodPost, "/login")
+	assert.Equal(t, b.cookie, string(resp.Body()))
+	assert.NotEqual(t, oldID, b.cookie)
+
+	// 旧标识的数据已删除，防止会话固定
+	_, data, _ = store.Load(context.Background(), oldID)
+	assert.Nil(t, data)
+	attacker := &browser{t: t, engine: b.engine, cookie: oldID}
+	resp = attacker.do(consts.MethodGet, "/me")
+	assert.Empty(t, string(resp.Body()))
+
+	resp = b.do(consts.MethodGet, "/peek")
+	assert.Equal(t, "1:false", string(resp.Body()))
+}
+
+func TestCookieAttributes(t *testing.T) {
+	engine := newTestEngine(NewMemoryStore(),
+		WithCookieDomain("example.com"),
+		WithCookiePath("/app"),
+		WithCookieMaxAge(time.Hour),
+		WithCookieSecure(true),
+		WithCookieSameSite(protocol.CookieSameSiteStrictMode),
+	)
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/count", nil).Result()
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+	cookie.SetKey("session")
+	assert.True(t, resp.Header.Cookie(cookie))
+	assert.Equal(t, "example.com", string(cookie.Domain()))
+	assert.Equal(t, "/app", string(cookie.Path()))
+	assert.Equal(t, 3600, cookie.MaxAge())
+	assert.True(t, cookie.Secure())
+	assert.True(t, cookie.HTTPOnly())
+	assert.Equal(t, protocol.CookieSameSiteStrictMode, cookie.SameSite())
+
+	// 会话 Cookie
+	engine = newTestEngine(NewMemoryStore(), WithCookieMaxAge(0), WithCookieHTTPOnly(false))
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/count", nil).Result()
+	cookie.Reset()
+	cookie.SetKey("session")
+	assert.True(t, resp.Header.Cookie(cookie))
+	assert.Zero(t, cookie.MaxAge())
+	assert.True(t, cookie.Expire().IsZero() || cookie.Expire().Equal(protocol.CookieExpireUnlimited))
+	assert.False(t, cookie.HTTPOnly())
+}
+
+// 总是出错的存储。
+type errStore struct{}
+
+func (errStore) Load(context.Context, string) (string, []byte, error) {
+	return "", nil, errors.New("load error")
+}
+
+func (errStore) Save(context.Context, string, []byte, time.Duration) (string, error) {
+	return "", errors.New("save error")
+}
+
+func (errStore) Delete(context.Context, string) error {
+	return errors.New("delete error")
+}
+
+func TestSessionOperations(t *testing.T) {
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetCookie("session", "x")
+	New("session", errStore{})(context.Background(), ctx)
+	s := Default(ctx)
+
+	// 加载失败时以新会话继续
+	assert.True(t, s.IsNew())
+	assert.Len(t, s.ID(), base64IDLength)
+	assert.Nil(t, s.Save())
+
+	s.Set("a", 1)
+	s.Set("b", []string{"x"})
+	assert.Equal(t, 1, s.Get("a"))
+	s.Delete("a")
+	s.Delete("missing")
+	assert.Nil(t, s.Get("a"))
+	s.Clear()
+	assert.Nil(t, s.Get("b"))
+
+	s.AddFlash("one")
+	s.AddFlash("two")
+	s.AddFlash("error", "errors")
+	assert.Equal(t, []any{"one", "two"}, s.Flashes())
+	assert.Nil(t, s.Flashes())
+	assert.Equal(t, []any{"error"}, s.Flashes("errors"))
+
+	id := s.ID()
+	assert.Nil(t, s.RenewID())
+	assert.NotEqual(t, id, s.ID())
+	assert.Equal(t, "save error", s.Save().Error())
+
+	assert.Panics(t, func() { Default(app.NewContext(0)) })
+}
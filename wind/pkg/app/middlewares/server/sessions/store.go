@@ -0,0 +1,225 @@
+package sessions
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 会话 Cookie 无有效期时，存储保留会话数据的时长。
+	defaultTTL = 24 * time.Hour
+
+	// 内存存储写入多少次后清理一次过期的会话。
+	sweepInterval = 1024
+
+	// 文件存储中会话文件名的前缀。
+	filePrefix = "session_"
+)
+
+// Store 保存会话数据。
+type Store interface {
+	// Load 返回 Cookie 值对应的会话标识与数据，会话不存在、已过期或无效时 data 为 nil。
+	Load(c context.Context, cookie string) (id string, data []byte, err error)
+
+	// Save 保存会话数据并在 maxAge 后过期，返回写入 Cookie 的值。
+	//
+	// maxAge 为零时由存储自行决定保留时长。
+	Save(c context.Context, id string, data []byte, maxAge time.Duration) (cookie string, err error)
+
+	// Delete 删除会话数据，会话不存在时不返回错误。
+	Delete(c context.Context, id string) error
+}
+
+// 计算过期时间。
+func expireAt(now time.Time, maxAge time.Duration) time.Time {
+	if maxAge <= 0 {
+		maxAge = defaultTTL
+	}
+	return now.Add(maxAge)
+}
+
+// 校验会话标识，以免被构造为文件路径等。
+func validID(id string) bool {
+	if len(id) != base64IDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// 编码后的会话标识长度。
+const base64IDLength = (idLength*8 + 5) / 6
+
+// MemoryStore 是将会话数据保存在内存中的存储，适用于单实例部署。
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	writes   int
+	now      func() time.Time
+}
+
+type memorySession struct {
+	data     []byte
+	expireAt time.Time
+}
+
+// NewMemoryStore 创建一个内存存储。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession), now: time.Now}
+}
+
+// Load 实现 Store 接口。
+func (s *MemoryStore) Load(_ context.Context, cookie string) (string, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ms, ok := s.sessions[cookie]; ok && s.now().Before(ms.expireAt) {
+		return cookie, ms.data, nil
+	}
+	return "", nil, nil
+}
+
+// Save 实现 Store 接口。
+func (s *MemoryStore) Save(_ context.Context, id string, data []byte, maxAge time.Duration) (string, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = memorySession{data: append([]byte(nil), data...), expireAt: expireAt(now, maxAge)}
+
+	if s.writes++; s.writes >= sweepInterval {
+		s.writes = 0
+		for k, ms := range s.sessions {
+			if !now.Before(ms.expireAt) {
+				delete(s.sessions, k)
+			}
+		}
+	}
+	return id, nil
+}
+
+// Delete 实现 Store 接口。
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// FileStore 是将每个会话保存为一个文件的存储，适用于单实例部署。
+//
+// 过期的会话文件在加载时删除，也可定期调用 Cleanup 清理。
+type FileStore struct {
+	dir string
+	now func() time.Time
+}
+
+// NewFileStore 创建一个将会话保存在 dir 目录下的文件存储，目录不存在时自动创建。
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, now: time.Now}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, filePrefix+id)
+}
+
+// Load 实现 Store 接口。
+func (s *FileStore) Load(_ context.Context, cookie string) (string, []byte, error) {
+	if !validID(cookie) {
+		return "", nil, nil
+	}
+	b, err := os.ReadFile(s.path(cookie))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return "", nil, err
+	}
+	// 文件内容为 8 字节的过期时间及会话数据
+	if len(b) < 8 {
+		return "", nil, nil
+	}
+	if !s.now().Before(time.Unix(0, int64(binary.BigEndian.Uint64(b)))) {
+		_ = os.Remove(s.path(cookie))
+		return "", nil, nil
+	}
+	return cookie, b[8:], nil
+}
+
+// Save 实现 Store 接口。
+func (s *FileStore) Save(_ context.Context, id string, data []byte, maxAge time.Duration) (string, error) {
+	if !validID(id) {
+		return "", errors.New("sessions：无效的会话标识")
+	}
+	b := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(b, uint64(expireAt(s.now(), maxAge).UnixNano()))
+	copy(b[8:], data)
+
+	// 先写入临时文件再重命名，避免并发读取到不完整的内容
+	f, err := os.CreateTemp(s.dir, ".tmp_")
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(id))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return id, nil
+}
+
+// Delete 实现 Store 接口。
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	if !validID(id) {
+		return nil
+	}
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Cleanup 删除过期的会话文件。
+func (s *FileStore) Cleanup() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) {
+			continue
+		}
+		b := make([]byte, 8)
+		f, err := os.Open(filepath.Join(s.dir, name))
+		if err != nil {
+			continue
+		}
+		n, _ := f.Read(b)
+		_ = f.Close()
+		if n < 8 || !now.Before(time.Unix(0, int64(binary.BigEndian.Uint64(b)))) {
+			_ = os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package sessions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testID(t *testing.T) string {
+	id, err := newID()
+	assert.Nil(t, err)
+	assert.True(t, validID(id))
+	return id
+}
+
+// 各存储通用的行为。
+func testStore(t *testing.T, s Store, advance func(d time.Duration)) {
+	c := context.Background()
+	id := testID(t)
+
+	cookie, err := s.Save(c, id, []byte("data"), time.Hour)
+	assert.Nil(t, err)
+	gotID, data, err := s.Load(c, cookie)
+	assert.Nil(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, []byte("data"), data)
+
+	// 覆盖保存
+	cookie, err = s.Save(c, id, []byte("updated"), time.Hour)
+	assert.Nil(t, err)
+	_, data, _ = s.Load(c, cookie)
+	assert.Equal(t, []byte("updated"), data)
+
+	// 不存在或无效的 Cookie
+	for _, v := range []string{"", "missing", testID(t), "../../etc/passwd", strings.Repeat("!", 43)} {
+		_, data, err = s.Load(c, v)
+		assert.Nil(t, err, v)
+		assert.Nil(t, data, v)
+	}
+
+	// 过期
+	advance(time.Hour)
+	_, data, err = s.Load(c, cookie)
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+
+	// 未指定有效期时使用默认的保留时长
+	cookie, err = s.Save(c, id, []byte("data"), 0)
+	assert.Nil(t, err)
+	advance(defaultTTL - time.Second)
+	_, data, _ = s.Load(c, cookie)
+	assert.Equal(t, []byte("data"), data)
+	advance(time.Second)
+	_, data, _ = s.Load(c, cookie)
+	assert.Nil(t, data)
+
+	assert.Nil(t, s.Delete(c, testID(t)))
+	assert.Nil(t, s.Delete(c, "../invalid"))
+	_, err = s.Save(c, "../invalid", nil, time.Hour)
+	if _, ok := s.(*MemoryStore); !ok {
+		assert.NotNil(t, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	testStore(t, s, func(d time.Duration) { now = now.Add(d) })
+
+	c := context.Background()
+	id := testID(t)
+	data := []byte("data")
+	_, err := s.Save(c, id, data, time.Hour)
+	assert.Nil(t, err)
+	data[0] = 'D'
+	_, got, _ := s.Load(c, id)
+	assert.Equal(t, []byte("data"), got)
+	assert.Nil(t, s.Delete(c, id))
+	_, got, _ = s.Load(c, id)
+	assert.Nil(t, got)
+
+	// 定期清理过期的会话
+	s = NewMemoryStore()
+	now = time.Now()
+	s.now = func() time.Time { return now }
+	for i := 0; i < sweepInterval-1; i++ {
+		_, err = s.Save(c, testID(t), nil, time.Second)
+		assert.Nil(t, err)
+	}
+	now = now.Add(time.Second)
+	_, err = s.Save(c, id, nil, time.Hour)
+	assert.Nil(t, err)
+	assert.Len(t, s.sessions, 1)
+}
+
+func TestFileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	s, err := NewFileStore(dir)
+	assert.Nil(t, err)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	testStore(t, s, func(d time.Duration) { now = now.Add(d) })
+
+	c := context.Background()
+	id := testID(t)
+	_, err = s.Save(c, id, []byte("data"), time.Hour)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "session_"+id))
+	assert.Nil(t, err)
+	assert.Nil(t, s.Delete(c, id))
+	_, err = os.Stat(filepath.Join(dir, "session_"+id))
+	assert.True(t, os.IsNotExist(err))
+
+	// 清理过期及损坏的会话文件
+	expired, valid := testID(t), testID(t)
+	_, err = s.Save(c, expired, nil, time.Minute)
+	assert.Nil(t, err)
+	_, err = s.Save(c, valid, nil, time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "session_"+testID(t)), []byte("x"), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "other"), []byte("x"), 0o600))
+	now = now.Add(time.Minute)
+	assert.Nil(t, s.Cleanup())
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"other", "session_" + valid}, names)
+
+	_, err = NewFileStore(filepath.Join(dir, "other", "sub"))
+	assert.NotNil(t, err)
+}
+
+func TestCookieStore(t *testing.T) {
+	s := NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	testStore(t, s, func(d time.Duration) { now = now.Add(d) })
+
+	c := context.Background()
+	id := testID(t)
+	cookie, err := s.Save(c, id, []byte("secret data"), time.Hour)
+	assert.Nil(t, err)
+	assert.NotContains(t, cookie, id)
+
+	// 每次加密的结果均不同
+	other, err := s.Save(c, id, []byte("secret data"), time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, cookie, other)
+
+	// 篡改
+	b := []byte(cookie)
+	b[len(b)/2] ^= 1
+	_, data, err := s.Load(c, string(b))
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+
+	// 密钥轮换
+	rotated := NewCookieStore([]byte("new secret"), []byte("0123456789abcdef0123456789abcdef"))
+	rotated.now = s.now
+	gotID, data, err := rotated.Load(c, cookie)
+	assert.Nil(t, err)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, []byte("secret data"), data)
+	cookie, err = rotated.Save(c, id, data, time.Hour)
+	assert.Nil(t, err)
+	_, data, _ = s.Load(c, cookie)
+	assert.Nil(t, data)
+
+	_, err = s.Save(c, id, make([]byte, maxCookieSize), time.Hour)
+	assert.Equal(t, ErrCookieTooLarge, err)
+
+	assert.Panics(t, func() { NewCookieStore(nil) })
+	assert.Panics(t, func() { NewCookieStore([]byte("secret"), []byte{}) })
+}
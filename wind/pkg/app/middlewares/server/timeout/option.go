@@ -0,0 +1,76 @@
+package timeout
+
+import (
+	"context"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 默认的处理超时时间。
+const defaultTimeout = 10 * time.Second
+
+// 表示一个处理超时的自定义选项结构体。
+type options struct {
+	// 处理链的超时时间。
+	timeout time.Duration
+
+	// 按路由覆盖的超时时间，不大于零表示不限。
+	routes map[string]time.Duration
+
+	// 超时时的处理器。
+	timeoutHandler func(c context.Context, ctx *app.RequestContext)
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 默认的超时处理器，以 503 响应。
+func defaultTimeoutHandler(c context.Context, ctx *app.RequestContext) {
+	ctx.String(consts.StatusServiceUnavailable, consts.StatusMessage(consts.StatusServiceUnavailable))
+}
+
+// 创建一个自定义处理超时的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		timeout:        defaultTimeout,
+		routes:         make(map[string]time.Duration),
+		timeoutHandler: defaultTimeoutHandler,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithTimeout 设置处理链的超时时间，默认 10 秒，不大于零表示不限。
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRouteTimeout 为注册的路由（如 /user/:name）单独设置超时时间，不大于零表示不限。
+//
+// 长轮询、事件流、WebSocket 等长时间占用连接的路由应设置为不限。
+func WithRouteTimeout(fullPath string, d time.Duration) Option {
+	return func(o *options) {
+		o.routes[fullPath] = d
+	}
+}
+
+// WithTimeoutHandler 自定义超时时的处理器，默认以 503 响应。处理器返回后请求即被中止。
+//
+// 如需以 504 响应：
+//
+//	timeout.WithTimeoutHandler(func(c context.Context, ctx *app.RequestContext) {
+//		ctx.String(consts.StatusGatewayTimeout, "处理超时")
+//	})
+func WithTimeoutHandler(f func(c context.Context, ctx *app.RequestContext)) Option {
+	return func(o *options) {
+		o.timeoutHandler = f
+	}
+}
@@ -0,0 +1,31 @@
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, defaultTimeout, opts.timeout)
+	assert.Empty(t, opts.routes)
+	assert.Equal(t, fmt.Sprintf("%p", defaultTimeoutHandler), fmt.Sprintf("%p", opts.timeoutHandler))
+}
+
+func TestOption(t *testing.T) {
+	handler := func(c context.Context, ctx *app.RequestContext) {}
+	opts := newOptions(
+		WithTimeout(time.Second),
+		WithRouteTimeout("/upload", time.Minute),
+		WithRouteTimeout("/events", 0),
+		WithTimeoutHandler(handler),
+	)
+	assert.Equal(t, time.Second, opts.timeout)
+	assert.Equal(t, map[string]time.Duration{"/upload": time.Minute, "/events": 0}, opts.routes)
+	assert.Equal(t, fmt.Sprintf("%p", handler), fmt.Sprintf("%p", opts.timeoutHandler))
+}
@@ -0,0 +1,101 @@
+package timeout
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 处理链中的恐慌，需在请求协程中重新引发。
+type panicError struct {
+	value any
+}
+
+// New 返回一个处理超时中间件。
+//
+// 中间件为后续处理链派生带截止时间的 context.Context，并在副本（RequestContext.Copy）上
+// 以新协程执行后续处理链。处理链按时完成时，将副本的响应、键值对和错误合并回原上下文；
+// 超时则由超时处理器写入响应并立即返回，此后处理链仍在副本上运行直至结束，
+// 但不会再触及原上下文，因此原上下文可被安全地放回池中复用。
+//
+// 处理器应关注 c.Done() 以便及时退出。流式请求正文将先被完整读取。
+// 处理链中的恐慌会在请求协程中重新引发，以便由外层的恢复中间件处理。
+//
+// 流式响应、劫持连接（如 SSE、WebSocket）的路由应通过 WithRouteTimeout(path, 0) 排除。
+func New(opts ...Option) app.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c context.Context, ctx *app.RequestContext) {
+		d := o.timeout
+		if rd, ok := o.routes[ctx.FullPath()]; ok {
+			d = rd
+		}
+		if d <= 0 {
+			ctx.Next(c)
+			return
+		}
+
+		// 流式正文与连接绑定，不能在请求结束后继续读取
+		if ctx.Request.IsBodyStream() {
+			if _, err := ctx.Request.BodyE(); err != nil {
+				ctx.AbortWithMsg(err.Error(), consts.StatusBadRequest)
+				return
+			}
+		}
+
+		tc, cancel := context.WithTimeout(c, d)
+		defer cancel()
+
+		cp := ctx.Copy()
+		cp.SetHandlers(ctx.Handlers()[ctx.GetIndex()+1:])
+		cp.SetIndex(-1)
+
+		done := make(chan *panicError, 1)
+		go func() {
+			defer func() {
+				if v := recover(); v != nil {
+					done <- &panicError{value: v}
+					return
+				}
+				done <- nil
+			}()
+			cp.Next(tc)
+		}()
+
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p.value)
+			}
+			merge(ctx, cp)
+		case <-tc.Done():
+			o.timeoutHandler(c, ctx)
+			ctx.Abort()
+		}
+	}
+}
+
+// 将副本的处理结果合并回原上下文，并跳过原上下文中已在副本上执行过的处理器。
+func merge(ctx, cp *app.RequestContext) {
+	cp.Response.CopyTo(&ctx.Response)
+	if cp.Response.IsBodyStream() {
+		ctx.Response.SetBodyStream(cp.Response.BodyStream(), cp.Response.Header.ContentLength())
+	}
+	if w := cp.Response.GetHijackWriter(); w != nil {
+		ctx.Response.HijackWriter(w)
+	}
+	if h := cp.GetHijackHandler(); h != nil {
+		ctx.SetHijackHandler(h)
+	}
+	for k, v := range cp.Keys {
+		ctx.Set(k, v)
+	}
+	ctx.Errors = append(ctx.Errors, cp.Errors...)
+
+	if cp.IsAborted() {
+		ctx.Abort()
+	} else {
+		ctx.SetIndex(int8(len(ctx.Handlers())))
+	}
+}
@@ -0,0 +1,162 @@
+package timeout
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// 阻塞至上下文结束后再写入响应的处理器。
+func slow(finished chan<- struct{}) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		<-c.Done()
+		ctx.String(consts.StatusOK, "late")
+		ctx.Set("late", true)
+		close(finished)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	var after []string
+	engine.Use(func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set("X-Outer", "1")
+		ctx.Next(c)
+		after = append(after, ctx.GetString("user"), ctx.Errors.String())
+	}, New(WithTimeout(50*time.Millisecond)))
+	engine.GET("/fast", func(c context.Context, ctx *app.RequestContext) {
+		_, ok := c.Deadline()
+		assert.True(t, ok)
+		ctx.Set("user", "tom")
+		ctx.Errors = append(ctx.Errors, &errors.Error{Err: stderrors.New("warn")})
+		ctx.Next(c)
+	}, func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusCreated, "ok")
+	})
+	finished := make(chan struct{})
+	engine.GET("/slow", slow(finished))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/fast", nil).Result()
+	assert.Equal(t, consts.StatusCreated, resp.StatusCode())
+	assert.Equal(t, "ok", string(resp.Body()))
+	assert.Equal(t, "1", string(resp.Header.Peek("X-Outer")))
+	assert.Equal(t, []string{"tom", "Error #01: warn\n"}, after)
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/slow", nil).Result()
+	assert.Equal(t, consts.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, "Service Unavailable", string(resp.Body()))
+	assert.Equal(t, "1", string(resp.Header.Peek("X-Outer")))
+	<-finished
+}
+
+// 超时后处理器继续运行，但不影响已被复用的原上下文。
+func TestTimeoutContextReuse(t *testing.T) {
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	ctx := app.NewContext(0)
+	ctx.SetHandlers(app.HandlersChain{New(WithTimeout(10 * time.Millisecond)), func(c context.Context, ctx *app.RequestContext) {
+		<-release
+		ctx.String(consts.StatusOK, "late")
+		ctx.Set("late", true)
+		close(finished)
+	}})
+	ctx.Next(context.Background())
+	assert.Equal(t, consts.StatusServiceUnavailable, ctx.Response.StatusCode())
+
+	// 模拟放回池中并用于下一个请求
+	ctx.Reset()
+	ctx.Response.SetStatusCode(consts.StatusAccepted)
+	close(release)
+	<-finished
+	assert.Equal(t, consts.StatusAccepted, ctx.Response.StatusCode())
+	_, exists := ctx.Get("late")
+	assert.False(t, exists)
+}
+
+func TestTimeoutRoute(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(
+		WithTimeout(10*time.Millisecond),
+		WithRouteTimeout("/long/:id", time.Second),
+		WithRouteTimeout("/events", 0),
+	))
+	sleep := func(c context.Context, ctx *app.RequestContext) {
+		time.Sleep(30 * time.Millisecond)
+		_, ok := c.Deadline()
+		ctx.SetStatusCode(consts.StatusOK)
+		ctx.Response.Header.Set("X-Deadline", map[bool]string{true: "yes", false: "no"}[ok])
+	}
+	engine.GET("/long/:id", sleep)
+	engine.GET("/events", sleep)
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/long/1", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "yes", string(resp.Header.Peek("X-Deadline")))
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/events", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "no", string(resp.Header.Peek("X-Deadline")))
+}
+
+func TestTimeoutHandler(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(New(WithTimeout(10*time.Millisecond), WithTimeoutHandler(func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusGatewayTimeout, "处理超时")
+	})))
+	finished := make(chan struct{})
+	engine.GET("/slow", slow(finished))
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/slow", nil).Result()
+	assert.Equal(t, consts.StatusGatewayTimeout, resp.StatusCode())
+	assert.Equal(t, "处理超时", string(resp.Body()))
+	<-finished
+}
+
+func TestTimeoutAbort(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	var reached bool
+	engine.Use(New(WithTimeout(time.Second)))
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		ctx.AbortWithStatus(consts.StatusUnauthorized)
+	}, func(c context.Context, ctx *app.RequestContext) {
+		reached = true
+	})
+
+	resp := ut.PerformRequest(engine, consts.MethodGet, "/", nil).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+	assert.False(t, reached)
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	ctx := app.NewContext(0)
+	ctx.SetHandlers(app.HandlersChain{New(), func(c context.Context, ctx *app.RequestContext) {
+		panic("boom")
+	}})
+	assert.PanicsWithValue(t, "boom", func() { ctx.Next(context.Background()) })
+}
+
+func TestTimeoutBodyStream(t *testing.T) {
+	ctx := app.NewContext(0)
+	ctx.Request.SetBodyStream(strings.NewReader("request"), -1)
+	ctx.SetHandlers(app.HandlersChain{New(), func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetBodyStream(bytes.NewReader(append(ctx.Request.Body(), "-response"...)), -1)
+	}})
+	ctx.Next(context.Background())
+
+	assert.True(t, ctx.Response.IsBodyStream())
+	body, err := io.ReadAll(ctx.Response.BodyStream())
+	assert.Nil(t, err)
+	assert.Equal(t, "request-response", string(body))
+}
@@ -0,0 +1,442 @@
+package binding
+
+import (
+	"bytes"
+	stdJson "encoding/json"
+	"errors"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	errs "github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/common/json"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	jsonContentType     = []byte("application/json")
+	protobufContentType = []byte("application/x-protobuf")
+
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// 除正文外的参数来源，按绑定优先级排列。
+var paramSources = []string{SourcePath, SourceForm, SourceQuery, SourceCookie, SourceHeader}
+
+type defaultBinder struct {
+	config *BindConfig
+	cache  sync.Map // reflect.Type -> *structInfo
+}
+
+// NewDefaultBinder 创建给定配置的默认绑定器，config 为 nil 时使用 NewBindConfig。
+//
+// 绑定顺序：
+//   - 先按 Content-Type 将 JSON 或 Protobuf 正文解码到整个结构体；
+//   - 再依次按 path > form > query > cookie > header 标签绑定字段，后者仅在前者缺失时生效；
+//   - 最后对仍为零值的字段应用 default 标签，并检查标记了 required 的字段。
+//
+// 标签形如 `query:"name,required"`，键名为空时使用字段名；未声明任何标签的字段按字段名从 form 和 query 绑定。
+// 未声明参数标签的结构体字段会被递归绑定；声明了参数标签的结构体、映射字段则将参数值按 JSON 解码。
+func NewDefaultBinder(config *BindConfig) Binder {
+	if config == nil {
+		config = NewBindConfig()
+	}
+	if config.TimeLayout == "" {
+		config.TimeLayout = NewBindConfig().TimeLayout
+	}
+	return &defaultBinder{config: config}
+}
+
+func (b *defaultBinder) Name() string {
+	return "wind"
+}
+
+func (b *defaultBinder) Bind(req *protocol.Request, v any, params PathParam) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errInvalidTarget
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return errInvalidTarget
+	}
+
+	if err := b.bindBody(req, v); err != nil {
+		return err
+	}
+
+	s := &bindState{req: req, params: params}
+	_, err := b.bindStruct(s, rv, b.structInfo(rv.Type()))
+	return err
+}
+
+// 按 Content-Type 解码请求正文。
+func (b *defaultBinder) bindBody(req *protocol.Request, v any) error {
+	ct := req.Header.ContentType()
+	switch {
+	case bytes.HasPrefix(ct, jsonContentType):
+		body := req.Body()
+		if len(body) == 0 {
+			return nil
+		}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		if b.config.EnableDecoderUseNumber {
+			dec.UseNumber()
+		}
+		if b.config.EnableDecoderDisallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(v); err != nil {
+			be := &BindError{Source: SourceJSON, Err: err}
+			var te *stdJson.UnmarshalTypeError
+			if errors.As(err, &te) {
+				be.Field, be.Key = te.Field, te.Field
+			}
+			return be
+		}
+	case bytes.HasPrefix(ct, protobufContentType):
+		body := req.Body()
+		if len(body) == 0 {
+			return nil
+		}
+		msg, ok := v.(proto.Message)
+		if !ok {
+			return &BindError{Source: SourceProtobuf, Err: errors.New("绑定目标未实现 proto.Message 接口")}
+		}
+		if err := proto.Unmarshal(body, msg); err != nil {
+			return &BindError{Source: SourceProtobuf, Err: err}
+		}
+	}
+	return nil
+}
+
+// 单次绑定的状态，缓存已解析的多部分表单。
+type bindState struct {
+	req       *protocol.Request
+	params    PathParam
+	form      *multipart.Form
+	formErr   error
+	formReady bool
+	binding   map[reflect.Type]bool // 绑定路径上的结构体类型
+}
+
+func (s *bindState) multipartForm() (*multipart.Form, error) {
+	if !s.formReady {
+		s.formReady = true
+		if len(s.req.Header.MultipartFormBoundary()) > 0 {
+			s.form, s.formErr = s.req.MultipartForm()
+			if errors.Is(s.formErr, errs.ErrNoMultipartForm) {
+				s.formErr = nil
+			}
+		}
+	}
+	return s.form, s.formErr
+}
+
+// 从给定来源查找参数值。
+func (s *bindState) lookup(source, key string) ([]string, error) {
+	switch source {
+	case SourcePath:
+		if s.params == nil {
+			return nil, nil
+		}
+		if v, ok := s.params.Get(key); ok {
+			return []string{v}, nil
+		}
+	case SourceForm:
+		if vs := toStrings(s.req.PostArgs().PeekAll(key)); len(vs) > 0 {
+			return vs, nil
+		}
+		mf, err := s.multipartForm()
+		if err != nil {
+			return nil, err
+		}
+		if mf != nil && len(mf.Value[key]) > 0 {
+			return mf.Value[key], nil
+		}
+	case SourceQuery:
+		return toStrings(s.req.URI().QueryArgs().PeekAll(key)), nil
+	case SourceCookie:
+		if v := s.req.Header.Cookie(key); len(v) > 0 {
+			return []string{string(v)}, nil
+		}
+	case SourceHeader:
+		return toStrings(s.req.Header.PeekAll(key)), nil
+	}
+	return nil, nil
+}
+
+func toStrings(bs [][]byte) []string {
+	if len(bs) == 0 {
+		return nil
+	}
+	ss := make([]string, len(bs))
+	for i, b := range bs {
+		ss[i] = string(b)
+	}
+	return ss
+}
+
+type tagSource struct {
+	source string
+	key    string
+}
+
+type fieldInfo struct {
+	index    int
+	name     string
+	sources  []tagSource
+	defVal   string
+	hasDef   bool
+	required bool
+	isFile   bool
+	nested   *structInfo // 未声明参数标签的嵌套结构体
+}
+
+type structInfo struct {
+	fields []*fieldInfo
+}
+
+func (b *defaultBinder) structInfo(t reflect.Type) *structInfo {
+	if v, ok := b.cache.Load(t); ok {
+		return v.(*structInfo)
+	}
+	si := b.parseStruct(t, map[reflect.Type]*structInfo{})
+	v, _ := b.cache.LoadOrStore(t, si)
+	return v.(*structInfo)
+}
+
+func (b *defaultBinder) parseStruct(t reflect.Type, visiting map[reflect.Type]*structInfo) *structInfo {
+	if si, ok := visiting[t]; ok {
+		return si
+	}
+	si := &structInfo{}
+	visiting[t] = si
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		fi := &fieldInfo{index: i, name: sf.Name}
+		var required, hasParamTag, hasBodyTag bool
+		for _, source := range paramSources {
+			tag, ok := sf.Tag.Lookup(source)
+			if !ok {
+				continue
+			}
+			hasParamTag = true
+			key, opts := parseTag(tag, sf.Name)
+			if key == "-" {
+				continue
+			}
+			fi.sources = append(fi.sources, tagSource{source: source, key: key})
+			required = required || opts == "required"
+		}
+		for _, source := range []string{SourceJSON, SourceProtobuf} {
+			if tag, ok := sf.Tag.Lookup(source); ok {
+				hasBodyTag = true
+				if source == SourceJSON {
+					_, opts := parseTag(tag, sf.Name)
+					required = required || opts == "required"
+				}
+			}
+		}
+		fi.defVal, fi.hasDef = sf.Tag.Lookup(SourceDefault)
+		fi.required = required
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if !hasParamTag && !hasBodyTag && isNestedStruct(ft) {
+			fi.nested = b.parseStruct(ft, visiting)
+			si.fields = append(si.fields, fi)
+			continue
+		}
+		if !hasParamTag && !hasBodyTag {
+			fi.sources = []tagSource{{SourceForm, sf.Name}, {SourceQuery, sf.Name}}
+		}
+		fi.isFile = isFileHeaderType(sf.Type)
+		if len(fi.sources) == 0 && !fi.hasDef && !fi.required {
+			continue
+		}
+		si.fields = append(si.fields, fi)
+	}
+	return si
+}
+
+// 解析 `key,opts` 形式的标签。
+func parseTag(tag, fieldName string) (key, opts string) {
+	key, opts, _ = strings.Cut(tag, ",")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		key = fieldName
+	}
+	return key, strings.TrimSpace(opts)
+}
+
+// 绑定结构体字段，返回是否有字段被赋值。
+func (b *defaultBinder) bindStruct(s *bindState, rv reflect.Value, si *structInfo) (bool, error) {
+	t := rv.Type()
+	if s.binding == nil {
+		s.binding = make(map[reflect.Type]bool)
+	}
+	if !s.binding[t] {
+		s.binding[t] = true
+		defer delete(s.binding, t)
+	}
+
+	var bound bool
+	for _, fi := range si.fields {
+		fv := rv.Field(fi.index)
+		ok, err := b.bindField(s, fv, fi)
+		if err != nil {
+			return bound, err
+		}
+		bound = bound || ok
+	}
+	return bound, nil
+}
+
+func (b *defaultBinder) bindField(s *bindState, fv reflect.Value, fi *fieldInfo) (bool, error) {
+	if fi.nested != nil {
+		return b.bindNested(s, fv, fi)
+	}
+
+	for _, ts := range fi.sources {
+		if fi.isFile {
+			if ts.source != SourceForm {
+				continue
+			}
+			ok, err := b.bindFile(s, fv, ts.key)
+			if err != nil {
+				return false, &BindError{Field: fi.name, Source: ts.source, Key: ts.key, Err: err}
+			}
+			if ok {
+				return true, nil
+			}
+			continue
+		}
+
+		vals, err := s.lookup(ts.source, ts.key)
+		if err != nil {
+			return false, &BindError{Field: fi.name, Source: ts.source, Key: ts.key, Err: err}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		if err = b.setValues(fv, vals); err != nil {
+			return false, &BindError{Field: fi.name, Source: ts.source, Key: ts.key, Value: strings.Join(vals, ","), Err: err}
+		}
+		return true, nil
+	}
+
+	if !fv.IsZero() {
+		return false, nil
+	}
+	if fi.hasDef {
+		if err := b.setDefault(fv, fi.defVal); err != nil {
+			return false, &BindError{Field: fi.name, Source: SourceDefault, Value: fi.defVal, Err: err}
+		}
+		return true, nil
+	}
+	if fi.required {
+		be := &BindError{Field: fi.name, Source: SourceJSON, Key: fi.name, Err: ErrMissingRequired}
+		if len(fi.sources) > 0 {
+			be.Source, be.Key = fi.sources[0].source, fi.sources[0].key
+		}
+		return false, be
+	}
+	return false, nil
+}
+
+// 递归绑定嵌套结构体，指针字段仅在有参数时才分配。
+func (b *defaultBinder) bindNested(s *bindState, fv reflect.Value, fi *fieldInfo) (bool, error) {
+	target := fv
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			// 自引用的指针字段，如链表节点，再分配会无限递归
+			if s.binding[fv.Type().Elem()] {
+				return false, nil
+			}
+			target = reflect.New(fv.Type().Elem())
+		}
+		target = target.Elem()
+	}
+
+	bound, err := b.bindStruct(s, target, fi.nested)
+	if err != nil {
+		var be *BindError
+		if errors.As(err, &be) && be.Field != "" {
+			be.Field = fi.name + "." + be.Field
+		}
+		return false, err
+	}
+	if bound && fv.Kind() == reflect.Pointer && fv.IsNil() {
+		fv.Set(target.Addr())
+	}
+	return bound, nil
+}
+
+func (b *defaultBinder) bindFile(s *bindState, fv reflect.Value, key string) (bool, error) {
+	mf, err := s.multipartForm()
+	if err != nil {
+		return false, err
+	}
+	if mf == nil || len(mf.File[key]) == 0 {
+		return false, nil
+	}
+	files := mf.File[key]
+
+	t := fv.Type()
+	switch {
+	case t.Kind() == reflect.Slice:
+		sv := reflect.MakeSlice(t, len(files), len(files))
+		for i, fh := range files {
+			setFileHeader(sv.Index(i), fh)
+		}
+		fv.Set(sv)
+	default:
+		setFileHeader(fv, files[0])
+	}
+	return true, nil
+}
+
+func setFileHeader(v reflect.Value, fh *multipart.FileHeader) {
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.ValueOf(fh))
+		return
+	}
+	v.Set(reflect.ValueOf(*fh))
+}
+
+func (b *defaultBinder) setDefault(fv reflect.Value, def string) error {
+	t := derefType(fv.Type())
+	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && strings.HasPrefix(def, "[") {
+		return json.Unmarshal(bytesconv.S2b(def), fv.Addr().Interface())
+	}
+	return b.setValues(fv, []string{def})
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && t != fileHeaderType && !isTextUnmarshaler(t)
+}
+
+func isFileHeaderType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return derefType(t) == fileHeaderType
+}
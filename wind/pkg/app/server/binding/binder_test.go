@@ -0,0 +1,270 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/common/testdata/proto"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/route/param"
+	protoBuf "google.golang.org/protobuf/proto"
+)
+
+func newRequest(uri string) *protocol.Request {
+	req := &protocol.Request{}
+	req.SetRequestURI(uri)
+	return req
+}
+
+func TestBindTags(t *testing.T) {
+	type Req struct {
+		ID      int64    `path:"id"`
+		Page    int      `query:"page"`
+		Tags    []string `query:"tag"`
+		Token   string   `header:"X-Token"`
+		Session string   `cookie:"sid"`
+		Name    string   `form:"name"`
+		Lang    string   `query:"lang" default:"zh"`
+		Limit   *int     `query:"limit"`
+		Keyword string
+		Ignored string `query:"-"`
+	}
+
+	req := newRequest("http://example.com/users/42?page=2&tag=a&tag=b&limit=10&Keyword=go&Ignored=x")
+	req.Header.Set("X-Token", "secret")
+	req.Header.SetCookie("sid", "s1")
+	req.Header.SetContentTypeBytes([]byte("application/x-www-form-urlencoded"))
+	req.SetBodyString("name=wind")
+
+	var r Req
+	err := DefaultBinder().Bind(req, &r, param.Params{{Key: "id", Value: "42"}})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, int64(42), r.ID)
+	assert.DeepEqual(t, 2, r.Page)
+	assert.DeepEqual(t, []string{"a", "b"}, r.Tags)
+	assert.DeepEqual(t, "secret", r.Token)
+	assert.DeepEqual(t, "s1", r.Session)
+	assert.DeepEqual(t, "wind", r.Name)
+	assert.DeepEqual(t, "zh", r.Lang)
+	assert.DeepEqual(t, 10, *r.Limit)
+	assert.DeepEqual(t, "go", r.Keyword)
+	assert.DeepEqual(t, "", r.Ignored)
+}
+
+func TestBindPriority(t *testing.T) {
+	type Req struct {
+		ID string `path:"id" query:"id"`
+	}
+	var r Req
+	err := DefaultBinder().Bind(newRequest("/?id=query"), &r, param.Params{{Key: "id", Value: "path"}})
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "path", r.ID)
+
+	r = Req{}
+	err = DefaultBinder().Bind(newRequest("/?id=query"), &r, nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "query", r.ID)
+}
+
+func TestBindJSON(t *testing.T) {
+	type Req struct {
+		Name  string `json:"name"`
+		Age   int    `json:"age" default:"18"`
+		Page  int    `query:"page"`
+		Email string `json:"email,required"`
+	}
+
+	req := newRequest("/?page=3")
+	req.Header.SetContentTypeBytes([]byte("application/json; charset=utf-8"))
+	req.SetBodyString(`{"name":"wind","email":"a@b.c"}`)
+
+	var r Req
+	assert.Nil(t, DefaultBinder().Bind(req, &r, nil))
+	assert.DeepEqual(t, Req{Name: "wind", Age: 18, Page: 3, Email: "a@b.c"}, r)
+
+	// 缺少必填字段
+	req.SetBodyString(`{"name":"wind"}`)
+	r = Req{}
+	err := DefaultBinder().Bind(req, &r, nil)
+	var be *BindError
+	assert.True(t, errors.As(err, &be))
+	assert.DeepEqual(t, "Email", be.Field)
+	assert.DeepEqual(t, SourceJSON, be.Source)
+	assert.True(t, errors.Is(err, ErrMissingRequired))
+
+	// 正文格式错误
+	req.SetBodyString(`{"name":`)
+	err = DefaultBinder().Bind(req, &r, nil)
+	assert.True(t, errors.As(err, &be))
+	assert.DeepEqual(t, SourceJSON, be.Source)
+}
+
+func TestBindProtobuf(t *testing.T) {
+	body, err := protoBuf.Marshal(&proto.TestStruct{Body: []byte("hello")})
+	assert.Nil(t, err)
+
+	req := newRequest("/")
+	req.Header.SetContentTypeBytes([]byte("application/x-protobuf"))
+	req.SetBody(body)
+
+	var r proto.TestStruct
+	assert.Nil(t, DefaultBinder().Bind(req, &r, nil))
+	assert.DeepEqual(t, []byte("hello"), r.Body)
+}
+
+func TestBindNested(t *testing.T) {
+	type Page struct {
+		Num  int `query:"page" default:"1"`
+		Size int `query:"size"`
+	}
+	type Filter struct {
+		Status string `query:"status"`
+	}
+	type Req struct {
+		Page
+		Filter *Filter
+		Empty  *Filter
+		Meta   map[string]string `query:"meta"`
+		Items  []struct {
+			ID int `json:"id"`
+		} `query:"items"`
+	}
+
+	var r Req
+	err := DefaultBinder().Bind(newRequest(`/?size=20&status=on&meta={"k":"v"}&items=[{"id":1},{"id":2}]`), &r, nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 1, r.Num)
+	assert.DeepEqual(t, 20, r.Size)
+	assert.DeepEqual(t, "on", r.Filter.Status)
+	assert.DeepEqual(t, "on", r.Empty.Status)
+	assert.DeepEqual(t, map[string]string{"k": "v"}, r.Meta)
+	assert.DeepEqual(t, 2, len(r.Items))
+	assert.DeepEqual(t, 2, r.Items[1].ID)
+
+	type Lazy struct {
+		Filter *Filter
+	}
+	var l Lazy
+	assert.Nil(t, DefaultBinder().Bind(newRequest("/"), &l, nil))
+	assert.Nil(t, l.Filter)
+}
+
+func TestBindRecursive(t *testing.T) {
+	type node struct {
+		Name string `query:"name"`
+		Next *node
+	}
+
+	var n node
+	assert.Nil(t, DefaultBinder().Bind(newRequest("/?name=a"), &n, nil))
+	assert.DeepEqual(t, "a", n.Name)
+	assert.Nil(t, n.Next)
+
+	// 已分配的节点仍会绑定
+	n = node{Next: &node{}}
+	assert.Nil(t, DefaultBinder().Bind(newRequest("/?name=b"), &n, nil))
+	assert.DeepEqual(t, "b", n.Name)
+	assert.DeepEqual(t, "b", n.Next.Name)
+	assert.Nil(t, n.Next.Next)
+
+	type tree struct {
+		Left  *tree
+		Right *tree
+		Value int `query:"value"`
+	}
+	var tr tree
+	assert.Nil(t, DefaultBinder().Bind(newRequest("/?value=1"), &tr, nil))
+	assert.DeepEqual(t, 1, tr.Value)
+	assert.Nil(t, tr.Left)
+}
+
+func TestBindTime(t *testing.T) {
+	type Req struct {
+		From    time.Time     `query:"from"`
+		To      *time.Time    `query:"to"`
+		Timeout time.Duration `query:"timeout"`
+	}
+	var r Req
+	err := DefaultBinder().Bind(newRequest("/?from=2023-06-01T08:00:00Z&to=1685606400&timeout=1.5s"), &r, nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC), r.From.UTC())
+	assert.DeepEqual(t, int64(1685606400), r.To.Unix())
+	assert.DeepEqual(t, 1500*time.Millisecond, r.Timeout)
+
+	b := NewDefaultBinder(&BindConfig{TimeLayout: "2006-01-02"})
+	r = Req{}
+	assert.Nil(t, b.Bind(newRequest("/?from=2023-06-01"), &r, nil))
+	assert.DeepEqual(t, 2023, r.From.Year())
+}
+
+func TestBindMultipart(t *testing.T) {
+	type Req struct {
+		Title  string                  `form:"title"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Photos []*multipart.FileHeader `form:"photo"`
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	_ = w.WriteField("title", "相册")
+	fw, _ := w.CreateFormFile("avatar", "a.png")
+	_, _ = fw.Write([]byte("avatar"))
+	for _, name := range []string{"1.png", "2.png"} {
+		fw, _ = w.CreateFormFile("photo", name)
+		_, _ = fw.Write([]byte(name))
+	}
+	_ = w.Close()
+
+	req := newRequest("/upload")
+	req.Header.SetMethod("POST")
+	req.Header.SetContentTypeBytes([]byte(w.FormDataContentType()))
+	req.SetBody(body.Bytes())
+
+	var r Req
+	assert.Nil(t, DefaultBinder().Bind(req, &r, nil))
+	assert.DeepEqual(t, "相册", r.Title)
+	assert.DeepEqual(t, "a.png", r.Avatar.Filename)
+	assert.DeepEqual(t, 2, len(r.Photos))
+	assert.DeepEqual(t, "2.png", r.Photos[1].Filename)
+}
+
+func TestBindErrors(t *testing.T) {
+	type Inner struct {
+		Age int `query:"age"`
+	}
+	type Req struct {
+		Inner Inner
+		Token string `header:"X-Token,required"`
+	}
+
+	var r Req
+	err := DefaultBinder().Bind(newRequest("/?age=abc"), &r, nil)
+	var be *BindError
+	assert.True(t, errors.As(err, &be))
+	assert.DeepEqual(t, "Inner.Age", be.Field)
+	assert.DeepEqual(t, SourceQuery, be.Source)
+	assert.DeepEqual(t, "age", be.Key)
+	assert.DeepEqual(t, "abc", be.Value)
+
+	err = DefaultBinder().Bind(newRequest("/?age=1"), &r, nil)
+	assert.True(t, errors.As(err, &be))
+	assert.DeepEqual(t, "Token", be.Field)
+	assert.DeepEqual(t, SourceHeader, be.Source)
+	assert.True(t, errors.Is(err, ErrMissingRequired))
+
+	// 空值在宽松模式下视为零值
+	type Num struct {
+		N int `query:"n"`
+	}
+	var n Num
+	assert.NotNil(t, DefaultBinder().Bind(newRequest("/?n="), &n, nil))
+	assert.Nil(t, NewDefaultBinder(&BindConfig{LooseZeroMode: true}).Bind(newRequest("/?n="), &n, nil))
+
+	assert.DeepEqual(t, errInvalidTarget, DefaultBinder().Bind(newRequest("/"), r, nil))
+	var np *Req
+	assert.DeepEqual(t, errInvalidTarget, DefaultBinder().Bind(newRequest("/"), np, nil))
+}
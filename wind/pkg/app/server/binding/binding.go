@@ -0,0 +1,80 @@
+package binding
+
+import (
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// PathParam 路由参数的取值接口，route/param.Params 实现了该接口。
+type PathParam interface {
+	Get(name string) (string, bool)
+}
+
+// Binder 将请求中的参数绑定到结构体。
+type Binder interface {
+	// Name 返回绑定器名称。
+	Name() string
+
+	// Bind 依据结构体字段标签，将请求参数绑定到 v，v 必须是非空的结构体指针。
+	Bind(req *protocol.Request, v any, params PathParam) error
+}
+
+// StructValidator 校验结构体字段。
+type StructValidator interface {
+	// Name 返回校验器名称。
+	Name() string
+
+	// ValidateStruct 校验结构体，非结构体或其指针直接通过。
+	ValidateStruct(v any) error
+}
+
+// BindConfig 默认绑定器的配置。
+type BindConfig struct {
+	// LooseZeroMode 为 true 时，数值、布尔等类型的空字符串参数视为零值，否则报错。
+	// 默认 false。
+	LooseZeroMode bool
+
+	// EnableDecoderUseNumber 为 true 时，JSON 正文中的数字按 json.Number 解码到 any 字段。
+	EnableDecoderUseNumber bool
+
+	// EnableDecoderDisallowUnknownFields 为 true 时，JSON 正文中出现未知字段将报错。
+	EnableDecoderDisallowUnknownFields bool
+
+	// TimeLayout 是解析 time.Time 字段的时间格式，默认 time.RFC3339。
+	// 纯数字的参数值始终按 Unix 秒解析。
+	TimeLayout string
+}
+
+// NewBindConfig 创建默认的绑定器配置。
+func NewBindConfig() *BindConfig {
+	return &BindConfig{
+		TimeLayout: time.RFC3339,
+	}
+}
+
+// ValidateConfig 默认校验器的配置。
+type ValidateConfig struct {
+	// ErrorFactory 自定义校验失败时返回的错误，默认返回 *ValidateError。
+	ErrorFactory func(field, msg string) error
+}
+
+// NewValidateConfig 创建默认的校验器配置。
+func NewValidateConfig() *ValidateConfig {
+	return &ValidateConfig{}
+}
+
+var (
+	defaultBind     = NewDefaultBinder(nil)
+	defaultValidate = NewValidator(nil)
+)
+
+// DefaultBinder 返回默认配置的绑定器。
+func DefaultBinder() Binder {
+	return defaultBind
+}
+
+// DefaultValidator 返回默认配置的校验器。
+func DefaultValidator() StructValidator {
+	return defaultValidate
+}
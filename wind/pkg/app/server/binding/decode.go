@@ -0,0 +1,165 @@
+package binding
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/common/json"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func isTextUnmarshaler(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// 将参数值写入字段，切片和数组字段逐个接收多个值，其余字段取第一个值。
+func (b *defaultBinder) setValues(fv reflect.Value, vals []string) error {
+	t := fv.Type()
+	switch {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		if isJSONKind(t.Elem()) && len(vals) == 1 && isJSONArray(vals[0]) {
+			return json.Unmarshal(bytesconv.S2b(vals[0]), fv.Addr().Interface())
+		}
+		sv := reflect.MakeSlice(t, len(vals), len(vals))
+		for i, s := range vals {
+			if err := b.setValue(sv.Index(i), s); err != nil {
+				return err
+			}
+		}
+		fv.Set(sv)
+		return nil
+	case t.Kind() == reflect.Array:
+		for i := 0; i < t.Len() && i < len(vals); i++ {
+			if err := b.setValue(fv.Index(i), vals[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return b.setValue(fv, vals[0])
+}
+
+func (b *defaultBinder) setValue(v reflect.Value, s string) error {
+	t := v.Type()
+	if t.Kind() == reflect.Pointer {
+		elem := reflect.New(t.Elem())
+		if err := b.setValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch t {
+	case timeType:
+		return b.setTime(v, s)
+	case durationType:
+		if s == "" && b.config.LooseZeroMode {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if isTextUnmarshaler(t) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(bytesconv.S2b(s))
+	}
+
+	if s == "" && b.config.LooseZeroMode && isScalarKind(t.Kind()) {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		x, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(x)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	case reflect.Slice:
+		// []byte
+		v.SetBytes([]byte(s))
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(s))
+			return nil
+		}
+		return json.Unmarshal(bytesconv.S2b(s), v.Addr().Interface())
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", t)
+	}
+	return nil
+}
+
+func (b *defaultBinder) setTime(v reflect.Value, s string) error {
+	if s == "" && b.config.LooseZeroMode {
+		v.Set(reflect.Zero(timeType))
+		return nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		v.Set(reflect.ValueOf(time.Unix(sec, 0)))
+		return nil
+	}
+	tm, err := time.Parse(b.config.TimeLayout, s)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(tm))
+	return nil
+}
+
+func isScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// 元素为结构体或映射的切片，可由单个 JSON 数组参数整体解码。
+func isJSONKind(t reflect.Type) bool {
+	t = derefType(t)
+	return (t.Kind() == reflect.Struct && t != timeType && !isTextUnmarshaler(t)) || t.Kind() == reflect.Map
+}
+
+func isJSONArray(s string) bool {
+	return len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']'
+}
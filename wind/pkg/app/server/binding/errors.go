@@ -0,0 +1,61 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMissingRequired 表示必填参数缺失。
+	ErrMissingRequired = errors.New("缺少必填参数")
+
+	errInvalidTarget = errors.New("绑定目标必须是非空的结构体指针")
+)
+
+// 参数来源，即结构体字段可用的绑定标签。
+const (
+	SourcePath     = "path"
+	SourceForm     = "form"
+	SourceQuery    = "query"
+	SourceCookie   = "cookie"
+	SourceHeader   = "header"
+	SourceJSON     = "json"
+	SourceProtobuf = "protobuf"
+	SourceDefault  = "default"
+)
+
+// BindError 描述某个字段从某个来源绑定失败的原因。
+type BindError struct {
+	Field  string // 字段路径，如 User.Age
+	Source string // 参数来源，如 query、header
+	Key    string // 参数键名
+	Value  string // 原始参数值
+	Err    error
+}
+
+// Error 实现 error 接口。
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("绑定失败：来源=%s：%v", e.Source, e.Err)
+	}
+	if e.Value == "" {
+		return fmt.Sprintf("绑定字段 %s 失败：来源=%s 键=%s：%v", e.Field, e.Source, e.Key, e.Err)
+	}
+	return fmt.Sprintf("绑定字段 %s 失败：来源=%s 键=%s 值=%q：%v", e.Field, e.Source, e.Key, e.Value, e.Err)
+}
+
+// Unwrap 返回底层错误。
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateError 描述某个字段未通过 vd 表达式校验。
+type ValidateError struct {
+	Field string // 字段路径，如 Items[0].Name
+	Msg   string // 校验失败的说明，可由 vd 标签的 msg 自定义
+}
+
+// Error 实现 error 接口。
+func (e *ValidateError) Error() string {
+	return fmt.Sprintf("校验字段 %s 失败：%s", e.Field, e.Msg)
+}
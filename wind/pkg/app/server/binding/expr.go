@@ -0,0 +1,623 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidateFunc 是可在 vd 表达式中调用的函数。
+//
+// 参数中的数值统一为 float64，字符串、布尔值和 nil 保持原样，其余类型为字段的原始值。
+type ValidateFunc func(args ...any) (any, error)
+
+var (
+	funcsMu sync.RWMutex
+	funcs   = map[string]ValidateFunc{
+		"len":     lenFunc,
+		"mblen":   mblenFunc,
+		"regexp":  regexpFunc,
+		"in":      inFunc,
+		"sprintf": sprintfFunc,
+	}
+
+	regexpCache sync.Map // string -> *regexp.Regexp
+)
+
+// MustRegValidateFunc 注册可在 vd 表达式中调用的函数。
+//
+// 内置函数：len、mblen、regexp、in、sprintf。
+// 同名函数已存在时 panic，除非 force 为 true。
+func MustRegValidateFunc(name string, fn ValidateFunc, force ...bool) {
+	funcsMu.Lock()
+	defer funcsMu.Unlock()
+	if _, ok := funcs[name]; ok && (len(force) == 0 || !force[0]) {
+		panic(fmt.Sprintf("校验函数 %s 已存在", name))
+	}
+	funcs[name] = fn
+}
+
+func lookupFunc(name string) (ValidateFunc, bool) {
+	funcsMu.RLock()
+	defer funcsMu.RUnlock()
+	fn, ok := funcs[name]
+	return fn, ok
+}
+
+// len(x)：字符串的字节数，或切片、数组、映射的长度。
+func lenFunc(args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("len 需要 1 个参数")
+	}
+	switch x := args[0].(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len(x)), nil
+	}
+	rv := reflect.ValueOf(args[0])
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(rv.Len()), nil
+	}
+	return nil, fmt.Errorf("len 不支持类型 %T", args[0])
+}
+
+// mblen(x)：字符串的字符数，其余同 len。
+func mblenFunc(args ...any) (any, error) {
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			return float64(utf8.RuneCountInString(s)), nil
+		}
+	}
+	return lenFunc(args...)
+}
+
+// regexp(pattern, x)：x 是否匹配正则表达式，x 缺省为当前字段。
+func regexpFunc(args ...any) (any, error) {
+	if len(args) != 2 {
+		return nil, errors.New("regexp 需要 1 到 2 个参数")
+	}
+	pattern, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("regexp 的表达式必须是字符串")
+	}
+	var re *regexp.Regexp
+	if v, ok := regexpCache.Load(pattern); ok {
+		re = v.(*regexp.Regexp)
+	} else {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+		regexpCache.Store(pattern, re)
+	}
+	switch x := args[1].(type) {
+	case nil:
+		return false, nil
+	case string:
+		return re.MatchString(x), nil
+	}
+	return nil, fmt.Errorf("regexp 不支持类型 %T", args[1])
+}
+
+// in(x, a, b, ...)：x 是否等于后续任一参数。
+func inFunc(args ...any) (any, error) {
+	if len(args) < 2 {
+		return nil, errors.New("in 至少需要 2 个参数")
+	}
+	for _, v := range args[1:] {
+		if equal(args[0], v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sprintf(format, args...)：格式化字符串，通常用于 msg。
+func sprintfFunc(args ...any) (any, error) {
+	if len(args) == 0 {
+		return nil, errors.New("sprintf 至少需要 1 个参数")
+	}
+	format, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("sprintf 的格式必须是字符串")
+	}
+	return fmt.Sprintf(format, args[1:]...), nil
+}
+
+// 表达式求值环境。
+type exprEnv struct {
+	cur    reflect.Value // 当前字段，即 $
+	parent reflect.Value // 当前字段所在的结构体，用于 (Field)$ 引用
+}
+
+type exprNode func(env *exprEnv) (any, error)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokCur      // $
+	tokFieldRef // (Field)$
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+}
+
+var fieldRefRe = regexp.MustCompile(`^\(\s*([A-Za-z_][A-Za-z0-9_.]*)\s*\)\$`)
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '$':
+			toks = append(toks, token{kind: tokCur, text: "$"})
+			i++
+		case c == '(' && fieldRefRe.MatchString(src[i:]):
+			m := fieldRefRe.FindStringSubmatch(src[i:])
+			toks = append(toks, token{kind: tokFieldRef, text: m[1]})
+			i += len(m[0])
+		case c == '\'':
+			s, n, err := readString(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{kind: tokString, text: s})
+			i += n
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("非法数字 %q", src[i:j])
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[i:j], num: f})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j]})
+			i = j
+		default:
+			if i+1 < len(src) {
+				switch op := src[i : i+2]; op {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					toks = append(toks, token{kind: tokOp, text: op})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("()!<>+-*/%,", rune(c)) {
+				return nil, fmt.Errorf("非法字符 %q", c)
+			}
+			toks = append(toks, token{kind: tokOp, text: string(c)})
+			i++
+		}
+	}
+	return append(toks, token{kind: tokEOF}), nil
+}
+
+// 读取单引号字符串，支持 \' 和 \\ 转义，其余反斜杠原样保留以便书写正则表达式。
+func readString(src string) (string, int, error) {
+	var sb strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if i+1 < len(src) && (src[i+1] == '\'' || src[i+1] == '\\') {
+				i++
+			}
+			sb.WriteByte(src[i])
+		case '\'':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(src[i])
+		}
+	}
+	return "", 0, errors.New("字符串缺少结束引号")
+}
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+// 编译 vd 表达式。
+//
+// 语法：$ 表示当前字段，(Field)$ 表示同一结构体中的其他字段；
+// 支持数字、单引号字符串、true、false、nil，运算符 ! - * / % + < <= > >= == != && || 和括号，
+// 以及 len、regexp 等函数调用。
+func compileExpr(src string) (exprNode, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	n, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("多余的内容 %q", p.peek().text)
+	}
+	return n, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) expect(op string) error {
+	if t := p.next(); t.kind != tokOp || t.text != op {
+		return fmt.Errorf("期望 %q，实际为 %q", op, t.text)
+	}
+	return nil
+}
+
+var precedences = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level == len(precedences) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokOp || !contains(precedences[level], t.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode(t.text, left, right)
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	t := p.peek()
+	if t.kind == tokOp && (t.text == "!" || t.text == "-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "!" {
+			return func(env *exprEnv) (any, error) {
+				v, err := x(env)
+				if err != nil {
+					return nil, err
+				}
+				return !truthy(v), nil
+			}, nil
+		}
+		return func(env *exprEnv) (any, error) {
+			v, err := x(env)
+			if err != nil {
+				return nil, err
+			}
+			f, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("无法对 %T 取负", v)
+			}
+			return -f, nil
+		}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return constNode(t.num), nil
+	case tokString:
+		return constNode(t.text), nil
+	case tokCur:
+		return func(env *exprEnv) (any, error) {
+			return normalize(env.cur), nil
+		}, nil
+	case tokFieldRef:
+		path := strings.Split(t.text, ".")
+		return func(env *exprEnv) (any, error) {
+			v := env.parent
+			for _, name := range path {
+				v = reflect.Indirect(v)
+				if v.Kind() != reflect.Struct {
+					return nil, nil
+				}
+				if v = v.FieldByName(name); !v.IsValid() {
+					return nil, fmt.Errorf("字段 %s 不存在", t.text)
+				}
+			}
+			return normalize(v), nil
+		}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return constNode(true), nil
+		case "false":
+			return constNode(false), nil
+		case "nil", "null":
+			return constNode(nil), nil
+		}
+		return p.parseCall(t.text)
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	if t.kind == tokEOF {
+		return nil, errors.New("表达式不完整")
+	}
+	return nil, fmt.Errorf("意外的 %q", t.text)
+}
+
+func (p *exprParser) parseCall(name string) (exprNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, fmt.Errorf("未知标识符 %s", name)
+	}
+	var args []exprNode
+	if t := p.peek(); t.kind == tokOp && t.text == ")" {
+		p.next()
+	} else {
+		for {
+			arg, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			t := p.next()
+			if t.kind == tokOp && t.text == ")" {
+				break
+			}
+			if t.kind != tokOp || t.text != "," {
+				return nil, fmt.Errorf("函数 %s 的参数列表不完整", name)
+			}
+		}
+	}
+	// regexp 省略第二个参数时匹配当前字段
+	if name == "regexp" && len(args) == 1 {
+		args = append(args, func(env *exprEnv) (any, error) {
+			return normalize(env.cur), nil
+		})
+	}
+	if _, ok := lookupFunc(name); !ok {
+		return nil, fmt.Errorf("未知函数 %s", name)
+	}
+
+	return func(env *exprEnv) (any, error) {
+		fn, _ := lookupFunc(name)
+		vals := make([]any, len(args))
+		for i, arg := range args {
+			v, err := arg(env)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		return fn(vals...)
+	}, nil
+}
+
+func constNode(v any) exprNode {
+	return func(*exprEnv) (any, error) {
+		return v, nil
+	}
+}
+
+func binaryNode(op string, left, right exprNode) exprNode {
+	switch op {
+	case "&&", "||":
+		return func(env *exprEnv) (any, error) {
+			l, err := left(env)
+			if err != nil {
+				return nil, err
+			}
+			// 短路求值
+			if truthy(l) == (op == "||") {
+				return op == "||", nil
+			}
+			r, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return truthy(r), nil
+		}
+	}
+	return func(env *exprEnv) (any, error) {
+		l, err := left(env)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(env)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "==":
+			return equal(l, r), nil
+		case "!=":
+			return !equal(l, r), nil
+		case "<", "<=", ">", ">=":
+			return compare(op, l, r)
+		}
+		return arith(op, l, r)
+	}
+}
+
+func compare(op string, l, r any) (any, error) {
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return nil, fmt.Errorf("无法比较 %T 与 %T", l, r)
+		}
+		switch {
+		case lv < rv:
+			c = -1
+		case lv > rv:
+			c = 1
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("无法比较 %T 与 %T", l, r)
+		}
+		c = strings.Compare(lv, rv)
+	default:
+		return nil, fmt.Errorf("无法比较 %T 与 %T", l, r)
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func arith(op string, l, r any) (any, error) {
+	if op == "+" {
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+	}
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("无法对 %T 与 %T 执行 %s 运算", l, r, op)
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errors.New("除数为零")
+		}
+		return lf / rf, nil
+	}
+	if int64(rf) == 0 {
+		return nil, errors.New("除数为零")
+	}
+	return float64(int64(lf) % int64(rf)), nil
+}
+
+func equal(l, r any) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	switch l.(type) {
+	case float64, string, bool:
+		return l == r
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+// 将字段值转为表达式的值：数值统一为 float64，空指针为 nil。
+func normalize(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const vdTag = "vd"
+
+type defaultValidator struct {
+	config *ValidateConfig
+	cache  sync.Map // reflect.Type -> *vdStruct
+}
+
+// NewValidator 创建给定配置的默认校验器，config 为 nil 时使用 NewValidateConfig。
+//
+// 校验规则由字段的 vd 标签声明，形如：
+//
+//	Name string `vd:"len($)>0 && len($)<=32; msg:'名称长度须在 1 到 32 之间'"`
+//	Age  int    `vd:"$>=18 || (Role)$=='admin'"`
+//
+// 嵌套结构体及其切片、数组会被递归校验。
+func NewValidator(config *ValidateConfig) StructValidator {
+	if config == nil {
+		config = NewValidateConfig()
+	}
+	return &defaultValidator{config: config}
+}
+
+func (v *defaultValidator) Name() string {
+	return "vd"
+}
+
+func (v *defaultValidator) ValidateStruct(obj any) error {
+	rv := reflect.ValueOf(obj)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return v.validate(rv, "")
+}
+
+type vdRule struct {
+	src  string
+	expr exprNode
+	msg  exprNode
+}
+
+type vdField struct {
+	index   int
+	name    string
+	rule    *vdRule
+	recurse bool
+}
+
+type vdStruct struct {
+	fields []*vdField
+	err    error
+}
+
+func (v *defaultValidator) structInfo(t reflect.Type) *vdStruct {
+	if x, ok := v.cache.Load(t); ok {
+		return x.(*vdStruct)
+	}
+	vs := &vdStruct{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := &vdField{index: i, name: sf.Name, recurse: needRecurse(sf.Type)}
+		if tag, ok := sf.Tag.Lookup(vdTag); ok && tag != "-" {
+			rule, err := parseRule(tag)
+			if err != nil {
+				vs.err = fmt.Errorf("字段 %s.%s 的 vd 标签有误：%w", t.Name(), sf.Name, err)
+				break
+			}
+			f.rule = rule
+		}
+		if f.rule != nil || f.recurse {
+			vs.fields = append(vs.fields, f)
+		}
+	}
+	x, _ := v.cache.LoadOrStore(t, vs)
+	return x.(*vdStruct)
+}
+
+// 解析 `expr; msg:expr` 形式的标签，表达式可带 @: 前缀。
+func parseRule(tag string) (*vdRule, error) {
+	rule := &vdRule{}
+	for _, seg := range splitTopLevel(tag) {
+		seg = strings.TrimSpace(seg)
+		switch {
+		case seg == "":
+		case strings.HasPrefix(seg, "msg:"):
+			msg, err := compileExpr(strings.TrimPrefix(seg, "msg:"))
+			if err != nil {
+				return nil, err
+			}
+			rule.msg = msg
+		default:
+			seg = strings.TrimSpace(strings.TrimPrefix(seg, "@:"))
+			expr, err := compileExpr(seg)
+			if err != nil {
+				return nil, err
+			}
+			rule.src, rule.expr = seg, expr
+		}
+	}
+	if rule.expr == nil {
+		return nil, fmt.Errorf("缺少校验表达式")
+	}
+	return rule, nil
+}
+
+// 按引号外的分号切分标签。
+func splitTopLevel(s string) []string {
+	var parts []string
+	var quoted bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\'':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func (v *defaultValidator) validate(rv reflect.Value, prefix string) error {
+	vs := v.structInfo(rv.Type())
+	if vs.err != nil {
+		return vs.err
+	}
+	for _, f := range vs.fields {
+		fv := rv.Field(f.index)
+		name := prefix + f.name
+		if f.rule != nil {
+			if err := v.check(f.rule, fv, rv, name); err != nil {
+				return err
+			}
+		}
+		if f.recurse {
+			if err := v.validateValue(fv, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *defaultValidator) check(rule *vdRule, fv, parent reflect.Value, name string) error {
+	env := &exprEnv{cur: fv, parent: parent}
+	ok, err := rule.expr(env)
+	if err != nil {
+		return v.newError(name, fmt.Sprintf("表达式 %s 求值出错：%v", rule.src, err))
+	}
+	if truthy(ok) {
+		return nil
+	}
+
+	msg := "不满足 " + rule.src
+	if rule.msg != nil {
+		m, err := rule.msg(env)
+		if err != nil {
+			return v.newError(name, fmt.Sprintf("错误信息求值出错：%v", err))
+		}
+		msg = fmt.Sprint(m)
+	}
+	return v.newError(name, msg)
+}
+
+func (v *defaultValidator) newError(field, msg string) error {
+	if v.config.ErrorFactory != nil {
+		return v.config.ErrorFactory(field, msg)
+	}
+	return &ValidateError{Field: field, Msg: msg}
+}
+
+// 递归校验嵌套结构体及其切片、数组。
+func (v *defaultValidator) validateValue(fv reflect.Value, name string) error {
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		return v.validate(fv, name+".")
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.validateValue(fv.Index(i), name+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func needRecurse(t reflect.Type) bool {
+	t = derefType(t)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = derefType(t.Elem())
+	}
+	return t.Kind() == reflect.Struct && t != timeType && t != fileHeaderType
+}
@@ -0,0 +1,141 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+)
+
+func TestValidate(t *testing.T) {
+	type Item struct {
+		Name string `vd:"mblen($)>0 && mblen($)<=4; msg:'名称长度须在 1 到 4 之间'"`
+	}
+	type Req struct {
+		Age   int      `vd:"$>=18 || (Role)$=='admin'"`
+		Role  string   `vd:"in($, 'user', 'admin')"`
+		Email string   `vd:"regexp('^\\w+@\\w+\\.\\w+$')"`
+		Tags  []string `vd:"len($)<=2; msg:sprintf('标签过多：%v', len($))"`
+		Items []Item
+		Owner *Item
+		Score *float64 `vd:"$==nil || ($>=0 && $<=100)"`
+	}
+
+	v := DefaultValidator()
+	ok := Req{Age: 20, Role: "user", Email: "a@b.c", Items: []Item{{Name: "一二三四"}}}
+	assert.Nil(t, v.ValidateStruct(&ok))
+
+	cases := []struct {
+		modify func(r *Req)
+		field  string
+		msg    string
+	}{
+		{func(r *Req) { r.Age = 10 }, "Age", "不满足 $>=18 || (Role)$=='admin'"},
+		{func(r *Req) { r.Age, r.Role = 10, "admin" }, "", ""},
+		{func(r *Req) { r.Role = "root" }, "Role", ""},
+		{func(r *Req) { r.Email = "abc" }, "Email", ""},
+		{func(r *Req) { r.Tags = []string{"a", "b", "c"} }, "Tags", "标签过多：3"},
+		{func(r *Req) { r.Items = append(r.Items, Item{Name: "一二三四五"}) }, "Items[1].Name", "名称长度须在 1 到 4 之间"},
+		{func(r *Req) { r.Owner = &Item{} }, "Owner.Name", "名称长度须在 1 到 4 之间"},
+		{func(r *Req) { s := 101.0; r.Score = &s }, "Score", ""},
+	}
+	for _, c := range cases {
+		r := ok
+		r.Items = append([]Item(nil), ok.Items...)
+		c.modify(&r)
+		err := v.ValidateStruct(r)
+		if c.field == "" {
+			assert.Nil(t, err)
+			continue
+		}
+		var ve *ValidateError
+		assert.True(t, errors.As(err, &ve))
+		assert.DeepEqual(t, c.field, ve.Field)
+		if c.msg != "" {
+			assert.DeepEqual(t, c.msg, ve.Msg)
+		}
+	}
+
+	// 非结构体直接通过
+	assert.Nil(t, v.ValidateStruct(1))
+	assert.Nil(t, v.ValidateStruct((*Req)(nil)))
+}
+
+func TestValidateErrorFactory(t *testing.T) {
+	type Req struct {
+		Name string `vd:"len($)>0"`
+	}
+	v := NewValidator(&ValidateConfig{ErrorFactory: func(field, msg string) error {
+		return fmt.Errorf("%s 无效", strings.ToLower(field))
+	}})
+	assert.DeepEqual(t, "name 无效", v.ValidateStruct(Req{}).Error())
+}
+
+func TestValidateBadExpr(t *testing.T) {
+	type Req struct {
+		Name string `vd:"len($)>"`
+	}
+	err := DefaultValidator().ValidateStruct(Req{})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "Name"))
+
+	type Unknown struct {
+		Name string `vd:"foo($)"`
+	}
+	assert.NotNil(t, DefaultValidator().ValidateStruct(Unknown{}))
+}
+
+func TestMustRegValidateFunc(t *testing.T) {
+	MustRegValidateFunc("even", func(args ...any) (any, error) {
+		n, ok := args[0].(float64)
+		return ok && int(n)%2 == 0, nil
+	})
+	defer func() {
+		funcsMu.Lock()
+		delete(funcs, "even")
+		funcsMu.Unlock()
+	}()
+
+	type Req struct {
+		N int `vd:"even($)"`
+	}
+	assert.Nil(t, DefaultValidator().ValidateStruct(Req{N: 2}))
+	assert.NotNil(t, DefaultValidator().ValidateStruct(Req{N: 3}))
+
+	assert.Panic(t, func() {
+		MustRegValidateFunc("len", lenFunc)
+	})
+	assert.NotPanic(t, func() {
+		MustRegValidateFunc("len", lenFunc, true)
+	})
+}
+
+func TestCompileExpr(t *testing.T) {
+	cases := map[string]any{
+		"1 + 2 * 3":           float64(7),
+		"(1 + 2) * 3":         float64(9),
+		"7 % 4":               float64(3),
+		"-2 < 1":              true,
+		"'a' + 'b' == 'ab'":   true,
+		"!(1 > 2) && true":    true,
+		"nil == nil":          true,
+		"'it\\'s' != 'it'":    true,
+		"len('中文')":           float64(6),
+		"mblen('中文')":         float64(2),
+		"regexp('^a', 'abc')": true,
+	}
+	for src, want := range cases {
+		expr, err := compileExpr(src)
+		assert.Nil(t, err)
+		got, err := expr(&exprEnv{})
+		assert.Nil(t, err)
+		assert.DeepEqual(t, want, got)
+	}
+
+	for _, src := range []string{"", "1 +", "(1", "'abc", "1 # 2", "len(1", "foo"} {
+		_, err := compileExpr(src)
+		assert.NotNil(t, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/favbox/gosky/wind/pkg/app/server/binding"
 	"github.com/favbox/gosky/wind/pkg/app/server/registry"
 	"github.com/favbox/gosky/wind/pkg/common/config"
 	"github.com/favbox/gosky/wind/pkg/common/tracer"
@@ -185,6 +186,18 @@ func WithStreamBody(b bool) config.Option {
 	}}
 }
 
+// WithDecompressRequestBody 按 Content-Encoding 透明解码请求正文。
+//
+// 支持 gzip、deflate、br 和 zstd。解码后的字节数受 WithMaxRequestBodySize 限制，超出则响应 413，
+// 不支持的编码响应 415，数据损坏响应 400。启用 WithStreamBody 时，请求正文流会边读边解码。
+//
+// 默认值：false，不解码。
+func WithDecompressRequestBody(b bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.DecompressRequestBody = b
+	}}
+}
+
 // WithNetwork 网络协议，可选：tcp，udp，unix（unix domain socket）。
 // 默认值：tcp。
 func WithNetwork(nw string) config.Option {
@@ -321,3 +334,35 @@ func WithOnConnect(fn func(ctx context.Context, conn network.Conn) context.Conte
 		o.OnConnect = fn
 	}}
 }
+
+// WithBindConfig 设置默认绑定器的配置。
+//
+// 默认值：binding.NewBindConfig()。
+func WithBindConfig(bc *binding.BindConfig) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.BindConfig = bc
+	}}
+}
+
+// WithValidateConfig 设置默认校验器的配置。
+//
+// 默认值：binding.NewValidateConfig()。
+func WithValidateConfig(vc *binding.ValidateConfig) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.ValidateConfig = vc
+	}}
+}
+
+// WithCustomBinder 设置自定义绑定器，设置后 WithBindConfig 不再生效。
+func WithCustomBinder(b binding.Binder) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.CustomBinder = b
+	}}
+}
+
+// WithCustomValidator 设置自定义校验器，设置后 WithValidateConfig 不再生效。
+func WithCustomValidator(v binding.StructValidator) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.CustomValidator = v
+	}}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/favbox/gosky/wind/pkg/app/server/binding"
 	"github.com/favbox/gosky/wind/pkg/app/server/registry"
 	"github.com/favbox/gosky/wind/pkg/common/config"
 	"github.com/favbox/gosky/wind/pkg/common/test/assert"
@@ -12,6 +13,8 @@ import (
 )
 
 func TestOptions(t *testing.T) {
+	bindConfig := &binding.BindConfig{LooseZeroMode: true}
+	validateConfig := &binding.ValidateConfig{}
 	info := &registry.Info{
 		ServiceName: "wind.test.api",
 		Addr:        utils.NewNetAddr("", ""),
@@ -30,6 +33,7 @@ func TestOptions(t *testing.T) {
 		WithUnescapePathValues(false),
 		WithDisablePreParseMultipartForm(true),
 		WithStreamBody(false),
+		WithDecompressRequestBody(true),
 		WithHostPorts(":8888"),
 		WithBasePath("/"),
 		WithMaxRequestBodySize(2),
@@ -46,6 +50,10 @@ func TestOptions(t *testing.T) {
 		WithTraceLevel(stats.LevelDisabled),
 		WithRegistry(nil, info),
 		WithAutoReloadRender(true, 5*time.Second),
+		WithBindConfig(bindConfig),
+		WithValidateConfig(validateConfig),
+		WithCustomBinder(binding.DefaultBinder()),
+		WithCustomValidator(binding.DefaultValidator()),
 	})
 
 	assert.DeepEqual(t, opt.ReadTimeout, time.Second)
@@ -60,6 +68,7 @@ func TestOptions(t *testing.T) {
 	assert.DeepEqual(t, opt.UnescapePathValues, false)
 	assert.DeepEqual(t, opt.DisablePreParseMultipartForm, true)
 	assert.DeepEqual(t, opt.StreamRequestBody, false)
+	assert.DeepEqual(t, opt.DecompressRequestBody, true)
 	assert.DeepEqual(t, opt.Addr, ":8888")
 	assert.DeepEqual(t, opt.BasePath, "/")
 	assert.DeepEqual(t, opt.MaxRequestBodySize, 2)
@@ -77,6 +86,10 @@ func TestOptions(t *testing.T) {
 	assert.DeepEqual(t, opt.Registry, nil)
 	assert.DeepEqual(t, opt.AutoReloadRender, true)
 	assert.DeepEqual(t, opt.AutoReloadInterval, 5*time.Second)
+	assert.DeepEqual(t, opt.BindConfig, bindConfig)
+	assert.DeepEqual(t, opt.ValidateConfig, validateConfig)
+	assert.DeepEqual(t, opt.CustomBinder, binding.DefaultBinder())
+	assert.DeepEqual(t, opt.CustomValidator, binding.DefaultValidator())
 }
 
 func TestDefaultOptions(t *testing.T) {
@@ -92,6 +105,7 @@ func TestDefaultOptions(t *testing.T) {
 	assert.DeepEqual(t, opt.UnescapePathValues, true)
 	assert.DeepEqual(t, opt.DisablePreParseMultipartForm, false)
 	assert.DeepEqual(t, opt.StreamRequestBody, false)
+	assert.DeepEqual(t, opt.DecompressRequestBody, false)
 	assert.DeepEqual(t, opt.Addr, ":8888")
 	assert.DeepEqual(t, opt.BasePath, "/")
 	assert.DeepEqual(t, opt.MaxRequestBodySize, 4*1024*1024)
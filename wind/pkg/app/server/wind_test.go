@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -531,3 +532,39 @@ func TestSrv(t *testing.T) {
 	go wind.Spin()
 	select {}
 }
+
+func TestDecompressRequestBodyStream(t *testing.T) {
+	engine := New(WithHostPorts("127.0.0.1:18767"), WithStreamBody(true), WithDecompressRequestBody(true))
+	engine.POST("/echo", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Data(consts.StatusOK, consts.MIMETextPlain, ctx.Request.Body())
+	})
+	// 不读取正文，由服务器跳过剩余的压缩数据
+	engine.POST("/ignore", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "ignored")
+	})
+	go engine.Run()
+	defer engine.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	src := strings.Repeat("wind 请求解压 ", 20000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(src))
+	zw.Close()
+
+	cli := &http.Client{}
+	for _, path := range []string{"/ignore", "/echo", "/ignore", "/echo"} {
+		req, _ := http.NewRequest(consts.MethodPost, "http://127.0.0.1:18767"+path, bytes.NewReader(buf.Bytes()))
+		req.Header.Set(consts.HeaderContentEncoding, "gzip")
+		resp, err := cli.Do(req)
+		assert.Nil(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.DeepEqual(t, consts.StatusOK, resp.StatusCode)
+		if path == "/echo" {
+			assert.DeepEqual(t, src, string(body))
+		} else {
+			assert.DeepEqual(t, "ignored", string(body))
+		}
+	}
+}
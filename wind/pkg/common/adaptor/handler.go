@@ -0,0 +1,38 @@
+package adaptor
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// WindHandler 将标准库的 http.Handler 转为 wind 的处理器。
+//
+// 响应正文写入 wind 响应的缓冲区，处理器返回后才发往客户端，不适用于长连接等流式场景。
+// 标准库请求的上下文为处理器的 c。
+func WindHandler(h http.Handler) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		req, err := GetCompatRequest(&ctx.Request)
+		if err != nil {
+			ctx.AbortWithMsg(err.Error(), consts.StatusInternalServerError)
+			return
+		}
+		req = req.WithContext(c)
+		req.RemoteAddr = ctx.RemoteAddr().String()
+		req.RequestURI = string(ctx.Request.RequestURI())
+
+		w := GetCompatResponseWriter(&ctx.Response)
+		h.ServeHTTP(w, req)
+		// 未写入正文的处理器同样需要写出标头
+		if cw := w.(*compatResponse); !cw.wroteHeader {
+			cw.WriteHeader(consts.StatusOK)
+		}
+	}
+}
+
+// WindHandlerFunc 将标准库的 http.HandlerFunc 转为 wind 的处理器，参见 WindHandler。
+func WindHandlerFunc(f http.HandlerFunc) app.HandlerFunc {
+	return WindHandler(f)
+}
@@ -0,0 +1,53 @@
+package adaptor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/route"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestWindHandler(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(func(c context.Context, ctx *app.RequestContext) {
+		ctx.Next(context.WithValue(c, ctxKey{}, "v"))
+	})
+	engine.POST("/echo", WindHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Query", r.URL.Query().Get("q"))
+		w.Header().Set("X-Header", r.Header.Get("X-Foo"))
+		w.Header().Set("X-Context", r.Context().Value(ctxKey{}).(string))
+		w.Header().Set(consts.HeaderContentType, "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+	engine.GET("/empty", WindHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Empty", "1")
+	})))
+
+	resp := ut.PerformRequest(engine, consts.MethodPost, "/echo?q=1", &ut.Body{Body: strings.NewReader("hello"), Len: 5},
+		ut.Header{Key: "X-Foo", Value: "bar"}).Result()
+	assert.Equal(t, consts.StatusCreated, resp.StatusCode())
+	assert.Equal(t, "hello", string(resp.Body()))
+	assert.Equal(t, "POST", string(resp.Header.Peek("X-Method")))
+	assert.Equal(t, "1", string(resp.Header.Peek("X-Query")))
+	assert.Equal(t, "bar", string(resp.Header.Peek("X-Header")))
+	assert.Equal(t, "v", string(resp.Header.Peek("X-Context")))
+	assert.Equal(t, "text/plain", string(resp.Header.ContentType()))
+
+	resp = ut.PerformRequest(engine, consts.MethodGet, "/empty", nil).Result()
+	assert.Equal(t, consts.StatusOK, resp.StatusCode())
+	assert.Equal(t, "1", string(resp.Header.Peek("X-Empty")))
+	assert.Empty(t, resp.Body())
+}
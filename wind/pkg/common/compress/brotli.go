@@ -0,0 +1,81 @@
+package compress
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotli 压缩级别
+const (
+	BrotliBestSpeed          = brotli.BestSpeed
+	BrotliBestCompression    = brotli.BestCompression
+	BrotliDefaultCompression = brotli.DefaultCompression
+)
+
+// 按 brotli 的压缩级别 [0..11] 初始化的编写器池。
+var brotliWriterPoolMap = func() []*sync.Pool {
+	m := make([]*sync.Pool, BrotliBestCompression+1)
+	for i := range m {
+		m[i] = &sync.Pool{}
+	}
+	return m
+}()
+
+// AppendBrotliBytes 以默认级别 brotli 压缩 src 并附加到 dst，然后返回。
+func AppendBrotliBytes(dst, src []byte) []byte {
+	return AppendBrotliBytesLevel(dst, src, BrotliDefaultCompression)
+}
+
+// AppendBrotliBytesLevel 附加 brotli 压缩后的 src 到 dst 并返回（使用指定的压缩级别）。
+//
+// 压缩级别介于 BrotliBestSpeed 和 BrotliBestCompression 之间，超出时使用 BrotliDefaultCompression。
+func AppendBrotliBytesLevel(dst, src []byte, level int) []byte {
+	w := &byteSliceWriter{dst}
+	_, _ = WriteBrotliLevel(w, src, level)
+	return w.b
+}
+
+// WriteBrotliLevel 以 brotli 压缩 p 并写入 w（使用指定压缩级别），返回写入 w 的压缩量。
+func WriteBrotliLevel(w io.Writer, p []byte, level int) (int, error) {
+	zw := AcquireBrotliWriter(w, level)
+	n, err := zw.Write(p)
+	if err != nil {
+		ReleaseBrotliWriter(zw, level)
+		return n, err
+	}
+	return n, releaseBrotliWriter(zw, level)
+}
+
+// AcquireBrotliWriter 获取写入 w 的 brotli 编写器。
+//
+// 用完记得调用 ReleaseBrotliWriter 释放，以降低 GC，提高性能。
+func AcquireBrotliWriter(w io.Writer, level int) *brotli.Writer {
+	nLevel := normalizeBrotliLevel(level)
+	v := brotliWriterPoolMap[nLevel].Get()
+	if v == nil {
+		return brotli.NewWriterLevel(w, nLevel)
+	}
+	zw := v.(*brotli.Writer)
+	zw.Reset(w)
+	return zw
+}
+
+// ReleaseBrotliWriter 关闭 brotli 编写器并放回指定级别池。
+func ReleaseBrotliWriter(zw *brotli.Writer, level int) {
+	_ = releaseBrotliWriter(zw, level)
+}
+
+func releaseBrotliWriter(zw *brotli.Writer, level int) error {
+	err := zw.Close()
+	brotliWriterPoolMap[normalizeBrotliLevel(level)].Put(zw)
+	return err
+}
+
+func normalizeBrotliLevel(level int) int {
+	if level < BrotliBestSpeed || level > BrotliBestCompression {
+		return BrotliDefaultCompression
+	}
+	return level
+}
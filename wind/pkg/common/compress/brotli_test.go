@@ -0,0 +1,42 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressAppendBrotliBytesLevel(t *testing.T) {
+	src := bytes.Repeat([]byte("hello brotli "), 100)
+	for _, level := range []int{BrotliBestSpeed, BrotliDefaultCompression, BrotliBestCompression, -1} {
+		// 重复获取以覆盖池中编写器的复用
+		for i := 0; i < 2; i++ {
+			dst := AppendBrotliBytesLevel([]byte("!!!"), src, level)
+			if string(dst[:3]) != "!!!" {
+				t.Fatalf("前缀被覆盖：%q", dst[:3])
+			}
+			res, err := io.ReadAll(brotli.NewReader(bytes.NewReader(dst[3:])))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !bytes.Equal(res, src) {
+				t.Fatalf("级别 %d 解压结果不一致", level)
+			}
+		}
+	}
+}
+
+func TestCompressBrotliWriterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	zw := AcquireBrotliWriter(&buf, BrotliBestSpeed)
+	_, _ = zw.Write([]byte("hello"))
+	if err := zw.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("冲刷后应写出已压缩的数据")
+	}
+	ReleaseBrotliWriter(zw, BrotliBestSpeed)
+}
@@ -0,0 +1,119 @@
+package compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstd 解码的最大窗口，与 RFC 8878 对 HTTP 的建议一致。
+const zstdMaxWindow = 8 << 20
+
+// ErrUnsupportedEncoding 表示不支持的内容编码。
+var ErrUnsupportedEncoding = errors.New("不支持的内容编码")
+
+var (
+	brotliReaderPool sync.Pool
+	zstdReaderPool   sync.Pool
+)
+
+// NewDecoder 返回按 Content-Encoding 逐层解码 r 的数据流。
+//
+// 支持 gzip（含 x-gzip）、deflate、br 和 zstd，多个编码以逗号分隔时按相反顺序解码，
+// identity 会被忽略。遇到不支持的编码时返回 ErrUnsupportedEncoding。
+//
+// 用完须调用 Close 将解码器放回池中，Close 不会关闭 r。
+func NewDecoder(r io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	d := &chainDecoder{Reader: r}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+		zr, release, err := acquireDecoder(d.Reader, encoding)
+		if err != nil {
+			_ = d.Close()
+			return nil, err
+		}
+		d.Reader = zr
+		d.releases = append(d.releases, release)
+	}
+	return d, nil
+}
+
+// 逐层解码的数据流。
+type chainDecoder struct {
+	io.Reader
+	releases []func()
+}
+
+func (d *chainDecoder) Close() error {
+	for i := len(d.releases) - 1; i >= 0; i-- {
+		d.releases[i]()
+	}
+	d.releases = nil
+	return nil
+}
+
+func acquireDecoder(r io.Reader, encoding string) (io.Reader, func(), error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := AcquireGzipReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { ReleaseGzipReader(zr) }, nil
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		zr, _ := brotliReaderPool.Get().(*brotli.Reader)
+		if zr == nil {
+			zr = brotli.NewReader(r)
+		} else if err := zr.Reset(r); err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { brotliReaderPool.Put(zr) }, nil
+	case "zstd":
+		zr, _ := zstdReaderPool.Get().(*zstd.Decoder)
+		if zr == nil {
+			var err error
+			zr, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+			if err != nil {
+				return nil, nil, err
+			}
+		} else if err := zr.Reset(r); err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { zstdReaderPool.Put(zr) }, nil
+	}
+	return nil, nil, fmt.Errorf("%w：%s", ErrUnsupportedEncoding, encoding)
+}
+
+// HTTP 的 deflate 应为 zlib 格式，但不少客户端发送的是原始 deflate 数据，此处按首部自动识别。
+func newDeflateReader(r io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && isZlibHeader(header) {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { _ = zr.Close() }, nil
+	}
+	zr := flate.NewReader(br)
+	return zr, func() { _ = zr.Close() }, nil
+}
+
+// 首字节的低 4 位为压缩方法 8，且前两个字节按大端序可被 31 整除。
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
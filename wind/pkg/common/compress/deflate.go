@@ -0,0 +1,53 @@
+package compress
+
+import (
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+var realDeflateWriterPoolMap = newCompressWriterPoolMap()
+
+// AppendDeflateBytesLevel 附加 deflate 压缩后的 src 到 dst 并返回（使用指定的压缩级别）。
+//
+// 按 HTTP 的约定，deflate 编码为 zlib 格式（RFC 1950）。支持的压缩级别与 AppendGzipBytesLevel 相同。
+func AppendDeflateBytesLevel(dst, src []byte, level int) []byte {
+	w := &byteSliceWriter{dst}
+	_, _ = WriteDeflateLevel(w, src, level)
+	return w.b
+}
+
+// WriteDeflateLevel 以 deflate 压缩 p 并写入 w（使用指定压缩级别），返回写入 w 的压缩量。
+func WriteDeflateLevel(w io.Writer, p []byte, level int) (int, error) {
+	zw := AcquireDeflateWriter(w, level)
+	n, err := zw.Write(p)
+	ReleaseDeflateWriter(zw, level)
+	return n, err
+}
+
+// AcquireDeflateWriter 获取写入 w 的 deflate 编写器。
+//
+// 用完记得调用 ReleaseDeflateWriter 释放，以降低 GC，提高性能。
+func AcquireDeflateWriter(w io.Writer, level int) *zlib.Writer {
+	nLevel := normalizeCompressLevel(level)
+	p := realDeflateWriterPoolMap[nLevel]
+	v := p.Get()
+	if v == nil {
+		zw, err := zlib.NewWriterLevel(w, nLevel-2)
+		if err != nil {
+			panic(fmt.Sprintf("BUG: 来自 zlib.NewWriterLevel(%d) 的意外错误：%s", level, err))
+		}
+		return zw
+	}
+	zw := v.(*zlib.Writer)
+	zw.Reset(w)
+	return zw
+}
+
+// ReleaseDeflateWriter 关闭 deflate 编写器并放回指定级别池。
+func ReleaseDeflateWriter(zw *zlib.Writer, level int) {
+	_ = zw.Close()
+	nLevel := normalizeCompressLevel(level)
+	p := realDeflateWriterPoolMap[nLevel]
+	p.Put(zw)
+}
@@ -0,0 +1,30 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+func TestCompressAppendDeflateBytesLevel(t *testing.T) {
+	src := bytes.Repeat([]byte("hello deflate "), 100)
+	for _, level := range []int{flate.BestSpeed, CompressDefaultCompression, flate.BestCompression, 100} {
+		dst := AppendDeflateBytesLevel([]byte("!!!"), src, level)
+		if string(dst[:3]) != "!!!" {
+			t.Fatalf("前缀被覆盖：%q", dst[:3])
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(dst[3:]))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		res, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !bytes.Equal(res, src) {
+			t.Fatalf("级别 %d 解压结果不一致", level)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstd 压缩级别
+const (
+	ZstdSpeedFastest           = int(zstd.SpeedFastest)
+	ZstdSpeedDefault           = int(zstd.SpeedDefault)
+	ZstdSpeedBetterCompression = int(zstd.SpeedBetterCompression)
+	ZstdSpeedBestCompression   = int(zstd.SpeedBestCompression)
+)
+
+// 按 zstd 的压缩级别 [1..4] 初始化的编码器池，下标 0 不使用。
+var zstdWriterPoolMap = func() []*sync.Pool {
+	m := make([]*sync.Pool, ZstdSpeedBestCompression+1)
+	for i := range m {
+		m[i] = &sync.Pool{}
+	}
+	return m
+}()
+
+// AppendZstdBytes 以默认级别 zstd 压缩 src 并附加到 dst，然后返回。
+func AppendZstdBytes(dst, src []byte) []byte {
+	return AppendZstdBytesLevel(dst, src, ZstdSpeedDefault)
+}
+
+// AppendZstdBytesLevel 附加 zstd 压缩后的 src 到 dst 并返回（使用指定的压缩级别）。
+//
+// 压缩级别介于 ZstdSpeedFastest 和 ZstdSpeedBestCompression 之间，超出时使用 ZstdSpeedDefault。
+func AppendZstdBytesLevel(dst, src []byte, level int) []byte {
+	zw := AcquireZstdWriter(nil, level)
+	dst = zw.EncodeAll(src, dst)
+	// EncodeAll 不改变编码器的流状态，无需关闭
+	zstdWriterPoolMap[normalizeZstdLevel(level)].Put(zw)
+	return dst
+}
+
+// WriteZstdLevel 以 zstd 压缩 p 并写入 w（使用指定压缩级别），返回写入 w 的压缩量。
+func WriteZstdLevel(w io.Writer, p []byte, level int) (int, error) {
+	zw := AcquireZstdWriter(w, level)
+	n, err := zw.Write(p)
+	if err != nil {
+		ReleaseZstdWriter(zw, level)
+		return n, err
+	}
+	return n, releaseZstdWriter(zw, level)
+}
+
+// AcquireZstdWriter 获取写入 w 的 zstd 编码器。
+//
+// 用完记得调用 ReleaseZstdWriter 释放，以降低 GC，提高性能。
+func AcquireZstdWriter(w io.Writer, level int) *zstd.Encoder {
+	nLevel := normalizeZstdLevel(level)
+	v := zstdWriterPoolMap[nLevel].Get()
+	if v == nil {
+		zw, err := zstd.NewWriter(w,
+			zstd.WithEncoderLevel(zstd.EncoderLevel(nLevel)),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+		)
+		if err != nil {
+			panic(fmt.Sprintf("BUG: 来自 zstd.NewWriter(%d) 的意外错误：%s", level, err))
+		}
+		return zw
+	}
+	zw := v.(*zstd.Encoder)
+	zw.Reset(w)
+	return zw
+}
+
+// ReleaseZstdWriter 关闭 zstd 编码器并放回指定级别池。
+func ReleaseZstdWriter(zw *zstd.Encoder, level int) {
+	_ = releaseZstdWriter(zw, level)
+}
+
+func releaseZstdWriter(zw *zstd.Encoder, level int) error {
+	err := zw.Close()
+	zstdWriterPoolMap[normalizeZstdLevel(level)].Put(zw)
+	return err
+}
+
+func normalizeZstdLevel(level int) int {
+	if level < ZstdSpeedFastest || level > ZstdSpeedBestCompression {
+		return ZstdSpeedDefault
+	}
+	return level
+}
@@ -0,0 +1,48 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressAppendZstdBytesLevel(t *testing.T) {
+	src := bytes.Repeat([]byte("hello zstd "), 100)
+	zr, _ := zstd.NewReader(nil)
+	defer zr.Close()
+	for _, level := range []int{ZstdSpeedFastest, ZstdSpeedDefault, ZstdSpeedBestCompression, 0} {
+		for i := 0; i < 2; i++ {
+			dst := AppendZstdBytesLevel([]byte("!!!"), src, level)
+			if string(dst[:3]) != "!!!" {
+				t.Fatalf("前缀被覆盖：%q", dst[:3])
+			}
+			res, err := zr.DecodeAll(dst[3:], nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !bytes.Equal(res, src) {
+				t.Fatalf("级别 %d 解压结果不一致", level)
+			}
+		}
+	}
+}
+
+func TestCompressWriteZstdLevel(t *testing.T) {
+	src := []byte("hello zstd stream")
+	var buf bytes.Buffer
+	n, err := WriteZstdLevel(&buf, src, ZstdSpeedDefault)
+	if err != nil || n != len(src) {
+		t.Fatalf("Unexpected result: %d, %v", n, err)
+	}
+	zr, _ := zstd.NewReader(&buf)
+	defer zr.Close()
+	res, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !bytes.Equal(res, src) {
+		t.Fatalf("Unexpected : %s. Expecting : %s", res, src)
+	}
+}
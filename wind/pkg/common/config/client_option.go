@@ -19,6 +19,10 @@ type ConnPoolState struct {
 	WaitConnNum int
 	// HostClient 地址
 	Addr string
+	// 活跃的流数量，仅适用于 HTTP/2 等多路复用的连接。
+	ActiveStreamNum int
+	// 对端允许的最大并发流数量之和，仅适用于 HTTP/2 等多路复用的连接。
+	MaxStreamNum int
 }
 
 type HostClientState interface {
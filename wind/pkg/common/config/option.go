@@ -68,6 +68,7 @@ type Options struct {
 	DisableKeepalive             bool          // 是否禁用长连接，默认否
 	DisablePreParseMultipartForm bool          // 是否不预先解析多部分表单，默认否
 	StreamRequestBody            bool          // 是否流式处理请求正文，默认否
+	DecompressRequestBody        bool          // 是否解码压缩的请求正文，默认否
 	NoDefaultServerHeader        bool          // 是否不要默认的服务器名称标头，默认否
 	DisablePrintRoute            bool          // 是否禁止打印路由，默认否
 	Network                      string        // 网络协议，可选 "tcp", "udp", "unix"(unix domain socket)，默认 "tcp"
@@ -106,6 +107,18 @@ type Options struct {
 	// HTML 模板自动重载时间间隔。
 	// 默认为0，即根据文件变更事件立即重载。
 	AutoReloadInterval time.Duration
+
+	// 默认绑定器的配置，类型为 *binding.BindConfig。
+	BindConfig any
+
+	// 默认校验器的配置，类型为 *binding.ValidateConfig。
+	ValidateConfig any
+
+	// 自定义绑定器，需实现 binding.Binder 接口。
+	CustomBinder any
+
+	// 自定义校验器，需实现 binding.StructValidator 接口。
+	CustomValidator any
 }
 
 // Apply 将指定的一组配置方法 opts 应用到配置项上。
@@ -128,95 +128,102 @@ func (l *defaultLogger) SetLevel(lv Level) {
 }
 
 func (l *defaultLogger) Trace(v ...any) {
-	l.logf(LevelTrace, nil, v...)
+	l.logf(nil, LevelTrace, nil, v...)
 }
 
 func (l *defaultLogger) Debug(v ...any) {
-	l.logf(LevelDebug, nil, v...)
+	l.logf(nil, LevelDebug, nil, v...)
 }
 
 func (l *defaultLogger) Info(v ...any) {
-	l.logf(LevelInfo, nil, v...)
+	l.logf(nil, LevelInfo, nil, v...)
 }
 
 func (l *defaultLogger) Notice(v ...any) {
-	l.logf(LevelNotice, nil, v...)
+	l.logf(nil, LevelNotice, nil, v...)
 }
 
 func (l *defaultLogger) Warn(v ...any) {
-	l.logf(LevelWarn, nil, v...)
+	l.logf(nil, LevelWarn, nil, v...)
 }
 
 func (l *defaultLogger) Error(v ...any) {
-	l.logf(LevelError, nil, v...)
+	l.logf(nil, LevelError, nil, v...)
 }
 
 func (l *defaultLogger) Fatal(v ...any) {
-	l.logf(LevelFatal, nil, v...)
+	l.logf(nil, LevelFatal, nil, v...)
 }
 
 func (l *defaultLogger) Tracef(format string, v ...any) {
-	l.logf(LevelTrace, &format, v...)
+	l.logf(nil, LevelTrace, &format, v...)
 }
 
 func (l *defaultLogger) Debugf(format string, v ...any) {
-	l.logf(LevelDebug, &format, v...)
+	l.logf(nil, LevelDebug, &format, v...)
 }
 
 func (l *defaultLogger) Infof(format string, v ...any) {
-	l.logf(LevelInfo, &format, v...)
+	l.logf(nil, LevelInfo, &format, v...)
 }
 
 func (l *defaultLogger) Noticef(format string, v ...any) {
-	l.logf(LevelNotice, &format, v...)
+	l.logf(nil, LevelNotice, &format, v...)
 }
 
 func (l *defaultLogger) Warnf(format string, v ...any) {
-	l.logf(LevelWarn, &format, v...)
+	l.logf(nil, LevelWarn, &format, v...)
 }
 
 func (l *defaultLogger) Errorf(format string, v ...any) {
-	l.logf(LevelError, &format, v...)
+	l.logf(nil, LevelError, &format, v...)
 }
 
 func (l *defaultLogger) Fatalf(format string, v ...any) {
-	l.logf(LevelFatal, &format, v...)
+	l.logf(nil, LevelFatal, &format, v...)
 }
 
 func (l *defaultLogger) CtxTracef(ctx context.Context, format string, v ...any) {
-	l.logf(LevelTrace, &format, v...)
+	l.logf(ctx, LevelTrace, &format, v...)
 }
 
 func (l *defaultLogger) CtxDebugf(ctx context.Context, format string, v ...any) {
-	l.logf(LevelDebug, &format, v...)
+	l.logf(ctx, LevelDebug, &format, v...)
 }
 
 func (l *defaultLogger) CtxInfof(ctx context.Context, format string, v ...any) {
-	l.logf(LevelInfo, &format, v...)
+	l.logf(ctx, LevelInfo, &format, v...)
 }
 
 func (l *defaultLogger) CtxNoticef(ctx context.Context, format string, v ...any) {
-	l.logf(LevelNotice, &format, v...)
+	l.logf(ctx, LevelNotice, &format, v...)
 }
 
 func (l *defaultLogger) CtxWarnf(ctx context.Context, format string, v ...any) {
-	l.logf(LevelWarn, &format, v...)
+	l.logf(ctx, LevelWarn, &format, v...)
 }
 
 func (l *defaultLogger) CtxErrorf(ctx context.Context, format string, v ...any) {
-	l.logf(LevelError, &format, v...)
+	l.logf(ctx, LevelError, &format, v...)
 }
 
 func (l *defaultLogger) CtxFatalf(ctx context.Context, format string, v ...any) {
-	l.logf(LevelFatal, &format, v...)
+	l.logf(ctx, LevelFatal, &format, v...)
 }
 
-func (l *defaultLogger) logf(lv Level, format *string, v ...any) {
+func (l *defaultLogger) logf(ctx context.Context, lv Level, format *string, v ...any) {
 	// 低于设置的日志级别，将不会输出。
 	if l.level > lv {
 		return
 	}
 	msg := lv.String()
+	if ctx != nil {
+		for _, extract := range ctxExtractors {
+			if s := extract(ctx); s != "" {
+				msg += s + " "
+			}
+		}
+	}
 	if format != nil {
 		msg += fmt.Sprintf(*format, v...)
 	} else {
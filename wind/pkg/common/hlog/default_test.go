@@ -129,3 +129,26 @@ func TestSetLevel(t *testing.T) {
 	assert.Equal(t, 7, int(stdLogger.level))
 	assert.Equal(t, "[?7] ", stdLogger.level.String())
 }
+
+func TestCtxExtractor(t *testing.T) {
+	initTestLogger()
+	defer func() { ctxExtractors = nil }()
+
+	var w byteSliceWriter
+	SetOutput(&w)
+
+	type key struct{}
+	AddCtxExtractor(func(ctx context.Context) string {
+		v, _ := ctx.Value(key{}).(string)
+		return v
+	})
+	ctx := context.WithValue(context.Background(), key{}, "user=42")
+
+	CtxInfof(ctx, "开始%s", "工作")
+	CtxInfof(context.Background(), "开始%s", "工作")
+	Info("开始工作")
+
+	assert.Equal(t, "[Info] user=42 开始工作\n"+
+		"[Info] 开始工作\n"+
+		"[Info] 开始工作\n", string(w.b))
+}
@@ -1,6 +1,7 @@
 package hlog
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
@@ -23,6 +24,21 @@ var (
 	}
 )
 
+// CtxExtractor 从上下文中提取需要附加到日志的内容，没有时返回空串。
+type CtxExtractor func(ctx context.Context) string
+
+// 默认记录器的上下文提取器
+var ctxExtractors []CtxExtractor
+
+// AddCtxExtractor 添加默认记录器的上下文提取器。
+//
+// 默认记录器的 Ctx 系列方法会将提取出的非空内容依次附加在日志级别之后，
+// 如请求标识中间件借此使日志自动带上当前请求的标识。经 SetLogger 设置的记录器需自行读取上下文。
+// 注意：该方法非并发安全，应在初始化阶段调用。
+func AddCtxExtractor(fn CtxExtractor) {
+	ctxExtractors = append(ctxExtractors, fn)
+}
+
 // SetOutput 设置默认记录器和系统记录器的输出器。
 // 默认为 os.Stderr。
 func SetOutput(w io.Writer) {
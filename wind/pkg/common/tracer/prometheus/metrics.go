@@ -0,0 +1,241 @@
+package prometheus
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 指标的元信息。
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+// 以文本格式写出指标的 HELP 和 TYPE 行。
+func (d *desc) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP ")
+	w.WriteString(d.name)
+	w.WriteByte(' ')
+	w.WriteString(helpReplacer.Replace(d.help))
+	w.WriteString("\n# TYPE ")
+	w.WriteString(d.name)
+	w.WriteByte(' ')
+	w.WriteString(d.typ)
+	w.WriteByte('\n')
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// 写出一个样本行，如 name{a="1",le="0.5"} 3。
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, l, values[i])
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(labelReplacer.Replace(value))
+	w.WriteByte('"')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// 标签值组合的唯一键。
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// 按标签值组合区分的一组时间序列。
+type vec struct {
+	mu        sync.RWMutex
+	series    map[string]any
+	values    map[string][]string
+	newSeries func() any
+}
+
+func newVec(newSeries func() any) vec {
+	return vec{
+		series:    make(map[string]any),
+		values:    make(map[string][]string),
+		newSeries: newSeries,
+	}
+}
+
+// 返回标签值组合对应的时间序列，不存在时创建。
+func (v *vec) with(values ...string) any {
+	key := seriesKey(values)
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok = v.series[key]; !ok {
+		s = v.newSeries()
+		v.series[key] = s
+		v.values[key] = append([]string(nil), values...)
+	}
+	return s
+}
+
+// 按标签值排序遍历时间序列，使输出稳定。
+func (v *vec) each(f func(values []string, s any)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	v.mu.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v.mu.RLock()
+		s, values := v.series[k], v.values[k]
+		v.mu.RUnlock()
+		f(values, s)
+	}
+}
+
+type counterVec struct {
+	desc
+	vec
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		desc: desc{name: name, help: help, typ: "counter", labels: labels},
+		vec:  newVec(func() any { return new(uint64) }),
+	}
+}
+
+func (c *counterVec) inc(values ...string) {
+	atomic.AddUint64(c.with(values...).(*uint64), 1)
+}
+
+func (c *counterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.each(func(values []string, s any) {
+		writeSample(w, c.name, c.labels, values, "", "", float64(atomic.LoadUint64(s.(*uint64))))
+	})
+}
+
+// 无标签的仪表盘。
+type gauge struct {
+	n int64 // 首字段以保证 32 位平台上原子操作的对齐
+	desc
+}
+
+func newGauge(name, help string) *gauge {
+	return &gauge{desc: desc{name: name, help: help, typ: "gauge"}}
+}
+
+func (g *gauge) add(n int64) {
+	atomic.AddInt64(&g.n, n)
+}
+
+func (g *gauge) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	writeSample(w, g.name, nil, nil, "", "", float64(atomic.LoadInt64(&g.n)))
+}
+
+// 直方图，buckets 为升序的上界，不含 +Inf。
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	i := sort.SearchFloat64s(buckets, v)
+	h.mu.Lock()
+	if i < len(buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+type histogramVec struct {
+	desc
+	vec
+	buckets []float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &histogramVec{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		vec:     newVec(func() any { return &histogram{counts: make([]uint64, len(buckets))} }),
+		buckets: buckets,
+	}
+}
+
+func (h *histogramVec) observe(v float64, values ...string) {
+	h.with(values...).(*histogram).observe(h.buckets, v)
+}
+
+func (h *histogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.each(func(values []string, v any) {
+		s := v.(*histogram)
+		s.mu.Lock()
+		counts := append([]uint64(nil), s.counts...)
+		count, sum := s.count, s.sum
+		s.mu.Unlock()
+
+		// 文本格式中桶的计数是累计的
+		var cumulative uint64
+		for i, b := range h.buckets {
+			cumulative += counts[i]
+			writeSample(w, h.name+"_bucket", h.labels, values, "le", formatFloat(b), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, values, "le", "+Inf", float64(count))
+		writeSample(w, h.name+"_sum", h.labels, values, "", "", sum)
+		writeSample(w, h.name+"_count", h.labels, values, "", "", float64(count))
+	})
+}
@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"bufio"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type writer interface {
+	write(w *bufio.Writer)
+}
+
+func text(m writer) string {
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	m.write(w)
+	_ = w.Flush()
+	return b.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	c := newCounterVec("requests_total", "Total\\requests.\nSecond line.", "method", "path")
+	assert.Equal(t, "# HELP requests_total Total\\\\requests.\\nSecond line.\n# TYPE requests_total counter\n", text(c))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.inc("GET", "/b")
+		}()
+	}
+	wg.Wait()
+	c.inc("GET", `/a"\`+"\n")
+
+	assert.Equal(t, `# HELP requests_total Total\\requests.\nSecond line.
+# TYPE requests_total counter
+requests_total{method="GET",path="/a\"\\\n"} 1
+requests_total{method="GET",path="/b"} 100
+`, text(c))
+}
+
+func TestGauge(t *testing.T) {
+	g := newGauge("in_flight", "In flight.")
+	g.add(3)
+	g.add(-1)
+	assert.Equal(t, "# HELP in_flight In flight.\n# TYPE in_flight gauge\nin_flight 2\n", text(g))
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := newHistogramVec("latency_seconds", "Latency.", []float64{1, 0.5}, "path")
+	h.observe(0.5, "/")
+	h.observe(0.7, "/")
+	h.observe(3, "/")
+	h.observe(0.1, "/x")
+
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/",le="0.5"} 1
+latency_seconds_bucket{path="/",le="1"} 2
+latency_seconds_bucket{path="/",le="+Inf"} 3
+latency_seconds_sum{path="/"} 4.2
+latency_seconds_count{path="/"} 3
+latency_seconds_bucket{path="/x",le="0.5"} 1
+latency_seconds_bucket{path="/x",le="1"} 1
+latency_seconds_bucket{path="/x",le="+Inf"} 1
+latency_seconds_sum{path="/x"} 0.1
+latency_seconds_count{path="/x"} 1
+`, text(h))
+}
+
+func TestFormatFloat(t *testing.T) {
+	assert.Equal(t, "+Inf", formatFloat(math.Inf(1)))
+	assert.Equal(t, "-Inf", formatFloat(math.Inf(-1)))
+	assert.Equal(t, "NaN", formatFloat(math.NaN()))
+	assert.Equal(t, "1e+06", formatFloat(1e6))
+	assert.Equal(t, "0.005", formatFloat(0.005))
+	assert.Equal(t, "42", formatFloat(42))
+}
@@ -0,0 +1,63 @@
+package prometheus
+
+// 默认的指标名称前缀。
+const defaultNamespace = "wind"
+
+var (
+	// 默认的耗时直方图桶（秒），与 Prometheus 客户端库一致。
+	defaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// 默认的字节数直方图桶，从 64 B 至 4 MiB 按 4 倍递增。
+	defaultSizeBuckets = []float64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+)
+
+// 表示一个 Prometheus 跟踪器的自定义选项结构体。
+type options struct {
+	// 指标名称的前缀。
+	namespace string
+
+	// 耗时直方图的桶。
+	latencyBuckets []float64
+
+	// 请求和响应字节数直方图的桶。
+	sizeBuckets []float64
+}
+
+// Option 自定义选项的应用函数。
+type Option func(o *options)
+
+// 创建一个自定义 Prometheus 跟踪器的结构，并应用自定义选项。
+func newOptions(opts ...Option) *options {
+	cfg := &options{
+		namespace:      defaultNamespace,
+		latencyBuckets: defaultLatencyBuckets,
+		sizeBuckets:    defaultSizeBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithNamespace 设置指标名称的前缀，默认为 wind，为空时指标名称无前缀。
+func WithNamespace(ns string) Option {
+	return func(o *options) {
+		o.namespace = ns
+	}
+}
+
+// WithLatencyBuckets 设置耗时直方图的桶（秒），默认与 Prometheus 客户端库的 DefBuckets 一致。
+func WithLatencyBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.latencyBuckets = buckets
+	}
+}
+
+// WithSizeBuckets 设置请求和响应字节数直方图的桶，默认从 64 B 至 4 MiB 按 4 倍递增。
+func WithSizeBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.sizeBuckets = buckets
+	}
+}
@@ -0,0 +1,25 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOption(t *testing.T) {
+	opts := newOptions()
+	assert.Equal(t, "wind", opts.namespace)
+	assert.Equal(t, defaultLatencyBuckets, opts.latencyBuckets)
+	assert.Equal(t, defaultSizeBuckets, opts.sizeBuckets)
+}
+
+func TestOption(t *testing.T) {
+	opts := newOptions(
+		WithNamespace("app"),
+		WithLatencyBuckets(0.1, 1),
+		WithSizeBuckets(100, 1000),
+	)
+	assert.Equal(t, "app", opts.namespace)
+	assert.Equal(t, []float64{0.1, 1}, opts.latencyBuckets)
+	assert.Equal(t, []float64{100, 1000}, opts.sizeBuckets)
+}
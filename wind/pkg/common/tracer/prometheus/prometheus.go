@@ -0,0 +1,165 @@
+package prometheus
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/server"
+	"github.com/favbox/gosky/wind/pkg/common/tracer"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+const (
+	// DefaultPath 是指标路由的默认路径。
+	DefaultPath = "/metrics"
+
+	// ContentType 是 Prometheus 文本格式的内容类型。
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+const (
+	// 请求开始时间在 RequestContext.Keys 中的键。
+	keyStart = "prometheus_start"
+
+	// 未匹配路由的请求的 path 标签值，避免以原始路径为标签值导致时间序列无限增长。
+	unmatchedPath = "<unmatched>"
+
+	// 非标准请求方法的 method 标签值。
+	otherMethod = "OTHER"
+)
+
+var knownMethods = map[string]bool{
+	consts.MethodGet:     true,
+	consts.MethodHead:    true,
+	consts.MethodPost:    true,
+	consts.MethodPut:     true,
+	consts.MethodPatch:   true,
+	consts.MethodDelete:  true,
+	consts.MethodConnect: true,
+	consts.MethodOptions: true,
+	consts.MethodTrace:   true,
+}
+
+var _ tracer.Tracer = (*ServerTracer)(nil)
+
+// ServerTracer 是以 Prometheus 文本格式导出 HTTP 服务指标的跟踪器，需经 server.WithTracer 注册。
+//
+// 导出的指标如下，名称前缀默认为 wind，可通过 WithNamespace 修改：
+//
+//   - wind_http_server_requests_total：请求数，标签为 method、path 和 status；
+//   - wind_http_server_request_duration_seconds：请求耗时直方图，标签同上；
+//   - wind_http_server_requests_in_flight：处理中的请求数；
+//   - wind_http_server_request_size_bytes：请求字节数直方图，标签为 method 和 path；
+//   - wind_http_server_response_size_bytes：响应字节数直方图，标签同上；
+//   - wind_http_server_panics_total：处理器恐慌数，标签同上，需注册 recovery 中间件。
+//
+// path 为匹配的路由，如 /user/:name，未匹配路由时为 <unmatched>；status 为状态码类别，如 2xx；
+// 非标准的请求方法统一记为 OTHER。耗时自读取请求前起算，至响应写出为止；
+// 收发字节数含标头，取自 traceinfo.HTTPStats。
+type ServerTracer struct {
+	requests     *counterVec
+	latency      *histogramVec
+	inFlight     *gauge
+	requestSize  *histogramVec
+	responseSize *histogramVec
+	panics       *counterVec
+}
+
+// NewServerTracer 创建一个 Prometheus 跟踪器。
+func NewServerTracer(opts ...Option) *ServerTracer {
+	o := newOptions(opts...)
+	prefix := "http_server_"
+	if o.namespace != "" {
+		prefix = o.namespace + "_" + prefix
+	}
+
+	return &ServerTracer{
+		requests:     newCounterVec(prefix+"requests_total", "Total number of HTTP requests.", "method", "path", "status"),
+		latency:      newHistogramVec(prefix+"request_duration_seconds", "HTTP request latency in seconds.", o.latencyBuckets, "method", "path", "status"),
+		inFlight:     newGauge(prefix+"requests_in_flight", "Number of HTTP requests being served."),
+		requestSize:  newHistogramVec(prefix+"request_size_bytes", "HTTP request size in bytes, including headers.", o.sizeBuckets, "method", "path"),
+		responseSize: newHistogramVec(prefix+"response_size_bytes", "HTTP response size in bytes, including headers.", o.sizeBuckets, "method", "path"),
+		panics:       newCounterVec(prefix+"panics_total", "Total number of recovered handler panics.", "method", "path"),
+	}
+}
+
+// Start 实现 tracer.Tracer 接口。
+func (t *ServerTracer) Start(c context.Context, ctx *app.RequestContext) context.Context {
+	t.inFlight.add(1)
+	ctx.Set(keyStart, time.Now())
+	return c
+}
+
+// Finish 实现 tracer.Tracer 接口。
+func (t *ServerTracer) Finish(_ context.Context, ctx *app.RequestContext) {
+	// 连接关闭时可能在请求结束后再次调用，仅统计与 Start 配对的调用
+	v, _ := ctx.Get(keyStart)
+	start, ok := v.(time.Time)
+	if !ok || start.IsZero() {
+		return
+	}
+	ctx.Set(keyStart, time.Time{})
+	t.inFlight.add(-1)
+
+	// 连接未发送任何请求即关闭
+	if ctx.Request.Header.GetProtocol() == "" {
+		return
+	}
+
+	method := string(ctx.Request.Header.Method())
+	if !knownMethods[method] {
+		method = otherMethod
+	}
+	path := ctx.FullPath()
+	if path == "" {
+		path = unmatchedPath
+	}
+	status := strconv.Itoa(ctx.Response.StatusCode()/100) + "xx"
+
+	t.requests.inc(method, path, status)
+	t.latency.observe(time.Since(start).Seconds(), method, path, status)
+	if ti := ctx.GetTraceInfo(); ti != nil {
+		st := ti.Stats()
+		t.requestSize.observe(float64(st.RecvSize()), method, path)
+		t.responseSize.observe(float64(st.SendSize()), method, path)
+		if panicked, _ := st.Panicked(); panicked {
+			t.panics.inc(method, path)
+		}
+	}
+}
+
+// Handler 返回以 Prometheus 文本格式响应指标的处理器，如：
+//
+//	h.GET(prometheus.DefaultPath, t.Handler())
+func (t *ServerTracer) Handler() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetContentType(ContentType)
+		w := bufio.NewWriter(ctx.Response.BodyWriter())
+		t.write(w)
+		_ = w.Flush()
+	}
+}
+
+// NewServer 创建一个仅在 DefaultPath 上提供指标的服务器，用于在独立的端口上监听，如：
+//
+//	go t.NewServer(":9091").Spin()
+//
+// handlers 在指标路由前执行，可用于认证。
+func (t *ServerTracer) NewServer(addr string, handlers ...app.HandlerFunc) *server.Wind {
+	w := server.New(server.WithHostPorts(addr), server.WithDisablePrintRoute(true))
+	w.GET(DefaultPath, append(handlers, t.Handler())...)
+	return w
+}
+
+// 以文本格式写出所有指标。
+func (t *ServerTracer) write(w *bufio.Writer) {
+	t.requests.write(w)
+	t.latency.write(w)
+	t.inFlight.write(w)
+	t.requestSize.write(w)
+	t.responseSize.write(w)
+	t.panics.write(w)
+}
@@ -0,0 +1,154 @@
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/middlewares/server/recovery"
+	"github.com/favbox/gosky/wind/pkg/app/server"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/stats"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/traceinfo"
+	"github.com/favbox/gosky/wind/pkg/common/ut"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/stretchr/testify/assert"
+)
+
+// 模拟服务器对一个请求调用跟踪器。
+func serve(tr *ServerTracer, method, fullPath string, status int, panicked bool) {
+	ctx := app.NewContext(0)
+	ti := traceinfo.NewTraceInfo()
+	ti.Stats().SetLevel(stats.LevelBase)
+	ctx.SetTraceInfo(ti)
+
+	c := tr.Start(context.Background(), ctx)
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.Header.SetProtocol(consts.HTTP11)
+	ctx.SetFullPath(fullPath)
+	ctx.SetStatusCode(status)
+	ti.Stats().SetRecvSize(100)
+	ti.Stats().SetSendSize(300)
+	if panicked {
+		ti.Stats().SetPanicked("boom")
+	}
+	tr.Finish(c, ctx)
+	// 连接关闭时的重复调用不重复统计
+	tr.Finish(c, ctx)
+}
+
+func metrics(tr *ServerTracer) string {
+	engine := server.New()
+	engine.GET(DefaultPath, tr.Handler())
+	resp := ut.PerformRequest(engine.Engine, consts.MethodGet, DefaultPath, nil).Result()
+	if string(resp.Header.ContentType()) != ContentType {
+		return ""
+	}
+	return string(resp.Body())
+}
+
+func TestServerTracer(t *testing.T) {
+	tr := NewServerTracer(WithLatencyBuckets(1), WithSizeBuckets(200))
+	serve(tr, consts.MethodGet, "/user/:name", consts.StatusOK, false)
+	serve(tr, consts.MethodGet, "/user/:name", consts.StatusCreated, false)
+	serve(tr, consts.MethodPost, "/user/:name", consts.StatusInternalServerError, true)
+	serve(tr, "PROPFIND", "", consts.StatusNotFound, false)
+
+	out := metrics(tr)
+	assert.Contains(t, out, `
+# TYPE wind_http_server_requests_total counter
+wind_http_server_requests_total{method="GET",path="/user/:name",status="2xx"} 2
+wind_http_server_requests_total{method="OTHER",path="<unmatched>",status="4xx"} 1
+wind_http_server_requests_total{method="POST",path="/user/:name",status="5xx"} 1
+`)
+	assert.Contains(t, out, `wind_http_server_request_duration_seconds_bucket{method="GET",path="/user/:name",status="2xx",le="1"} 2
+wind_http_server_request_duration_seconds_bucket{method="GET",path="/user/:name",status="2xx",le="+Inf"} 2
+`)
+	assert.Contains(t, out, "wind_http_server_requests_in_flight 0\n")
+	assert.Contains(t, out, `wind_http_server_request_size_bytes_bucket{method="GET",path="/user/:name",le="200"} 2
+wind_http_server_request_size_bytes_bucket{method="GET",path="/user/:name",le="+Inf"} 2
+wind_http_server_request_size_bytes_sum{method="GET",path="/user/:name"} 200
+`)
+	assert.Contains(t, out, `wind_http_server_response_size_bytes_bucket{method="GET",path="/user/:name",le="200"} 0
+wind_http_server_response_size_bytes_bucket{method="GET",path="/user/:name",le="+Inf"} 2
+wind_http_server_response_size_bytes_sum{method="GET",path="/user/:name"} 600
+`)
+	assert.Contains(t, out, `
+# TYPE wind_http_server_panics_total counter
+wind_http_server_panics_total{method="POST",path="/user/:name"} 1
+`)
+}
+
+func TestServerTracerInFlight(t *testing.T) {
+	tr := NewServerTracer(WithNamespace(""))
+	ctx := app.NewContext(0)
+	c := tr.Start(context.Background(), ctx)
+	assert.Contains(t, metrics(tr), "\nhttp_server_requests_in_flight 1\n")
+
+	// 未收到请求即关闭的连接不计入请求数
+	tr.Finish(c, ctx)
+	out := metrics(tr)
+	assert.Contains(t, out, "\nhttp_server_requests_in_flight 0\n")
+	assert.NotContains(t, out, "http_server_requests_total{")
+}
+
+func TestServerTracerServe(t *testing.T) {
+	tr := NewServerTracer()
+	h := server.New(server.WithHostPorts("127.0.0.1:10091"), server.WithTracer(tr), server.WithDisablePrintRoute(true))
+	h.Use(recovery.Recovery())
+	h.GET("/hello/:name", func(c context.Context, ctx *app.RequestContext) {
+		ctx.String(consts.StatusOK, "hello "+ctx.Param("name"))
+	})
+	h.GET("/panic", func(c context.Context, ctx *app.RequestContext) {
+		panic("boom")
+	})
+	h.GET(DefaultPath, tr.Handler())
+	go h.Spin()
+	defer h.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	get := func(path string, keepAlive bool) {
+		req, _ := http.NewRequest(consts.MethodGet, "http://127.0.0.1:10091"+path, nil)
+		req.Close = !keepAlive
+		resp, err := http.DefaultClient.Do(req)
+		if assert.Nil(t, err) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+	}
+	get("/hello/a", true)
+	get("/hello/b", true)
+	get("/hello/c", false)
+	get("/panic", false)
+	get("/missing", false)
+
+	assert.Eventually(t, func() bool {
+		req, _ := http.NewRequest(consts.MethodGet, "http://127.0.0.1:10091"+DefaultPath, nil)
+		req.Close = true
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		out := string(b)
+		return strings.Contains(out, `wind_http_server_requests_total{method="GET",path="/hello/:name",status="2xx"} 3`+"\n") &&
+			strings.Contains(out, `wind_http_server_requests_total{method="GET",path="/panic",status="5xx"} 1`+"\n") &&
+			strings.Contains(out, `wind_http_server_requests_total{method="GET",path="<unmatched>",status="4xx"} 1`+"\n") &&
+			strings.Contains(out, `wind_http_server_panics_total{method="GET",path="/panic"} 1`+"\n") &&
+			strings.Contains(out, "wind_http_server_requests_in_flight 1\n")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNewServer(t *testing.T) {
+	tr := NewServerTracer()
+	w := tr.NewServer("127.0.0.1:0", func(c context.Context, ctx *app.RequestContext) {
+		ctx.AbortWithStatus(consts.StatusUnauthorized)
+	})
+	assert.Equal(t, "127.0.0.1:0", w.GetOptions().Addr)
+	resp := ut.PerformRequest(w.Engine, consts.MethodGet, DefaultPath, nil).Result()
+	assert.Equal(t, consts.StatusUnauthorized, resp.StatusCode())
+}
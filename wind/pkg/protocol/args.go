@@ -128,6 +128,11 @@ func (a *Args) PeekExists(key string) (string, bool) {
 	return peekArgStrExists(a.args, key)
 }
 
+// PeekAll 返回指定键的所有查询参数值。
+func (a *Args) PeekAll(key string) [][]byte {
+	return peekAllArgBytesToDst(nil, a.args, bytesconv.S2b(key))
+}
+
 // VisitAll 对每个参数执行 f，类似于map。
 // f 在返回后不能保留对 key 和 value 的引用。
 // 如果需要你得制作 key/value 的副本。
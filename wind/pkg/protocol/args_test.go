@@ -49,6 +49,16 @@ func TestArgsPeekExists(t *testing.T) {
 	assert.True(t, b4)
 }
 
+func TestArgsPeekAll(t *testing.T) {
+	var a Args
+	a.Add("q1", "foo")
+	a.Add("q2", "bar")
+	a.Add("q1", "baz")
+	assert.DeepEqual(t, [][]byte{[]byte("foo"), []byte("baz")}, a.PeekAll("q1"))
+	assert.DeepEqual(t, [][]byte{[]byte("bar")}, a.PeekAll("q2"))
+	assert.Nil(t, a.PeekAll("q3"))
+}
+
 func TestSetArg(t *testing.T) {
 	a := Args{args: setArg(nil, "q1", "foo", true)}
 	a.Add("", "")
@@ -12,6 +12,9 @@ const (
 	// DefaultMaxIdleConnDuration 闲置长连接超过此时长后会被关闭。
 	DefaultMaxIdleConnDuration = 10 * time.Second
 
+	// DefaultMaxRetryTimes 客户端默认的最大尝试次数，包括初始调用。
+	DefaultMaxRetryTimes = 1
+
 	// DefaultMaxInMemoryFileSize 定义解析多部分表单使用的内存文件大小，若超此值，则写入磁盘。
 	DefaultMaxInMemoryFileSize = 16 * 1024 * 1024
 )
@@ -3,8 +3,11 @@ package consts
 const (
 	HeaderDate = "Date"
 
-	HeaderIfModifiedSince = "If-Modified-Since"
-	HeaderLastModified    = "Last-Modified"
+	HeaderIfMatch           = "If-Match"
+	HeaderIfModifiedSince   = "If-Modified-Since"
+	HeaderIfNoneMatch       = "If-None-Match"
+	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+	HeaderLastModified      = "Last-Modified"
 
 	HeaderLocation = "Location" // 重定向
 )
@@ -52,6 +55,7 @@ const (
 // 响应上下文类
 const (
 	HeaderAllow       = "Allow"
+	HeaderRetryAfter  = "Retry-After"
 	HeaderServer      = "Server"
 	HeaderServerLower = "server"
 )
@@ -60,6 +64,7 @@ const (
 const (
 	HeaderFrom          = "From"
 	HeaderHost          = "Host"
+	HeaderOrigin        = "Origin"
 	HeaderReferer       = "Referer"
 	HeaderRefererPolicy = "Referer-Policy"
 	HeaderUserAgent     = "User-Agent"
@@ -83,6 +88,53 @@ const (
 	HeaderAltSvc         = "Alt-Svc"
 )
 
+// 缓存类
+const (
+	HeaderAge          = "Age"
+	HeaderCacheControl = "Cache-Control"
+	HeaderETag         = "ETag"
+	HeaderExpires      = "Expires"
+	HeaderPragma       = "Pragma"
+	HeaderVary         = "Vary"
+)
+
+// 跨域资源共享类
+const (
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
+)
+
+// 安全类
+const (
+	HeaderContentSecurityPolicy           = "Content-Security-Policy"
+	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+	HeaderCrossOriginEmbedderPolicy       = "Cross-Origin-Embedder-Policy"
+	HeaderCrossOriginOpenerPolicy         = "Cross-Origin-Opener-Policy"
+	HeaderCrossOriginResourcePolicy       = "Cross-Origin-Resource-Policy"
+	HeaderPermissionsPolicy               = "Permissions-Policy"
+	HeaderReferrerPolicy                  = "Referrer-Policy"
+	HeaderStrictTransportSecurity         = "Strict-Transport-Security"
+	HeaderXContentTypeOptions             = "X-Content-Type-Options"
+	HeaderXForwardedProto                 = "X-Forwarded-Proto"
+	HeaderXFrameOptions                   = "X-Frame-Options"
+)
+
+// WebSocket 类
+const (
+	HeaderUpgrade                = "Upgrade"
+	HeaderSecWebSocketKey        = "Sec-WebSocket-Key"
+	HeaderSecWebSocketAccept     = "Sec-WebSocket-Accept"
+	HeaderSecWebSocketVersion    = "Sec-WebSocket-Version"
+	HeaderSecWebSocketProtocol   = "Sec-WebSocket-Protocol"
+	HeaderSecWebSocketExtensions = "Sec-WebSocket-Extensions"
+)
+
 // 协议类
 const (
 	HTTP11 = "HTTP/1.1"
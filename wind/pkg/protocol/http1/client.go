@@ -305,10 +305,7 @@ func (c *HostClient) Do(ctx context.Context, req *protocol.Request, resp *protoc
 			continue
 		}
 
-		if isDefaultRetryFunc {
-			break
-		}
-
+		// 未配置重试时 maxAttempts 为 1，默认重试函数仅在配置了 RetryConfig 时生效。
 		attempts++
 		if attempts >= maxAttempts {
 			break
@@ -0,0 +1,563 @@
+package http2
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/client/retry"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	errs "github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/network/dialer"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/client"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/protocol/http1/proxy"
+)
+
+var (
+	errTimeout        = errs.New(errs.ErrTimeout, errs.ErrorTypePublic, "http2 host client")
+	errNotNegotiated  = errs.NewPublic("http2：服务器未通过 ALPN 协商 h2 协议")
+	errProxyCleartext = errs.NewPublic("http2：明文 h2c 连接不支持代理")
+	errClientClosed   = errs.NewPublic("http2：主机客户端已关闭")
+	defaultClientName = []byte("wind")
+
+	// 未经对端处理的请求（流被拒绝或连接失效）在新连接上重试的最大次数，不计入 RetryConfig 的尝试次数
+	maxConnAttempts = 2
+
+	defaultObservationInterval = 5 * time.Second
+)
+
+// ClientOptions 表示 HTTP/2 客户端选项。
+type ClientOptions struct {
+	// 客户端名称。用于 User-Agent 请求标头。
+	Name string
+
+	// 若在请求时排除 User-Agent 标头，则设为真。
+	NoDefaultUserAgentHeader bool
+
+	// 用于建立主机连接的拨号器。
+	//
+	// 若未设置，则使用默认拨号器。
+	Dialer network.Dialer
+
+	// 建立主机连接的超时时间。
+	DialTimeout time.Duration
+
+	// TLS 配置，https 请求通过 ALPN 协商 h2 协议。
+	//
+	// http 请求则使用 h2c 先验知识，直接发送 HTTP/2 连接前言。
+	TLSConfig *tls.Config
+
+	// 每个主机的最大连接数。
+	//
+	// 仅当已有连接的并发流均被占满时才会新建连接，默认值为 consts.DefaultMaxConnsPerHost。
+	MaxConns int
+
+	// 无活跃流的连接超过此时长后会被关闭。
+	//
+	// 默认值为 consts.DefaultMaxIdleConnDuration。
+	MaxIdleConnDuration time.Duration
+
+	// 等待响应（包括正文）的最大时长，默认不限时长。
+	ReadTimeout time.Duration
+
+	// 发送请求（包括正文）的最大时长，默认不限时长。
+	WriteTimeout time.Duration
+
+	// 响应正文的最大字节数，超限则返回 errs.ErrBodyTooLarge。默认不限大小。
+	MaxResponseBodySize int
+
+	// 若为真，则响应标头名称按原样传递，而无需规范化。
+	DisableHeaderNamesNormalizing bool
+
+	// 是否启用响应的正文流。
+	ResponseBodyStream bool
+
+	// 流级初始接收窗口，默认 1MB。
+	InitialWindowSize int32
+
+	// 连接级初始接收窗口，默认 1MB。
+	InitialConnWindowSize int32
+
+	// 允许的最大响应头大小，默认 1MB。
+	MaxHeaderListSize uint32
+
+	// 与重试相关的所有配置，语义同 HTTP/1 客户端。
+	RetryConfig *retry.Config
+
+	// 判断请求是否应重试，默认为 client.DefaultRetryIf。
+	RetryIfFunc client.RetryIfFunc
+
+	// 观察主机客户端的状态。
+	StateObserve config.HostClientStateFunc
+
+	// 观察间隔时长，默认 5 秒。
+	ObservationInterval time.Duration
+}
+
+func (o *ClientOptions) normalize() {
+	if o.Dialer == nil {
+		o.Dialer = dialer.DefaultDialer()
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = consts.DefaultDialTimeout
+	}
+	if o.MaxConns <= 0 {
+		o.MaxConns = consts.DefaultMaxConnsPerHost
+	}
+	if o.MaxIdleConnDuration <= 0 {
+		o.MaxIdleConnDuration = consts.DefaultMaxIdleConnDuration
+	}
+	if o.InitialWindowSize <= 0 {
+		o.InitialWindowSize = defaultStreamWindowSize
+	}
+	if o.InitialConnWindowSize < initialWindowSize {
+		o.InitialConnWindowSize = defaultConnWindowSize
+	}
+	if o.MaxHeaderListSize == 0 {
+		o.MaxHeaderListSize = defaultMaxHeaderListSize
+	}
+	if o.ObservationInterval <= 0 {
+		o.ObservationInterval = defaultObservationInterval
+	}
+}
+
+// HostClient 表示 HTTP/2 主机客户端。
+//
+// 同一主机的并发请求复用同一连接上的多个流，仅当对端的并发流上限被占满时才新建连接。
+//
+// 并发协程的调用是安全的。
+type HostClient struct {
+	*ClientOptions
+
+	Addr     string
+	IsTLS    bool
+	ProxyURI *protocol.URI
+
+	tlsConfig *tls.Config
+
+	mu      sync.Mutex
+	conns   []*clientConn
+	dialing bool
+	waiters int
+	// 有流释放或连接变化时关闭并替换，用于唤醒等待者
+	changed chan struct{}
+
+	pendingRequests int32
+	closeOnce       sync.Once
+	closed          chan struct{}
+}
+
+// NewHostClient 创建新的 HTTP/2 主机客户端。
+func NewHostClient(c *ClientOptions) client.HostClient {
+	opt := *c
+	opt.normalize()
+	return &HostClient{
+		ClientOptions: &opt,
+		changed:       make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+}
+
+// SetDynamicConfig 设置主机地址等动态配置。
+func (c *HostClient) SetDynamicConfig(dc *client.DynamicConfig) {
+	c.Addr = dc.Addr
+	c.ProxyURI = dc.ProxyURI
+	c.IsTLS = dc.IsTLS
+
+	// 设置 addr 后开始观察，以避免数据竞争
+	if c.StateObserve != nil {
+		go func() {
+			t := time.NewTicker(c.ObservationInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-c.closed:
+					return
+				case <-t.C:
+					c.StateObserve(c)
+				}
+			}
+		}()
+	}
+}
+
+// Do 执行给定的 http 请求 req 并设置相应的 resp。
+//
+// 被对端拒绝（REFUSED_STREAM 或 GOAWAY）而未经处理的请求，以及连接失效时的幂等请求，
+// 会自动在新连接上重试。其余的重试由 RetryConfig 和 RetryIfFunc 控制，语义同 HTTP/1 客户端。
+func (c *HostClient) Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	var (
+		err                error
+		isDefaultRetryFunc                    = true
+		attempts           uint               = 0 // 当前尝试次数
+		connAttempts                          = 0 // 连接层面的重试次数
+		maxAttempts        uint               = 1 // 最多尝试次数
+		isRequestRetryable client.RetryIfFunc = client.DefaultRetryIf
+	)
+	retryCfg := c.RetryConfig
+	if retryCfg != nil {
+		maxAttempts = retryCfg.MaxAttemptTimes
+	}
+	if c.RetryIfFunc != nil {
+		isRequestRetryable = c.RetryIfFunc
+		isDefaultRetryFunc = false
+	}
+
+	atomic.AddInt32(&c.pendingRequests, 1)
+	defer atomic.AddInt32(&c.pendingRequests, -1)
+
+	nilResp := resp == nil
+	if nilResp {
+		resp = protocol.AcquireResponse()
+		defer protocol.ReleaseResponse(resp)
+	}
+
+	req.Options().StartRequest()
+	for {
+		select {
+		case <-ctx.Done():
+			req.CloseBodyStream()
+			return ctx.Err()
+		default:
+		}
+
+		err = c.do(ctx, req, resp)
+		if err == nil && isDefaultRetryFunc {
+			break
+		}
+		// 请求正文流无法回放
+		if req.IsBodyStream() {
+			break
+		}
+
+		var re *retryableError
+		if errors.As(err, &re) && (re.refused || client.DefaultRetryIf(req, resp, err)) && connAttempts < maxConnAttempts {
+			connAttempts++
+			continue
+		}
+
+		// 未配置重试时 maxAttempts 为 1，默认重试函数仅在配置了 RetryConfig 时生效。
+		attempts++
+		if attempts >= maxAttempts {
+			break
+		}
+		if !isRequestRetryable(req, resp, err) {
+			break
+		}
+		time.Sleep(retry.Delay(attempts, err, retryCfg))
+	}
+
+	var re *retryableError
+	if errors.As(err, &re) {
+		err = re.err
+	}
+	return err
+}
+
+func (c *HostClient) do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	opts := req.Options()
+	readTimeout, writeTimeout, dialTimeout := c.ReadTimeout, c.WriteTimeout, c.DialTimeout
+	if opts.ReadTimeout() > 0 {
+		readTimeout = opts.ReadTimeout()
+	}
+	if opts.WriteTimeout() > 0 {
+		writeTimeout = opts.WriteTimeout()
+	}
+	if opts.DialTimeout() > 0 {
+		dialTimeout = opts.DialTimeout()
+	}
+
+	var deadline time.Time
+	if t := opts.RequestTimeout(); t > 0 {
+		deadline = opts.StartTime().Add(t)
+		if time.Until(deadline) <= 0 {
+			return errTimeout
+		}
+	}
+
+	customSkipBody := resp.SkipBody
+	resp.Reset()
+	resp.SkipBody = customSkipBody
+
+	cc, err := c.acquireConn(ctx, dialTimeout, deadline)
+	if err != nil {
+		return err
+	}
+	resp.ParseNetAddr(cc.conn)
+
+	return cc.roundTrip(ctx, req, resp, roundTripConfig{
+		readTimeout:  readTimeout,
+		writeTimeout: writeTimeout,
+		deadline:     deadline,
+	})
+}
+
+// 获取可承载新流的连接，必要时新建连接或等待流释放。成功时已为请求预留一个流。
+func (c *HostClient) acquireConn(ctx context.Context, dialTimeout time.Duration, deadline time.Time) (*clientConn, error) {
+	var timer <-chan time.Time
+	if !deadline.IsZero() {
+		t := time.NewTimer(time.Until(deadline))
+		defer t.Stop()
+		timer = t.C
+	}
+
+	for {
+		c.mu.Lock()
+		select {
+		case <-c.closed:
+			c.mu.Unlock()
+			return nil, errClientClosed
+		default:
+		}
+		for _, cc := range c.conns {
+			if cc.reserveStream() {
+				c.mu.Unlock()
+				return cc, nil
+			}
+		}
+
+		// 已有连接均已占满，在上限内新建连接，同一时刻只拨号一次
+		if !c.dialing && len(c.conns) < c.MaxConns {
+			c.dialing = true
+			c.mu.Unlock()
+
+			cc, err := c.dialConn(dialTimeout)
+			c.mu.Lock()
+			c.dialing = false
+			if err == nil {
+				c.conns = append(c.conns, cc)
+			}
+			c.notifyLocked()
+			c.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		wait := c.changed
+		c.waiters++
+		c.mu.Unlock()
+
+		var err error
+		select {
+		case <-wait:
+		case <-timer:
+			err = errTimeout
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-c.closed:
+			err = errClientClosed
+		}
+
+		c.mu.Lock()
+		c.waiters--
+		c.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// 唤醒所有等待连接或流的请求，调用前须持有 c.mu。
+func (c *HostClient) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *HostClient) notify() {
+	c.mu.Lock()
+	c.notifyLocked()
+	c.mu.Unlock()
+}
+
+func (c *HostClient) dialConn(timeout time.Duration) (*clientConn, error) {
+	addr := c.Addr
+	var tlsConfig *tls.Config
+	if c.IsTLS {
+		tlsConfig = c.cachedTLSConfig(addr)
+	}
+	if c.ProxyURI != nil && !c.IsTLS {
+		return nil, errProxyCleartext
+	}
+
+	var conn network.Conn
+	var err error
+	if c.ProxyURI != nil {
+		// 先用 tcp 连接代理，再通过 CONNECT 隧道添加 TLS
+		conn, err = c.Dialer.DialConnection("tcp", string(c.ProxyURI.Host()), timeout, nil)
+		if err == nil {
+			conn, err = proxy.SetupProxy(conn, addr, c.ProxyURI, tlsConfig, c.IsTLS, c.Dialer)
+		}
+	} else {
+		conn, err = c.Dialer.DialConnection("tcp", addr, timeout, tlsConfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.IsTLS {
+		if err = checkNegotiated(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	cc, err := newClientConn(c, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return cc, nil
+}
+
+// 完成 TLS 握手并确认 ALPN 协商出 h2 协议。
+func checkNegotiated(conn network.Conn) error {
+	tlsConn, ok := conn.(network.ConnTLSer)
+	if !ok {
+		return nil
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	if tlsConn.ConnectionState().NegotiatedProtocol != http2NextProto {
+		return errNotNegotiated
+	}
+	return nil
+}
+
+const http2NextProto = "h2"
+
+func (c *HostClient) cachedTLSConfig(addr string) *tls.Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tlsConfig != nil {
+		return c.tlsConfig
+	}
+	cfg := &tls.Config{}
+	if c.TLSConfig != nil {
+		cfg = c.TLSConfig.Clone()
+	}
+	cfg.NextProtos = []string{http2NextProto}
+	if cfg.ClientSessionCache == nil {
+		cfg.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	if cfg.ServerName == "" {
+		host := addr
+		if i := strings.LastIndexByte(addr, ':'); i >= 0 {
+			host = addr[:i]
+		}
+		cfg.ServerName = strings.Trim(host, "[]")
+	}
+	c.tlsConfig = cfg
+	return cfg
+}
+
+// 连接关闭后从连接池中移除。
+func (c *HostClient) removeConn(cc *clientConn) {
+	c.mu.Lock()
+	for i, x := range c.conns {
+		if x == cc {
+			c.conns = append(c.conns[:i], c.conns[i+1:]...)
+			break
+		}
+	}
+	c.notifyLocked()
+	c.mu.Unlock()
+}
+
+func (c *HostClient) clientName() []byte {
+	if c.NoDefaultUserAgentHeader {
+		return nil
+	}
+	if c.Name != "" {
+		return []byte(c.Name)
+	}
+	return defaultClientName
+}
+
+// CloseIdleConnections 关闭没有活跃流的连接，不会中断正在使用的连接。
+func (c *HostClient) CloseIdleConnections() {
+	c.mu.Lock()
+	conns := append([]*clientConn(nil), c.conns...)
+	c.mu.Unlock()
+	for _, cc := range conns {
+		cc.closeIfIdle()
+	}
+}
+
+// Close 关闭主机客户端及其所有连接。
+func (c *HostClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.mu.Lock()
+		conns := append([]*clientConn(nil), c.conns...)
+		c.mu.Unlock()
+		for _, cc := range conns {
+			cc.close(errClientClosed)
+		}
+	})
+	return nil
+}
+
+// ShouldRemove 若没有任何连接，则可移除。
+func (c *HostClient) ShouldRemove() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.conns) == 0 && !c.dialing && c.waiters == 0
+}
+
+// ConnectionCount 返回当前的连接数。
+func (c *HostClient) ConnectionCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.conns)
+}
+
+// PendingRequests 返回客户端正在执行的当前请求数。
+func (c *HostClient) PendingRequests() int {
+	return int(atomic.LoadInt32(&c.pendingRequests))
+}
+
+// ConnPoolState 返回主机客户端的连接池状态，包括活跃流及最大并发流数量。
+func (c *HostClient) ConnPoolState() config.ConnPoolState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cps := config.ConnPoolState{
+		TotalConnNum: len(c.conns),
+		WaitConnNum:  c.waiters,
+		Addr:         c.Addr,
+	}
+	for _, cc := range c.conns {
+		active, max := cc.streamCount()
+		if active == 0 {
+			cps.PoolConnNum++
+		}
+		cps.ActiveStreamNum += active
+		cps.MaxStreamNum += max
+	}
+	return cps
+}
+
+// 可在新连接上重试的错误。
+type retryableError struct {
+	err     error
+	refused bool // 对端明确表示未处理该请求
+}
+
+func (e *retryableError) Error() string {
+	return fmt.Sprintf("%v（可重试）", e.err)
+}
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+var _ io.Closer = (*HostClient)(nil)
@@ -0,0 +1,963 @@
+package http2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/favbox/gosky/wind/internal/bytestr"
+	errs "github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/utils"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+var (
+	errRefused         = errs.NewPublic("http2：对端拒绝了该流，请求未被处理")
+	errConnLost        = errs.NewPublic("http2：连接在收到响应前已关闭")
+	errMalformedResp   = errs.NewPublic("http2：响应标头格式有误")
+	errBodyTooLarge    = errs.New(errs.ErrBodyTooLarge, errs.ErrorTypePublic, "http2 host client")
+	errStreamCanceled  = errs.NewPublic("http2：流已被取消")
+	errMissingSettings = errs.NewPublic("http2：服务器未以 SETTINGS 帧开始连接")
+)
+
+// 对端设置 MAX_CONCURRENT_STREAMS 之前假定的并发流上限。
+const defaultPeerMaxStreams = 100
+
+// 客户端的 HTTP/2 连接。
+//
+// 读循环独占帧的读取并分发给各流；请求协程通过 frameWriter 串行写帧。
+// 共享状态由 mu 保护，锁顺序为 HostClient.mu → frameWriter.mu → clientConn.mu。
+type clientConn struct {
+	hc     *HostClient
+	conn   network.Conn
+	framer *http2.Framer
+	fw     *frameWriter
+
+	mu                sync.Mutex
+	cond              *sync.Cond // 发送窗口变化时广播
+	streams           map[uint32]*clientStream
+	reserved          int // 已预留但尚未发出标头的流
+	nextStreamID      uint32
+	peerMaxStreams    uint32
+	peerInitialWindow int32
+	outflow           int32
+	inflow            int32
+	goAway            bool
+	closed            bool
+	err               error
+	idleTimer         *time.Timer
+}
+
+func newClientConn(hc *HostClient, conn network.Conn) (*clientConn, error) {
+	cc := &clientConn{
+		hc:                hc,
+		conn:              conn,
+		fw:                newFrameWriter(conn),
+		framer:            http2.NewFramer(nil, conn),
+		streams:           make(map[uint32]*clientStream),
+		nextStreamID:      1,
+		peerMaxStreams:    defaultPeerMaxStreams,
+		peerInitialWindow: initialWindowSize,
+		outflow:           initialWindowSize,
+		inflow:            hc.InitialConnWindowSize,
+	}
+	cc.cond = sync.NewCond(&cc.mu)
+	cc.framer.SetMaxReadFrameSize(defaultMaxReadFrameSize)
+	cc.framer.MaxHeaderListSize = hc.MaxHeaderListSize
+	cc.framer.ReadMetaHeaders = hpack.NewDecoder(initialHeaderTableSz, nil)
+
+	err := cc.fw.write(func(fr *http2.Framer) error {
+		if _, err := cc.fw.bw.Write(bytestr.StrClientPreface); err != nil {
+			return err
+		}
+		err := fr.WriteSettings(
+			http2.Setting{ID: http2.SettingEnablePush, Val: 0},
+			http2.Setting{ID: http2.SettingInitialWindowSize, Val: uint32(hc.InitialWindowSize)},
+			http2.Setting{ID: http2.SettingMaxFrameSize, Val: defaultMaxReadFrameSize},
+			http2.Setting{ID: http2.SettingMaxHeaderListSize, Val: hc.MaxHeaderListSize},
+		)
+		if err != nil {
+			return err
+		}
+		return fr.WriteWindowUpdate(0, uint32(hc.InitialConnWindowSize-initialWindowSize))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 服务器的首帧必为 SETTINGS，据此确定并发流上限后再投入使用
+	_ = conn.SetReadTimeout(hc.DialTimeout)
+	f, err := cc.framer.ReadFrame()
+	_ = conn.SetReadTimeout(0)
+	if err != nil {
+		return nil, err
+	}
+	sf, ok := f.(*http2.SettingsFrame)
+	if !ok || sf.IsAck() {
+		return nil, errMissingSettings
+	}
+	if err = cc.processSettings(sf); err != nil {
+		return nil, err
+	}
+
+	go cc.readLoop()
+	return cc, nil
+}
+
+// 为新请求预留一个流，调用方须持有 HostClient.mu。
+func (cc *clientConn) reserveStream() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.closed || cc.goAway || cc.nextStreamID > 1<<31-1 {
+		return false
+	}
+	if uint32(len(cc.streams)+cc.reserved) >= cc.peerMaxStreams {
+		return false
+	}
+	cc.reserved++
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	return true
+}
+
+// 返回活跃流数量及对端允许的最大并发流数量。
+func (cc *clientConn) streamCount() (active, max int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return len(cc.streams) + cc.reserved, int(cc.peerMaxStreams)
+}
+
+// 释放流占用的并发数，连接无活跃流时启动闲置计时。
+func (cc *clientConn) releaseStream(cs *clientStream) {
+	cc.mu.Lock()
+	if cs != nil {
+		delete(cc.streams, cs.id)
+	} else {
+		cc.reserved--
+	}
+	idle := len(cc.streams) == 0 && cc.reserved == 0
+	shouldClose := idle && (cc.goAway || cc.closed)
+	if idle && !shouldClose {
+		if cc.idleTimer == nil {
+			cc.idleTimer = time.AfterFunc(cc.hc.MaxIdleConnDuration, cc.closeIfIdle)
+		} else {
+			cc.idleTimer.Reset(cc.hc.MaxIdleConnDuration)
+		}
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+
+	if shouldClose {
+		cc.close(nil)
+	}
+	cc.hc.notify()
+}
+
+func (cc *clientConn) closeIfIdle() {
+	cc.mu.Lock()
+	idle := len(cc.streams) == 0 && cc.reserved == 0
+	cc.mu.Unlock()
+	if idle {
+		_ = cc.fw.write(func(fr *http2.Framer) error {
+			return fr.WriteGoAway(0, http2.ErrCodeNo, nil)
+		})
+		cc.close(nil)
+	}
+}
+
+// 关闭连接，并以 err 结束所有未完成的流。
+func (cc *clientConn) close(err error) {
+	cc.mu.Lock()
+	if cc.closed {
+		cc.mu.Unlock()
+		return
+	}
+	cc.closed = true
+	if err == nil {
+		err = errs.ErrConnectionClosed
+	}
+	cc.err = err
+	if cc.idleTimer != nil {
+		cc.idleTimer.Stop()
+	}
+	streams := make([]*clientStream, 0, len(cc.streams))
+	for _, cs := range cc.streams {
+		streams = append(streams, cs)
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+
+	for _, cs := range streams {
+		cs.abort(&retryableError{err: errConnLost})
+	}
+	_ = cc.conn.Close()
+	cc.hc.removeConn(cc)
+}
+
+func (cc *clientConn) readLoop() {
+	var err error
+	defer func() {
+		cc.close(err)
+	}()
+	for {
+		var f http2.Frame
+		f, err = cc.framer.ReadFrame()
+		if err != nil {
+			var se http2.StreamError
+			if errors.As(err, &se) {
+				if cs := cc.stream(se.StreamID); cs != nil {
+					cs.abort(se)
+				}
+				cc.writeRST(se.StreamID, se.Code)
+				continue
+			}
+			return
+		}
+		if err = cc.processFrame(f); err != nil {
+			var ce http2.ConnectionError
+			if errors.As(err, &ce) {
+				_ = cc.fw.write(func(fr *http2.Framer) error {
+					return fr.WriteGoAway(0, http2.ErrCode(ce), nil)
+				})
+			}
+			return
+		}
+	}
+}
+
+func (cc *clientConn) stream(id uint32) *clientStream {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.streams[id]
+}
+
+func (cc *clientConn) writeRST(id uint32, code http2.ErrCode) {
+	_ = cc.fw.write(func(fr *http2.Framer) error { return fr.WriteRSTStream(id, code) })
+}
+
+func (cc *clientConn) processFrame(f http2.Frame) error {
+	switch f := f.(type) {
+	case *http2.SettingsFrame:
+		return cc.processSettings(f)
+	case *http2.MetaHeadersFrame:
+		cs := cc.stream(f.StreamID)
+		if cs == nil {
+			return nil
+		}
+		cs.onHeaders(f)
+		return nil
+	case *http2.DataFrame:
+		return cc.processData(f)
+	case *http2.WindowUpdateFrame:
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		if f.StreamID == 0 {
+			if !addWindow(&cc.outflow, int32(f.Increment)) {
+				return http2.ConnectionError(http2.ErrCodeFlowControl)
+			}
+		} else if cs := cc.streams[f.StreamID]; cs != nil {
+			if !addWindow(&cs.outflow, int32(f.Increment)) {
+				go cs.cancel(http2.ErrCodeFlowControl, errStreamCanceled)
+			}
+		}
+		cc.cond.Broadcast()
+		return nil
+	case *http2.RSTStreamFrame:
+		if cs := cc.stream(f.StreamID); cs != nil {
+			if f.ErrCode == http2.ErrCodeRefusedStream {
+				cs.abort(&retryableError{err: errRefused, refused: true})
+			} else {
+				cs.abort(http2.StreamError{StreamID: f.StreamID, Code: f.ErrCode})
+			}
+		}
+		return nil
+	case *http2.GoAwayFrame:
+		cc.processGoAway(f)
+		return nil
+	case *http2.PingFrame:
+		if f.IsAck() {
+			return nil
+		}
+		return cc.fw.write(func(fr *http2.Framer) error { return fr.WritePing(true, f.Data) })
+	case *http2.PushPromiseFrame:
+		// 已通过 SETTINGS_ENABLE_PUSH 禁止推送
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+	return nil
+}
+
+func (cc *clientConn) processSettings(f *http2.SettingsFrame) error {
+	if f.IsAck() {
+		return nil
+	}
+	err := f.ForeachSetting(func(s http2.Setting) error {
+		if err := s.Valid(); err != nil {
+			return err
+		}
+		switch s.ID {
+		case http2.SettingMaxConcurrentStreams:
+			cc.mu.Lock()
+			cc.peerMaxStreams = s.Val
+			cc.mu.Unlock()
+		case http2.SettingInitialWindowSize:
+			cc.mu.Lock()
+			delta := int32(s.Val) - cc.peerInitialWindow
+			cc.peerInitialWindow = int32(s.Val)
+			for _, cs := range cc.streams {
+				if !addWindow(&cs.outflow, delta) {
+					cc.mu.Unlock()
+					return http2.ConnectionError(http2.ErrCodeFlowControl)
+				}
+			}
+			cc.cond.Broadcast()
+			cc.mu.Unlock()
+		case http2.SettingMaxFrameSize:
+			cc.fw.setMaxFrameSize(s.Val)
+		case http2.SettingHeaderTableSize:
+			cc.fw.setHeaderTableSize(s.Val)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = cc.fw.write(func(fr *http2.Framer) error { return fr.WriteSettingsAck() }); err != nil {
+		return err
+	}
+	// 并发流上限可能已调大
+	cc.hc.notify()
+	return nil
+}
+
+// 对端不再接受新流，标识大于 LastStreamID 的流未被处理，可安全重试。
+func (cc *clientConn) processGoAway(f *http2.GoAwayFrame) {
+	cc.mu.Lock()
+	cc.goAway = true
+	var unprocessed []*clientStream
+	for id, cs := range cc.streams {
+		if id > f.LastStreamID {
+			unprocessed = append(unprocessed, cs)
+		}
+	}
+	idle := len(cc.streams) == 0 && cc.reserved == 0
+	cc.mu.Unlock()
+
+	if f.ErrCode != http2.ErrCodeNo {
+		hlog.SystemLogger().Debugf("HTTP/2 收到 GOAWAY：地址=%s, 错误码=%v", cc.hc.Addr, f.ErrCode)
+	}
+	for _, cs := range unprocessed {
+		cs.abort(&retryableError{err: errRefused, refused: true})
+	}
+	if idle {
+		cc.close(nil)
+	}
+	cc.hc.notify()
+}
+
+func (cc *clientConn) processData(f *http2.DataFrame) error {
+	n := int32(f.Length)
+	cc.mu.Lock()
+	if n > cc.inflow {
+		cc.mu.Unlock()
+		return http2.ConnectionError(http2.ErrCodeFlowControl)
+	}
+	cs := cc.streams[f.StreamID]
+	if cs == nil || n > cs.inflow {
+		cc.mu.Unlock()
+		// 已结束的流也需归还连接级窗口
+		cc.refund(nil, n)
+		if cs != nil {
+			cs.cancel(http2.ErrCodeFlowControl, errStreamCanceled)
+		}
+		return nil
+	}
+	cc.inflow -= n
+	cs.inflow -= n
+	// 填充字节不会交给读取方，直接计入待归还
+	cs.unrefunded += n - int32(len(f.Data()))
+	cs.buf.Write(f.Data())
+	if f.StreamEnded() {
+		cs.remoteDone = true
+	}
+	cs.cond.Broadcast()
+	cc.mu.Unlock()
+
+	// 连接级窗口在收到时即归还，避免个别读取缓慢的流阻塞整个连接
+	cc.refund(nil, n)
+	if f.StreamEnded() {
+		cs.finishRemote()
+	}
+	return nil
+}
+
+// 归还连接级窗口，cs 非空时同时归还流级窗口。
+func (cc *clientConn) refund(cs *clientStream, n int32) {
+	if n <= 0 {
+		return
+	}
+	cc.mu.Lock()
+	if cs == nil {
+		cc.inflow += n
+	} else {
+		cs.inflow += n
+	}
+	cc.mu.Unlock()
+	_ = cc.fw.write(func(fr *http2.Framer) error {
+		if cs == nil {
+			return fr.WriteWindowUpdate(0, uint32(n))
+		}
+		return fr.WriteWindowUpdate(cs.id, uint32(n))
+	})
+}
+
+// 按连接及流的发送窗口分片发送请求正文，窗口不足时阻塞等待。
+func (cc *clientConn) writeData(cs *clientStream, p []byte, endStream bool) error {
+	for {
+		n := 0
+		if len(p) > 0 {
+			var err error
+			if n, err = cc.reserveWindow(cs, len(p)); err != nil {
+				return err
+			}
+		}
+		chunk := p[:n]
+		p = p[n:]
+		end := endStream && len(p) == 0
+		if err := cc.fw.write(func(fr *http2.Framer) error { return fr.WriteData(cs.id, end, chunk) }); err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return nil
+		}
+	}
+}
+
+func (cc *clientConn) reserveWindow(cs *clientStream, want int) (int, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for {
+		if cs.err != nil {
+			return 0, cs.err
+		}
+		if cc.closed {
+			return 0, cc.err
+		}
+		n := int32(want)
+		if maxFrame := int32(cc.fw.maxFrameSize()); n > maxFrame {
+			n = maxFrame
+		}
+		if n > cc.outflow {
+			n = cc.outflow
+		}
+		if n > cs.outflow {
+			n = cs.outflow
+		}
+		if n > 0 {
+			cc.outflow -= n
+			cs.outflow -= n
+			return int(n), nil
+		}
+		cc.cond.Wait()
+	}
+}
+
+type roundTripConfig struct {
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	deadline     time.Time
+}
+
+// 在预留的流上发送请求并读取响应。
+func (cc *clientConn) roundTrip(ctx context.Context, req *protocol.Request, resp *protocol.Response, rc roundTripConfig) error {
+	cs := &clientStream{cc: cc, gotHeaders: make(chan struct{}), done: make(chan struct{})}
+	cs.cond = sync.NewCond(&cc.mu)
+
+	var body []byte
+	var bodyStream io.Reader
+	if req.IsBodyStream() {
+		bodyStream = req.BodyStream()
+	} else {
+		body = req.BodyBytes()
+	}
+	hasBody := len(body) > 0 || bodyStream != nil
+	hasTrailer := !req.Header.Trailer().Empty()
+	fields := cc.requestHeaderFields(req, body, bodyStream != nil)
+
+	// 在写锁内分配流标识，保证标识按发送顺序递增
+	assigned, registered := false, false
+	err := cc.fw.writeHeaders(func() uint32 {
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		assigned = true
+		cs.id = cc.nextStreamID
+		cc.nextStreamID += 2
+		cs.outflow = cc.peerInitialWindow
+		cs.inflow = cc.hc.InitialWindowSize
+		cc.reserved--
+		if cc.closed {
+			// 连接已关闭，帧不会被对端处理
+			cs.err = &retryableError{err: errConnLost}
+			return cs.id
+		}
+		cc.streams[cs.id] = cs
+		registered = true
+		return cs.id
+	}, fields, !hasBody && !hasTrailer)
+	if !registered {
+		if assigned {
+			cc.releaseStream(cs)
+		} else {
+			cc.releaseStream(nil)
+		}
+		if err == nil {
+			return cs.err
+		}
+		return &retryableError{err: err}
+	}
+	if err != nil {
+		cs.abort(&retryableError{err: err})
+		cc.releaseStream(cs)
+		return &retryableError{err: err}
+	}
+
+	stop := cs.watch(ctx, rc)
+	defer stop()
+
+	if hasBody || hasTrailer {
+		if werr := cs.writeBody(req, body, bodyStream, hasTrailer); werr != nil {
+			// 对端可能已提前完成响应（如 413），此时以响应为准
+			select {
+			case <-cs.gotHeaders:
+			default:
+				cs.cancel(http2.ErrCodeCancel, werr)
+				cc.releaseStream(cs)
+				return cs.failure(werr)
+			}
+		}
+	}
+
+	select {
+	case <-cs.gotHeaders:
+	case <-cs.done:
+	}
+	if err := cs.fillResponse(resp); err != nil {
+		cc.releaseStream(cs)
+		return cs.failure(err)
+	}
+
+	if resp.SkipBody || resp.MustSkipBody() {
+		cs.cancel(http2.ErrCodeCancel, errStreamCanceled)
+		cc.releaseStream(cs)
+		return nil
+	}
+
+	// 正文流的读取不受本次请求超时约束
+	if cc.hc.ResponseBodyStream {
+		resp.SetBodyStream(&bodyReader{cs: cs, resp: resp}, resp.Header.ContentLength())
+		return nil
+	}
+
+	err = cs.readBody(resp, cc.hc.MaxResponseBodySize)
+	cc.releaseStream(cs)
+	if err != nil {
+		return cs.failure(err)
+	}
+	return nil
+}
+
+// 构造请求的 HPACK 字段，标头名称一律小写。
+func (cc *clientConn) requestHeaderFields(req *protocol.Request, body []byte, isStream bool) []hpack.HeaderField {
+	h := &req.Header
+	uri := req.URI()
+
+	authority := string(h.Host())
+	if authority == "" {
+		authority = string(uri.Host())
+	}
+	if authority == "" {
+		authority = cc.hc.Addr
+	}
+	scheme := "http"
+	if cc.hc.IsTLS {
+		scheme = "https"
+	}
+
+	method := string(h.Method())
+	fields := make([]hpack.HeaderField, 0, 8)
+	fields = append(fields,
+		hpack.HeaderField{Name: ":method", Value: method},
+		hpack.HeaderField{Name: ":authority", Value: authority},
+	)
+	if method != "CONNECT" {
+		fields = append(fields,
+			hpack.HeaderField{Name: ":scheme", Value: scheme},
+			hpack.HeaderField{Name: ":path", Value: string(uri.RequestURI())},
+		)
+	}
+
+	hasUA := false
+	h.VisitAll(func(k, v []byte) {
+		if isConnectionHeader(k) ||
+			utils.CaseInsensitiveCompare(k, bytestr.StrHost) ||
+			utils.CaseInsensitiveCompare(k, bytestr.StrContentLength) {
+			return
+		}
+		if utils.CaseInsensitiveCompare(k, bytestr.StrUserAgent) {
+			hasUA = true
+		}
+		fields = append(fields, hpack.HeaderField{Name: string(bytes.ToLower(k)), Value: string(v)})
+	})
+	if !hasUA {
+		if name := cc.hc.clientName(); len(name) > 0 {
+			fields = append(fields, hpack.HeaderField{Name: "user-agent", Value: string(name)})
+		}
+	}
+
+	switch {
+	case isStream:
+		if n := h.ContentLength(); n >= 0 {
+			fields = append(fields, hpack.HeaderField{Name: "content-length", Value: strconv.Itoa(n)})
+		}
+	case len(body) > 0 || methodExpectsBody(method):
+		fields = append(fields, hpack.HeaderField{Name: "content-length", Value: strconv.Itoa(len(body))})
+	}
+	return fields
+}
+
+func methodExpectsBody(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH"
+}
+
+// 单个请求流。
+type clientStream struct {
+	cc   *clientConn
+	id   uint32
+	cond *sync.Cond // 与 cc.mu 关联，数据到达或出错时广播
+
+	gotHeaders chan struct{} // 收到最终响应标头后关闭
+	done       chan struct{} // 流结束（正常或出错）后关闭
+	doneOnce   sync.Once
+
+	// 以下字段由 cc.mu 保护
+	outflow     int32
+	inflow      int32
+	unrefunded  int32
+	buf         bytes.Buffer
+	remoteDone  bool
+	err         error
+	status      int
+	respFields  []hpack.HeaderField
+	trailer     []hpack.HeaderField
+	headersSeen bool
+}
+
+// 处理响应标头或尾部标头。
+func (cs *clientStream) onHeaders(f *http2.MetaHeadersFrame) {
+	cc := cs.cc
+	cc.mu.Lock()
+	if cs.headersSeen {
+		// 尾部标头必须结束流
+		if !f.StreamEnded() {
+			cc.mu.Unlock()
+			cs.cancel(http2.ErrCodeProtocol, errMalformedResp)
+			return
+		}
+		cs.trailer = f.RegularFields()
+		cs.remoteDone = true
+		cs.cond.Broadcast()
+		cc.mu.Unlock()
+		cs.finishRemote()
+		return
+	}
+
+	status, err := strconv.Atoi(f.PseudoValue("status"))
+	if err != nil || status < 100 || status > 999 {
+		cc.mu.Unlock()
+		cs.cancel(http2.ErrCodeProtocol, errMalformedResp)
+		return
+	}
+	// 忽略 1xx 信息性响应
+	if status < 200 && status != 101 {
+		cc.mu.Unlock()
+		return
+	}
+	cs.status = status
+	cs.respFields = f.RegularFields()
+	cs.headersSeen = true
+	if f.StreamEnded() {
+		cs.remoteDone = true
+	}
+	cs.cond.Broadcast()
+	cc.mu.Unlock()
+
+	close(cs.gotHeaders)
+	if f.StreamEnded() {
+		cs.finishRemote()
+	}
+}
+
+// 对端已结束发送。
+func (cs *clientStream) finishRemote() {
+	cs.doneOnce.Do(func() { close(cs.done) })
+}
+
+// 以 err 结束流，唤醒所有等待方。
+func (cs *clientStream) abort(err error) {
+	cc := cs.cc
+	cc.mu.Lock()
+	if cs.err == nil {
+		cs.err = err
+	}
+	cs.cond.Broadcast()
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	cs.doneOnce.Do(func() { close(cs.done) })
+}
+
+// 主动重置流。
+func (cs *clientStream) cancel(code http2.ErrCode, err error) {
+	cc := cs.cc
+	cc.mu.Lock()
+	remoteDone := cs.remoteDone && cs.err == nil
+	cc.mu.Unlock()
+	cs.abort(err)
+	if !remoteDone {
+		cc.writeRST(cs.id, code)
+	}
+}
+
+// 监视上下文及超时，触发时取消流。返回的函数用于停止监视。
+func (cs *clientStream) watch(ctx context.Context, rc roundTripConfig) func() {
+	// 写超时与读超时相加作为整个往返的时限，未设读超时则不限时长
+	timeout := rc.readTimeout
+	if timeout > 0 && rc.writeTimeout > 0 {
+		timeout += rc.writeTimeout
+	}
+	if !rc.deadline.IsZero() {
+		if left := time.Until(rc.deadline); timeout <= 0 || left < timeout {
+			timeout = left
+		}
+	}
+
+	stopCh := make(chan struct{})
+	var stopOnce sync.Once
+	var timer <-chan time.Time
+	var t *time.Timer
+	if timeout > 0 {
+		t = time.NewTimer(timeout)
+		timer = t.C
+	}
+	go func() {
+		select {
+		case <-timer:
+			cs.cancel(http2.ErrCodeCancel, errTimeout)
+		case <-ctx.Done():
+			cs.cancel(http2.ErrCodeCancel, ctx.Err())
+		case <-cs.done:
+			// 对端已结束，等待读取方停止监视
+			select {
+			case <-timer:
+				cs.cancel(http2.ErrCodeCancel, errTimeout)
+			case <-ctx.Done():
+			case <-stopCh:
+			}
+		case <-stopCh:
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}()
+	return func() { stopOnce.Do(func() { close(stopCh) }) }
+}
+
+func (cs *clientStream) writeBody(req *protocol.Request, body []byte, bodyStream io.Reader, hasTrailer bool) error {
+	cc := cs.cc
+	if bodyStream != nil {
+		buf := make([]byte, 16<<10)
+		for {
+			n, err := bodyStream.Read(buf)
+			if n > 0 {
+				if werr := cc.writeData(cs, buf[:n], false); werr != nil {
+					return werr
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		req.CloseBodyStream()
+	} else if len(body) > 0 {
+		if err := cc.writeData(cs, body, !hasTrailer); err != nil {
+			return err
+		}
+		if !hasTrailer {
+			return nil
+		}
+	}
+
+	if hasTrailer {
+		var fields []hpack.HeaderField
+		req.Header.Trailer().VisitAll(func(k, v []byte) {
+			fields = append(fields, hpack.HeaderField{Name: string(bytes.ToLower(k)), Value: string(v)})
+		})
+		return cc.fw.writeHeaders(fixedID(cs.id), fields, true)
+	}
+	return cc.writeData(cs, nil, true)
+}
+
+// 将收到的响应标头填入 resp。
+func (cs *clientStream) fillResponse(resp *protocol.Response) error {
+	cc := cs.cc
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if !cs.headersSeen {
+		if cs.err != nil {
+			return cs.err
+		}
+		return errMalformedResp
+	}
+	if cc.hc.DisableHeaderNamesNormalizing {
+		resp.Header.DisableNormalizing()
+	}
+	resp.Header.SetStatusCode(cs.status)
+	contentLength := -1
+	for _, f := range cs.respFields {
+		if f.Name == "content-length" {
+			if n, err := strconv.Atoi(f.Value); err == nil {
+				contentLength = n
+			}
+			continue
+		}
+		resp.Header.Add(f.Name, f.Value)
+	}
+	resp.Header.SetContentLength(contentLength)
+	return nil
+}
+
+// 读取响应正文，由读取方按消费进度归还流级窗口。
+func (cs *clientStream) read(p []byte) (int, error) {
+	cc := cs.cc
+	cc.mu.Lock()
+	for cs.buf.Len() == 0 && !cs.remoteDone && cs.err == nil {
+		cs.cond.Wait()
+	}
+	if cs.buf.Len() == 0 {
+		err := cs.err
+		cc.mu.Unlock()
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	n, _ := cs.buf.Read(p)
+	cs.unrefunded += int32(n)
+	var refund int32
+	if !cs.remoteDone && cs.unrefunded >= cc.hc.InitialWindowSize/4 {
+		refund = cs.unrefunded
+		cs.unrefunded = 0
+	}
+	cc.mu.Unlock()
+
+	cc.refund(cs, refund)
+	return n, nil
+}
+
+func (cs *clientStream) readBody(resp *protocol.Response, maxBodySize int) error {
+	bb := resp.BodyBuffer()
+	buf := make([]byte, 16<<10)
+	for {
+		n, err := cs.read(buf)
+		if n > 0 {
+			if maxBodySize > 0 && len(bb.B)+n > maxBodySize {
+				cs.cancel(http2.ErrCodeCancel, errBodyTooLarge)
+				return errBodyTooLarge
+			}
+			bb.B = append(bb.B, buf[:n]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	resp.Header.SetContentLength(len(bb.B))
+	cs.fillTrailer(resp)
+	return nil
+}
+
+func (cs *clientStream) fillTrailer(resp *protocol.Response) {
+	cc := cs.cc
+	cc.mu.Lock()
+	trailer := cs.trailer
+	cc.mu.Unlock()
+	// 响应头中的 Trailer 声明会预先登记空值，首个值需覆盖而非追加
+	t := resp.Header.Trailer()
+	for _, f := range trailer {
+		if len(t.Peek(f.Name)) == 0 {
+			_ = t.Set(f.Name, f.Value)
+		} else {
+			_ = t.Add(f.Name, f.Value)
+		}
+	}
+}
+
+// 返回流出错时应报告给调用方的错误。
+func (cs *clientStream) failure(err error) error {
+	cc := cs.cc
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cs.err != nil {
+		return cs.err
+	}
+	return err
+}
+
+// 响应正文流，读完或关闭时释放流。
+type bodyReader struct {
+	cs     *clientStream
+	resp   *protocol.Response
+	closed bool
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, io.EOF
+	}
+	n, err := r.cs.read(p)
+	if err == io.EOF {
+		r.cs.fillTrailer(r.resp)
+		r.release()
+	}
+	return n, err
+}
+
+// Close 提前关闭时以 CANCEL 重置流。
+func (r *bodyReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.cs.cancel(http2.ErrCodeCancel, errStreamCanceled)
+	r.release()
+	return nil
+}
+
+func (r *bodyReader) release() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.cs.cc.releaseStream(r.cs)
+}
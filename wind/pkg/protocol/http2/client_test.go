@@ -0,0 +1,289 @@
+package http2
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/client/retry"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	errs "github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/network/standard"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/client"
+)
+
+func newTestHostClient(addr string, isTLS bool, opt *ClientOptions) *HostClient {
+	if opt.Dialer == nil {
+		opt.Dialer = standard.NewDialer()
+	}
+	hc := NewHostClient(opt).(*HostClient)
+	hc.SetDynamicConfig(&client.DynamicConfig{Addr: addr, IsTLS: isTLS})
+	return hc
+}
+
+func doGet(hc *HostClient, uri string) (*protocol.Response, error) {
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI(uri)
+	resp := &protocol.Response{}
+	return resp, hc.Do(context.Background(), req, resp)
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	addr := listenServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Set("X-Method", string(ctx.Request.Method()))
+		ctx.Response.Header.Set("X-UA", string(ctx.UserAgent()))
+		ctx.Response.Header.Set("X-Host", string(ctx.Host()))
+		ctx.Response.Header.Trailer().Set("X-Sum", "7")
+		ctx.Write(ctx.Request.Body())
+	})
+	hc := newTestHostClient(addr, false, &ClientOptions{Name: "tester"})
+	defer hc.Close()
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/echo")
+	req.Header.SetMethod("POST")
+	req.SetBodyString("hello")
+	resp := protocol.AcquireResponse()
+	defer protocol.ReleaseResponse(resp)
+
+	assert.Nil(t, hc.Do(context.Background(), req, resp))
+	assert.DeepEqual(t, 200, resp.StatusCode())
+	assert.DeepEqual(t, "hello", string(resp.Body()))
+	assert.DeepEqual(t, 5, resp.Header.ContentLength())
+	assert.DeepEqual(t, "POST", string(resp.Header.Peek("X-Method")))
+	assert.DeepEqual(t, "tester", string(resp.Header.Peek("X-UA")))
+	assert.DeepEqual(t, "example.com", string(resp.Header.Peek("X-Host")))
+	assert.DeepEqual(t, "7", string(resp.Header.Trailer().Peek("X-Sum")))
+}
+
+func TestClientMultiplexing(t *testing.T) {
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(10)
+	addr := listenServer(t, Option{MaxConcurrentStreams: 10}, func(c context.Context, ctx *app.RequestContext) {
+		started.Done()
+		<-release
+		ctx.WriteString(ctx.Query("i"))
+	})
+	hc := newTestHostClient(addr, false, &ClientOptions{})
+	defer hc.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := doGet(hc, fmt.Sprintf("http://example.com/?i=%d", i))
+			assert.Nil(t, err)
+			assert.DeepEqual(t, fmt.Sprint(i), string(resp.Body()))
+		}(i)
+	}
+
+	// 所有请求均在处理中时，应只占用一个连接
+	started.Wait()
+	state := hc.ConnPoolState()
+	assert.DeepEqual(t, 1, state.TotalConnNum)
+	assert.DeepEqual(t, 10, state.ActiveStreamNum)
+	assert.DeepEqual(t, 10, state.MaxStreamNum)
+	assert.DeepEqual(t, 0, state.PoolConnNum)
+
+	close(release)
+	wg.Wait()
+	state = hc.ConnPoolState()
+	assert.DeepEqual(t, 1, hc.ConnectionCount())
+	assert.DeepEqual(t, 0, state.ActiveStreamNum)
+	assert.DeepEqual(t, 1, state.PoolConnNum)
+}
+
+func TestClientOpensConnWhenStreamsExhausted(t *testing.T) {
+	release := make(chan struct{})
+	addr := listenServer(t, Option{MaxConcurrentStreams: 2}, func(c context.Context, ctx *app.RequestContext) {
+		<-release
+	})
+	hc := newTestHostClient(addr, false, &ClientOptions{})
+	defer hc.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := doGet(hc, "http://example.com/")
+			assert.Nil(t, err)
+		}()
+	}
+	waitFor(t, func() bool { return hc.ConnPoolState().ActiveStreamNum == 4 })
+	assert.DeepEqual(t, 2, hc.ConnectionCount())
+	assert.DeepEqual(t, 4, hc.ConnPoolState().MaxStreamNum)
+	close(release)
+	wg.Wait()
+}
+
+func TestClientResponseBodyStream(t *testing.T) {
+	addr := listenServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		w := ctx.GetWriter()
+		for i := 0; i < 3; i++ {
+			w.WriteBinary([]byte(fmt.Sprintf("part%d;", i)))
+			w.Flush()
+		}
+	})
+	hc := newTestHostClient(addr, false, &ClientOptions{ResponseBodyStream: true})
+	defer hc.Close()
+
+	resp, err := doGet(hc, "http://example.com/")
+	assert.Nil(t, err)
+	assert.True(t, resp.IsBodyStream())
+	assert.DeepEqual(t, 1, hc.ConnPoolState().ActiveStreamNum)
+	body, err := io.ReadAll(resp.BodyStream())
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "part0;part1;part2;", string(body))
+	assert.Nil(t, resp.CloseBodyStream())
+	assert.DeepEqual(t, 0, hc.ConnPoolState().ActiveStreamNum)
+}
+
+func TestClientLargeBody(t *testing.T) {
+	large := strings.Repeat("0123456789", 300000)
+	addr := listenServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		ctx.Write(ctx.Request.Body())
+	})
+	hc := newTestHostClient(addr, false, &ClientOptions{})
+	defer hc.Close()
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/")
+	req.Header.SetMethod("PUT")
+	req.SetBodyStream(strings.NewReader(large), len(large))
+	resp := &protocol.Response{}
+	assert.Nil(t, hc.Do(context.Background(), req, resp))
+	assert.True(t, large == string(resp.Body()))
+
+	// 超出响应正文上限
+	hc.MaxResponseBodySize = 1024
+	req.SetBodyString(large)
+	err := hc.Do(context.Background(), req, resp)
+	assert.True(t, errors.Is(err, errs.ErrBodyTooLarge))
+}
+
+func TestClientTimeout(t *testing.T) {
+	addr := listenServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		if ctx.Query("sleep") != "" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		ctx.WriteString("ok")
+	})
+	hc := newTestHostClient(addr, false, &ClientOptions{})
+	defer hc.Close()
+
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	req.SetRequestURI("http://example.com/?sleep=1")
+	req.SetOptions(config.WithRequestTimeout(50 * time.Millisecond))
+	err := hc.Do(context.Background(), req, &protocol.Response{})
+	assert.True(t, errors.Is(err, errs.ErrTimeout))
+
+	// 超时只重置单个流，连接仍可继续使用
+	resp, err := doGet(hc, "http://example.com/")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "ok", string(resp.Body()))
+	assert.DeepEqual(t, 1, hc.ConnectionCount())
+}
+
+func TestClientALPN(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	hc := newTestHostClient(ts.Listener.Addr().String(), true, &ClientOptions{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+	})
+	defer hc.Close()
+
+	resp, err := doGet(hc, "https://example.com/")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "HTTP/2.0", string(resp.Body()))
+
+	// 服务器不支持 h2 时报错
+	ts1 := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts1.Close()
+	hc1 := newTestHostClient(ts1.Listener.Addr().String(), true, &ClientOptions{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+	})
+	defer hc1.Close()
+	_, err = doGet(hc1, "https://example.com/")
+	assert.NotNil(t, err)
+}
+
+func TestClientCloseIdleConnections(t *testing.T) {
+	addr := listenServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {})
+	hc := newTestHostClient(addr, false, &ClientOptions{MaxIdleConnDuration: 50 * time.Millisecond})
+	defer hc.Close()
+
+	_, err := doGet(hc, "http://example.com/")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 1, hc.ConnectionCount())
+	waitFor(t, func() bool { return hc.ConnectionCount() == 0 })
+	assert.True(t, hc.ShouldRemove())
+}
+
+func TestClientRetryConfig(t *testing.T) {
+	var calls int32
+	addr := listenServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			ctx.SetStatusCode(503)
+		}
+	})
+	retryIf := func(req *protocol.Request, resp *protocol.Response, err error) bool {
+		return err != nil || resp.StatusCode() == 503
+	}
+
+	// 未配置 RetryConfig 时不重试
+	hc := newTestHostClient(addr, false, &ClientOptions{RetryIfFunc: retryIf})
+	defer hc.Close()
+	resp, err := doGet(hc, "http://example.com/")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 503, resp.StatusCode())
+
+	hc = newTestHostClient(addr, false, &ClientOptions{
+		RetryConfig: &retry.Config{MaxAttemptTimes: 3, DelayPolicy: retry.DefaultDelayPolicy},
+		RetryIfFunc: retryIf,
+	})
+	defer hc.Close()
+	resp, err = doGet(hc, "http://example.com/")
+	assert.Nil(t, err)
+	assert.DeepEqual(t, 200, resp.StatusCode())
+	assert.DeepEqual(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClientStateObserveDefaultInterval(t *testing.T) {
+	hc := newTestHostClient("127.0.0.1:0", false, &ClientOptions{StateObserve: func(config.HostClientState) {}})
+	defer hc.Close()
+	assert.DeepEqual(t, defaultObservationInterval, hc.ObservationInterval)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待条件超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,580 @@
+package http2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/favbox/gosky/wind/internal/bytestr"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol/suite"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+var (
+	errBadPreface   = errors.New("http2：客户端连接前言无效")
+	errConnClosed   = errors.New("http2：连接已关闭")
+	errStreamClosed = errors.New("http2：流已关闭")
+)
+
+// 服务器端的 HTTP/2 连接。
+//
+// 读循环独占帧的读取，处理器协程通过 frameWriter 串行写帧；
+// 流表、流控窗口等共享状态由 mu 保护，发送窗口不足时在 cond 上等待。
+type serverConn struct {
+	baseCtx context.Context
+	cancel  context.CancelFunc
+	conn    network.Conn
+	opt     *Option
+	core    suite.Core
+
+	framer *http2.Framer // 仅供读循环读取帧
+	fw     *frameWriter
+
+	mu                sync.Mutex
+	cond              *sync.Cond
+	streams           map[uint32]*stream
+	maxClientStreamID uint32
+	peerInitialWindow int32
+	outflow           int32 // 连接级发送窗口
+	inflow            int32 // 连接级接收窗口
+	inGoAway          bool
+	closed            bool
+	running           uint32 // 运行中的处理器数，含已被对端重置的流
+	refused           uint32 // 处理器已满时连续拒绝的流数
+	handlers          sync.WaitGroup
+	goAwayOnce        sync.Once
+	closeOnce         sync.Once
+}
+
+func newServerConn(c context.Context, conn network.Conn, opt *Option, core suite.Core) *serverConn {
+	ctx, cancel := context.WithCancel(c)
+	sc := &serverConn{
+		baseCtx:           ctx,
+		cancel:            cancel,
+		conn:              conn,
+		opt:               opt,
+		core:              core,
+		streams:           make(map[uint32]*stream),
+		peerInitialWindow: initialWindowSize,
+		outflow:           initialWindowSize,
+		inflow:            initialWindowSize,
+	}
+	sc.cond = sync.NewCond(&sc.mu)
+	sc.fw = newFrameWriter(conn)
+	sc.framer = http2.NewFramer(nil, conn)
+	sc.framer.SetMaxReadFrameSize(opt.MaxReadFrameSize)
+	sc.framer.MaxHeaderListSize = opt.MaxHeaderListSize
+	sc.framer.ReadMetaHeaders = hpack.NewDecoder(initialHeaderTableSz, nil)
+	return sc
+}
+
+func (sc *serverConn) serve() (err error) {
+	defer func() {
+		sc.close()
+		sc.handlers.Wait()
+		if errors.Is(err, io.EOF) || errors.Is(err, errConnClosed) {
+			err = nil
+		}
+	}()
+
+	if err = sc.readPreface(); err != nil {
+		return err
+	}
+
+	settings := []http2.Setting{
+		{ID: http2.SettingMaxFrameSize, Val: sc.opt.MaxReadFrameSize},
+		{ID: http2.SettingMaxConcurrentStreams, Val: sc.opt.MaxConcurrentStreams},
+		{ID: http2.SettingInitialWindowSize, Val: uint32(sc.opt.InitialWindowSize)},
+		{ID: http2.SettingMaxHeaderListSize, Val: sc.opt.MaxHeaderListSize},
+	}
+	if err = sc.fw.write(func(fr *http2.Framer) error { return fr.WriteSettings(settings...) }); err != nil {
+		return err
+	}
+	if diff := sc.opt.InitialConnWindowSize - initialWindowSize; diff > 0 {
+		sc.inflow += diff
+		if err = sc.fw.write(func(fr *http2.Framer) error { return fr.WriteWindowUpdate(0, uint32(diff)) }); err != nil {
+			return err
+		}
+	}
+
+	for {
+		sc.setIdleTimeout()
+		f, err := sc.framer.ReadFrame()
+		if err != nil {
+			var se http2.StreamError
+			if errors.As(err, &se) {
+				sc.resetStream(se.StreamID, se.Code)
+				continue
+			}
+			var ce http2.ConnectionError
+			if errors.As(err, &ce) {
+				sc.goAway(http2.ErrCode(ce))
+				return err
+			}
+			if sc.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if err = sc.processFrame(f); err != nil {
+			var ce http2.ConnectionError
+			if errors.As(err, &ce) {
+				sc.goAway(http2.ErrCode(ce))
+			}
+			return err
+		}
+	}
+}
+
+// 读取并校验客户端连接前言。
+func (sc *serverConn) readPreface() error {
+	if sc.opt.ReadTimeout > 0 {
+		_ = sc.conn.SetReadTimeout(sc.opt.ReadTimeout)
+	}
+	buf := make([]byte, len(bytestr.StrClientPreface))
+	if _, err := io.ReadFull(sc.conn, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, bytestr.StrClientPreface) {
+		return errBadPreface
+	}
+	return nil
+}
+
+// 无活跃流时按闲置超时等待下一帧，否则不设读取超时。
+func (sc *serverConn) setIdleTimeout() {
+	sc.mu.Lock()
+	idle := len(sc.streams) == 0
+	sc.mu.Unlock()
+	if idle && sc.opt.IdleTimeout > 0 {
+		_ = sc.conn.SetReadTimeout(sc.opt.IdleTimeout)
+	} else {
+		_ = sc.conn.SetReadTimeout(0)
+	}
+}
+
+func (sc *serverConn) processFrame(f http2.Frame) error {
+	switch f := f.(type) {
+	case *http2.SettingsFrame:
+		return sc.processSettings(f)
+	case *http2.MetaHeadersFrame:
+		return sc.processHeaders(f)
+	case *http2.DataFrame:
+		return sc.processData(f)
+	case *http2.WindowUpdateFrame:
+		return sc.processWindowUpdate(f)
+	case *http2.PingFrame:
+		if f.IsAck() {
+			return nil
+		}
+		return sc.fw.write(func(fr *http2.Framer) error { return fr.WritePing(true, f.Data) })
+	case *http2.RSTStreamFrame:
+		sc.mu.Lock()
+		st := sc.streams[f.StreamID]
+		sc.mu.Unlock()
+		if st == nil && f.StreamID > sc.maxClientStreamID {
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		if st != nil {
+			sc.closeStream(st)
+		}
+		return nil
+	case *http2.GoAwayFrame:
+		sc.mu.Lock()
+		sc.inGoAway = true
+		idle := len(sc.streams) == 0
+		sc.mu.Unlock()
+		if idle {
+			return errConnClosed
+		}
+		return nil
+	case *http2.PushPromiseFrame:
+		// 客户端不得推送
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	case *http2.PriorityFrame:
+		return nil
+	}
+	// 忽略未知帧类型
+	return nil
+}
+
+func (sc *serverConn) processSettings(f *http2.SettingsFrame) error {
+	if f.IsAck() {
+		return nil
+	}
+	err := f.ForeachSetting(func(s http2.Setting) error {
+		if err := s.Valid(); err != nil {
+			return err
+		}
+		switch s.ID {
+		case http2.SettingHeaderTableSize:
+			sc.fw.setHeaderTableSize(s.Val)
+		case http2.SettingMaxFrameSize:
+			sc.fw.setMaxFrameSize(s.Val)
+		case http2.SettingInitialWindowSize:
+			return sc.updateInitialWindow(int32(s.Val))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return sc.fw.write(func(fr *http2.Framer) error { return fr.WriteSettingsAck() })
+}
+
+// 对端修改初始窗口时，按差值调整所有流的发送窗口，详见 RFC 9113 第 6.9.2 节。
+func (sc *serverConn) updateInitialWindow(val int32) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delta := val - sc.peerInitialWindow
+	sc.peerInitialWindow = val
+	for _, st := range sc.streams {
+		if !addWindow(&st.outflow, delta) {
+			return http2.ConnectionError(http2.ErrCodeFlowControl)
+		}
+	}
+	sc.cond.Broadcast()
+	return nil
+}
+
+func (sc *serverConn) processWindowUpdate(f *http2.WindowUpdateFrame) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if f.StreamID == 0 {
+		if !addWindow(&sc.outflow, int32(f.Increment)) {
+			return http2.ConnectionError(http2.ErrCodeFlowControl)
+		}
+		sc.cond.Broadcast()
+		return nil
+	}
+	st := sc.streams[f.StreamID]
+	if st == nil {
+		if f.StreamID > sc.maxClientStreamID {
+			return http2.ConnectionError(http2.ErrCodeProtocol)
+		}
+		return nil
+	}
+	if !addWindow(&st.outflow, int32(f.Increment)) {
+		sc.mu.Unlock()
+		sc.resetStream(st.id, http2.ErrCodeFlowControl)
+		sc.mu.Lock()
+		return nil
+	}
+	sc.cond.Broadcast()
+	return nil
+}
+
+func (sc *serverConn) processHeaders(f *http2.MetaHeadersFrame) error {
+	id := f.StreamID
+	if id%2 != 1 {
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+
+	sc.mu.Lock()
+	st := sc.streams[id]
+	if st != nil {
+		state := st.state
+		sc.mu.Unlock()
+		// 已打开的流上再次收到 HEADERS 即为请求尾部标头
+		if state != stateOpen {
+			sc.resetStream(id, http2.ErrCodeStreamClosed)
+			return nil
+		}
+		if !f.StreamEnded() {
+			sc.resetStream(id, http2.ErrCodeProtocol)
+			return nil
+		}
+		st.trailer = f.RegularFields()
+		return sc.endRemote(st)
+	}
+	if id <= sc.maxClientStreamID {
+		sc.mu.Unlock()
+		return http2.ConnectionError(http2.ErrCodeStreamClosed)
+	}
+	sc.maxClientStreamID = id
+	if sc.inGoAway {
+		sc.mu.Unlock()
+		return nil
+	}
+	if uint32(len(sc.streams)) >= sc.opt.MaxConcurrentStreams {
+		sc.mu.Unlock()
+		sc.resetStream(id, http2.ErrCodeRefusedStream)
+		return nil
+	}
+	// 被重置的流的处理器仍在运行，同样占用并发数，以免对端反复打开并重置流无限创建处理器（CVE-2023-44487）
+	if sc.running >= sc.opt.MaxConcurrentStreams {
+		sc.refused++
+		calm := sc.refused > sc.opt.MaxConcurrentStreams
+		sc.mu.Unlock()
+		if calm {
+			// 对端无视拒绝持续打开流
+			return http2.ConnectionError(http2.ErrCodeEnhanceYourCalm)
+		}
+		sc.resetStream(id, http2.ErrCodeRefusedStream)
+		return nil
+	}
+	sc.refused = 0
+	if f.Truncated {
+		sc.mu.Unlock()
+		sc.writeSimpleResponse(id, 431)
+		return nil
+	}
+
+	st = newStream(sc, id, f)
+	sc.streams[id] = st
+	sc.mu.Unlock()
+
+	if err := st.checkPseudoHeaders(); err != nil {
+		sc.resetStream(id, http2.ErrCodeProtocol)
+		return nil
+	}
+	if f.StreamEnded() {
+		return sc.endRemote(st)
+	}
+	return nil
+}
+
+func (sc *serverConn) processData(f *http2.DataFrame) error {
+	id := f.StreamID
+	n := int32(f.Length)
+
+	sc.mu.Lock()
+	if n > sc.inflow {
+		sc.mu.Unlock()
+		return http2.ConnectionError(http2.ErrCodeFlowControl)
+	}
+	st := sc.streams[id]
+	if st == nil && id > sc.maxClientStreamID {
+		sc.mu.Unlock()
+		return http2.ConnectionError(http2.ErrCodeProtocol)
+	}
+	sc.inflow -= n
+	if st == nil || st.state != stateOpen {
+		sc.mu.Unlock()
+		// 已关闭的流也需归还连接级窗口
+		sc.refund(0, n)
+		sc.resetStream(id, http2.ErrCodeStreamClosed)
+		return nil
+	}
+	if n > st.inflow {
+		sc.mu.Unlock()
+		sc.refund(0, n)
+		sc.resetStream(id, http2.ErrCodeFlowControl)
+		return nil
+	}
+	st.inflow -= n
+	sc.mu.Unlock()
+
+	data := f.Data()
+	if sc.opt.MaxRequestBodySize > 0 && st.body.Len()+len(data) > sc.opt.MaxRequestBodySize {
+		// 正文超限，直接响应 413 并以 NO_ERROR 重置，告知对端停止发送
+		sc.refund(0, n)
+		sc.writeSimpleResponse(id, 413)
+		sc.resetStream(id, http2.ErrCodeNo)
+		return nil
+	}
+	st.body.Write(data)
+
+	if f.StreamEnded() {
+		sc.refund(0, n)
+		return sc.endRemote(st)
+	}
+	sc.refund(id, n)
+	return nil
+}
+
+// 归还接收窗口：请求正文已整体缓存，收到即可归还。
+func (sc *serverConn) refund(streamID uint32, n int32) {
+	if n <= 0 {
+		return
+	}
+	sc.mu.Lock()
+	sc.inflow += n
+	if st := sc.streams[streamID]; st != nil {
+		st.inflow += n
+	}
+	sc.mu.Unlock()
+
+	_ = sc.fw.write(func(fr *http2.Framer) error {
+		if err := fr.WriteWindowUpdate(0, uint32(n)); err != nil {
+			return err
+		}
+		if streamID != 0 {
+			return fr.WriteWindowUpdate(streamID, uint32(n))
+		}
+		return nil
+	})
+}
+
+// 对端结束发送，分派请求给处理器。
+func (sc *serverConn) endRemote(st *stream) error {
+	if err := st.checkContentLength(); err != nil {
+		sc.resetStream(st.id, http2.ErrCodeProtocol)
+		return nil
+	}
+
+	sc.mu.Lock()
+	if sc.running >= sc.opt.MaxConcurrentStreams {
+		sc.mu.Unlock()
+		sc.resetStream(st.id, http2.ErrCodeRefusedStream)
+		return nil
+	}
+	st.state = stateHalfClosedRemote
+	sc.running++
+	sc.mu.Unlock()
+
+	sc.handlers.Add(1)
+	go st.serve()
+	return nil
+}
+
+// 处理器结束，释放其占用的并发数。
+func (sc *serverConn) handlerDone() {
+	sc.mu.Lock()
+	sc.running--
+	sc.mu.Unlock()
+	sc.handlers.Done()
+}
+
+// 发送 RST_STREAM 并关闭流。
+func (sc *serverConn) resetStream(id uint32, code http2.ErrCode) {
+	sc.mu.Lock()
+	st := sc.streams[id]
+	sc.mu.Unlock()
+	if st != nil {
+		sc.closeStream(st)
+	}
+	_ = sc.fw.write(func(fr *http2.Framer) error { return fr.WriteRSTStream(id, code) })
+}
+
+// 从流表中移除流，并唤醒等待发送窗口的协程。
+func (sc *serverConn) closeStream(st *stream) {
+	sc.mu.Lock()
+	if st.state == stateClosed {
+		sc.mu.Unlock()
+		return
+	}
+	st.state = stateClosed
+	delete(sc.streams, st.id)
+	idle := len(sc.streams) == 0
+	goAway := sc.inGoAway
+	sc.cond.Broadcast()
+	sc.mu.Unlock()
+
+	st.cancel()
+	if idle && goAway {
+		sc.close()
+	}
+}
+
+// 发送 GOAWAY 帧，之后不再接受新的流。
+func (sc *serverConn) goAway(code http2.ErrCode) {
+	sc.goAwayOnce.Do(func() {
+		sc.mu.Lock()
+		sc.inGoAway = true
+		last := sc.maxClientStreamID
+		idle := len(sc.streams) == 0
+		sc.mu.Unlock()
+
+		_ = sc.fw.write(func(fr *http2.Framer) error { return fr.WriteGoAway(last, code, nil) })
+		if idle && code == http2.ErrCodeNo {
+			sc.close()
+		}
+	})
+}
+
+func (sc *serverConn) close() {
+	sc.closeOnce.Do(func() {
+		sc.mu.Lock()
+		sc.closed = true
+		sc.cond.Broadcast()
+		sc.mu.Unlock()
+		sc.cancel()
+		if err := sc.conn.Close(); err != nil {
+			hlog.SystemLogger().Debugf("HTTP/2 关闭连接出错：%v", err)
+		}
+	})
+}
+
+func (sc *serverConn) isClosed() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.closed
+}
+
+// 按连接及流的发送窗口分片写入 DATA 帧，窗口不足时阻塞等待。
+func (sc *serverConn) writeData(st *stream, p []byte, endStream bool) error {
+	if len(p) == 0 && !endStream {
+		return nil
+	}
+	for {
+		n := 0
+		if len(p) > 0 {
+			var err error
+			if n, err = sc.reserveWindow(st, len(p)); err != nil {
+				return err
+			}
+		}
+		chunk := p[:n]
+		p = p[n:]
+		end := endStream && len(p) == 0
+		if err := sc.fw.write(func(fr *http2.Framer) error { return fr.WriteData(st.id, end, chunk) }); err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return nil
+		}
+	}
+}
+
+func (sc *serverConn) reserveWindow(st *stream, want int) (int, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for {
+		if sc.closed {
+			return 0, errConnClosed
+		}
+		if st.state == stateClosed {
+			return 0, errStreamClosed
+		}
+		n := int32(want)
+		if maxFrame := int32(sc.fw.maxFrameSize()); n > maxFrame {
+			n = maxFrame
+		}
+		if n > sc.outflow {
+			n = sc.outflow
+		}
+		if n > st.outflow {
+			n = st.outflow
+		}
+		if n > 0 {
+			sc.outflow -= n
+			st.outflow -= n
+			return int(n), nil
+		}
+		sc.cond.Wait()
+	}
+}
+
+// 写入仅含状态码的响应，用于在分派处理器前拒绝请求。
+func (sc *serverConn) writeSimpleResponse(id uint32, status int) {
+	_ = sc.fw.writeHeaders(fixedID(id), []hpack.HeaderField{
+		{Name: ":status", Value: statusText(status)},
+		{Name: "content-length", Value: "0"},
+	}, true)
+}
+
+// 增加窗口并检查溢出，窗口不得超过 2^31-1。
+func addWindow(w *int32, n int32) bool {
+	sum := int64(*w) + int64(n)
+	if sum > 1<<31-1 {
+		return false
+	}
+	*w = int32(sum)
+	return true
+}
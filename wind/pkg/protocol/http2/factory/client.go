@@ -0,0 +1,24 @@
+package factory
+
+import (
+	"github.com/favbox/gosky/wind/pkg/protocol/client"
+	"github.com/favbox/gosky/wind/pkg/protocol/http2"
+	"github.com/favbox/gosky/wind/pkg/protocol/suite"
+)
+
+var _ suite.ClientFactory = (*clientFactory)(nil)
+
+type clientFactory struct {
+	option *http2.ClientOptions
+}
+
+func (c *clientFactory) NewHostClient() (hc client.HostClient, err error) {
+	return http2.NewHostClient(c.option), nil
+}
+
+// NewClientFactory 创建 HTTP/2 客户端工厂。
+func NewClientFactory(option *http2.ClientOptions) suite.ClientFactory {
+	return &clientFactory{
+		option: option,
+	}
+}
@@ -0,0 +1,107 @@
+package http2
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// 串行写帧的编写器，服务器与客户端共用。
+//
+// HPACK 编码器的动态表依赖标头块的发送顺序，故编码与写帧须在同一把锁内完成。
+type frameWriter struct {
+	mu     sync.Mutex
+	bw     *bufio.Writer
+	framer *http2.Framer
+	henc   *hpack.Encoder
+	hbuf   bytes.Buffer
+
+	peerMaxFrameSize uint32 // 原子访问
+}
+
+func newFrameWriter(w io.Writer) *frameWriter {
+	fw := &frameWriter{
+		bw:               bufio.NewWriterSize(w, 4<<10),
+		peerMaxFrameSize: initialMaxFrameSize,
+	}
+	fw.framer = http2.NewFramer(fw.bw, nil)
+	fw.henc = hpack.NewEncoder(&fw.hbuf)
+	return fw
+}
+
+// 串行写入帧并刷新。
+func (fw *frameWriter) write(fn func(fr *http2.Framer) error) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if err := fn(fw.framer); err != nil {
+		return err
+	}
+	return fw.bw.Flush()
+}
+
+// 编码并写入标头块，超出对端最大帧大小时拆分为 CONTINUATION 帧。
+//
+// id 在持锁状态下调用，使客户端分配的流标识与发送顺序一致。
+func (fw *frameWriter) writeHeaders(id func() uint32, fields []hpack.HeaderField, endStream bool) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.hbuf.Reset()
+	for _, f := range fields {
+		if err := fw.henc.WriteField(f); err != nil {
+			return err
+		}
+	}
+	sid := id()
+	block := fw.hbuf.Bytes()
+	maxFrame := fw.maxFrameSize()
+	first := true
+	for first || len(block) > 0 {
+		chunk := block
+		if len(chunk) > maxFrame {
+			chunk = chunk[:maxFrame]
+		}
+		block = block[len(chunk):]
+		endHeaders := len(block) == 0
+		var err error
+		if first {
+			err = fw.framer.WriteHeaders(http2.HeadersFrameParam{
+				StreamID:      sid,
+				BlockFragment: chunk,
+				EndStream:     endStream,
+				EndHeaders:    endHeaders,
+			})
+			first = false
+		} else {
+			err = fw.framer.WriteContinuation(sid, endHeaders, chunk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return fw.bw.Flush()
+}
+
+func (fw *frameWriter) maxFrameSize() int {
+	return int(atomic.LoadUint32(&fw.peerMaxFrameSize))
+}
+
+func (fw *frameWriter) setMaxFrameSize(n uint32) {
+	atomic.StoreUint32(&fw.peerMaxFrameSize, n)
+}
+
+func (fw *frameWriter) setHeaderTableSize(n uint32) {
+	fw.mu.Lock()
+	fw.henc.SetMaxDynamicTableSizeLimit(n)
+	fw.mu.Unlock()
+}
+
+// 返回固定的流标识，用于 writeHeaders。
+func fixedID(id uint32) func() uint32 {
+	return func() uint32 { return id }
+}
@@ -0,0 +1,69 @@
+package http2
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/favbox/gosky/wind/internal/bytestr"
+	"github.com/favbox/gosky/wind/pkg/common/utils"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP/2 中禁止出现的连接级标头，详见 RFC 9113 第 8.2.2 节。
+var connectionHeaders = [][]byte{
+	[]byte("connection"),
+	[]byte("keep-alive"),
+	[]byte("proxy-connection"),
+	[]byte("transfer-encoding"),
+	[]byte("upgrade"),
+}
+
+func isConnectionHeader(key []byte) bool {
+	for _, h := range connectionHeaders {
+		if utils.CaseInsensitiveCompare(key, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// 将响应头转为 HPACK 字段，标头名称一律小写。
+//
+// contentLength 非负时写入 content-length，否则仅沿用处理器显式设置的正值。
+func responseHeaderFields(h *protocol.ResponseHeader, contentLength int) []hpack.HeaderField {
+	status := h.StatusCode()
+	if status <= 0 {
+		status = consts.StatusOK
+	}
+	fields := make([]hpack.HeaderField, 0, 8)
+	fields = append(fields, hpack.HeaderField{Name: ":status", Value: statusText(status)})
+
+	hasDate := false
+	h.VisitAll(func(k, v []byte) {
+		if isConnectionHeader(k) || utils.CaseInsensitiveCompare(k, bytestr.StrContentLength) {
+			return
+		}
+		if utils.CaseInsensitiveCompare(k, bytestr.StrDate) {
+			hasDate = true
+		}
+		fields = append(fields, hpack.HeaderField{Name: string(bytes.ToLower(k)), Value: string(v)})
+	})
+	if !hasDate {
+		protocol.ServerDateOnce.Do(protocol.UpdateServerDate)
+		fields = append(fields, hpack.HeaderField{Name: "date", Value: string(protocol.ServerDate.Load().([]byte))})
+	}
+
+	if contentLength < 0 && h.ContentLength() > 0 {
+		contentLength = h.ContentLength()
+	}
+	if contentLength >= 0 && !h.MustSkipContentLength() {
+		fields = append(fields, hpack.HeaderField{Name: "content-length", Value: strconv.Itoa(contentLength)})
+	}
+	return fields
+}
+
+func statusText(status int) string {
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,57 @@
+package http2
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/server/render"
+)
+
+const (
+	defaultMaxConcurrentStreams = 250
+	defaultMaxReadFrameSize     = 1 << 20
+	defaultStreamWindowSize     = 1 << 20
+	defaultConnWindowSize       = 1 << 20
+	defaultMaxHeaderListSize    = 1 << 20
+
+	// 协议规定的初始值，详见 RFC 9113 第 6.5.2 节。
+	initialWindowSize    = 65535
+	initialMaxFrameSize  = 16384
+	initialHeaderTableSz = 4096
+)
+
+// Option 表示 HTTP/2 服务器选项。
+type Option struct {
+	MaxConcurrentStreams  uint32            // 单连接的最大并发流数，也限制运行中的处理器数，默认 250
+	MaxReadFrameSize      uint32            // 允许对端发送的最大帧大小，默认 1MB
+	InitialWindowSize     int32             // 流级初始接收窗口，默认 1MB
+	InitialConnWindowSize int32             // 连接级初始接收窗口，默认 1MB
+	MaxHeaderListSize     uint32            // 允许的最大请求头大小，默认 1MB
+	MaxRequestBodySize    int               // 最大请求正文大小，超出则响应 413
+	IdleTimeout           time.Duration     // 无活跃流时的连接闲置超时，0 代表永不超时
+	ReadTimeout           time.Duration     // 读取连接前言的超时时长
+	NoDefaultServerHeader bool              // 是否不要默认服务器名称
+	ServerName            []byte            // 服务器名称
+	TLS                   *tls.Config       // 安全链接配置
+	EnableTrace           bool              // 是否启用链路追踪
+	HTMLRender            render.HTMLRender // HTML 渲染器
+}
+
+// 将未设置的选项替换为默认值。
+func (o *Option) normalize() {
+	if o.MaxConcurrentStreams == 0 {
+		o.MaxConcurrentStreams = defaultMaxConcurrentStreams
+	}
+	if o.MaxReadFrameSize < initialMaxFrameSize || o.MaxReadFrameSize > 1<<24-1 {
+		o.MaxReadFrameSize = defaultMaxReadFrameSize
+	}
+	if o.InitialWindowSize <= 0 {
+		o.InitialWindowSize = defaultStreamWindowSize
+	}
+	if o.InitialConnWindowSize < initialWindowSize {
+		o.InitialConnWindowSize = defaultConnWindowSize
+	}
+	if o.MaxHeaderListSize == 0 {
+		o.MaxHeaderListSize = defaultMaxHeaderListSize
+	}
+}
@@ -0,0 +1,30 @@
+package http2
+
+import (
+	"context"
+
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol/suite"
+)
+
+// NewServer 创建新的 HTTP/2 服务器。
+func NewServer() *Server {
+	return &Server{}
+}
+
+// Server 表示 HTTP/2 服务器结构体。
+//
+// 实现 protocol.Server 协议服务器，支持 TLS ALPN "h2" 协商及明文 h2c 先验知识连接。
+// 每个请求流都会在独立的协程中运行与 HTTP/1.1 相同的处理链，不支持服务器推送和连接劫持。
+type Server struct {
+	Option
+	Core suite.Core
+}
+
+// Serve 提供连接服务，直至连接关闭、出错或服务器退出。
+func (s *Server) Serve(c context.Context, conn network.Conn) error {
+	opt := s.Option
+	opt.normalize()
+	sc := newServerConn(c, conn, &opt, s.Core)
+	return sc.serve()
+}
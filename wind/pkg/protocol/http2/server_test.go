@@ -0,0 +1,350 @@
+package http2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/common/tracer"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+type mockCore struct {
+	ctxPool     *sync.Pool
+	mockHandler func(c context.Context, ctx *app.RequestContext)
+}
+
+func (m *mockCore) IsRunning() bool              { return true }
+func (m *mockCore) GetCtxPool() *sync.Pool       { return m.ctxPool }
+func (m *mockCore) GetTracer() tracer.Controller { return nil }
+func (m *mockCore) ServeHTTP(c context.Context, ctx *app.RequestContext) {
+	m.mockHandler(c, ctx)
+}
+
+// 用 bufio 包装 net.Conn，满足 network.Conn 接口。
+type testConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *testConn) Read(p []byte) (int, error)            { return c.r.Read(p) }
+func (c *testConn) Len() int                              { return c.r.Buffered() }
+func (c *testConn) Peek(n int) ([]byte, error)            { return c.r.Peek(n) }
+func (c *testConn) Skip(n int) error                      { _, err := c.r.Discard(n); return err }
+func (c *testConn) ReadByte() (byte, error)               { return c.r.ReadByte() }
+func (c *testConn) Release() error                        { return nil }
+func (c *testConn) Malloc(n int) ([]byte, error)          { return make([]byte, n), nil }
+func (c *testConn) WriteBinary(b []byte) (int, error)     { return c.Write(b) }
+func (c *testConn) Flush() error                          { return nil }
+func (c *testConn) SetWriteTimeout(t time.Duration) error { return nil }
+
+func (c *testConn) ReadBinary(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(c.r, b)
+	return b, err
+}
+
+func (c *testConn) SetReadTimeout(t time.Duration) error {
+	if t <= 0 {
+		return c.SetReadDeadline(time.Time{})
+	}
+	return c.SetReadDeadline(time.Now().Add(t))
+}
+
+var _ network.Conn = (*testConn)(nil)
+
+// 启动 h2c 服务器，返回基于先验知识的客户端。
+func startServer(t *testing.T, opt Option, h func(c context.Context, ctx *app.RequestContext)) *http.Client {
+	addr := listenServer(t, opt, h)
+	tr := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, _ string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	t.Cleanup(tr.CloseIdleConnections)
+	return &http.Client{Transport: tr}
+}
+
+// 在本地随机端口启动 h2c 服务器，返回监听地址。
+func listenServer(t *testing.T, opt Option, h func(c context.Context, ctx *app.RequestContext)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := NewServer()
+	s.Option = opt
+	s.Core = &mockCore{
+		ctxPool:     &sync.Pool{New: func() any { return app.NewContext(0) }},
+		mockHandler: h,
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.Serve(context.Background(), &testConn{Conn: c, r: bufio.NewReader(c)})
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	client := startServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		assert.DeepEqual(t, "HTTP/2.0", string(ctx.Request.Header.GetProtocol()))
+		ctx.Response.Header.Set("X-Method", string(ctx.Request.Method()))
+		ctx.Response.Header.Set("X-Query", ctx.Query("q"))
+		ctx.Response.Header.Set("X-Token", string(ctx.GetHeader("X-Token")))
+		ctx.Response.Header.Set("X-Host", string(ctx.Host()))
+		ctx.Response.Header.Set("Connection", "keep-alive")
+		ctx.Write(ctx.Request.Body())
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/echo?q=1", strings.NewReader("hello"))
+	req.Header.Set("X-Token", "abc")
+	resp, err := client.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.DeepEqual(t, 200, resp.StatusCode)
+	assert.DeepEqual(t, 2, resp.ProtoMajor)
+	assert.DeepEqual(t, "hello", string(body))
+	assert.DeepEqual(t, "POST", resp.Header.Get("X-Method"))
+	assert.DeepEqual(t, "1", resp.Header.Get("X-Query"))
+	assert.DeepEqual(t, "abc", resp.Header.Get("X-Token"))
+	assert.DeepEqual(t, "example.com", resp.Header.Get("X-Host"))
+	assert.DeepEqual(t, "", resp.Header.Get("Connection"))
+	assert.DeepEqual(t, int64(5), resp.ContentLength)
+}
+
+func TestServerConcurrentStreams(t *testing.T) {
+	client := startServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		time.Sleep(10 * time.Millisecond)
+		ctx.WriteString(ctx.Query("i"))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Get(fmt.Sprintf("http://example.com/?i=%d", i))
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.DeepEqual(t, fmt.Sprint(i), string(body))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestServerFlowControl(t *testing.T) {
+	// 响应超出默认的 64KB 初始窗口，需等待客户端的 WINDOW_UPDATE
+	large := bytes.Repeat([]byte("0123456789"), 50000)
+	client := startServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		assert.DeepEqual(t, len(large), len(ctx.Request.Body()))
+		ctx.Write(large)
+	})
+
+	resp, err := client.Post("http://example.com/", "text/plain", bytes.NewReader(large))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.True(t, bytes.Equal(large, body))
+}
+
+func TestServerStreamingResponse(t *testing.T) {
+	client := startServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		ctx.SetContentType("text/event-stream")
+		w := ctx.GetWriter()
+		for i := 0; i < 3; i++ {
+			w.WriteBinary([]byte(fmt.Sprintf("part%d;", i)))
+			assert.Nil(t, w.Flush())
+		}
+		ctx.Response.Header.Trailer().Set("X-Done", "yes")
+	})
+
+	resp, err := client.Get("http://example.com/stream")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.DeepEqual(t, "part0;part1;part2;", string(body))
+	assert.DeepEqual(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.DeepEqual(t, int64(-1), resp.ContentLength)
+}
+
+func TestServerBodyStreamAndTrailer(t *testing.T) {
+	client := startServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		ctx.Response.Header.Trailer().Set("X-Checksum", "42")
+		ctx.SetBodyStream(strings.NewReader("streamed body"), -1)
+	})
+
+	resp, err := client.Get("http://example.com/")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.DeepEqual(t, "streamed body", string(body))
+	assert.DeepEqual(t, "42", resp.Trailer.Get("X-Checksum"))
+}
+
+func TestServerHeadAndNoContent(t *testing.T) {
+	client := startServer(t, Option{}, func(c context.Context, ctx *app.RequestContext) {
+		if string(ctx.Path()) == "/empty" {
+			ctx.SetStatusCode(204)
+			return
+		}
+		ctx.WriteString("ignored for HEAD")
+	})
+
+	resp, err := client.Head("http://example.com/")
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.DeepEqual(t, 0, len(body))
+	assert.DeepEqual(t, int64(16), resp.ContentLength)
+
+	resp, err = client.Get("http://example.com/empty")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.DeepEqual(t, 204, resp.StatusCode)
+}
+
+func TestServerMaxRequestBodySize(t *testing.T) {
+	client := startServer(t, Option{MaxRequestBodySize: 10}, func(c context.Context, ctx *app.RequestContext) {
+		ctx.WriteString("ok")
+	})
+
+	resp, err := client.Post("http://example.com/", "text/plain", strings.NewReader(strings.Repeat("x", 100)))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.DeepEqual(t, 413, resp.StatusCode)
+
+	// 同一连接仍可继续使用
+	resp, err = client.Post("http://example.com/", "text/plain", strings.NewReader("small"))
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.DeepEqual(t, "ok", string(body))
+}
+
+func TestServerBadPreface(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		s := NewServer()
+		s.Core = &mockCore{ctxPool: &sync.Pool{New: func() any { return app.NewContext(0) }}}
+		errCh <- s.Serve(context.Background(), &testConn{Conn: c, r: bufio.NewReader(c)})
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	defer c.Close()
+	c.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	assert.DeepEqual(t, errBadPreface, <-errCh)
+}
+
+// 对端反复打开并立即重置流时，运行中的处理器数不超过 MaxConcurrentStreams。
+func TestServerRapidReset(t *testing.T) {
+	const maxStreams = 4
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	release := make(chan struct{})
+	defer close(release)
+	addr := listenServer(t, Option{MaxConcurrentStreams: maxStreams}, func(c context.Context, ctx *app.RequestContext) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	c, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer c.Close()
+	_, err = c.Write([]byte(http2.ClientPreface))
+	assert.Nil(t, err)
+	fr := http2.NewFramer(c, c)
+	assert.Nil(t, fr.WriteSettings())
+
+	var buf bytes.Buffer
+	enc := hpack.NewEncoder(&buf)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: "example.com"},
+		{Name: ":path", Value: "/"},
+	} {
+		_ = enc.WriteField(f)
+	}
+
+	// 持续读取服务器的帧，直至收到 GOAWAY
+	goAway := make(chan http2.ErrCode, 1)
+	go func() {
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				close(goAway)
+				return
+			}
+			if ga, ok := f.(*http2.GoAwayFrame); ok {
+				goAway <- ga.ErrCode
+				return
+			}
+		}
+	}()
+
+	for id := uint32(1); id < 200; id += 2 {
+		if err := fr.WriteHeaders(http2.HeadersFrameParam{
+			StreamID:      id,
+			BlockFragment: buf.Bytes(),
+			EndStream:     true,
+			EndHeaders:    true,
+		}); err != nil {
+			break
+		}
+		if err := fr.WriteRSTStream(id, http2.ErrCodeCancel); err != nil {
+			break
+		}
+	}
+
+	select {
+	case code := <-goAway:
+		assert.DeepEqual(t, http2.ErrCodeEnhanceYourCalm, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到 GOAWAY")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, peak <= maxStreams)
+}
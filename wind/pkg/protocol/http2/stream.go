@@ -0,0 +1,412 @@
+package http2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	internalStats "github.com/favbox/gosky/wind/internal/stats"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/bytebufferpool"
+	"github.com/favbox/gosky/wind/pkg/common/hlog"
+	"github.com/favbox/gosky/wind/pkg/common/tracer/stats"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+var errMalformedRequest = errors.New("http2：请求标头格式有误")
+
+type streamState int
+
+const (
+	stateOpen streamState = iota
+	stateHalfClosedRemote
+	stateClosed
+)
+
+// 单个请求流。
+type stream struct {
+	sc     *serverConn
+	id     uint32
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 以下字段由 sc.mu 保护
+	state   streamState
+	inflow  int32
+	outflow int32
+
+	// 以下字段仅由读循环在分派处理器前访问
+	fields  []hpack.HeaderField
+	pseudo  pseudoHeaders
+	trailer []hpack.HeaderField
+	body    bytes.Buffer
+}
+
+type pseudoHeaders struct {
+	method, scheme, authority, path string
+}
+
+// 调用前须持有 sc.mu。
+func newStream(sc *serverConn, id uint32, f *http2.MetaHeadersFrame) *stream {
+	ctx, cancel := context.WithCancel(sc.baseCtx)
+	return &stream{
+		sc:      sc,
+		id:      id,
+		ctx:     ctx,
+		cancel:  cancel,
+		state:   stateOpen,
+		inflow:  sc.opt.InitialWindowSize,
+		outflow: sc.peerInitialWindow,
+		fields:  f.RegularFields(),
+		pseudo: pseudoHeaders{
+			method:    f.PseudoValue("method"),
+			scheme:    f.PseudoValue("scheme"),
+			authority: f.PseudoValue("authority"),
+			path:      f.PseudoValue("path"),
+		},
+	}
+}
+
+// 校验请求伪标头，详见 RFC 9113 第 8.3.1 节。
+func (st *stream) checkPseudoHeaders() error {
+	p := st.pseudo
+	if p.method == "" {
+		return errMalformedRequest
+	}
+	if p.method == consts.MethodConnect {
+		if p.scheme != "" || p.path != "" || p.authority == "" {
+			return errMalformedRequest
+		}
+		return nil
+	}
+	if p.scheme == "" || p.path == "" {
+		return errMalformedRequest
+	}
+	if p.path[0] != '/' && !(p.path == "*" && p.method == consts.MethodOptions) {
+		return errMalformedRequest
+	}
+	return nil
+}
+
+// 校验 content-length 与实际接收的正文长度是否一致。
+func (st *stream) checkContentLength() error {
+	for _, f := range st.fields {
+		if f.Name != "content-length" {
+			continue
+		}
+		n, err := strconv.Atoi(f.Value)
+		if err != nil || n != st.body.Len() {
+			return errMalformedRequest
+		}
+	}
+	return nil
+}
+
+// 在独立协程中运行处理链并写回响应。
+func (st *stream) serve() {
+	sc := st.sc
+	defer sc.handlerDone()
+
+	ctx := sc.core.GetCtxPool().Get().(*app.RequestContext)
+	conn := &streamConn{st: st, ctx: ctx, parent: sc.conn}
+
+	var err error
+	traceCtl := sc.core.GetTracer()
+	defer func() {
+		if r := recover(); r != nil {
+			hlog.SystemLogger().Errorf("HTTP/2 流处理出现恐慌：%v", r)
+			sc.resetStream(st.id, http2.ErrCodeInternal)
+		}
+		if sc.opt.EnableTrace {
+			if err != nil {
+				ctx.GetTraceInfo().Stats().SetError(err)
+			}
+			traceCtl.DoFinish(st.ctx, ctx, err)
+		}
+		sc.closeStream(st)
+		ctx.Reset()
+		sc.core.GetCtxPool().Put(ctx)
+	}()
+
+	ctx.HTMLRender = sc.opt.HTMLRender
+	ctx.SetConn(conn)
+	ctx.SetEnableTrace(sc.opt.EnableTrace)
+	if sc.opt.EnableTrace {
+		traceCtl.DoStart(st.ctx, ctx)
+	}
+
+	st.fillRequest(ctx)
+	if !sc.opt.NoDefaultServerHeader && sc.opt.ServerName != nil {
+		ctx.Response.Header.SetServerBytes(sc.opt.ServerName)
+	}
+
+	if sc.opt.EnableTrace {
+		ctx.GetTraceInfo().Stats().SetRecvSize(st.body.Len())
+		internalStats.Record(ctx.GetTraceInfo(), stats.ServerHandleStart, nil)
+	}
+
+	// ⭐️ 处理请求，与 HTTP/1.1 使用同一处理链。
+	sc.core.ServeHTTP(st.ctx, ctx)
+
+	if sc.opt.EnableTrace {
+		internalStats.Record(ctx.GetTraceInfo(), stats.ServerHandleFinish, nil)
+		internalStats.Record(ctx.GetTraceInfo(), stats.WriteStart, nil)
+	}
+	if ctx.IsHead() {
+		ctx.Response.SkipBody = true
+	}
+	if h := ctx.GetHijackHandler(); h != nil {
+		ctx.SetHijackHandler(nil)
+		hlog.SystemLogger().Warnf("HTTP/2 不支持连接劫持，已忽略：路径=%s", ctx.Request.URI().PathOriginal())
+	}
+	err = conn.finish()
+	if sc.opt.EnableTrace {
+		internalStats.Record(ctx.GetTraceInfo(), stats.WriteFinish, err)
+	}
+	if err != nil && !errors.Is(err, errStreamClosed) && !errors.Is(err, errConnClosed) {
+		hlog.SystemLogger().Debugf("HTTP/2 写入响应出错：流=%d, 错误=%v", st.id, err)
+	}
+
+	// 引擎退出时通知对端不再接受新流，待现有流处理完毕后关闭连接
+	if !sc.core.IsRunning() {
+		sc.goAway(http2.ErrCodeNo)
+	}
+}
+
+// 将标头帧中的字段填入请求。
+func (st *stream) fillRequest(ctx *app.RequestContext) {
+	req := &ctx.Request
+	p := st.pseudo
+
+	req.Header.SetMethod(p.method)
+	req.Header.SetProtocol(consts.HTTP20)
+	req.SetIsTLS(st.sc.opt.TLS != nil || p.scheme == "https")
+	for _, f := range st.fields {
+		if f.Name == "content-length" {
+			continue
+		}
+		req.Header.Add(f.Name, f.Value)
+	}
+	if p.method == consts.MethodConnect {
+		req.SetRequestURI(p.authority)
+	} else {
+		req.SetRequestURI(p.path)
+	}
+	if p.authority != "" {
+		req.SetHost(p.authority)
+	}
+	if p.scheme != "" {
+		req.URI().SetScheme(p.scheme)
+	}
+	if st.body.Len() > 0 {
+		req.SetBody(st.body.Bytes())
+	}
+	req.Header.SetContentLength(st.body.Len())
+	for _, f := range st.trailer {
+		_ = req.Header.Trailer().Add(f.Name, f.Value)
+	}
+}
+
+// 流级连接，供处理器通过 ctx.GetWriter() 直接写出响应正文。
+//
+// 首次刷新时发送响应标头，此后写入的数据作为 DATA 帧发出；读取总是返回 io.EOF。
+type streamConn struct {
+	st     *stream
+	ctx    *app.RequestContext
+	parent network.Conn
+
+	buf         []byte
+	wroteHeader bool
+	finished    bool
+}
+
+func (c *streamConn) Read(p []byte) (int, error)            { return 0, io.EOF }
+func (c *streamConn) Len() int                              { return 0 }
+func (c *streamConn) Peek(n int) ([]byte, error)            { return nil, io.EOF }
+func (c *streamConn) Skip(n int) error                      { return io.EOF }
+func (c *streamConn) ReadByte() (byte, error)               { return 0, io.EOF }
+func (c *streamConn) ReadBinary(n int) ([]byte, error)      { return nil, io.EOF }
+func (c *streamConn) Release() error                        { return nil }
+func (c *streamConn) LocalAddr() net.Addr                   { return c.parent.LocalAddr() }
+func (c *streamConn) RemoteAddr() net.Addr                  { return c.parent.RemoteAddr() }
+func (c *streamConn) SetDeadline(t time.Time) error         { return nil }
+func (c *streamConn) SetReadDeadline(t time.Time) error     { return nil }
+func (c *streamConn) SetWriteDeadline(t time.Time) error    { return nil }
+func (c *streamConn) SetReadTimeout(t time.Duration) error  { return nil }
+func (c *streamConn) SetWriteTimeout(t time.Duration) error { return nil }
+
+// Close 以 CANCEL 重置当前流，不影响所在的连接。
+func (c *streamConn) Close() error {
+	if c.finished {
+		return nil
+	}
+	c.finished = true
+	c.st.sc.resetStream(c.st.id, http2.ErrCodeCancel)
+	return nil
+}
+
+func (c *streamConn) Write(p []byte) (int, error) {
+	if c.finished {
+		return 0, errStreamClosed
+	}
+	c.buf = append(c.buf, p...)
+	return len(p), nil
+}
+
+func (c *streamConn) Malloc(n int) ([]byte, error) {
+	l := len(c.buf)
+	if cap(c.buf)-l < n {
+		nb := make([]byte, l, l+n+l/2)
+		copy(nb, c.buf)
+		c.buf = nb
+	}
+	c.buf = c.buf[:l+n]
+	return c.buf[l:], nil
+}
+
+func (c *streamConn) WriteBinary(b []byte) (int, error) {
+	return c.Write(b)
+}
+
+// Flush 发送响应标头（若尚未发送）及已缓冲的正文。
+func (c *streamConn) Flush() error {
+	if c.finished {
+		return errStreamClosed
+	}
+	if err := c.writeHeader(false); err != nil {
+		return err
+	}
+	if len(c.buf) == 0 {
+		return nil
+	}
+	err := c.st.sc.writeData(c.st, c.buf, false)
+	c.buf = c.buf[:0]
+	return err
+}
+
+func (c *streamConn) writeHeader(endStream bool) error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	fields := responseHeaderFields(&c.ctx.Response.Header, -1)
+	return c.st.sc.fw.writeHeaders(fixedID(c.st.id), fields, endStream)
+}
+
+// 处理器返回后写出剩余的响应并结束流。
+func (c *streamConn) finish() error {
+	if c.finished {
+		return nil
+	}
+	resp := &c.ctx.Response
+	sc := c.st.sc
+
+	// 已劫持响应写入器或已直接写出数据，则进入流式模式
+	if w := resp.GetHijackWriter(); w != nil {
+		if err := w.Finalize(); err != nil {
+			return err
+		}
+	}
+	if c.wroteHeader || len(c.buf) > 0 {
+		if err := c.Flush(); err != nil {
+			return err
+		}
+		c.finished = true
+		return c.writeEnd(resp)
+	}
+	c.finished = true
+
+	if resp.MustSkipBody() {
+		c.wroteHeader = true
+		size := -1
+		if !resp.IsBodyStream() && len(resp.BodyBytes()) > 0 {
+			size = len(resp.BodyBytes())
+		}
+		fields := responseHeaderFields(&resp.Header, size)
+		return sc.fw.writeHeaders(fixedID(c.st.id), fields, true)
+	}
+
+	if resp.IsBodyStream() {
+		return c.writeBodyStream(resp.BodyStream(), resp.Header.ContentLength())
+	}
+
+	body := resp.BodyBytes()
+	c.wroteHeader = true
+	fields := responseHeaderFields(&resp.Header, len(body))
+	noBody := len(body) == 0 && resp.Header.Trailer().Empty()
+	if err := sc.fw.writeHeaders(fixedID(c.st.id), fields, noBody); err != nil {
+		return err
+	}
+	if noBody {
+		return nil
+	}
+	if err := sc.writeData(c.st, body, resp.Header.Trailer().Empty()); err != nil {
+		return err
+	}
+	return c.writeTrailer(resp)
+}
+
+func (c *streamConn) writeBodyStream(r io.Reader, size int) error {
+	sc := c.st.sc
+	defer func() {
+		if rc, ok := r.(io.Closer); ok {
+			_ = rc.Close()
+		}
+	}()
+
+	c.wroteHeader = true
+	if err := sc.fw.writeHeaders(fixedID(c.st.id), responseHeaderFields(&c.ctx.Response.Header, size), false); err != nil {
+		return err
+	}
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+	if cap(bb.B) < 16<<10 {
+		bb.B = make([]byte, 16<<10)
+	}
+	buf := bb.B[:cap(bb.B)]
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if werr := sc.writeData(c.st, buf[:n], false); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return c.writeEnd(&c.ctx.Response)
+}
+
+// 以尾部标头或空 DATA 帧结束流。
+func (c *streamConn) writeEnd(resp *protocol.Response) error {
+	if !resp.Header.Trailer().Empty() {
+		return c.writeTrailer(resp)
+	}
+	return c.st.sc.writeData(c.st, nil, true)
+}
+
+func (c *streamConn) writeTrailer(resp *protocol.Response) error {
+	trailer := resp.Header.Trailer()
+	if trailer.Empty() {
+		return nil
+	}
+	var fields []hpack.HeaderField
+	trailer.VisitAll(func(k, v []byte) {
+		fields = append(fields, hpack.HeaderField{Name: strings.ToLower(string(k)), Value: string(v)})
+	})
+	return c.st.sc.fw.writeHeaders(fixedID(c.st.id), fields, true)
+}
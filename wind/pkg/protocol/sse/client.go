@@ -0,0 +1,164 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 默认重连间隔。
+const defaultReconnectDelay = 3 * time.Second
+
+// ErrNoContent 表示服务端以 204 响应要求客户端停止重连。
+var ErrNoContent = errors.New("sse：服务端要求停止重连")
+
+// Doer 执行 HTTP 请求，*client.Client 即实现了该接口。
+//
+// 为使事件实时送达，客户端应启用响应的正文流（client.WithResponseBodyStream）。
+type Doer interface {
+	Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error
+}
+
+// EventHandler 处理收到的事件，返回错误则停止订阅。
+type EventHandler func(e *Event) error
+
+// Client 订阅事件流，断线后携带 Last-Event-ID 自动重连。
+type Client struct {
+	// 执行请求的 HTTP 客户端。
+	Doer Doer
+
+	// 最近收到的事件标识，可预先设置以从指定事件之后续订。
+	LastEventID string
+
+	// 重连间隔，服务端通过 retry 字段指定时以其为准。默认 3 秒。
+	ReconnectDelay time.Duration
+
+	// 连续重连失败的最大次数，超出后返回最后一次的错误。为零时不限次数。
+	MaxRetries int
+
+	// 连接建立（收到 200 响应）时的回调，可选。
+	OnConnect func()
+}
+
+// NewClient 创建基于 d 的事件流客户端。
+func NewClient(d Doer) *Client {
+	return &Client{Doer: d}
+}
+
+// Subscribe 以 req 为模板订阅事件流，逐条交由 handler 处理。
+//
+// 连接断开或读取出错时，等待重连间隔后重新请求，并在请求中携带 Last-Event-ID。
+// 以下情况结束订阅：ctx 结束、handler 返回错误、服务端响应 204（返回 ErrNoContent）、
+// 响应状态码不是 200 或内容类型不是事件流、连续重连失败超过 MaxRetries。
+func (c *Client) Subscribe(ctx context.Context, req *protocol.Request, handler EventHandler) error {
+	delay := c.ReconnectDelay
+	if delay <= 0 {
+		delay = defaultReconnectDelay
+	}
+
+	failures := 0
+	for {
+		connected, retry, err := c.connect(ctx, req, handler)
+		if retry > 0 {
+			delay = retry
+		}
+		var fatal *fatalError
+		if errors.As(err, &fatal) {
+			return fatal.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if connected {
+			failures = 0
+		} else {
+			failures++
+			if c.MaxRetries > 0 && failures > c.MaxRetries {
+				return err
+			}
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// 不应重连的错误。
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+
+// 建立一次连接并读取事件，直至连接断开。
+func (c *Client) connect(ctx context.Context, tmpl *protocol.Request, handler EventHandler) (connected bool, retry time.Duration, err error) {
+	req := protocol.AcquireRequest()
+	resp := protocol.AcquireResponse()
+	defer func() {
+		_ = resp.CloseBodyStream()
+		protocol.ReleaseResponse(resp)
+		protocol.ReleaseRequest(req)
+	}()
+
+	tmpl.CopyTo(req)
+	if len(req.Header.Method()) == 0 {
+		req.Header.SetMethod(consts.MethodGet)
+	}
+	req.Header.Set(consts.HeaderAccept, "text/event-stream")
+	req.Header.Set(consts.HeaderCacheControl, "no-cache")
+	if c.LastEventID != "" {
+		req.Header.Set(HeaderLastEventID, c.LastEventID)
+	}
+
+	if err = c.Doer.Do(ctx, req, resp); err != nil {
+		return false, 0, err
+	}
+
+	switch status := resp.StatusCode(); {
+	case status == consts.StatusNoContent:
+		return false, 0, &fatalError{ErrNoContent}
+	case status != consts.StatusOK:
+		return false, 0, &fatalError{fmt.Errorf("sse：意外的响应状态码 %d", status)}
+	}
+	if ct := string(resp.Header.ContentType()); !strings.HasPrefix(ct, "text/event-stream") {
+		return false, 0, &fatalError{fmt.Errorf("sse：意外的内容类型 %q", ct)}
+	}
+	if c.OnConnect != nil {
+		c.OnConnect()
+	}
+
+	body := resp.BodyStream()
+	if body == nil {
+		body = bytes.NewReader(resp.Body())
+	}
+	r := NewReader(body)
+	// 以已知的标识为初值，连接在收到 id 字段前断开时不会将其清空
+	r.lastEventID = c.LastEventID
+	for {
+		e, err := r.Next()
+		// 未派发的事件也可能更新了标识和重连间隔
+		c.LastEventID = r.LastEventID()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return true, r.Retry(), err
+		}
+		if err = handler(e); err != nil {
+			return true, r.Retry(), &fatalError{err}
+		}
+	}
+}
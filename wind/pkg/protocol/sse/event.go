@@ -0,0 +1,96 @@
+package sse
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app/server/render"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+// ContentType 为事件流的内容类型。
+const ContentType = "text/event-stream; charset=utf-8"
+
+var errInvalidField = errors.New("sse：id 和 event 不能包含换行符或空字符")
+
+var _ render.Render = (*Event)(nil)
+
+// Event 表示一条服务器推送事件，详见 HTML 标准第 9.2 节。
+type Event struct {
+	// 事件标识，客户端重连时通过 Last-Event-ID 标头回传。
+	ID string
+
+	// 事件类型，为空时客户端按 message 事件处理。
+	Event string
+
+	// 事件数据，多行数据按行拆分为多个 data 字段。
+	Data string
+
+	// 客户端的重连间隔，按毫秒发送，为零时不发送。
+	Retry time.Duration
+}
+
+// AppendTo 将事件按事件流格式编码后追加到 dst。
+func (e *Event) AppendTo(dst []byte) ([]byte, error) {
+	if strings.ContainsAny(e.ID, "\r\n\x00") || strings.ContainsAny(e.Event, "\r\n\x00") {
+		return dst, errInvalidField
+	}
+	if e.ID != "" {
+		dst = appendField(dst, "id", e.ID)
+	}
+	if e.Event != "" {
+		dst = appendField(dst, "event", e.Event)
+	}
+	if e.Retry > 0 {
+		dst = appendField(dst, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+	if e.Data != "" {
+		dst = appendMultiline(dst, "data", e.Data)
+	}
+	return append(dst, '\n'), nil
+}
+
+// Render 将事件写入响应正文，适用于一次性返回若干事件的场景。
+func (e *Event) Render(resp *protocol.Response) error {
+	e.WriteContentType(resp)
+	b, err := e.AppendTo(nil)
+	if err != nil {
+		return err
+	}
+	resp.AppendBody(b)
+	return nil
+}
+
+// WriteContentType 写入事件流的内容类型。
+func (e *Event) WriteContentType(resp *protocol.Response) {
+	resp.Header.SetContentType(ContentType)
+}
+
+func appendField(dst []byte, name, value string) []byte {
+	dst = append(dst, name...)
+	dst = append(dst, ": "...)
+	dst = append(dst, value...)
+	return append(dst, '\n')
+}
+
+// 按 CRLF、LF 或 CR 拆行，每行各写一个同名字段。
+func appendMultiline(dst []byte, name, value string) []byte {
+	for {
+		i := strings.IndexAny(value, "\r\n")
+		if i < 0 {
+			return appendField(dst, name, value)
+		}
+		dst = appendField(dst, name, value[:i])
+		if value[i] == '\r' && i+1 < len(value) && value[i+1] == '\n' {
+			i++
+		}
+		value = value[i+1:]
+	}
+}
+
+// 注释行以冒号开头，客户端会忽略，常用于保活。
+func appendComment(dst []byte, text string) []byte {
+	return appendMultiline(dst, "", text)
+}
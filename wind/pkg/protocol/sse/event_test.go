@@ -0,0 +1,45 @@
+package sse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+)
+
+func TestEventAppendTo(t *testing.T) {
+	cases := []struct {
+		event Event
+		want  string
+	}{
+		{Event{Data: "hello"}, "data: hello\n\n"},
+		{Event{ID: "1", Event: "update", Data: "hello", Retry: 1500 * time.Millisecond}, "id: 1\nevent: update\nretry: 1500\ndata: hello\n\n"},
+		{Event{Data: "a\nb\r\nc\rd"}, "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+		{Event{Data: "a\n"}, "data: a\ndata: \n\n"},
+		{Event{ID: "2"}, "id: 2\n\n"},
+	}
+	for _, c := range cases {
+		b, err := c.event.AppendTo(nil)
+		assert.Nil(t, err)
+		assert.DeepEqual(t, c.want, string(b))
+	}
+
+	for _, e := range []Event{{ID: "1\n2"}, {Event: "a\rb"}, {ID: "\x00"}} {
+		_, err := e.AppendTo(nil)
+		assert.DeepEqual(t, errInvalidField, err)
+	}
+}
+
+func TestEventRender(t *testing.T) {
+	var resp protocol.Response
+	assert.Nil(t, (&Event{Event: "ping", Data: "1"}).Render(&resp))
+	assert.Nil(t, (&Event{Data: "2"}).Render(&resp))
+	assert.DeepEqual(t, ContentType, string(resp.Header.ContentType()))
+	assert.DeepEqual(t, "event: ping\ndata: 1\n\ndata: 2\n\n", string(resp.Body()))
+}
+
+func TestAppendComment(t *testing.T) {
+	assert.DeepEqual(t, ": ping\n", string(appendComment(nil, "ping")))
+	assert.DeepEqual(t, ": a\n: b\n", string(appendComment(nil, "a\nb")))
+}
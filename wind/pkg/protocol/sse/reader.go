@@ -0,0 +1,151 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单条事件（含所有字段）的默认最大字节数。
+const defaultMaxEventSize = 1 << 20
+
+// ErrEventTooLarge 表示单条事件超出读取上限。
+var ErrEventTooLarge = errors.New("sse：事件超出读取上限")
+
+// Reader 从事件流中逐条解析事件。
+//
+// 解析规则遵循 HTML 标准第 9.2.6 节：忽略注释行，
+// 没有数据的事件不会返回，但其中的 id 和 retry 字段仍然生效。
+type Reader struct {
+	r *bufio.Reader
+
+	// 单条事件的最大字节数，默认 1MB。
+	MaxEventSize int
+
+	lastEventID string
+	retry       time.Duration
+	started     bool
+	skipLF      bool
+
+	data      bytes.Buffer
+	eventType string
+	size      int
+}
+
+// NewReader 创建从 r 读取事件流的 Reader。
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), MaxEventSize: defaultMaxEventSize}
+}
+
+// LastEventID 返回最近一次收到的事件标识。
+func (r *Reader) LastEventID() string {
+	return r.lastEventID
+}
+
+// Retry 返回服务端最近一次指定的重连间隔，未指定时为零。
+func (r *Reader) Retry() time.Duration {
+	return r.retry
+}
+
+// Next 返回下一条事件。事件流结束时返回 io.EOF，末尾不完整的事件会被丢弃。
+func (r *Reader) Next() (*Event, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			if e := r.dispatch(); e != nil {
+				return e, nil
+			}
+			continue
+		}
+		r.processField(line)
+	}
+}
+
+// 读取一行，行尾可以是 CRLF、LF 或 CR。
+func (r *Reader) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			// 没有行尾的末行不构成完整事件
+			return "", err
+		}
+		// 上一行以 CR 结尾时，紧随的 LF 属于同一行尾；不预读，以免阻塞在实时流上
+		if r.skipLF {
+			r.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+		if b == '\n' {
+			break
+		}
+		if b == '\r' {
+			r.skipLF = true
+			break
+		}
+		line = append(line, b)
+		if r.size+len(line) > r.MaxEventSize {
+			return "", ErrEventTooLarge
+		}
+	}
+	r.size += len(line)
+
+	s := string(line)
+	if !r.started {
+		r.started = true
+		s = strings.TrimPrefix(s, "\ufeff")
+	}
+	return s, nil
+}
+
+func (r *Reader) processField(line string) {
+	if line[0] == ':' {
+		return
+	}
+	name, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+
+	switch name {
+	case "event":
+		r.eventType = value
+	case "data":
+		r.data.WriteString(value)
+		r.data.WriteByte('\n')
+	case "id":
+		if !strings.ContainsRune(value, 0) {
+			r.lastEventID = value
+		}
+	case "retry":
+		if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+			r.retry = time.Duration(ms) * time.Millisecond
+		}
+	}
+}
+
+// 以空行结束一条事件，没有数据时返回 nil。
+func (r *Reader) dispatch() *Event {
+	defer func() {
+		r.data.Reset()
+		r.eventType = ""
+		r.size = 0
+	}()
+	if r.data.Len() == 0 {
+		return nil
+	}
+	data := r.data.Bytes()
+	return &Event{
+		ID:    r.lastEventID,
+		Event: r.eventType,
+		Data:  string(data[:len(data)-1]),
+		Retry: r.retry,
+	}
+}
@@ -0,0 +1,76 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+)
+
+func readAll(t *testing.T, r *Reader) []Event {
+	var events []Event
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return events
+		}
+		assert.Nil(t, err)
+		events = append(events, *e)
+	}
+}
+
+func TestReader(t *testing.T) {
+	stream := "\ufeff: 注释\n" +
+		"data: first\n\n" +
+		"id: 1\nevent: update\ndata:second\ndata:  line\n\n" +
+		"retry: 2000\n\n" +
+		"data\n\n" +
+		"unknown: field\nid: 2\n\n" +
+		"data: last\n\n" +
+		"data: incomplete"
+	r := NewReader(strings.NewReader(stream))
+	events := readAll(t, r)
+	assert.DeepEqual(t, []Event{
+		{Data: "first"},
+		{ID: "1", Event: "update", Data: "second\n line"},
+		{ID: "1", Data: "", Retry: 2 * time.Second},
+		{ID: "2", Data: "last", Retry: 2 * time.Second},
+	}, events)
+	assert.DeepEqual(t, "2", r.LastEventID())
+	assert.DeepEqual(t, 2*time.Second, r.Retry())
+}
+
+func TestReaderLineEndings(t *testing.T) {
+	for _, stream := range []string{
+		"data: a\r\ndata: b\r\n\r\n",
+		"data: a\rdata: b\r\r",
+		"data: a\ndata: b\n\n",
+		"data: a\r\ndata: b\n\r",
+	} {
+		events := readAll(t, NewReader(strings.NewReader(stream)))
+		assert.DeepEqual(t, 1, len(events))
+		assert.DeepEqual(t, "a\nb", events[0].Data)
+	}
+}
+
+func TestReaderIgnoresInvalidFields(t *testing.T) {
+	r := NewReader(strings.NewReader("id: 1\n\nid: a\x00b\nretry: x\ndata: d\n\n"))
+	events := readAll(t, r)
+	assert.DeepEqual(t, 1, len(events))
+	assert.DeepEqual(t, "1", events[0].ID)
+	assert.DeepEqual(t, time.Duration(0), r.Retry())
+}
+
+func TestReaderMaxEventSize(t *testing.T) {
+	r := NewReader(strings.NewReader("data: " + strings.Repeat("a", 64) + "\n\n"))
+	r.MaxEventSize = 32
+	_, err := r.Next()
+	assert.DeepEqual(t, ErrEventTooLarge, err)
+
+	// 上限针对单条事件，而非整个事件流
+	r = NewReader(strings.NewReader(strings.Repeat("data: 0123456789\n\n", 10)))
+	r.MaxEventSize = 32
+	assert.DeepEqual(t, 10, len(readAll(t, r)))
+}
@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/protocol/http1/resp"
+)
+
+// HeaderLastEventID 为客户端重连时携带的最后事件标识标头。
+const HeaderLastEventID = "Last-Event-ID"
+
+// ErrStreamClosed 表示客户端已断开或事件流已出错，后续事件不会再发出。
+var ErrStreamClosed = errors.New("sse：事件流已关闭")
+
+// Stream 将事件逐条推送给客户端。
+//
+// HTTP/1.1 下使用分块传输编码写入响应，HTTP/2 下直接写入流的 DATA 帧。
+// 每条事件写入后立即冲刷，写入失败即视为客户端已断开。
+//
+// 并发协程的调用是安全的。
+type Stream struct {
+	ctx *app.RequestContext
+	w   flushWriter
+
+	mu  sync.Mutex
+	buf []byte
+	err error
+}
+
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// 经 RequestContext.Flush 冲刷的分块写入器。
+type chunkedWriter struct {
+	ctx *app.RequestContext
+}
+
+func (w chunkedWriter) Write(p []byte) (int, error) {
+	return w.ctx.Response.GetHijackWriter().Write(p)
+}
+
+func (w chunkedWriter) Flush() error {
+	return w.ctx.Flush()
+}
+
+// NewStream 设置事件流的响应标头，并返回推送事件的 Stream。
+//
+// 响应标头随首条事件一并发出，调用后不应再修改响应。
+func NewStream(ctx *app.RequestContext) *Stream {
+	ctx.SetStatusCode(consts.StatusOK)
+	ctx.Response.Header.SetContentType(ContentType)
+	ctx.Response.Header.Set(consts.HeaderCacheControl, "no-cache")
+	// 禁止 Nginx 等反向代理缓冲事件
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+
+	s := &Stream{ctx: ctx}
+	if ctx.Request.Header.GetProtocol() == consts.HTTP20 {
+		// HTTP/2 的连接写入器会将冲刷的数据作为 DATA 帧发出
+		s.w = ctx.GetConn()
+	} else {
+		ctx.Response.HijackWriter(resp.NewChunkedBodyWriter(&ctx.Response, ctx.GetWriter()))
+		s.w = chunkedWriter{ctx: ctx}
+	}
+	return s
+}
+
+// LastEventID 返回客户端重连时携带的最后事件标识，首次连接时为空。
+func LastEventID(ctx *app.RequestContext) string {
+	return string(ctx.Request.Header.Peek(HeaderLastEventID))
+}
+
+// Publish 推送一条事件并立即冲刷。
+//
+// 客户端断开后返回 ErrStreamClosed 包装的写入错误，处理器应据此结束推送。
+func (s *Stream) Publish(e *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	var err error
+	if s.buf, err = e.AppendTo(s.buf[:0]); err != nil {
+		return err
+	}
+	return s.flushLocked()
+}
+
+// Comment 推送一行注释，客户端会忽略注释，可用于保活及探测客户端是否断开。
+func (s *Stream) Comment(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = appendComment(s.buf[:0], text)
+	return s.flushLocked()
+}
+
+func (s *Stream) flushLocked() error {
+	_, err := s.w.Write(s.buf)
+	if err == nil {
+		err = s.w.Flush()
+	}
+	if err != nil {
+		s.err = fmt.Errorf("%w：%v", ErrStreamClosed, err)
+	}
+	return s.err
+}
+
+// Err 返回导致事件流关闭的错误，事件流正常时返回 nil。
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,161 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/client"
+	"github.com/favbox/gosky/wind/pkg/app/server"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+func TestStream(t *testing.T) {
+	const addr = "127.0.0.1:18766"
+	h := server.New(server.WithHostPorts(addr))
+	closed := make(chan error, 1)
+	h.GET("/events", func(c context.Context, ctx *app.RequestContext) {
+		last, _ := strconv.Atoi(LastEventID(ctx))
+		if last >= 3 {
+			ctx.SetStatusCode(consts.StatusNoContent)
+			return
+		}
+		s := NewStream(ctx)
+		assert.Nil(t, s.Comment("hello"))
+		// 每次连接推送两条事件后断开，由客户端续订
+		for i := last + 1; i <= last+2 && i <= 3; i++ {
+			assert.Nil(t, s.Publish(&Event{ID: strconv.Itoa(i), Data: "event\n" + strconv.Itoa(i), Retry: 10 * time.Millisecond}))
+		}
+	})
+	h.GET("/heartbeat", func(c context.Context, ctx *app.RequestContext) {
+		s := NewStream(ctx)
+		for {
+			if err := s.Comment("ping"); err != nil {
+				closed <- s.Err()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	go h.Run()
+	defer h.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	t.Run("subscribe", func(t *testing.T) {
+		cli, err := client.NewClient(client.WithResponseBodyStream(true))
+		assert.Nil(t, err)
+		req := protocol.AcquireRequest()
+		defer protocol.ReleaseRequest(req)
+		req.SetRequestURI("http://" + addr + "/events")
+
+		connects := 0
+		c := NewClient(cli)
+		c.OnConnect = func() { connects++ }
+		var got []string
+		err = c.Subscribe(context.Background(), req, func(e *Event) error {
+			got = append(got, e.ID+":"+e.Data)
+			return nil
+		})
+		assert.True(t, errors.Is(err, ErrNoContent))
+		assert.DeepEqual(t, []string{"1:event\n1", "2:event\n2", "3:event\n3"}, got)
+		assert.DeepEqual(t, 2, connects)
+		assert.DeepEqual(t, "3", c.LastEventID)
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		cli, _ := client.NewClient(client.WithResponseBodyStream(true))
+		req := protocol.AcquireRequest()
+		defer protocol.ReleaseRequest(req)
+		req.SetRequestURI("http://" + addr + "/events")
+
+		stop := errors.New("stop")
+		err := NewClient(cli).Subscribe(context.Background(), req, func(e *Event) error { return stop })
+		assert.DeepEqual(t, stop, err)
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		nc, err := net.Dial("tcp", addr)
+		assert.Nil(t, err)
+		nc.Write([]byte("GET /heartbeat HTTP/1.1\r\nHost: " + addr + "\r\n\r\n"))
+		resp, err := http.ReadResponse(bufio.NewReader(nc), nil)
+		assert.Nil(t, err)
+		assert.DeepEqual(t, ContentType, resp.Header.Get(consts.HeaderContentType))
+		assert.DeepEqual(t, "chunked", resp.TransferEncoding[0])
+		assert.DeepEqual(t, "no-cache", resp.Header.Get(consts.HeaderCacheControl))
+		nc.Close()
+
+		select {
+		case err := <-closed:
+			assert.True(t, errors.Is(err, ErrStreamClosed))
+		case <-time.After(3 * time.Second):
+			t.Fatal("未能探测到客户端断开")
+		}
+	})
+}
+
+func TestClientRejectsResponse(t *testing.T) {
+	d := doerFunc(func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+		assert.DeepEqual(t, "text/event-stream", string(req.Header.Peek(consts.HeaderAccept)))
+		assert.DeepEqual(t, "7", string(req.Header.Peek(HeaderLastEventID)))
+		resp.Header.SetContentType("text/plain")
+		return nil
+	})
+	c := NewClient(d)
+	c.LastEventID = "7"
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+	assert.NotNil(t, c.Subscribe(context.Background(), req, func(e *Event) error { return nil }))
+
+	// 连接失败超过重试次数
+	calls := 0
+	d = func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+		calls++
+		return errors.New("dial")
+	}
+	c = &Client{Doer: d, ReconnectDelay: time.Millisecond, MaxRetries: 2}
+	assert.NotNil(t, c.Subscribe(context.Background(), req, func(e *Event) error { return nil }))
+	assert.DeepEqual(t, 3, calls)
+}
+
+func TestClientReconnectKeepsLastEventID(t *testing.T) {
+	// 首次连接未收到任何事件即断开，重连时仍应携带预设的标识
+	bodies := []string{": ping\n", "id: 8\ndata: a\n\n: ping\n"}
+	var ids []string
+	d := doerFunc(func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+		ids = append(ids, string(req.Header.Peek(HeaderLastEventID)))
+		if len(ids) > len(bodies) {
+			resp.SetStatusCode(consts.StatusNoContent)
+			return nil
+		}
+		resp.Header.SetContentType(ContentType)
+		resp.SetBodyString(bodies[len(ids)-1])
+		return nil
+	})
+	c := &Client{Doer: d, LastEventID: "7", ReconnectDelay: time.Millisecond}
+	req := protocol.AcquireRequest()
+	defer protocol.ReleaseRequest(req)
+
+	var got []string
+	err := c.Subscribe(context.Background(), req, func(e *Event) error {
+		got = append(got, e.ID)
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrNoContent))
+	assert.DeepEqual(t, []string{"8"}, got)
+	assert.DeepEqual(t, []string{"7", "7", "8"}, ids)
+	assert.DeepEqual(t, "8", c.LastEventID)
+}
+
+type doerFunc func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error
+
+func (f doerFunc) Do(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+	return f(ctx, req, resp)
+}
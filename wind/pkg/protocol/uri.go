@@ -113,7 +113,7 @@ func (u *URI) parse(host, uri []byte, isTLS bool) {
 	}
 
 	if isTLS {
-		u.scheme = append(u.scheme[:0], bytestr.StrHTTP...)
+		u.scheme = append(u.scheme[:0], bytestr.StrHTTPS...)
 	}
 
 	if n := bytes.Index(host, bytestr.StrAt); n >= 0 {
@@ -176,6 +176,16 @@ func testParseHostWithStr(t *testing.T, host, expectUsername, expectPassword str
 	assert.Equal(t, expectPassword, string(u.Password()))
 }
 
+func TestParseTLS(t *testing.T) {
+	var u URI
+	u.parse([]byte("example.com"), []byte("/foo"), true)
+	assert.Equal(t, "https", string(u.Scheme()))
+	assert.Equal(t, "https://example.com/foo", string(u.FullURI()))
+
+	u.parse([]byte("example.com"), []byte("/foo"), false)
+	assert.Equal(t, "http", string(u.Scheme()))
+}
+
 func TestURIPathEscape(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+	"sync"
+)
+
+// permessage-deflate 扩展，详见 RFC 7692。
+//
+// 双方均不保留压缩上下文，每条消息独立压缩，从而无需在连接上常驻 flate 状态。
+
+const (
+	compressionExtension = "permessage-deflate"
+	compressionResponse  = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+	// 压缩块以同步标记结尾，发送时去除，接收时补回
+	deflateTail = "\x00\x00\xff\xff"
+	// 补回同步标记后再追加一个空的最终块，使解压器以 io.EOF 结束
+	deflateFinalTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+)
+
+var (
+	flateWriterPools [maxCompressionLevel - minCompressionLevel + 1]sync.Pool
+	flateReaderPool  = sync.Pool{New: func() any {
+		return flate.NewReader(nil)
+	}}
+)
+
+// 压缩单条消息的负载。
+func compressData(p []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	pool := &flateWriterPools[level-minCompressionLevel]
+	fw, _ := pool.Get().(*flate.Writer)
+	if fw == nil {
+		fw, _ = flate.NewWriter(&buf, level)
+	} else {
+		fw.Reset(&buf)
+	}
+	defer pool.Put(fw)
+
+	if _, err := fw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte(deflateTail)), nil
+}
+
+// 解压单条消息的读取器，读完后归还 flate 解压器。
+type decompressReader struct {
+	fr io.ReadCloser
+}
+
+func newDecompressReader(r io.Reader) io.Reader {
+	fr := flateReaderPool.Get().(io.ReadCloser)
+	_ = fr.(flate.Resetter).Reset(io.MultiReader(r, strings.NewReader(deflateFinalTail)), nil)
+	return &decompressReader{fr: fr}
+}
+
+func (r *decompressReader) Read(p []byte) (int, error) {
+	if r.fr == nil {
+		return 0, io.EOF
+	}
+	n, err := r.fr.Read(p)
+	if err != nil {
+		if err == io.EOF {
+			_ = r.fr.Close()
+			flateReaderPool.Put(r.fr)
+		}
+		r.fr = nil
+	}
+	return n, err
+}
+
+// 从客户端的扩展提议中选出可接受的 permessage-deflate 提议。
+//
+// Go 的 flate 实现固定使用 32KB 窗口，故拒绝要求更小服务端窗口的提议。
+func acceptCompression(offers [][]byte) bool {
+	for _, header := range offers {
+		for _, offer := range strings.Split(string(header), ",") {
+			params := strings.Split(offer, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), compressionExtension) {
+				continue
+			}
+			ok := true
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					ok = ok && value == "15"
+				default:
+					ok = false
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,847 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/favbox/gosky/wind/pkg/network"
+)
+
+// 消息类型，取值与 RFC 6455 的操作码一致。
+const (
+	// TextMessage 表示 UTF-8 编码的文本消息。
+	TextMessage = 1
+
+	// BinaryMessage 表示二进制消息。
+	BinaryMessage = 2
+
+	// CloseMessage 表示关闭控制消息，可用 FormatCloseMessage 构造其负载。
+	CloseMessage = 8
+
+	// PingMessage 表示 ping 控制消息。
+	PingMessage = 9
+
+	// PongMessage 表示 pong 控制消息。
+	PongMessage = 10
+)
+
+// 关闭码，详见 RFC 6455 第 11.7 节。
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseServiceRestart          = 1012
+	CloseTryAgainLater           = 1013
+	CloseTLSHandshake            = 1015
+)
+
+const (
+	finalBit = 1 << 7
+	rsv1Bit  = 1 << 6
+	rsv2Bit  = 1 << 5
+	rsv3Bit  = 1 << 4
+	maskBit  = 1 << 7
+
+	continuationFrame = 0
+	noFrame           = -1
+
+	maxFrameHeaderSize   = 2 + 8 + 4
+	maxControlPayload    = 125
+	defaultReadBufSize   = 4096
+	defaultWriteBufSize  = 4096
+	defaultControlWait   = time.Second
+	minCompressionLevel  = -2 // flate.HuffmanOnly
+	maxCompressionLevel  = 9  // flate.BestCompression
+	defaultCompressLevel = 1  // flate.BestSpeed
+)
+
+var (
+	// ErrCloseSent 表示已发送关闭帧，不可再发送数据消息。
+	ErrCloseSent = errors.New("websocket：已发送关闭帧")
+
+	// ErrReadLimit 表示消息超出读取上限。
+	ErrReadLimit = errors.New("websocket：消息超出读取上限")
+
+	errWriteClosed        = errors.New("websocket：写入已关闭的消息写入器")
+	errInvalidControl     = errors.New("websocket：控制帧的负载超过 125 字节")
+	errInvalidMessageType = errors.New("websocket：无效的消息类型")
+	errBadCompression     = errors.New("websocket：无效的压缩级别")
+	errUnexpectedEOF      = &CloseError{Code: CloseAbnormalClosure, Text: io.ErrUnexpectedEOF.Error()}
+)
+
+// CloseError 表示对端发来的关闭帧，或连接的异常关闭。
+type CloseError struct {
+	// Code 为关闭码。
+	Code int
+
+	// Text 为关闭原因，可能为空。
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	s := "websocket：连接已关闭，关闭码 " + strconv.Itoa(e.Code)
+	if e.Text != "" {
+		s += "：" + e.Text
+	}
+	return s
+}
+
+// IsCloseError 报告 err 是否为关闭码在 codes 之中的 *CloseError。
+func IsCloseError(err error, codes ...int) bool {
+	var e *CloseError
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, code := range codes {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnexpectedCloseError 报告 err 是否为关闭码不在 expectedCodes 之中的 *CloseError。
+func IsUnexpectedCloseError(err error, expectedCodes ...int) bool {
+	var e *CloseError
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, code := range expectedCodes {
+		if e.Code == code {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatCloseMessage 构造关闭帧的负载。
+//
+// 关闭码为 CloseNoStatusReceived 时返回空负载。
+func FormatCloseMessage(closeCode int, text string) []byte {
+	if closeCode == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(closeCode))
+	copy(buf[2:], text)
+	return buf
+}
+
+// 是否为允许出现在关闭帧中的关闭码。
+func isValidReceivedCloseCode(code int) bool {
+	switch code {
+	case CloseNoStatusReceived, CloseAbnormalClosure, CloseTLSHandshake:
+		return false
+	}
+	return (code >= CloseNormalClosure && code <= CloseTryAgainLater) || (code >= 3000 && code <= 4999)
+}
+
+func isControl(frameType int) bool {
+	return frameType == CloseMessage || frameType == PingMessage || frameType == PongMessage
+}
+
+func isData(frameType int) bool {
+	return frameType == TextMessage || frameType == BinaryMessage
+}
+
+// Conn 表示一个 WebSocket 连接。
+//
+// 同一时刻最多只能有一个协程读取。写入方法（NextWriter、WriteMessage、WriteControl 等）
+// 可被多个协程并发调用：数据消息整体串行发送，控制帧则可穿插在分片之间。
+type Conn struct {
+	conn        network.Conn
+	isServer    bool
+	subprotocol string
+
+	// 写状态
+	msgMu         sync.Mutex // 保护整条数据消息，自 NextWriter 起至 Close 止
+	frameMu       sync.Mutex // 保护单帧写入及以下字段
+	writeBufSize  int
+	writeDeadline time.Time
+	writeErr      error
+	closeSent     bool
+	frameHeader   [maxFrameHeaderSize]byte
+	maskBuf       []byte
+
+	compressionNegotiated bool
+	enableWriteCompress   bool
+	compressionLevel      int
+
+	// 读状态，仅由读取协程访问
+	readBufSize   int
+	readLimit     int64
+	readErr       error
+	readRemaining int64
+	readFinal     bool
+	readLength    int64
+	readMasked    bool
+	readMaskKey   [4]byte
+	readMaskPos   int
+	readCompress  bool
+	readHeader    [8]byte
+	controlBuf    [maxControlPayload]byte
+	messageReader *messageReader
+
+	handlePing  func(appData string) error
+	handlePong  func(appData string) error
+	handleClose func(code int, text string) error
+}
+
+func newConn(conn network.Conn, isServer bool, readBufSize, writeBufSize int) *Conn {
+	if readBufSize <= 0 {
+		readBufSize = defaultReadBufSize
+	}
+	if writeBufSize <= 0 {
+		writeBufSize = defaultWriteBufSize
+	}
+	c := &Conn{
+		conn:             conn,
+		isServer:         isServer,
+		readBufSize:      readBufSize,
+		writeBufSize:     writeBufSize,
+		readFinal:        true,
+		compressionLevel: defaultCompressLevel,
+	}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	c.SetCloseHandler(nil)
+	return c
+}
+
+// Subprotocol 返回协商出的子协议。
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn 返回底层连接。
+func (c *Conn) NetConn() network.Conn {
+	return c.conn
+}
+
+// LocalAddr 返回本端网络地址。
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr 返回对端网络地址。
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close 直接关闭底层连接，不发送关闭帧。
+//
+// 如需优雅关闭，应先用 WriteControl 发送 CloseMessage。
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// SetReadDeadline 设置底层连接的读取截止时间，超时后连接状态已损坏，后续读取均返回错误。
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline 设置底层连接的写入截止时间，超时后连接状态已损坏，后续写入均返回错误。
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.frameMu.Lock()
+	defer c.frameMu.Unlock()
+	c.writeDeadline = t
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit 设置单条消息的最大字节数，启用压缩时按解压后的大小计算。
+//
+// 超出上限时向对端发送 CloseMessageTooBig 关闭帧，并返回 ErrReadLimit。
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// EnableWriteCompression 启用或禁用后续消息的压缩，仅在握手时协商了压缩扩展时生效。
+func (c *Conn) EnableWriteCompression(enable bool) {
+	c.frameMu.Lock()
+	c.enableWriteCompress = enable
+	c.frameMu.Unlock()
+}
+
+// SetCompressionLevel 设置后续消息的压缩级别，取值同 compress/flate。
+func (c *Conn) SetCompressionLevel(level int) error {
+	if level < minCompressionLevel || level > maxCompressionLevel {
+		return errBadCompression
+	}
+	c.frameMu.Lock()
+	c.compressionLevel = level
+	c.frameMu.Unlock()
+	return nil
+}
+
+// PingHandler 返回 ping 消息的处理器。
+func (c *Conn) PingHandler() func(appData string) error {
+	return c.handlePing
+}
+
+// SetPingHandler 设置 ping 消息的处理器，h 为 nil 时使用默认处理器。
+//
+// 默认处理器以相同负载回复 pong 消息。处理器在读取方法（NextReader、ReadMessage）中被调用。
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(message string) error {
+			err := c.WriteControl(PongMessage, []byte(message), time.Now().Add(defaultControlWait))
+			if err == ErrCloseSent {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return nil
+			}
+			return err
+		}
+	}
+	c.handlePing = h
+}
+
+// PongHandler 返回 pong 消息的处理器。
+func (c *Conn) PongHandler() func(appData string) error {
+	return c.handlePong
+}
+
+// SetPongHandler 设置 pong 消息的处理器，h 为 nil 时忽略 pong 消息。
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.handlePong = h
+}
+
+// CloseHandler 返回关闭消息的处理器。
+func (c *Conn) CloseHandler() func(code int, text string) error {
+	return c.handleClose
+}
+
+// SetCloseHandler 设置关闭消息的处理器，h 为 nil 时使用默认处理器。
+//
+// 默认处理器以相同的关闭码回复关闭帧。处理器返回后，读取方法返回 *CloseError。
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			message := FormatCloseMessage(code, "")
+			_ = c.WriteControl(CloseMessage, message, time.Now().Add(defaultControlWait))
+			return nil
+		}
+	}
+	c.handleClose = h
+}
+
+// 写入方法
+
+// 写入一帧，调用方须持有 frameMu。
+func (c *Conn) writeFrameLocked(frameType int, final, compressed bool, payload []byte) error {
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+
+	b0 := byte(frameType)
+	if final {
+		b0 |= finalBit
+	}
+	if compressed {
+		b0 |= rsv1Bit
+	}
+	var b1 byte
+	if !c.isServer {
+		b1 |= maskBit
+	}
+
+	hdr := c.frameHeader[:2]
+	hdr[0] = b0
+	length := len(payload)
+	switch {
+	case length >= 65536:
+		hdr[1] = b1 | 127
+		hdr = hdr[:10]
+		binary.BigEndian.PutUint64(hdr[2:], uint64(length))
+	case length > 125:
+		hdr[1] = b1 | 126
+		hdr = hdr[:4]
+		binary.BigEndian.PutUint16(hdr[2:], uint16(length))
+	default:
+		hdr[1] = b1 | byte(length)
+	}
+
+	if !c.isServer {
+		// 客户端帧须掩码，且不能改动调用方的数据
+		var key [4]byte
+		_, _ = rand.Read(key[:])
+		hdr = append(hdr, key[:]...)
+		c.maskBuf = append(c.maskBuf[:0], payload...)
+		maskBytes(key, 0, c.maskBuf)
+		payload = c.maskBuf
+	}
+
+	_, err := c.conn.WriteBinary(hdr)
+	if err == nil && len(payload) > 0 {
+		_, err = c.conn.WriteBinary(payload)
+	}
+	if err == nil {
+		err = c.conn.Flush()
+	}
+	if err != nil {
+		c.writeErr = err
+		return err
+	}
+	if frameType == CloseMessage {
+		c.closeSent = true
+	}
+	return nil
+}
+
+// WriteControl 发送控制消息，deadline 为零值时不限时长。可与其他写入方法并发调用。
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if !isControl(messageType) {
+		return errInvalidMessageType
+	}
+	if len(data) > maxControlPayload {
+		return errInvalidControl
+	}
+
+	c.frameMu.Lock()
+	defer c.frameMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if !deadline.IsZero() {
+		_ = c.conn.SetWriteDeadline(deadline)
+		defer func() { _ = c.conn.SetWriteDeadline(c.writeDeadline) }()
+	}
+	return c.writeFrameLocked(messageType, true, false, data)
+}
+
+// NextWriter 返回下一条数据消息的写入器，消息在写入器关闭时发送完毕。
+//
+// 写入的数据超出写缓冲区大小时，以分片形式逐帧发送。写入器关闭之前，其他协程的数据消息会被阻塞。
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if !isData(messageType) {
+		return nil, errInvalidMessageType
+	}
+	c.msgMu.Lock()
+
+	c.frameMu.Lock()
+	err := c.writeErr
+	if err == nil && c.closeSent {
+		err = ErrCloseSent
+	}
+	compress := c.compressionNegotiated && c.enableWriteCompress
+	level := c.compressionLevel
+	c.frameMu.Unlock()
+	if err != nil {
+		c.msgMu.Unlock()
+		return nil, err
+	}
+
+	return &messageWriter{
+		c:         c,
+		frameType: messageType,
+		compress:  compress,
+		level:     level,
+	}, nil
+}
+
+// WriteMessage 发送一条消息，可用于数据消息和控制消息。
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if isControl(messageType) {
+		return c.WriteControl(messageType, data, time.Time{})
+	}
+	w, err := c.NextWriter(messageType)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// 数据消息写入器。
+type messageWriter struct {
+	c         *Conn
+	frameType int // 首帧为消息类型，后续分片为续帧
+	buf       []byte
+	compress  bool
+	level     int
+	err       error
+	closed    bool
+}
+
+// 发送缓冲区中的数据作为一帧。
+func (w *messageWriter) flushFrame(final bool, compressed bool) error {
+	c := w.c
+	c.frameMu.Lock()
+	if c.closeSent {
+		c.frameMu.Unlock()
+		return ErrCloseSent
+	}
+	err := c.writeFrameLocked(w.frameType, final, compressed, w.buf)
+	c.frameMu.Unlock()
+	w.frameType = continuationFrame
+	w.buf = w.buf[:0]
+	return err
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errWriteClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	// 压缩消息需整体压缩后再分片
+	if w.compress {
+		w.buf = append(w.buf, p...)
+		return len(p), nil
+	}
+
+	n := len(p)
+	bufSize := w.c.writeBufSize
+	for len(p) > 0 {
+		if len(w.buf) == bufSize {
+			if w.err = w.flushFrame(false, false); w.err != nil {
+				return 0, w.err
+			}
+		}
+		m := bufSize - len(w.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// Close 发送消息的最后一帧，并允许其他协程发送消息。
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return errWriteClosed
+	}
+	w.closed = true
+	defer w.c.msgMu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+
+	if !w.compress {
+		return w.flushFrame(true, false)
+	}
+
+	data, err := compressData(w.buf, w.level)
+	if err != nil {
+		return err
+	}
+	bufSize := w.c.writeBufSize
+	first := true
+	for first || len(data) > 0 {
+		n := len(data)
+		if n > bufSize {
+			n = bufSize
+		}
+		w.buf = data[:n]
+		data = data[n:]
+		if err = w.flushFrame(len(data) == 0, first); err != nil {
+			return err
+		}
+		first = false
+	}
+	return nil
+}
+
+// 读取方法
+
+// 以关闭帧告知对端协议错误，并返回对应错误。
+func (c *Conn) protocolError(closeCode int, message string) error {
+	_ = c.WriteControl(CloseMessage, FormatCloseMessage(closeCode, message), time.Now().Add(defaultControlWait))
+	return errors.New("websocket：" + message)
+}
+
+func (c *Conn) readFull(p []byte) error {
+	_, err := io.ReadFull(c.conn, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errUnexpectedEOF
+	}
+	return err
+}
+
+// 跳过当前帧的剩余负载。
+func (c *Conn) discardRemaining() error {
+	for c.readRemaining > 0 {
+		n := c.readRemaining
+		if n > int64(len(c.controlBuf)) {
+			n = int64(len(c.controlBuf))
+		}
+		if err := c.readFull(c.controlBuf[:n]); err != nil {
+			return err
+		}
+		c.readRemaining -= n
+	}
+	return nil
+}
+
+// 读取下一帧的帧头，控制帧在此处理。返回数据帧或续帧的类型，控制帧返回 noFrame。
+func (c *Conn) advanceFrame() (int, error) {
+	if err := c.discardRemaining(); err != nil {
+		return noFrame, err
+	}
+
+	hdr := c.readHeader[:2]
+	if err := c.readFull(hdr); err != nil {
+		return noFrame, err
+	}
+	final := hdr[0]&finalBit != 0
+	rsv1 := hdr[0]&rsv1Bit != 0
+	frameType := int(hdr[0] & 0xf)
+	masked := hdr[1]&maskBit != 0
+	length := int64(hdr[1] & 0x7f)
+
+	if hdr[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return noFrame, c.protocolError(CloseProtocolError, "保留位 RSV2/RSV3 不为零")
+	}
+	if rsv1 && (!c.compressionNegotiated || !isData(frameType)) {
+		return noFrame, c.protocolError(CloseProtocolError, "保留位 RSV1 不为零")
+	}
+
+	switch {
+	case isControl(frameType):
+		if length > maxControlPayload {
+			return noFrame, c.protocolError(CloseProtocolError, "控制帧的负载超过 125 字节")
+		}
+		if !final {
+			return noFrame, c.protocolError(CloseProtocolError, "控制帧不得分片")
+		}
+	case isData(frameType):
+		if !c.readFinal {
+			return noFrame, c.protocolError(CloseProtocolError, "上一条消息尚未结束")
+		}
+		c.readFinal = final
+		c.readCompress = rsv1
+		c.readLength = 0
+	case frameType == continuationFrame:
+		if c.readFinal {
+			return noFrame, c.protocolError(CloseProtocolError, "意外的续帧")
+		}
+		c.readFinal = final
+	default:
+		return noFrame, c.protocolError(CloseProtocolError, "未知的操作码 "+strconv.Itoa(frameType))
+	}
+
+	switch length {
+	case 126:
+		if err := c.readFull(c.readHeader[:2]); err != nil {
+			return noFrame, err
+		}
+		length = int64(binary.BigEndian.Uint16(c.readHeader[:2]))
+	case 127:
+		if err := c.readFull(c.readHeader[:8]); err != nil {
+			return noFrame, err
+		}
+		length = int64(binary.BigEndian.Uint64(c.readHeader[:8]))
+		if length < 0 {
+			return noFrame, c.protocolError(CloseProtocolError, "帧长度无效")
+		}
+	}
+
+	if masked != c.isServer {
+		if c.isServer {
+			return noFrame, c.protocolError(CloseProtocolError, "客户端帧须掩码")
+		}
+		return noFrame, c.protocolError(CloseProtocolError, "服务端帧不得掩码")
+	}
+	c.readMasked = masked
+	if masked {
+		if err := c.readFull(c.readMaskKey[:]); err != nil {
+			return noFrame, err
+		}
+		c.readMaskPos = 0
+	}
+
+	if !isControl(frameType) {
+		c.readLength += length
+		if c.readLimit > 0 && c.readLength > c.readLimit {
+			_ = c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""), time.Now().Add(defaultControlWait))
+			return noFrame, ErrReadLimit
+		}
+		c.readRemaining = length
+		return frameType, nil
+	}
+
+	payload := c.controlBuf[:length]
+	if err := c.readFull(payload); err != nil {
+		return noFrame, err
+	}
+	if c.readMasked {
+		maskBytes(c.readMaskKey, 0, payload)
+	}
+
+	switch frameType {
+	case PingMessage:
+		if err := c.handlePing(string(payload)); err != nil {
+			return noFrame, err
+		}
+	case PongMessage:
+		if err := c.handlePong(string(payload)); err != nil {
+			return noFrame, err
+		}
+	case CloseMessage:
+		code := CloseNoStatusReceived
+		text := ""
+		if len(payload) == 1 {
+			return noFrame, c.protocolError(CloseProtocolError, "关闭帧的负载无效")
+		}
+		if len(payload) >= 2 {
+			code = int(binary.BigEndian.Uint16(payload))
+			if !isValidReceivedCloseCode(code) {
+				return noFrame, c.protocolError(CloseProtocolError, "无效的关闭码 "+strconv.Itoa(code))
+			}
+			text = string(payload[2:])
+			if !utf8.ValidString(text) {
+				return noFrame, c.protocolError(CloseInvalidFramePayloadData, "关闭原因不是有效的 UTF-8 文本")
+			}
+		}
+		if err := c.handleClose(code, text); err != nil {
+			return noFrame, err
+		}
+		return noFrame, &CloseError{Code: code, Text: text}
+	}
+	return noFrame, nil
+}
+
+// NextReader 返回下一条数据消息的类型及读取器，期间收到的控制消息交由相应处理器处理。
+//
+// 上一条消息未读完的部分会被丢弃。返回错误后连接不可再读，后续调用均返回相同错误。
+func (c *Conn) NextReader() (messageType int, r io.Reader, err error) {
+	c.messageReader = nil
+	for c.readErr == nil {
+		frameType, err := c.advanceFrame()
+		if err != nil {
+			c.readErr = err
+			break
+		}
+		if !isData(frameType) {
+			continue
+		}
+
+		mr := &messageReader{c: c}
+		c.messageReader = mr
+		r = mr
+		if c.readCompress {
+			r = newDecompressReader(r)
+		}
+		if c.readLimit > 0 && c.readCompress {
+			r = &limitReader{c: c, r: r, remaining: c.readLimit}
+		}
+		return frameType, r, nil
+	}
+	return noFrame, nil, c.readErr
+}
+
+// ReadMessage 读取下一条完整的数据消息。
+//
+// 文本消息不是有效的 UTF-8 时，以 CloseInvalidFramePayloadData 关闭连接。
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	messageType, r, err := c.NextReader()
+	if err != nil {
+		return messageType, nil, err
+	}
+	p, err = io.ReadAll(r)
+	if err != nil {
+		return messageType, p, err
+	}
+	if messageType == TextMessage && !utf8.Valid(p) {
+		c.readErr = c.protocolError(CloseInvalidFramePayloadData, "文本消息不是有效的 UTF-8 文本")
+		return messageType, nil, c.readErr
+	}
+	return messageType, p, nil
+}
+
+// 单条消息的读取器，跨越该消息的所有分片。
+type messageReader struct {
+	c *Conn
+}
+
+func (r *messageReader) Read(b []byte) (int, error) {
+	c := r.c
+	if c.messageReader != r {
+		return 0, io.EOF
+	}
+
+	for c.readErr == nil {
+		if c.readRemaining > 0 {
+			if int64(len(b)) > c.readRemaining {
+				b = b[:c.readRemaining]
+			}
+			if len(b) > c.readBufSize {
+				b = b[:c.readBufSize]
+			}
+			n, err := c.conn.Read(b)
+			if c.readMasked {
+				c.readMaskPos = maskBytes(c.readMaskKey, c.readMaskPos, b[:n])
+			}
+			c.readRemaining -= int64(n)
+			if err == io.EOF {
+				err = errUnexpectedEOF
+			}
+			c.readErr = err
+			return n, err
+		}
+
+		if c.readFinal {
+			c.messageReader = nil
+			return 0, io.EOF
+		}
+		if _, err := c.advanceFrame(); err != nil {
+			c.readErr = err
+		}
+	}
+
+	return 0, c.readErr
+}
+
+// 按解压后的大小限制消息长度。
+type limitReader struct {
+	c         *Conn
+	r         io.Reader
+	remaining int64
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		c := r.c
+		_ = c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""), time.Now().Add(defaultControlWait))
+		c.readErr = ErrReadLimit
+		return 0, ErrReadLimit
+	}
+	return n, err
+}
+
+// 以 key 对 b 进行掩码（或去掩码），返回下一字节对应的掩码位置。
+func maskBytes(key [4]byte, pos int, b []byte) int {
+	for i := range b {
+		b[i] ^= key[pos&3]
+		pos++
+	}
+	return pos & 3
+}
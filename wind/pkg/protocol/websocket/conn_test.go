@@ -0,0 +1,357 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/network"
+)
+
+// 用 bufio 包装 net.Conn，满足 network.Conn 接口。
+type testConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func newTestConn(c net.Conn) *testConn {
+	return &testConn{Conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+}
+
+func (c *testConn) Read(p []byte) (int, error)            { return c.r.Read(p) }
+func (c *testConn) Len() int                              { return c.r.Buffered() }
+func (c *testConn) Peek(n int) ([]byte, error)            { return c.r.Peek(n) }
+func (c *testConn) Skip(n int) error                      { _, err := c.r.Discard(n); return err }
+func (c *testConn) ReadByte() (byte, error)               { return c.r.ReadByte() }
+func (c *testConn) Release() error                        { return nil }
+func (c *testConn) Malloc(n int) ([]byte, error)          { return make([]byte, n), nil }
+func (c *testConn) WriteBinary(b []byte) (int, error)     { return c.w.Write(b) }
+func (c *testConn) Flush() error                          { return c.w.Flush() }
+func (c *testConn) SetReadTimeout(t time.Duration) error  { return nil }
+func (c *testConn) SetWriteTimeout(t time.Duration) error { return nil }
+
+func (c *testConn) ReadBinary(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(c.r, b)
+	return b, err
+}
+
+var _ network.Conn = (*testConn)(nil)
+
+// 返回经本地 TCP 连接相连的服务端与客户端。
+func newConnPair(t *testing.T, compress bool) (server, client *Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	cc, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	sc := <-accepted
+
+	server = newConn(newTestConn(sc), true, 0, 0)
+	client = newConn(newTestConn(cc), false, 0, 0)
+	server.compressionNegotiated, server.enableWriteCompress = compress, compress
+	client.compressionNegotiated, client.enableWriteCompress = compress, compress
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return server, client
+}
+
+func TestConnEcho(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		server, client := newConnPair(t, compress)
+		go func() {
+			for {
+				mt, p, err := server.ReadMessage()
+				if err != nil {
+					return
+				}
+				server.WriteMessage(mt, p)
+			}
+		}()
+
+		large := bytes.Repeat([]byte("wind websocket "), 10000)
+		cases := []struct {
+			mt   int
+			data []byte
+		}{
+			{TextMessage, []byte("hello")},
+			{BinaryMessage, []byte{0, 1, 2, 3}},
+			{TextMessage, []byte{}},
+			{BinaryMessage, large},
+		}
+		for _, c := range cases {
+			assert.Nil(t, client.WriteMessage(c.mt, c.data))
+			mt, p, err := client.ReadMessage()
+			assert.Nil(t, err)
+			assert.DeepEqual(t, c.mt, mt)
+			assert.True(t, bytes.Equal(c.data, p))
+		}
+	}
+}
+
+func TestConnFragmentation(t *testing.T) {
+	server, client := newConnPair(t, false)
+	server.writeBufSize = 4
+
+	// 以多次写入构造分片消息
+	go func() {
+		w, _ := server.NextWriter(TextMessage)
+		io.WriteString(w, "hello, ")
+		io.WriteString(w, "fragmented world")
+		w.Close()
+	}()
+
+	mt, r, err := client.NextReader()
+	assert.Nil(t, err)
+	assert.DeepEqual(t, TextMessage, mt)
+	p, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "hello, fragmented world", string(p))
+
+	// 控制帧可穿插在分片之间，读取方自动回复 pong
+	pong := make(chan string, 1)
+	server.SetPongHandler(func(appData string) error {
+		pong <- appData
+		return nil
+	})
+	msgs := make(chan string, 1)
+	go func() {
+		for {
+			_, p, err := server.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgs <- string(p)
+		}
+	}()
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	assert.Nil(t, client.frameWrite(TextMessage, false, []byte("part1;")))
+	assert.Nil(t, client.WriteControl(PingMessage, []byte("ping"), time.Time{}))
+	assert.Nil(t, client.frameWrite(continuationFrame, true, []byte("part2")))
+	assert.DeepEqual(t, "part1;part2", <-msgs)
+
+	assert.Nil(t, server.WriteControl(PingMessage, []byte("from server"), time.Time{}))
+	select {
+	case <-time.After(time.Second):
+		t.Fatal("未收到 pong")
+	case p := <-pong:
+		assert.DeepEqual(t, "from server", p)
+	}
+}
+
+// 写入单帧，用于构造分片。
+func (c *Conn) frameWrite(frameType int, final bool, p []byte) error {
+	c.frameMu.Lock()
+	defer c.frameMu.Unlock()
+	return c.writeFrameLocked(frameType, final, false, p)
+}
+
+func TestConnPingPong(t *testing.T) {
+	server, client := newConnPair(t, false)
+	go func() {
+		for {
+			if _, _, err := server.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	pong := make(chan string, 1)
+	client.SetPongHandler(func(appData string) error {
+		pong <- appData
+		return nil
+	})
+	go client.ReadMessage()
+
+	assert.Nil(t, client.WriteControl(PingMessage, []byte("are you there"), time.Now().Add(time.Second)))
+	select {
+	case <-time.After(time.Second):
+		t.Fatal("未收到 pong")
+	case p := <-pong:
+		assert.DeepEqual(t, "are you there", p)
+	}
+
+	assert.DeepEqual(t, errInvalidControl, client.WriteControl(PingMessage, make([]byte, 126), time.Time{}))
+	assert.DeepEqual(t, errInvalidMessageType, client.WriteControl(TextMessage, nil, time.Time{}))
+}
+
+func TestConnClose(t *testing.T) {
+	server, client := newConnPair(t, false)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		_, _, err := server.ReadMessage()
+		serverErr <- err
+	}()
+
+	assert.Nil(t, client.WriteControl(CloseMessage, FormatCloseMessage(CloseGoingAway, "bye"), time.Now().Add(time.Second)))
+	err := <-serverErr
+	assert.True(t, IsCloseError(err, CloseGoingAway))
+	assert.DeepEqual(t, "bye", err.(*CloseError).Text)
+	assert.False(t, IsUnexpectedCloseError(err, CloseGoingAway))
+
+	// 服务端默认回复相同的关闭码
+	_, _, err = client.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseGoingAway))
+
+	// 关闭帧发出后不可再发送消息
+	assert.DeepEqual(t, ErrCloseSent, server.WriteMessage(TextMessage, []byte("late")))
+	_, err = client.NextWriter(TextMessage)
+	assert.DeepEqual(t, ErrCloseSent, err)
+
+	// 读取错误会一直保留
+	_, _, err = client.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseGoingAway))
+}
+
+func TestConnAbnormalClose(t *testing.T) {
+	server, client := newConnPair(t, false)
+	client.Close()
+	_, _, err := server.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseAbnormalClosure))
+	assert.True(t, IsUnexpectedCloseError(err, CloseNormalClosure, CloseGoingAway))
+}
+
+func TestConnReadLimit(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		server, client := newConnPair(t, compress)
+		server.SetReadLimit(64)
+
+		serverErr := make(chan error, 1)
+		go func() {
+			_, _, err := server.ReadMessage()
+			if err == nil {
+				_, _, err = server.ReadMessage()
+			}
+			serverErr <- err
+		}()
+
+		assert.Nil(t, client.WriteMessage(TextMessage, []byte("small")))
+		// 压缩后远小于上限，但解压后超出
+		assert.Nil(t, client.WriteMessage(TextMessage, bytes.Repeat([]byte("a"), 1000)))
+		assert.DeepEqual(t, ErrReadLimit, <-serverErr)
+
+		_, _, err := client.ReadMessage()
+		assert.True(t, IsCloseError(err, CloseMessageTooBig))
+	}
+}
+
+func TestConnConcurrentWrites(t *testing.T) {
+	server, client := newConnPair(t, false)
+	server.writeBufSize = 16
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := strings.Repeat(fmt.Sprint(i%10), 100)
+			assert.Nil(t, server.WriteMessage(TextMessage, []byte(msg)))
+			assert.Nil(t, server.WriteControl(PingMessage, []byte(fmt.Sprint(i)), time.Time{}))
+		}(i)
+	}
+
+	for i := 0; i < n; i++ {
+		_, p, err := client.ReadMessage()
+		assert.Nil(t, err)
+		assert.DeepEqual(t, 100, len(p))
+		assert.DeepEqual(t, strings.Repeat(string(p[0]), 100), string(p))
+	}
+	wg.Wait()
+}
+
+func TestConnProtocolErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		// 客户端帧未掩码
+		{"unmasked", []byte{finalBit | TextMessage, 0}, CloseProtocolError},
+		// 未协商压缩时设置 RSV1
+		{"rsv1", []byte{finalBit | rsv1Bit | TextMessage, maskBit, 0, 0, 0, 0}, CloseProtocolError},
+		// 分片的控制帧
+		{"fragmented ping", []byte{PingMessage, maskBit, 0, 0, 0, 0}, CloseProtocolError},
+		// 无前序消息的续帧
+		{"continuation", []byte{finalBit, maskBit, 0, 0, 0, 0}, CloseProtocolError},
+		// 未知操作码
+		{"opcode", []byte{finalBit | 3, maskBit, 0, 0, 0, 0}, CloseProtocolError},
+		// 无效的 UTF-8 文本
+		{"utf8", []byte{finalBit | TextMessage, maskBit | 2, 0, 0, 0, 0, 0xc3, 0x28}, CloseInvalidFramePayloadData},
+	}
+	for _, c := range cases {
+		server, client := newConnPair(t, false)
+		client.conn.WriteBinary(c.frame)
+		client.conn.Flush()
+
+		_, _, err := server.ReadMessage()
+		assert.NotNil(t, err)
+		_, _, err = client.ReadMessage()
+		if !IsCloseError(err, c.code) {
+			t.Fatalf("%s：期望关闭码 %d，实际 %v", c.name, c.code, err)
+		}
+	}
+}
+
+func TestConnWriterMisuse(t *testing.T) {
+	server, _ := newConnPair(t, false)
+	_, err := server.NextWriter(PingMessage)
+	assert.DeepEqual(t, errInvalidMessageType, err)
+
+	w, err := server.NextWriter(BinaryMessage)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.DeepEqual(t, errWriteClosed, w.Close())
+	_, err = w.Write([]byte("x"))
+	assert.DeepEqual(t, errWriteClosed, err)
+
+	assert.DeepEqual(t, errBadCompression, server.SetCompressionLevel(10))
+	assert.Nil(t, server.SetCompressionLevel(9))
+}
+
+func TestCompressData(t *testing.T) {
+	src := bytes.Repeat([]byte("compress me "), 100)
+	for level := minCompressionLevel; level <= maxCompressionLevel; level++ {
+		data, err := compressData(src, level)
+		assert.Nil(t, err)
+		p, err := io.ReadAll(newDecompressReader(bytes.NewReader(data)))
+		assert.Nil(t, err)
+		assert.True(t, bytes.Equal(src, p))
+	}
+}
+
+func TestCloseErrorHelpers(t *testing.T) {
+	assert.DeepEqual(t, []byte{}, FormatCloseMessage(CloseNoStatusReceived, "ignored"))
+	assert.DeepEqual(t, []byte{0x03, 0xe8, 'o', 'k'}, FormatCloseMessage(CloseNormalClosure, "ok"))
+
+	err := fmt.Errorf("wrapped: %w", &CloseError{Code: CloseNormalClosure})
+	assert.True(t, IsCloseError(err, CloseNormalClosure))
+	assert.False(t, IsCloseError(err, CloseGoingAway))
+	assert.False(t, IsCloseError(errors.New("other"), CloseNormalClosure))
+	assert.False(t, IsUnexpectedCloseError(errors.New("other")))
+}
@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/network"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+// 用于计算 Sec-WebSocket-Accept 的固定 GUID，详见 RFC 6455 第 4.2.2 节。
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrBadHandshake 表示客户端的握手请求无效。
+var ErrBadHandshake = errors.New("websocket：握手请求无效")
+
+// Handler 接管升级后的 WebSocket 连接。
+//
+// 处理器返回后，底层连接将被关闭，除非引擎设置了 KeepHijackedConns。
+type Handler func(conn *Conn)
+
+// Upgrader 将 HTTP/1.1 请求升级为 WebSocket 连接。
+//
+// 升级基于 RequestContext.Hijack：响应 101 后，服务器将连接交给 Handler，不再处理其上的 HTTP 请求。
+type Upgrader struct {
+	// 读取帧负载时单次读取的最大字节数，默认 4KB。
+	ReadBufferSize int
+
+	// 写缓冲区大小，超出此大小的消息以分片发送，默认 4KB。
+	WriteBufferSize int
+
+	// 服务端支持的子协议，按优先级排列。
+	Subprotocols []string
+
+	// 握手失败时的响应函数，默认以状态码及其描述中止请求。
+	Error func(ctx *app.RequestContext, status int, reason error)
+
+	// 校验请求的 Origin 标头，返回假则拒绝握手。
+	//
+	// 默认仅允许未设置 Origin 或 Origin 与 Host 一致的请求。
+	CheckOrigin func(ctx *app.RequestContext) bool
+
+	// 是否在客户端支持时协商 permessage-deflate 压缩。
+	EnableCompression bool
+}
+
+func (u *Upgrader) returnError(ctx *app.RequestContext, status int, reason string) error {
+	err := fmt.Errorf("%w：%s", ErrBadHandshake, reason)
+	if u.Error != nil {
+		u.Error(ctx, status, err)
+	} else {
+		ctx.AbortWithMsg(consts.StatusMessage(status), status)
+	}
+	ctx.Response.Header.Set(consts.HeaderSecWebSocketVersion, "13")
+	return err
+}
+
+// 默认的来源校验：Origin 的主机须与请求的 Host 一致。
+func checkSameOrigin(ctx *app.RequestContext) bool {
+	origin := ctx.Request.Header.Peek(consts.HeaderOrigin)
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(string(origin))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, string(ctx.Host()))
+}
+
+func (u *Upgrader) selectSubprotocol(ctx *app.RequestContext) string {
+	if len(u.Subprotocols) == 0 {
+		return ""
+	}
+	clientProtocols := parseTokens(ctx.Request.Header.PeekAll(consts.HeaderSecWebSocketProtocol))
+	for _, sp := range u.Subprotocols {
+		for _, cp := range clientProtocols {
+			if cp == sp {
+				return sp
+			}
+		}
+	}
+	return ""
+}
+
+// Upgrade 校验握手请求，写入 101 响应，并在响应发出后以 WebSocket 连接调用 handler。
+//
+// 握手失败时已写入错误响应，返回的错误可用 errors.Is 与 ErrBadHandshake 比较。
+// 须在 HTTP/1.1 连接上使用，HTTP/2 请求会被拒绝。
+func (u *Upgrader) Upgrade(ctx *app.RequestContext, handler Handler) error {
+	h := &ctx.Request.Header
+	if h.GetProtocol() != consts.HTTP11 {
+		return u.returnError(ctx, consts.StatusBadRequest, "仅支持 HTTP/1.1 请求升级")
+	}
+	if !tokenListContains(h.PeekAll(consts.HeaderConnection), "upgrade") {
+		return u.returnError(ctx, consts.StatusBadRequest, "Connection 标头未包含 upgrade")
+	}
+	if !tokenListContains(h.PeekAll(consts.HeaderUpgrade), "websocket") {
+		return u.returnError(ctx, consts.StatusBadRequest, "Upgrade 标头未包含 websocket")
+	}
+	if string(h.Method()) != consts.MethodGet {
+		return u.returnError(ctx, consts.StatusMethodNotAllowed, "请求方法须为 GET")
+	}
+	if string(h.Peek(consts.HeaderSecWebSocketVersion)) != "13" {
+		return u.returnError(ctx, consts.StatusBadRequest, "不支持的 Sec-WebSocket-Version")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(ctx) {
+		return u.returnError(ctx, consts.StatusForbidden, "Origin 不被允许")
+	}
+
+	key := h.Peek(consts.HeaderSecWebSocketKey)
+	if !isValidChallengeKey(key) {
+		return u.returnError(ctx, consts.StatusBadRequest, "Sec-WebSocket-Key 无效")
+	}
+
+	subprotocol := u.selectSubprotocol(ctx)
+	compress := u.EnableCompression && acceptCompression(h.PeekAll(consts.HeaderSecWebSocketExtensions))
+
+	resp := &ctx.Response
+	resp.SetStatusCode(consts.StatusSwitchingProtocols)
+	resp.Header.Set(consts.HeaderUpgrade, "websocket")
+	resp.Header.Set(consts.HeaderConnection, "Upgrade")
+	resp.Header.Set(consts.HeaderSecWebSocketAccept, computeAcceptKey(key))
+	if subprotocol != "" {
+		resp.Header.Set(consts.HeaderSecWebSocketProtocol, subprotocol)
+	}
+	if compress {
+		resp.Header.Set(consts.HeaderSecWebSocketExtensions, compressionResponse)
+	}
+
+	ctx.Hijack(func(c network.Conn) {
+		conn := newConn(c, true, u.ReadBufferSize, u.WriteBufferSize)
+		conn.subprotocol = subprotocol
+		conn.compressionNegotiated = compress
+		conn.enableWriteCompress = compress
+		handler(conn)
+	})
+	return nil
+}
+
+// IsWebSocketUpgrade 报告请求是否为 WebSocket 握手请求。
+func IsWebSocketUpgrade(ctx *app.RequestContext) bool {
+	h := &ctx.Request.Header
+	return tokenListContains(h.PeekAll(consts.HeaderConnection), "upgrade") &&
+		tokenListContains(h.PeekAll(consts.HeaderUpgrade), "websocket")
+}
+
+// Subprotocols 返回客户端请求的子协议。
+func Subprotocols(ctx *app.RequestContext) []string {
+	return parseTokens(ctx.Request.Header.PeekAll(consts.HeaderSecWebSocketProtocol))
+}
+
+func computeAcceptKey(challengeKey []byte) string {
+	h := sha1.New()
+	h.Write(challengeKey)
+	h.Write([]byte(keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// 客户端的 Sec-WebSocket-Key 须为 16 字节随机数的 base64 编码。
+func isValidChallengeKey(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(key))
+	return err == nil && len(decoded) == 16
+}
+
+// 解析逗号分隔的标头值列表。
+func parseTokens(values [][]byte) []string {
+	var tokens []string
+	for _, v := range values {
+		for _, t := range strings.Split(string(v), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// 报告逗号分隔的标头值列表中是否包含 token（不区分大小写）。
+func tokenListContains(values [][]byte, token string) bool {
+	for _, t := range parseTokens(values) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
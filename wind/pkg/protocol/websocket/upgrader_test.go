@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/server"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+func newUpgradeContext() *app.RequestContext {
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod(consts.MethodGet)
+	ctx.Request.Header.SetProtocol(consts.HTTP11)
+	ctx.Request.Header.SetHost("example.com")
+	ctx.Request.Header.Set(consts.HeaderConnection, "keep-alive, Upgrade")
+	ctx.Request.Header.Set(consts.HeaderUpgrade, "websocket")
+	ctx.Request.Header.Set(consts.HeaderSecWebSocketVersion, "13")
+	ctx.Request.Header.Set(consts.HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+	return ctx
+}
+
+func TestUpgrade(t *testing.T) {
+	u := &Upgrader{Subprotocols: []string{"v2.chat", "chat"}, EnableCompression: true}
+	ctx := newUpgradeContext()
+	ctx.Request.Header.Set(consts.HeaderOrigin, "https://example.com")
+	ctx.Request.Header.Set(consts.HeaderSecWebSocketProtocol, "chat, v2.chat")
+	ctx.Request.Header.Set(consts.HeaderSecWebSocketExtensions, "permessage-deflate; client_max_window_bits")
+	assert.True(t, IsWebSocketUpgrade(ctx))
+	assert.DeepEqual(t, []string{"chat", "v2.chat"}, Subprotocols(ctx))
+
+	assert.Nil(t, u.Upgrade(ctx, func(conn *Conn) {}))
+	h := &ctx.Response.Header
+	assert.DeepEqual(t, consts.StatusSwitchingProtocols, ctx.Response.StatusCode())
+	// RFC 6455 第 1.3 节的示例
+	assert.DeepEqual(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", string(h.Peek(consts.HeaderSecWebSocketAccept)))
+	assert.DeepEqual(t, "websocket", string(h.Peek(consts.HeaderUpgrade)))
+	assert.DeepEqual(t, "Upgrade", string(h.Peek(consts.HeaderConnection)))
+	assert.DeepEqual(t, "v2.chat", string(h.Peek(consts.HeaderSecWebSocketProtocol)))
+	assert.DeepEqual(t, compressionResponse, string(h.Peek(consts.HeaderSecWebSocketExtensions)))
+	assert.True(t, ctx.Hijacked())
+}
+
+func TestUpgradeBadHandshake(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(ctx *app.RequestContext)
+		status int
+	}{
+		{"no connection", func(ctx *app.RequestContext) { ctx.Request.Header.DelBytes([]byte(consts.HeaderConnection)) }, consts.StatusBadRequest},
+		{"no upgrade", func(ctx *app.RequestContext) { ctx.Request.Header.Set(consts.HeaderUpgrade, "h2c") }, consts.StatusBadRequest},
+		{"method", func(ctx *app.RequestContext) { ctx.Request.Header.SetMethod(consts.MethodPost) }, consts.StatusMethodNotAllowed},
+		{"version", func(ctx *app.RequestContext) { ctx.Request.Header.Set(consts.HeaderSecWebSocketVersion, "8") }, consts.StatusBadRequest},
+		{"key", func(ctx *app.RequestContext) { ctx.Request.Header.Set(consts.HeaderSecWebSocketKey, "short") }, consts.StatusBadRequest},
+		{"origin", func(ctx *app.RequestContext) { ctx.Request.Header.Set(consts.HeaderOrigin, "https://evil.com") }, consts.StatusForbidden},
+		{"http2", func(ctx *app.RequestContext) { ctx.Request.Header.SetProtocol(consts.HTTP20) }, consts.StatusBadRequest},
+	}
+	for _, c := range cases {
+		ctx := newUpgradeContext()
+		c.modify(ctx)
+		err := (&Upgrader{}).Upgrade(ctx, func(conn *Conn) {})
+		assert.True(t, errors.Is(err, ErrBadHandshake))
+		assert.DeepEqual(t, c.status, ctx.Response.StatusCode())
+		assert.DeepEqual(t, "13", string(ctx.Response.Header.Peek(consts.HeaderSecWebSocketVersion)))
+		assert.False(t, ctx.Hijacked())
+	}
+
+	// 自定义来源校验及错误响应
+	u := &Upgrader{
+		CheckOrigin: func(ctx *app.RequestContext) bool { return true },
+		Error: func(ctx *app.RequestContext, status int, reason error) {
+			ctx.String(status, "拒绝升级")
+		},
+	}
+	ctx := newUpgradeContext()
+	ctx.Request.Header.Set(consts.HeaderOrigin, "https://evil.com")
+	assert.Nil(t, u.Upgrade(ctx, func(conn *Conn) {}))
+	ctx = newUpgradeContext()
+	ctx.Request.Header.DelBytes([]byte(consts.HeaderSecWebSocketKey))
+	assert.NotNil(t, u.Upgrade(ctx, func(conn *Conn) {}))
+	assert.DeepEqual(t, "拒绝升级", string(ctx.Response.Body()))
+}
+
+func TestAcceptCompression(t *testing.T) {
+	assert.True(t, acceptCompression([][]byte{[]byte("permessage-deflate")}))
+	assert.True(t, acceptCompression([][]byte{[]byte("x-webkit-deflate-frame, permessage-deflate; server_max_window_bits=15")}))
+	assert.False(t, acceptCompression([][]byte{[]byte("permessage-deflate; server_max_window_bits=10")}))
+	assert.False(t, acceptCompression([][]byte{[]byte("permessage-deflate; unknown")}))
+	assert.False(t, acceptCompression(nil))
+}
+
+func TestUpgradeServer(t *testing.T) {
+	const addr = "127.0.0.1:18765"
+	h := server.New(server.WithHostPorts(addr))
+	upgrader := &Upgrader{EnableCompression: true}
+	h.GET("/ws", func(c context.Context, ctx *app.RequestContext) {
+		err := upgrader.Upgrade(ctx, func(conn *Conn) {
+			for {
+				mt, p, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if err = conn.WriteMessage(mt, p); err != nil {
+					return
+				}
+			}
+		})
+		assert.Nil(t, err)
+	})
+	go h.Run()
+	defer h.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	nc, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer nc.Close()
+	nc.Write([]byte("GET /ws HTTP/1.1\r\n" +
+		"Host: " + addr + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Extensions: permessage-deflate\r\n\r\n"))
+
+	br := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(br, nil)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.DeepEqual(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get(consts.HeaderSecWebSocketAccept))
+	assert.DeepEqual(t, compressionResponse, resp.Header.Get(consts.HeaderSecWebSocketExtensions))
+
+	tc := newTestConn(nc)
+	tc.r = br
+	client := newConn(tc, false, 0, 0)
+	client.compressionNegotiated, client.enableWriteCompress = true, true
+	for _, msg := range []string{"hello", "wind"} {
+		assert.Nil(t, client.WriteMessage(TextMessage, []byte(msg)))
+		mt, p, err := client.ReadMessage()
+		assert.Nil(t, err)
+		assert.DeepEqual(t, TextMessage, mt)
+		assert.DeepEqual(t, msg, string(p))
+	}
+	assert.Nil(t, client.WriteControl(CloseMessage, FormatCloseMessage(CloseNormalClosure, ""), time.Now().Add(time.Second)))
+	_, _, err = client.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseNormalClosure))
+}
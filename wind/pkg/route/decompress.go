@@ -0,0 +1,123 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/favbox/gosky/wind/internal/bytesconv"
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/bytebufferpool"
+	"github.com/favbox/gosky/wind/pkg/common/compress"
+	errs "github.com/favbox/gosky/wind/pkg/common/errors"
+	"github.com/favbox/gosky/wind/pkg/protocol"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+var (
+	default413Body = []byte("413 请求实体过大")
+	default415Body = []byte("415 不支持的媒体类型")
+)
+
+// 按 Content-Encoding 解码请求正文，解码后移除该标头。
+//
+// 解码失败时已写入错误响应并返回 false。返回的 restore 不为空时，须在处理链结束后调用，
+// 以释放解码器并还原原始的正文流，供服务器读完剩余数据。
+func (engine *Engine) decompressRequestBody(c context.Context, ctx *app.RequestContext) (restore func(), ok bool) {
+	req := &ctx.Request
+	encoding := bytesconv.B2s(req.Header.PeekContentEncoding())
+	if encoding == "" {
+		return nil, true
+	}
+	maxBodySize := engine.options.MaxRequestBodySize
+
+	if req.IsBodyStream() {
+		buf, stream := req.BodyBuffer(), req.BodyStream()
+		zr, err := compress.NewDecoder(stream, encoding)
+		if err != nil {
+			serveDecompressError(c, ctx, err)
+			return nil, false
+		}
+		body := &decodedBody{r: zr, max: maxBodySize}
+		// 原始正文流会继续读取缓冲区中的预读数据，解码后的正文不能与其共用缓冲区
+		req.ConstructBodyStream(nil, body)
+		removeContentEncoding(req, -1)
+		return func() {
+			_ = body.Close()
+			req.ConstructBodyStream(buf, stream)
+		}, true
+	}
+
+	zr, err := compress.NewDecoder(bytes.NewReader(req.Body()), encoding)
+	if err != nil {
+		serveDecompressError(c, ctx, err)
+		return nil, false
+	}
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	body := &decodedBody{r: zr, max: maxBodySize}
+	_, err = buf.ReadFrom(body)
+	_ = body.Close()
+	if err != nil {
+		serveDecompressError(c, ctx, err)
+		return nil, false
+	}
+	req.SetBody(buf.B)
+	removeContentEncoding(req, len(buf.B))
+	return nil, true
+}
+
+func removeContentEncoding(req *protocol.Request, contentLength int) {
+	req.Header.DelBytes([]byte(consts.HeaderContentEncoding))
+	req.Header.SetContentLength(contentLength)
+}
+
+func serveDecompressError(c context.Context, ctx *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, compress.ErrUnsupportedEncoding):
+		serveError(c, ctx, consts.StatusUnsupportedMediaType, default415Body)
+	case errors.Is(err, errs.ErrBodyTooLarge):
+		serveError(c, ctx, consts.StatusRequestEntityTooLarge, default413Body)
+	default:
+		serveError(c, ctx, consts.StatusBadRequest, default400Body)
+	}
+}
+
+// 解码后的请求正文，累计字节数超出上限时返回 errs.ErrBodyTooLarge，以防范解压炸弹。
+type decodedBody struct {
+	r   io.ReadCloser
+	n   int
+	max int
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		return 0, io.EOF
+	}
+	if b.max > 0 && b.n >= b.max {
+		// 已达上限，再读一个字节确认是否仍有数据
+		var one [1]byte
+		n, err := b.r.Read(one[:])
+		if n > 0 {
+			return 0, errs.ErrBodyTooLarge
+		}
+		return 0, err
+	}
+	if b.max > 0 && len(p) > b.max-b.n {
+		p = p[:b.max-b.n]
+	}
+	n, err := b.r.Read(p)
+	b.n += n
+	return n, err
+}
+
+// Close 将解码器放回池中，不会关闭原始正文流。
+func (b *decodedBody) Close() error {
+	if b.r == nil {
+		return nil
+	}
+	err := b.r.Close()
+	b.r = nil
+	return err
+}
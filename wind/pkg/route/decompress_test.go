@@ -0,0 +1,135 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/common/compress"
+	"github.com/favbox/gosky/wind/pkg/common/config"
+	"github.com/favbox/gosky/wind/pkg/common/test/assert"
+	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+)
+
+var decompressTestBody = strings.Repeat(`{"name":"wind"}`, 100)
+
+func newDecompressEngine(opts ...config.Option) *Engine {
+	opts = append([]config.Option{{F: func(o *config.Options) { o.DecompressRequestBody = true }}}, opts...)
+	e := NewEngine(config.NewOptions(opts))
+	e.POST("/echo", func(c context.Context, ctx *app.RequestContext) {
+		if len(ctx.Request.Header.PeekContentEncoding()) > 0 {
+			ctx.String(consts.StatusInternalServerError, "未移除 Content-Encoding")
+			return
+		}
+		body, err := ctx.Request.BodyE()
+		if err != nil {
+			ctx.String(consts.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		ctx.Data(consts.StatusOK, consts.MIMETextPlain, body)
+	})
+	return e
+}
+
+func serveDecompress(e *Engine, encoding string, body []byte, stream bool) *app.RequestContext {
+	ctx := e.NewContext()
+	ctx.Request.SetRequestURI("/echo")
+	ctx.Request.Header.SetMethod(consts.MethodPost)
+	if encoding != "" {
+		ctx.Request.Header.Set(consts.HeaderContentEncoding, encoding)
+	}
+	if stream {
+		ctx.Request.SetBodyStream(bytes.NewReader(body), len(body))
+	} else {
+		ctx.Request.SetBody(body)
+	}
+	e.ServeHTTP(context.Background(), ctx)
+	return ctx
+}
+
+func encodeTestBody(encoding string) []byte {
+	src := []byte(decompressTestBody)
+	switch encoding {
+	case "gzip":
+		return compress.AppendGzipBytes(nil, src)
+	case "deflate":
+		return compress.AppendDeflateBytesLevel(nil, src, compress.CompressDefaultCompression)
+	case "br":
+		return compress.AppendBrotliBytes(nil, src)
+	case "zstd":
+		return compress.AppendZstdBytes(nil, src)
+	case "br, gzip":
+		return compress.AppendGzipBytes(nil, compress.AppendBrotliBytes(nil, src))
+	}
+	return src
+}
+
+func TestDecompressRequestBody(t *testing.T) {
+	e := newDecompressEngine()
+	for _, stream := range []bool{false, true} {
+		for _, encoding := range []string{"", "identity", "gzip", "deflate", "br", "zstd", "br, gzip"} {
+			ctx := serveDecompress(e, encoding, encodeTestBody(encoding), stream)
+			assert.DeepEqual(t, consts.StatusOK, ctx.Response.StatusCode())
+			assert.DeepEqual(t, decompressTestBody, string(ctx.Response.Body()))
+		}
+	}
+
+	// 未开启时原样交给处理器
+	e = NewEngine(config.NewOptions(nil))
+	e.POST("/echo", func(c context.Context, ctx *app.RequestContext) {
+		ctx.Data(consts.StatusOK, consts.MIMETextPlain, ctx.Request.Body())
+	})
+	body := encodeTestBody("gzip")
+	ctx := serveDecompress(e, "gzip", body, false)
+	assert.DeepEqual(t, body, ctx.Response.Body())
+}
+
+func TestDecompressRequestBodyError(t *testing.T) {
+	e := newDecompressEngine()
+	for _, stream := range []bool{false, true} {
+		ctx := serveDecompress(e, "compress", []byte("data"), stream)
+		assert.DeepEqual(t, consts.StatusUnsupportedMediaType, ctx.Response.StatusCode())
+
+		ctx = serveDecompress(e, "gzip", []byte("not gzip"), stream)
+		assert.DeepEqual(t, consts.StatusBadRequest, ctx.Response.StatusCode())
+	}
+	ctx := serveDecompress(e, "br", []byte("not brotli"), false)
+	assert.DeepEqual(t, consts.StatusBadRequest, ctx.Response.StatusCode())
+}
+
+func TestDecompressRequestBodyTooLarge(t *testing.T) {
+	// 压缩后很小，解码后超出上限
+	bomb := compress.AppendGzipBytes(nil, bytes.Repeat([]byte{0}, 1<<20))
+	e := newDecompressEngine(config.Option{F: func(o *config.Options) { o.MaxRequestBodySize = 64 << 10 }})
+
+	ctx := serveDecompress(e, "gzip", bomb, false)
+	assert.DeepEqual(t, consts.StatusRequestEntityTooLarge, ctx.Response.StatusCode())
+	assert.DeepEqual(t, default413Body, ctx.Response.Body())
+
+	// 流式正文在读取时报错
+	ctx = serveDecompress(e, "gzip", bomb, true)
+	assert.DeepEqual(t, consts.StatusRequestEntityTooLarge, ctx.Response.StatusCode())
+
+	// 恰好等于上限时不报错
+	exact := compress.AppendGzipBytes(nil, bytes.Repeat([]byte{0}, 64<<10))
+	ctx = serveDecompress(e, "gzip", exact, true)
+	assert.DeepEqual(t, consts.StatusOK, ctx.Response.StatusCode())
+	assert.DeepEqual(t, 64<<10, len(ctx.Response.Body()))
+}
+
+func TestDecodedBody(t *testing.T) {
+	src := compress.AppendBrotliBytes(nil, []byte("hello"))
+	zr, err := compress.NewDecoder(bytes.NewReader(src), "br")
+	assert.Nil(t, err)
+	b := &decodedBody{r: zr, max: 5}
+	p, err := io.ReadAll(b)
+	assert.Nil(t, err)
+	assert.DeepEqual(t, "hello", string(p))
+	assert.Nil(t, b.Close())
+	n, err := b.Read(p)
+	assert.DeepEqual(t, 0, n)
+	assert.DeepEqual(t, io.EOF, err)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/favbox/gosky/wind/internal/nocopy"
 	internalStats "github.com/favbox/gosky/wind/internal/stats"
 	"github.com/favbox/gosky/wind/pkg/app"
+	"github.com/favbox/gosky/wind/pkg/app/server/binding"
 	"github.com/favbox/gosky/wind/pkg/app/server/render"
 	"github.com/favbox/gosky/wind/pkg/common/config"
 	errs "github.com/favbox/gosky/wind/pkg/common/errors"
@@ -34,6 +35,8 @@ import (
 	"github.com/favbox/gosky/wind/pkg/protocol/consts"
 	"github.com/favbox/gosky/wind/pkg/protocol/http1"
 	"github.com/favbox/gosky/wind/pkg/protocol/http1/factory"
+	"github.com/favbox/gosky/wind/pkg/protocol/http2"
+	http2factory "github.com/favbox/gosky/wind/pkg/protocol/http2/factory"
 	"github.com/favbox/gosky/wind/pkg/protocol/suite"
 )
 
@@ -113,6 +116,7 @@ func NewEngine(opts *config.Options) *Engine {
 		engine.transport = opts.TransporterNewer(opts)
 	}
 	engine.RouterGroup.engine = engine
+	engine.initBinderAndValidator(opts)
 
 	traceLevel := initTrace(engine)
 
@@ -151,6 +155,9 @@ type Engine struct {
 	// 路由当前最大参数个数
 	maxParams uint16
 
+	// 已为路由组注册的预检通配路由
+	preflightPaths map[string]bool
+
 	allNoMethod app.HandlersChain // 内置的方法不允许处理器
 	allNoRoute  app.HandlersChain // 内置的路由找不到处理器
 	noRoute     app.HandlersChain // 用户的路由找不到处理器
@@ -209,6 +216,10 @@ type Engine struct {
 	clientIPFunc app.ClientIP
 	// 自定义获取表单值的函数。
 	formValueFunc app.FormValueFunc
+
+	// 请求绑定器和校验器。
+	binder    binding.Binder
+	validator binding.StructValidator
 }
 
 // NewContext 创建一个无请求/无响应信息的纯粹上下文。
@@ -243,13 +254,14 @@ func (engine *Engine) Run() (err error) {
 
 	hlog.SystemLogger().Infof("使用网络库=%s", engine.GetTransporterName())
 	err = engine.transport.ListenAndServe(func(ctx context.Context, conn any) error {
+		// 各连接并发处理，不可共用外层的 err
 		switch conn := conn.(type) {
 		case network.Conn:
-			err = engine.Serve(ctx, conn)
+			return engine.Serve(ctx, conn)
 		case network.StreamConn:
-			err = engine.ServeStream(ctx, conn)
+			return engine.ServeStream(ctx, conn)
 		}
-		return err
+		return nil
 	})
 
 	return
@@ -262,6 +274,11 @@ func (engine *Engine) Init() error {
 		engine.AddProtocol(suite.HTTP1, factory.NewServerFactory(newHttp1OptionFromEngine(engine)))
 	}
 
+	// 启用 H2C 或 ALPN 时，内置 HTTP2 协议的服务器实现
+	if (engine.options.H2C || engine.alpnEnable()) && !engine.HasServer(suite.HTTP2) {
+		engine.AddProtocol(suite.HTTP2, http2factory.NewServerFactory(newHttp2OptionFromEngine(engine)))
+	}
+
 	// 加载所有可用的服务器协议及其实现
 	serverMap, streamServerMap, err := engine.protocolSuite.LoadAll(engine)
 	if err != nil {
@@ -270,8 +287,11 @@ func (engine *Engine) Init() error {
 	engine.protocolServers = serverMap
 	engine.protocolStreamServers = streamServerMap
 
-	// 若启用 ALPN，则将 HTTP1 作为 TLS 的备用回退协议。
+	// 若启用 ALPN，则优先协商 HTTP2，并将 HTTP1 作为 TLS 的备用回退协议。
 	if engine.alpnEnable() {
+		if _, ok := engine.protocolServers[suite.HTTP2]; ok && !containsProto(engine.options.TLS.NextProtos, suite.HTTP2) {
+			engine.options.TLS.NextProtos = append(engine.options.TLS.NextProtos, suite.HTTP2)
+		}
 		engine.options.TLS.NextProtos = append(engine.options.TLS.NextProtos, suite.HTTP1)
 	}
 
@@ -365,10 +385,12 @@ func (engine *Engine) Serve(ctx context.Context, conn network.Conn) (err error)
 	if engine.options.H2C {
 		// 协议嗅探器
 		buf, _ := conn.Peek(len(bytestr.StrClientPreface))
-		if bytes.Equal(buf, bytestr.StrClientPreface) && engine.protocolServers[suite.HTTP2] != nil {
-			return engine.protocolServers[suite.HTTP2].Serve(ctx, conn)
+		if bytes.Equal(buf, bytestr.StrClientPreface) {
+			if server, ok := engine.protocolServers[suite.HTTP2]; ok {
+				return server.Serve(ctx, conn)
+			}
+			hlog.SystemLogger().Warn("HTTP2 服务器未加载，请求正在回退到 HTTP1 服务器")
 		}
-		hlog.SystemLogger().Warn("HTTP2 服务器未加载，请求正在回退到 HTTP1 服务器")
 	}
 
 	// ALPN 协议
@@ -464,6 +486,15 @@ func (engine *Engine) ServeHTTP(c context.Context, ctx *app.RequestContext) {
 		value := t[i].find(rPath, paramsPointer, unescape)
 
 		if value.handlers != nil {
+			if engine.options.DecompressRequestBody {
+				restore, ok := engine.decompressRequestBody(c, ctx)
+				if !ok {
+					return
+				}
+				if restore != nil {
+					defer restore()
+				}
+			}
 			ctx.SetHandlers(value.handlers)
 			ctx.SetFullPath(value.fullPath)
 			ctx.Next(c)
@@ -708,9 +739,38 @@ func (engine *Engine) allocateContext() *app.RequestContext {
 	ctx.Response.SetMaxKeepBodySize(engine.options.MaxKeepBodySize)
 	ctx.SetClientIPFunc(engine.clientIPFunc)
 	ctx.SetFormValueFunc(engine.formValueFunc)
+	ctx.SetBinder(engine.binder)
+	ctx.SetValidator(engine.validator)
 	return ctx
 }
 
+// 依据选项初始化请求绑定器和校验器。
+func (engine *Engine) initBinderAndValidator(opts *config.Options) {
+	if opts.CustomBinder != nil {
+		b, ok := opts.CustomBinder.(binding.Binder)
+		if !ok {
+			panic("自定义绑定器未实现 binding.Binder 接口")
+		}
+		engine.binder = b
+	} else if bc, ok := opts.BindConfig.(*binding.BindConfig); ok && bc != nil {
+		engine.binder = binding.NewDefaultBinder(bc)
+	} else {
+		engine.binder = binding.DefaultBinder()
+	}
+
+	if opts.CustomValidator != nil {
+		v, ok := opts.CustomValidator.(binding.StructValidator)
+		if !ok {
+			panic("自定义校验器未实现 binding.StructValidator 接口")
+		}
+		engine.validator = v
+	} else if vc, ok := opts.ValidateConfig.(*binding.ValidateConfig); ok && vc != nil {
+		engine.validator = binding.NewValidator(vc)
+	} else {
+		engine.validator = binding.DefaultValidator()
+	}
+}
+
 // 获取 TLS 连接的下一个协商协议。
 func (engine *Engine) getNextProto(conn network.Conn) (proto string, err error) {
 	if tlsConn, ok := conn.(network.ConnTLSer); ok {
@@ -830,6 +890,20 @@ func newHttp1OptionFromEngine(engine *Engine) *http1.Option {
 	return opt
 }
 
+func newHttp2OptionFromEngine(engine *Engine) *http2.Option {
+	opt := &http2.Option{
+		NoDefaultServerHeader: engine.options.NoDefaultServerHeader,
+		MaxRequestBodySize:    engine.options.MaxRequestBodySize,
+		IdleTimeout:           engine.options.IdleTimeout,
+		ReadTimeout:           engine.options.ReadTimeout,
+		ServerName:            engine.GetServerName(),
+		TLS:                   engine.options.TLS,
+		EnableTrace:           engine.IsTraceEnable(),
+		HTMLRender:            engine.htmlRender,
+	}
+	return opt
+}
+
 func initTrace(engine *Engine) stats.Level {
 	for _, t := range engine.options.Tracers {
 		if col, ok := t.(tracer.Tracer); ok {
@@ -1007,6 +1081,15 @@ func getRemoteAddrFromCloser(conn io.Closer) string {
 	return ""
 }
 
+func containsProto(protos []string, proto string) bool {
+	for _, p := range protos {
+		if p == proto {
+			return true
+		}
+	}
+	return false
+}
+
 func versionToALPN(v uint32) string {
 	if v == network.Version1 || v == network.Version2 {
 		return suite.HTTP3
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/favbox/gosky/wind/pkg/network"
 	"github.com/favbox/gosky/wind/pkg/network/standard"
 	"github.com/favbox/gosky/wind/pkg/protocol/consts"
+	"github.com/favbox/gosky/wind/pkg/protocol/suite"
 )
 
 type mockTransporter struct{}
@@ -467,3 +469,64 @@ func assertRoutePresent(t *testing.T, gets Routes, want Route) {
 func handlerTest1(c context.Context, ctx *app.RequestContext) {}
 
 func handlerTest2(c context.Context, ctx *app.RequestContext) {}
+
+func TestInitHTTP2Server(t *testing.T) {
+	opt := config.NewOptions(nil)
+	opt.H2C = true
+	e := NewEngine(opt)
+	assert.Nil(t, e.Init())
+	_, ok := e.protocolServers[suite.HTTP2]
+	assert.True(t, ok)
+
+	opt = config.NewOptions(nil)
+	opt.ALPN = true
+	opt.TLS = &tls.Config{}
+	e = NewEngine(opt)
+	assert.Nil(t, e.Init())
+	assert.DeepEqual(t, []string{suite.HTTP2, suite.HTTP1}, opt.TLS.NextProtos)
+
+	e = NewEngine(config.NewOptions(nil))
+	assert.Nil(t, e.Init())
+	_, ok = e.protocolServers[suite.HTTP2]
+	assert.False(t, ok)
+}
+
+// 并发调用连接处理函数的传输器。
+type concurrentTransporter struct {
+	mockTransporter
+	conns []any
+	errs  []error
+}
+
+func (m *concurrentTransporter) ListenAndServe(onData network.OnData) error {
+	m.errs = make([]error, len(m.conns))
+	var wg sync.WaitGroup
+	for i, conn := range m.conns {
+		wg.Add(1)
+		go func(i int, conn any) {
+			defer wg.Done()
+			m.errs[i] = onData(context.Background(), conn)
+		}(i, conn)
+	}
+	wg.Wait()
+	return nil
+}
+
+func TestRunConcurrentConns(t *testing.T) {
+	e := NewEngine(config.NewOptions(nil))
+	tr := &concurrentTransporter{}
+	for i := 0; i < 50; i++ {
+		tr.conns = append(tr.conns, mock.NewConn("BAD\r\n\r\n"), struct{}{})
+	}
+	e.transport = tr
+
+	assert.Nil(t, e.Run())
+	// 各连接的错误互不影响
+	for i, err := range tr.errs {
+		if i%2 == 0 {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
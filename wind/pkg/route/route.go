@@ -71,16 +71,19 @@ func (group *RouterGroup) BasePath() string {
 //
 // 例如，所有使用相同鉴权中间件的路由可以分到一个路由组。
 func (group *RouterGroup) Group(relativePath string, handlers ...app.HandlerFunc) *RouterGroup {
-	return &RouterGroup{
+	g := &RouterGroup{
 		Handlers: group.combineHandlers(handlers),
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
 	}
+	g.addPreflightRoute(handlers)
+	return g
 }
 
 // Use 添加给定中间件到该路由组。
 func (group *RouterGroup) Use(middleware ...app.HandlerFunc) Router {
 	group.Handlers = append(group.Handlers, middleware...)
+	group.addPreflightRoute(middleware)
 	return group.asObject()
 }
 
@@ -200,6 +203,38 @@ func (group *RouterGroup) handle(httpMethod, relativePath string, handlers app.H
 	return group.asObject()
 }
 
+// 若给定中间件会自行应答预检请求，则为该组注册 OPTIONS 通配路由，
+// 使预检请求无需另行注册路由即可经过该组的处理链。
+//
+// 根路由组无需注册：全局中间件会经由路由未找到或方法不允许的处理链生效。
+func (group *RouterGroup) addPreflightRoute(middleware app.HandlersChain) {
+	if group.root || group.engine == nil {
+		return
+	}
+	for _, h := range middleware {
+		if !app.IsPreflightHandler(h) {
+			continue
+		}
+		absolutePath := group.calculateAbsolutePath("/*path")
+		if group.engine.preflightPaths[absolutePath] {
+			return
+		}
+		if group.engine.preflightPaths == nil {
+			group.engine.preflightPaths = make(map[string]bool)
+		}
+		group.engine.preflightPaths[absolutePath] = true
+		group.engine.addRoute(consts.MethodOptions, absolutePath, group.combineHandlers(app.HandlersChain{preflightNotFound}))
+		return
+	}
+}
+
+// 预检通配路由的兜底处理器：非预检的 OPTIONS 请求按路由未找到响应。
+func preflightNotFound(c context.Context, ctx *app.RequestContext) {
+	ctx.SetStatusCode(consts.StatusNotFound)
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.Response.SetBody(default404Body)
+}
+
 func (group *RouterGroup) calculateAbsolutePath(relativePath string) string {
 	return joinPaths(group.basePath, relativePath)
 }